@@ -66,6 +66,83 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "410": {
+                        "description": "URL is deleted or expired",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/internal/stats": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get count of short URLs and users in JSON format",
+                "responses": {
+                    "200": {
+                        "description": "Statistics",
+                        "schema": {
+                            "$ref": "#/definitions/app.ResponseStats"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/internal/storage/compact": {
+            "post": {
+                "summary": "Compact file storage to snapshot on demand",
+                "responses": {
+                    "204": {
+                        "description": "Storage compacted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "501": {
+                        "description": "Storage does not support compaction",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
@@ -116,9 +193,15 @@ const docTemplate = `{
                         }
                     },
                     "409": {
-                        "description": "URL exists",
+                        "description": "Alias is already taken",
                         "schema": {
-                            "$ref": "#/definitions/delivery.APIGetOrCreateURL.Response"
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "URL is deleted or expired",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
@@ -174,6 +257,18 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "409": {
+                        "description": "Alias is already taken",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "URL is deleted or expired",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
@@ -183,7 +278,44 @@ const docTemplate = `{
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get user URLs in JSON format",
+                "summary": "Get page of user URLs in JSON format",
+                "parameters": [
+                    {
+                        "maximum": 1000,
+                        "type": "integer",
+                        "description": "Max count of URLs, 100 if empty",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from X-Next-Cursor header of previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Substring of original URL",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return deleted URLs too",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "description": "Sort by creation time",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "URLs created",
@@ -192,6 +324,12 @@ const docTemplate = `{
                             "items": {
                                 "$ref": "#/definitions/app.ResponseUserURL"
                             }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of next page, empty if page is last"
+                            }
                         }
                     },
                     "204": {
@@ -230,7 +368,7 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "summary": "Delete user URLs in JSON format",
                 "parameters": [
@@ -249,9 +387,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "202": {
-                        "description": "Accepted",
+                        "description": "Accepted, deletion job ID",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/app.ResponseDeleteUserURLs"
                         }
                     },
                     "400": {
@@ -271,19 +409,63 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/ping": {
+        "/api/user/urls/delete/{job_id}": {
             "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
+                ],
+                "summary": "Get status of user deletion job in JSON format",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deletion job ID",
+                        "name": "job_id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Ping database",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Status of job and of every URL in it",
+                        "schema": {
+                            "$ref": "#/definitions/app.ResponseDeletionJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
                         "schema": {
                             "type": "string"
                         }
@@ -303,27 +485,33 @@ const docTemplate = `{
                 }
             }
         },
-        "/{url_id}": {
+        "/api/user/urls/export": {
             "get": {
                 "produces": [
-                    "text/html"
+                    "text/csv",
+                    "application/x-ndjson"
                 ],
-                "summary": "Redirect to original URL",
+                "summary": "Export all user URLs including deleted in CSV or NDJSON format",
                 "parameters": [
                     {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
                         "type": "string",
-                        "example": "qwerty",
-                        "description": "URL ID",
-                        "name": "url_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Format of URLs, ndjson if empty",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "307": {
-                        "description": "Redirect to original URL",
+                    "200": {
+                        "description": "User URLs, CSV has header short_url,original_url,is_deleted,created_at",
                         "schema": {
-                            "type": "body"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/app.UserURLRecord"
+                            }
                         }
                     },
                     "400": {
@@ -332,61 +520,746 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "405": {
                         "description": "Method not allowed",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "410": {
-                        "description": "Gone",
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "app.RequestBatchURL": {
-            "type": "object",
-            "properties": {
-                "correlation_id": {
-                    "description": "ID for connect OriginalURL with ShortURL in ResponseBatchURL",
-                    "type": "string"
-                },
-                "original_url": {
-                    "type": "string"
-                }
-            }
-        },
-        "app.ResponseBatchURL": {
-            "type": "object",
-            "properties": {
-                "correlation_id": {
-                    "description": "ID for connect OriginalURL with ShortURL in RequestBatchURL",
-                    "type": "string"
-                },
-                "short_url": {
-                    "type": "string"
-                }
-            }
         },
-        "app.ResponseUserURL": {
-            "type": "object",
-            "properties": {
-                "original_url": {
-                    "type": "string"
-                },
-                "short_url": {
-                    "type": "string"
+        "/api/user/urls/import": {
+            "post": {
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Import user URLs in CSV or NDJSON format",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Format of URLs, Content-Type is used if empty",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "User URLs, CSV must have header with original_url column",
+                        "name": "urls",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/app.UserURLRecord"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Outcome of every row",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/app.ResponseImportUserURL"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
                 }
             }
         },
-        "delivery.APIGetOrCreateURL.Request": {
-            "type": "object",
-            "properties": {
+        "/api/user/urls/restore": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Restore deleted user URLs in JSON format",
+                "parameters": [
+                    {
+                        "description": "URL IDs",
+                        "name": "url_ids",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Status of every URL: restored or ignored",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/app.ResponseRestoreUserURL"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls/{url_id}": {
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Change original URL of user URL in JSON format",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "qwerty",
+                        "description": "URL ID",
+                        "name": "url_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New original URL",
+                        "name": "url",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/delivery.APIUpdateUserURL.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL with history of changes",
+                        "schema": {
+                            "$ref": "#/definitions/app.ResponseUpdateUserURL"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "URL is already shortened with another ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls/{url_id}/history": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get changes of original URL of user URL in JSON format",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "qwerty",
+                        "description": "URL ID",
+                        "name": "url_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Changes of original URL sorted by time",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/app.ResponseURLEdit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/urls/{url_id}/stats": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get user URL statistics in JSON format",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "qwerty",
+                        "description": "URL ID",
+                        "name": "url_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL statistics",
+                        "schema": {
+                            "$ref": "#/definitions/app.ResponseURLStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "Process is alive",
+                        "schema": {
+                            "$ref": "#/definitions/app.ResponseHealthCheck"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ping": {
+            "get": {
+                "produces": [
+                    "text/plain"
+                ],
+                "summary": "Ping database",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "App is ready",
+                        "schema": {
+                            "$ref": "#/definitions/app.ResponseReadiness"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "App is not ready",
+                        "schema": {
+                            "$ref": "#/definitions/app.ResponseReadiness"
+                        }
+                    }
+                }
+            }
+        },
+        "/{url_id}": {
+            "get": {
+                "produces": [
+                    "text/html"
+                ],
+                "summary": "Redirect to original URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "qwerty",
+                        "description": "URL ID",
+                        "name": "url_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "307": {
+                        "description": "Redirect to original URL",
+                        "schema": {
+                            "type": "body"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Method not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "app.RequestBatchURL": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "description": "custom URL ID, generated if empty",
+                    "type": "string"
+                },
+                "correlation_id": {
+                    "description": "ID for connect OriginalURL with ShortURL in ResponseBatchURL",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "absolute expiration time, can't be used with TTL",
+                    "type": "string"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "ttl": {
+                    "description": "lifetime in seconds, can't be used with ExpiresAt",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.ResponseBatchURL": {
+            "type": "object",
+            "properties": {
+                "correlation_id": {
+                    "description": "ID for connect OriginalURL with ShortURL in RequestBatchURL",
+                    "type": "string"
+                },
+                "short_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "app.ResponseDeleteUserURLs": {
+            "type": "object",
+            "properties": {
+                "job_id": {
+                    "description": "ID of deletion job",
+                    "type": "string"
+                }
+            }
+        },
+        "app.ResponseDeletionJob": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "count of failed attempts",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "job_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, done or failed",
+                    "type": "string"
+                },
+                "urls": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.ResponseDeletionJobURL"
+                    }
+                }
+            }
+        },
+        "app.ResponseDeletionJobURL": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, done, failed or ignored",
+                    "type": "string"
+                }
+            }
+        },
+        "app.ResponseHealthCheck": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "reason of failed check",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "ok or fail",
+                    "type": "string"
+                }
+            }
+        },
+        "app.ResponseImportUserURL": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "reason of invalid row",
+                    "type": "string"
+                },
+                "row": {
+                    "description": "number of row starting from 1, header of CSV is not counted",
+                    "type": "integer"
+                },
+                "short_url": {
+                    "description": "short URL of saved URL",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "imported, exists, alias_taken, skipped or invalid",
+                    "type": "string"
+                }
+            }
+        },
+        "app.ResponseReadiness": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/app.ResponseHealthCheck"
+                    }
+                },
+                "status": {
+                    "description": "ok if all checks passed, otherwise fail",
+                    "type": "string"
+                }
+            }
+        },
+        "app.ResponseRestoreUserURL": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "restored or ignored",
+                    "type": "string"
+                }
+            }
+        },
+        "app.ResponseStats": {
+            "type": "object",
+            "properties": {
+                "urls": {
+                    "description": "count of short URLs",
+                    "type": "integer"
+                },
+                "users": {
+                    "description": "count of users",
+                    "type": "integer"
+                }
+            }
+        },
+        "app.ResponseURLEdit": {
+            "type": "object",
+            "properties": {
+                "edited_at": {
+                    "type": "string"
+                },
+                "new_url": {
+                    "type": "string"
+                },
+                "old_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "app.ResponseURLStats": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "is_deleted": {
+                    "type": "boolean"
+                },
+                "last_accessed_at": {
+                    "type": "string"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "short_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "app.ResponseUpdateUserURL": {
+            "type": "object",
+            "properties": {
+                "history": {
+                    "description": "changes of original URL sorted by time",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/app.ResponseURLEdit"
+                    }
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "short_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "app.ResponseUserURL": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "deleted URL is purged after grace period since this time",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "is_deleted": {
+                    "type": "boolean"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "short_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "app.UserURLRecord": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "ignored by import",
+                    "type": "string"
+                },
+                "is_deleted": {
+                    "type": "boolean"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "short_url": {
+                    "description": "short ID is last path segment, new ID is generated if empty",
+                    "type": "string"
+                }
+            }
+        },
+        "delivery.APIGetOrCreateURL.Request": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "description": "custom URL ID, generated if empty",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "absolute expiration time, can't be used with TTL",
+                    "type": "string"
+                },
+                "ttl": {
+                    "description": "lifetime in seconds, can't be used with ExpiresAt",
+                    "type": "integer"
+                },
                 "url": {
                     "type": "string"
                 }
@@ -399,6 +1272,14 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "delivery.APIUpdateUserURL.Request": {
+            "type": "object",
+            "properties": {
+                "url": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
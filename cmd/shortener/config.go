@@ -1,20 +1,30 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"net"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 )
 
 // Config config data for app.
 type Config struct {
 	// Адрес запуска HTTP-сервера. Пример: localhost:8080
 	ServerAddress string `env:"SERVER_ADDRESS" mapstructure:"server_address"` // address to start the server
+	// Адрес запуска gRPC-сервера. Пример: localhost:3200
+	GRPCServerAddress string `env:"GRPC_SERVER_ADDRESS" mapstructure:"grpc_server_address"` // address to start the gRPC server
 	// Базовый адрес результирующего сокращённого URL
 	// Требования:
 	//     - Должен быть указан протокол (по умолчанию автоматически добавится http://): http/https
@@ -23,11 +33,56 @@ type Config struct {
 	BaseURL         string `env:"BASE_URL" mapstructure:"base_url"` // short URLs will be returned with this host
 	LogLevel        string `env:"LOG_LEVEL" mapstructure:"log_level"`
 	FileStoragePath string `env:"FILE_STORAGE_PATH" mapstructure:"file_storage_path"`
-	DatabaseDSN     string `env:"DATABASE_DSN" mapstructure:"database_dsn"`
-	EnableHTTPS     bool   `env:"ENABLE_HTTPS" mapstructure:"enable_https"`
-	Config          string `env:"CONFIG"`
+	// Файл отметок об удалении URL. По умолчанию deleted-url-db.json в директории FileStoragePath
+	DeletedURLsFileStoragePath string `env:"DELETED_URLS_FILE_STORAGE_PATH" mapstructure:"deleted_urls_file_storage_path"`
+	// Файл статистики переходов по URL. По умолчанию clicks-url-db.json в директории FileStoragePath
+	ClicksFileStoragePath string `env:"CLICKS_FILE_STORAGE_PATH" mapstructure:"clicks_file_storage_path"`
+	// Журнал задач удаления URL. По умолчанию deletion-job-db.json в директории FileStoragePath
+	DeletionJobsFileStoragePath string `env:"DELETION_JOBS_FILE_STORAGE_PATH" mapstructure:"deletion_jobs_file_storage_path"`
+	// Файл пользователей. По умолчанию user-db.json в директории FileStoragePath
+	UsersFileStoragePath string `env:"USERS_FILE_STORAGE_PATH" mapstructure:"users_file_storage_path"`
+	// Период сжатия файлового хранилища в снапшот. Отрицательное значение отключает периодическое сжатие. Пример: 1h
+	FileStorageCompactionInterval time.Duration `env:"FILE_STORAGE_COMPACTION_INTERVAL" mapstructure:"file_storage_compaction_interval"`
+	// Режим сброса файлового хранилища на диск: always (после каждой записи), interval (периодически), never (решает ОС)
+	FileStorageSync string `env:"FILE_STORAGE_SYNC" mapstructure:"file_storage_sync"`
+	// Период сброса файлового хранилища на диск в режиме interval. Пример: 1s
+	FileStorageSyncInterval time.Duration `env:"FILE_STORAGE_SYNC_INTERVAL" mapstructure:"file_storage_sync_interval"`
+	// DSN PostgreSQL или путь к файлу SQLite со схемой sqlite://. Пример: sqlite:///var/lib/shortener.db
+	DatabaseDSN string `env:"DATABASE_DSN" mapstructure:"database_dsn"`
+	// Не применять миграции при запуске (миграции применяются командой migrate up)
+	SkipMigrations bool   `env:"SKIP_MIGRATIONS" mapstructure:"skip_migrations"`
+	EnableHTTPS    bool   `env:"ENABLE_HTTPS" mapstructure:"enable_https"`
+	TrustedSubnet  string `env:"TRUSTED_SUBNET" mapstructure:"trusted_subnet"` // CIDR allowed to get /api/internal/stats
+	// Экспортёр трейсов: none, stdout, file, otlp
+	TraceExporter string `env:"TRACE_EXPORTER" mapstructure:"trace_exporter"`
+	// Адрес OTLP-коллектора (gRPC). Пример: localhost:4317
+	TraceEndpoint string `env:"TRACE_ENDPOINT" mapstructure:"trace_endpoint"`
+	// Файл трейсов для экспортёра file
+	TraceFile string `env:"TRACE_FILE" mapstructure:"trace_file"`
+	// Максимальная длительность одного запроса к БД. Пример: 5s
+	QueryTimeout time.Duration `env:"QUERY_TIMEOUT" mapstructure:"query_timeout"`
+	// Максимальная длительность graceful shutdown. Пример: 30s
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" mapstructure:"shutdown_timeout"`
+	// Период, в течение которого удалённый URL можно восстановить, затем он удаляется окончательно.
+	// Отрицательное значение отключает окончательное удаление. Пример: 720h
+	DeletedURLsGracePeriod time.Duration `env:"DELETED_URLS_GRACE_PERIOD" mapstructure:"deleted_urls_grace_period"`
+	// Время хранения выполненных и исчерпавших попытки задач на удаление URL, затем они удаляются.
+	// Отрицательное значение отключает удаление задач. Пример: 168h
+	DeletionJobsRetention time.Duration `env:"DELETION_JOBS_RETENTION" mapstructure:"deletion_jobs_retention"`
+	// Период удаления устаревших задач на удаление URL. Пример: 1h
+	DeletionJobsPurgeInterval time.Duration `env:"DELETION_JOBS_PURGE_INTERVAL" mapstructure:"deletion_jobs_purge_interval"`
+	// Область уникальности исходного URL: global (один короткий URL на всех пользователей), user (у каждого пользователя свой)
+	URLUniqueness string `env:"URL_UNIQUENESS" mapstructure:"url_uniqueness"`
+	Config        string `env:"CONFIG"`
 }
 
+// Errors for file storage config.
+var (
+	ErrFileStoragePathsCollide = errors.New("file storage paths must be different") // two kinds of records are written in one file
+	ErrFileStorageNotWritable  = errors.New("file storage path is not writable")    // file or its dir can not be written
+	ErrFileStoragePathIsDir    = errors.New("path is a directory")                  // path of file is existing dir
+)
+
 func readConfigFile(c *Config) error {
 	v := viper.New()
 
@@ -38,6 +93,10 @@ func readConfigFile(c *Config) error {
 	if err != nil {
 		return err
 	}
+	err = v.BindPFlag("grpc_server_address", pflag.Lookup("g"))
+	if err != nil {
+		return err
+	}
 	err = v.BindPFlag("base_url", pflag.Lookup("b"))
 	if err != nil {
 		return err
@@ -50,14 +109,86 @@ func readConfigFile(c *Config) error {
 	if err != nil {
 		return err
 	}
+	err = v.BindPFlag("deleted_urls_file_storage_path", pflag.Lookup("deleted-urls-file-storage-path"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("clicks_file_storage_path", pflag.Lookup("clicks-file-storage-path"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("deletion_jobs_file_storage_path", pflag.Lookup("deletion-jobs-file-storage-path"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("users_file_storage_path", pflag.Lookup("users-file-storage-path"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("file_storage_compaction_interval", pflag.Lookup("file-storage-compaction-interval"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("file_storage_sync", pflag.Lookup("file-storage-sync"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("file_storage_sync_interval", pflag.Lookup("file-storage-sync-interval"))
+	if err != nil {
+		return err
+	}
 	err = v.BindPFlag("database_dsn", pflag.Lookup("d"))
 	if err != nil {
 		return err
 	}
+	err = v.BindPFlag("skip_migrations", pflag.Lookup("skip-migrations"))
+	if err != nil {
+		return err
+	}
 	err = v.BindPFlag("enable_https", pflag.Lookup("s"))
 	if err != nil {
 		return err
 	}
+	err = v.BindPFlag("trusted_subnet", pflag.Lookup("t"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("trace_exporter", pflag.Lookup("trace-exporter"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("trace_endpoint", pflag.Lookup("trace-endpoint"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("trace_file", pflag.Lookup("trace-file"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("query_timeout", pflag.Lookup("query-timeout"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("shutdown_timeout", pflag.Lookup("shutdown-timeout"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("deleted_urls_grace_period", pflag.Lookup("deleted-urls-grace-period"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("deletion_jobs_retention", pflag.Lookup("deletion-jobs-retention"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("deletion_jobs_purge_interval", pflag.Lookup("deletion-jobs-purge-interval"))
+	if err != nil {
+		return err
+	}
+	err = v.BindPFlag("url_uniqueness", pflag.Lookup("url-uniqueness"))
+	if err != nil {
+		return err
+	}
 
 	v.SetConfigFile(c.Config)
 	v.AutomaticEnv()
@@ -80,11 +211,30 @@ func NewConfig() (*Config, error) {
 	c := &Config{}
 
 	flag.StringVar(&c.ServerAddress, "a", "", "Server address")
+	flag.StringVar(&c.GRPCServerAddress, "g", "", "gRPC server address")
 	flag.StringVar(&c.BaseURL, "b", "", "Base URL")
 	flag.StringVar(&c.LogLevel, "l", "", "Log level")
 	flag.StringVar(&c.FileStoragePath, "f", "", "File storage path")
+	flag.StringVar(&c.DeletedURLsFileStoragePath, "deleted-urls-file-storage-path", "", "Deleted URLs file storage path, default is in dir of file storage path")
+	flag.StringVar(&c.ClicksFileStoragePath, "clicks-file-storage-path", "", "Clicks file storage path, default is in dir of file storage path")
+	flag.StringVar(&c.DeletionJobsFileStoragePath, "deletion-jobs-file-storage-path", "", "Deletion jobs file storage path, default is in dir of file storage path")
+	flag.StringVar(&c.UsersFileStoragePath, "users-file-storage-path", "", "Users file storage path, default is in dir of file storage path")
+	flag.DurationVar(&c.FileStorageCompactionInterval, "file-storage-compaction-interval", 0, "File storage compaction interval, negative disables compaction")
+	flag.StringVar(&c.FileStorageSync, "file-storage-sync", "", "File storage sync mode: always, interval, never")
+	flag.DurationVar(&c.FileStorageSyncInterval, "file-storage-sync-interval", 0, "File storage sync interval in interval mode")
 	flag.StringVar(&c.DatabaseDSN, "d", "", "Database DSN")
+	flag.BoolVar(&c.SkipMigrations, "skip-migrations", false, "Skip applying migrations on startup")
 	flag.BoolVar(&c.EnableHTTPS, "s", false, "Enable HTTPS")
+	flag.StringVar(&c.TrustedSubnet, "t", "", "Trusted subnet (CIDR)")
+	flag.StringVar(&c.TraceExporter, "trace-exporter", "", "Trace exporter: none, stdout, file, otlp")
+	flag.StringVar(&c.TraceEndpoint, "trace-endpoint", "", "OTLP trace collector endpoint")
+	flag.StringVar(&c.TraceFile, "trace-file", "", "Trace file path")
+	flag.DurationVar(&c.QueryTimeout, "query-timeout", 0, "DB query timeout")
+	flag.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", 0, "Graceful shutdown timeout")
+	flag.DurationVar(&c.DeletedURLsGracePeriod, "deleted-urls-grace-period", 0, "Period to restore deleted URLs before they are purged, negative disables purging")
+	flag.DurationVar(&c.DeletionJobsRetention, "deletion-jobs-retention", 0, "Period to keep finished deletion jobs before they are removed, negative disables removing")
+	flag.DurationVar(&c.DeletionJobsPurgeInterval, "deletion-jobs-purge-interval", 0, "Interval of removing finished deletion jobs")
+	flag.StringVar(&c.URLUniqueness, "url-uniqueness", "", "Scope of original URL uniqueness: global, user")
 	flag.StringVar(&c.Config, "c", "", "Config path")
 	flag.Parse()
 
@@ -114,25 +264,94 @@ func NewConfig() (*Config, error) {
 		c.EnableHTTPS = true
 	}
 
-	// Если не ввели -a, -b, -l, -f то значения по-умолчанию
+	// Если не ввели -a, -g, -b, -l, -f то значения по-умолчанию
 	if c.ServerAddress == "" {
 		c.ServerAddress = Addr
 	}
+	if c.GRPCServerAddress == "" {
+		c.GRPCServerAddress = GRPCAddr
+	}
 	if c.BaseURL == "" {
 		c.BaseURL = ResultAddrPrefix
 	}
 	if c.LogLevel == "" {
 		c.LogLevel = LogLevel
 	}
+	if c.TraceExporter == "" {
+		c.TraceExporter = TraceExporter
+	}
+	if c.TraceFile == "" {
+		c.TraceFile = TraceFilePath
+	}
+	if c.QueryTimeout == 0 {
+		c.QueryTimeout = QueryTimeout
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = ShutdownTimeout
+	}
+	if c.DeletedURLsGracePeriod == 0 {
+		c.DeletedURLsGracePeriod = DeletedURLsGracePeriod
+	}
+	if c.DeletionJobsRetention == 0 {
+		c.DeletionJobsRetention = DeletionJobsRetention
+	}
+	if c.DeletionJobsPurgeInterval == 0 {
+		c.DeletionJobsPurgeInterval = DeletionJobsPurgeInterval
+	}
+	if c.URLUniqueness == "" {
+		c.URLUniqueness = URLUniqueness
+	}
+	if c.FileStorageCompactionInterval == 0 {
+		c.FileStorageCompactionInterval = FileStorageCompactionInterval
+	}
+	if c.FileStorageSync == "" {
+		c.FileStorageSync = FileStorageSync
+	}
+	if c.FileStorageSyncInterval == 0 {
+		c.FileStorageSyncInterval = FileStorageSyncInterval
+	}
 	if !foundFlagFileStoragePath && !foundEnvFileStoragePath {
 		c.FileStoragePath = URLsFileStoragePath
 	}
+	// остальные файлы хранилища по умолчанию лежат рядом с файлом URL
+	if c.FileStoragePath != "" {
+		dir := filepath.Dir(c.FileStoragePath)
+		if c.DeletedURLsFileStoragePath == "" {
+			c.DeletedURLsFileStoragePath = filepath.Join(dir, DeletedURLsFileStorageName)
+		}
+		if c.ClicksFileStoragePath == "" {
+			c.ClicksFileStoragePath = filepath.Join(dir, ClicksFileStorageName)
+		}
+		if c.DeletionJobsFileStoragePath == "" {
+			c.DeletionJobsFileStoragePath = filepath.Join(dir, DeletionJobsFileStorageName)
+		}
+		if c.UsersFileStoragePath == "" {
+			c.UsersFileStoragePath = filepath.Join(dir, UsersFileStorageName)
+		}
+	}
 
 	_, err = url.ParseRequestURI(c.BaseURL)
 	if err != nil {
 		return nil, err
 	}
 
+	err = filestorage.SyncPolicy{Mode: c.FileStorageSync, Interval: c.FileStorageSyncInterval}.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.ValidateURLUniqueness(c.URLUniqueness)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.TrustedSubnet != "" {
+		_, _, err = net.ParseCIDR(c.TrustedSubnet)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if !strings.HasPrefix(c.BaseURL, "http://") && !c.EnableHTTPS {
 		c.BaseURL = "http://" + c.BaseURL
 	} else if !strings.HasPrefix(c.BaseURL, "https://") && c.EnableHTTPS {
@@ -144,3 +363,70 @@ func NewConfig() (*Config, error) {
 
 	return c, nil
 }
+
+// CheckFileStoragePaths checks that files of file storage are different and can be written with their dirs.
+// Dirs must be writable, because files are replaced by compaction. Files are not used if DB is set.
+func (c *Config) CheckFileStoragePaths() error {
+	if c.DatabaseDSN != "" {
+		return nil
+	}
+
+	paths := []struct {
+		name string
+		path string
+	}{
+		{name: "FILE_STORAGE_PATH", path: c.FileStoragePath},
+		{name: "DELETED_URLS_FILE_STORAGE_PATH", path: c.DeletedURLsFileStoragePath},
+		{name: "CLICKS_FILE_STORAGE_PATH", path: c.ClicksFileStoragePath},
+		{name: "DELETION_JOBS_FILE_STORAGE_PATH", path: c.DeletionJobsFileStoragePath},
+		{name: "USERS_FILE_STORAGE_PATH", path: c.UsersFileStoragePath},
+	}
+
+	names := make(map[string]string, len(paths))
+	for _, p := range paths {
+		if p.path == "" {
+			continue
+		}
+
+		absPath, err := filepath.Abs(p.path)
+		if err != nil {
+			return fmt.Errorf("%w: %s=%s: %v", ErrFileStorageNotWritable, p.name, p.path, err)
+		}
+		if name, ok := names[absPath]; ok {
+			return fmt.Errorf("%w: %s and %s are %s", ErrFileStoragePathsCollide, name, p.name, p.path)
+		}
+		names[absPath] = p.name
+
+		err = checkWritable(p.path)
+		if err != nil {
+			return fmt.Errorf("%w: %s=%s: %v", ErrFileStorageNotWritable, p.name, p.path, err)
+		}
+	}
+
+	return nil
+}
+
+// checkWritable checks that existing file can be opened for appending and temp file can be created in its dir.
+func checkWritable(path string) error {
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		return ErrFileStoragePathIsDir
+	case err == nil:
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		if err = file.Close(); err != nil {
+			return err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".check-*")
+	if err != nil {
+		return err
+	}
+	return errors.Join(file.Close(), os.Remove(file.Name()))
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -9,13 +12,32 @@ import (
 
 func TestNewConfig(t *testing.T) {
 	expectedConfig := &Config{
-		ServerAddress:   "localhost:8080",
-		BaseURL:         "http://localhost:8080/",
-		LogLevel:        "INFO",
-		FileStoragePath: "/tmp/short-url-db.json",
-		DatabaseDSN:     "",
-		EnableHTTPS:     false,
-		Config:          "",
+		ServerAddress:                 "localhost:8080",
+		GRPCServerAddress:             "localhost:3200",
+		BaseURL:                       "http://localhost:8080/",
+		LogLevel:                      "INFO",
+		FileStoragePath:               "/tmp/short-url-db.json",
+		DeletedURLsFileStoragePath:    "/tmp/deleted-url-db.json",
+		ClicksFileStoragePath:         "/tmp/clicks-url-db.json",
+		DeletionJobsFileStoragePath:   "/tmp/deletion-job-db.json",
+		UsersFileStoragePath:          "/tmp/user-db.json",
+		FileStorageCompactionInterval: time.Hour,
+		FileStorageSync:               "interval",
+		FileStorageSyncInterval:       time.Second,
+		DatabaseDSN:                   "",
+		SkipMigrations:                false,
+		EnableHTTPS:                   false,
+		TrustedSubnet:                 "",
+		TraceExporter:                 "none",
+		TraceEndpoint:                 "",
+		TraceFile:                     "/tmp/shortener-trace.json",
+		QueryTimeout:                  5 * time.Second,
+		ShutdownTimeout:               30 * time.Second,
+		DeletedURLsGracePeriod:        30 * 24 * time.Hour,
+		DeletionJobsRetention:         7 * 24 * time.Hour,
+		DeletionJobsPurgeInterval:     time.Hour,
+		URLUniqueness:                 "global",
+		Config:                        "",
 	}
 
 	config, err := NewConfig()
@@ -25,3 +47,88 @@ func TestNewConfig(t *testing.T) {
 
 	t.TempDir()
 }
+
+func TestConfig_CheckFileStoragePaths(t *testing.T) {
+	dir := t.TempDir()
+	existingFile := filepath.Join(dir, "existing.json")
+	err := os.WriteFile(existingFile, nil, 0666)
+	require.NoError(t, err)
+
+	validConfig := func() *Config {
+		return &Config{
+			FileStoragePath:             existingFile,
+			DeletedURLsFileStoragePath:  filepath.Join(dir, "deleted.json"),
+			ClicksFileStoragePath:       filepath.Join(dir, "clicks.json"),
+			DeletionJobsFileStoragePath: filepath.Join(dir, "jobs.json"),
+			UsersFileStoragePath:        filepath.Join(dir, "users.json"),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		wantErr error
+	}{
+		{
+			name:   "valid paths",
+			change: func(c *Config) {},
+		},
+		{
+			name: "without file storage",
+			change: func(c *Config) {
+				c.FileStoragePath = ""
+				c.DeletedURLsFileStoragePath = ""
+				c.ClicksFileStoragePath = ""
+				c.DeletionJobsFileStoragePath = ""
+				c.UsersFileStoragePath = ""
+			},
+		},
+		{
+			name: "DB is used instead of files",
+			change: func(c *Config) {
+				c.DatabaseDSN = "sqlite://" + filepath.Join(dir, "db.sqlite")
+				c.UsersFileStoragePath = filepath.Join(dir, "not_exists", "users.json")
+			},
+		},
+		{
+			name: "the same file",
+			change: func(c *Config) {
+				c.UsersFileStoragePath = filepath.Join(dir, ".", "existing.json")
+			},
+			wantErr: ErrFileStoragePathsCollide,
+		},
+		{
+			name: "dir does not exist",
+			change: func(c *Config) {
+				c.UsersFileStoragePath = filepath.Join(dir, "not_exists", "users.json")
+			},
+			wantErr: ErrFileStorageNotWritable,
+		},
+		{
+			name: "path is dir",
+			change: func(c *Config) {
+				c.DeletedURLsFileStoragePath = dir
+			},
+			wantErr: ErrFileStorageNotWritable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.change(c)
+
+			err := c.CheckFileStoragePaths()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			// check does not leave temp files
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
@@ -9,20 +9,25 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
 	appDeliveryInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/delivery"
 	"github.com/MisterMaks/go-yandex-shortener/internal/app/delivery/mocks"
 	"github.com/MisterMaks/go-yandex-shortener/internal/gzip"
 	"github.com/MisterMaks/go-yandex-shortener/internal/logger"
+	"github.com/MisterMaks/go-yandex-shortener/internal/metrics"
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
 	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
 	"github.com/golang/mock/gomock"
 )
 
 func newExampleServer(m *mocks.MockAppUsecaseInterface) (*httptest.Server, error) {
-	appHandler := appDeliveryInternal.NewAppHandler(m)
+	appHandler := appDeliveryInternal.NewAppHandler(m, nil)
 
 	middlewares := &Middlewares{
+		Metrics:        metrics.Middleware,
+		Tracing:        tracing.Middleware,
 		RequestLogger:  logger.RequestLogger,
 		GzipMiddleware: gzip.GzipMiddleware,
 		AuthenticateOrRegister: func(h http.Handler) http.Handler {
@@ -37,6 +42,7 @@ func newExampleServer(m *mocks.MockAppUsecaseInterface) (*httptest.Server, error
 				h.ServeHTTP(w, r.WithContext(ctx))
 			})
 		},
+		TrustedSubnet: func(h http.Handler) http.Handler { return h },
 	}
 
 	u, err := url.ParseRequestURI(ResultAddrPrefix)
@@ -64,29 +70,36 @@ func newExampleMock() *mocks.MockAppUsecaseInterface {
 
 	m := mocks.NewMockAppUsecaseInterface(ctrl)
 
-	m.EXPECT().GetOrCreateURL(TestValidURL, TestUserID).Return(&app.URL{
+	m.EXPECT().GetOrCreateURL(gomock.Any(), TestValidURL, "", nil, TestUserID).Return(&app.URL{
 		ID:        TestID,
 		URL:       TestValidURL,
 		UserID:    TestUserID,
 		IsDeleted: false,
 	}, false, nil).AnyTimes()
 	m.EXPECT().GenerateShortURL(TestID).Return("http://localhost:8080/" + TestID).AnyTimes()
-	m.EXPECT().GetURL(TestID).Return(&app.URL{
+	m.EXPECT().GetURL(gomock.Any(), TestID).Return(&app.URL{
 		ID:        TestID,
 		URL:       TestValidURL,
 		UserID:    TestUserID,
 		IsDeleted: false,
 	}, nil)
-	m.EXPECT().GetOrCreateURLs([]app.RequestBatchURL{
+	m.EXPECT().SendURLClickInChan(TestID)
+	m.EXPECT().GetOrCreateURLs(gomock.Any(), []app.RequestBatchURL{
 		{CorrelationID: TestID, OriginalURL: TestValidURL},
 	}, TestUserID).Return([]app.ResponseBatchURL{
 		{CorrelationID: TestID, ShortURL: "http://localhost:8080/" + TestID},
 	}, nil).AnyTimes()
-	m.EXPECT().GetUserURLs(TestUserID).Return([]app.ResponseUserURL{
+	m.EXPECT().GetUserURLs(gomock.Any(), TestUserID, app.UserURLsParams{}).Return([]app.ResponseUserURL{
 		{ShortURL: "http://localhost:8080/" + TestID, OriginalURL: TestValidURL},
+	}, "", nil).AnyTimes()
+	m.EXPECT().EnqueueDeleteUserURLs(gomock.Any(), TestUserID, []string{TestID}).Return(TestJobID, nil).AnyTimes()
+	m.EXPECT().GetDeletionJob(gomock.Any(), TestJobID, TestUserID).Return(&app.ResponseDeletionJob{
+		JobID:     TestJobID,
+		Status:    app.DeletionJobDone,
+		CreatedAt: time.Date(2024, 10, 16, 15, 0, 0, 0, time.UTC),
+		URLs:      []app.ResponseDeletionJobURL{{ID: TestID, Status: app.DeletionJobDone}},
 	}, nil).AnyTimes()
-	m.EXPECT().SendDeleteUserURLsInChan(TestUserID, []string{TestID}).AnyTimes()
-	m.EXPECT().Ping().Return(nil).AnyTimes()
+	m.EXPECT().Ping(gomock.Any()).Return(nil).AnyTimes()
 
 	return m
 }
@@ -262,12 +275,48 @@ func Example() {
 		fmt.Println("Error:", err)
 		return
 	}
+
+	respBody, err = io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err = resp.Body.Close(); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	respBodyStr = string(respBody)
+	fmt.Println(resp.StatusCode)
+	fmt.Println(respBodyStr)
+
+	fmt.Println("Get deletion job status:")
+	apiGetDeletionJobURL := serverAddr + "/api/user/urls/delete/" + TestJobID
+	req, err = http.NewRequest(http.MethodGet, apiGetDeletionJobURL, bytes.NewReader(nil))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	resp, err = ts.Client().Do(req)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	respBody, err = io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
 	if err = resp.Body.Close(); err != nil {
 		fmt.Println("Error:", err)
 		return
 	}
 
+	respBodyStr = string(respBody)
 	fmt.Println(resp.StatusCode)
+	fmt.Println(respBodyStr)
 
 	fmt.Println("Ping DB:")
 	pingURL := serverAddr + "/ping"
@@ -310,6 +359,12 @@ func Example() {
 	//
 	// Delete user URLs:
 	// 202
+	// {"job_id":"job"}
+	//
+	// Get deletion job status:
+	// 200
+	// {"job_id":"job","status":"done","attempts":0,"created_at":"2024-10-16T15:00:00Z","urls":[{"id":"1","status":"done"}]}
+	//
 	// Ping DB:
 	// 200
 }
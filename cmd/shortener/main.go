@@ -4,8 +4,10 @@ import (
 	"context"
 	"crypto/tls"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -15,28 +17,37 @@ import (
 	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/api"
+	pb "github.com/MisterMaks/go-yandex-shortener/api/proto"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
 	appDeliveryInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/delivery"
 	appRepoInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/repo"
 	appUsecaseInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
 	"github.com/MisterMaks/go-yandex-shortener/internal/certcreator"
+	"github.com/MisterMaks/go-yandex-shortener/internal/database"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 	"github.com/MisterMaks/go-yandex-shortener/internal/gzip"
 	"github.com/MisterMaks/go-yandex-shortener/internal/logger"
+	"github.com/MisterMaks/go-yandex-shortener/internal/metrics"
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
+	"github.com/MisterMaks/go-yandex-shortener/internal/trustedsubnet"
 	userRepoInternal "github.com/MisterMaks/go-yandex-shortener/internal/user/repo"
 	userUsecaseInternal "github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
 	"github.com/go-chi/chi/v5"
-	_ "github.com/jackc/pgx/v5/stdlib"
-	"github.com/pressly/goose/v3"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // App constants.
 const (
 	Addr                          string = "localhost:8080"
+	GRPCAddr                      string = "localhost:3200"
 	ResultAddrPrefix              string = "localhost:8080"
 	URLsFileStoragePath           string = "/tmp/short-url-db.json"
-	DeletedURLsFileStoragePath    string = "/tmp/deleted-url-db.json"
-	UsersFileStoragePath          string = "/tmp/user-db.json"
+	DeletedURLsFileStorageName    string = "deleted-url-db.json"
+	ClicksFileStorageName         string = "clicks-url-db.json"
+	DeletionJobsFileStorageName   string = "deletion-job-db.json"
+	UsersFileStorageName          string = "user-db.json"
 	CountRegenerationsForLengthID uint   = 5
 	LengthID                      uint   = 5
 	MaxLengthID                   uint   = 20
@@ -44,7 +55,26 @@ const (
 	SecretKey                     string = "supersecretkey"
 	TokenExp                             = time.Hour * 3
 	DeleteURLsWaitingTime                = 5 * time.Second
-	DeleteURLsChanSize            uint   = 1024
+	DeleteURLsBatchSize           uint   = 100
+	DeleteURLsMaxAttempts         uint   = 5
+	DeleteURLsRetryBaseDelay             = 5 * time.Second
+	DeleteURLsRetryMaxDelay              = 5 * time.Minute
+	DeleteExpiredURLsWaitingTime         = time.Minute
+	PurgeDeletedURLsWaitingTime          = time.Hour
+	DeletedURLsGracePeriod               = 30 * 24 * time.Hour
+	DeletionJobsRetention                = 7 * 24 * time.Hour
+	DeletionJobsPurgeInterval            = time.Hour
+	ClicksWaitingTime                    = 5 * time.Second
+	ClicksChanSize                uint   = 1024
+	TraceExporter                 string = tracing.ExporterNone
+	TraceFilePath                 string = "/tmp/shortener-trace.json"
+	TracingShutdownTimeout               = 5 * time.Second
+	QueryTimeout                         = 5 * time.Second
+	ShutdownTimeout                      = 30 * time.Second
+	FileStorageCompactionInterval        = time.Hour
+	FileStorageSync               string = filestorage.SyncInterval
+	FileStorageSyncInterval              = time.Second
+	URLUniqueness                 string = app.URLUniquenessGlobal
 
 	ConfigKey string = "config"
 	AddrKey   string = "addr"
@@ -74,39 +104,37 @@ func printBuildInfo() {
 	}
 }
 
-func migrate(dsn string) error {
-	db, err := goose.OpenDBWithDriver("postgres", dsn)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			logger.Log.Fatal("Failed to close DB",
-				zap.Error(err),
-			)
-		}
-	}()
-	ctx := context.Background()
-	return goose.RunContext(ctx, "up", db, "./migrations/")
-}
-
 // AppHandlerInterface contains the necessary functions for the handlers of app.
 type AppHandlerInterface interface {
 	GetOrCreateURL(w http.ResponseWriter, r *http.Request)
 	APIGetOrCreateURL(w http.ResponseWriter, r *http.Request)
 	RedirectToURL(w http.ResponseWriter, r *http.Request)
 	Ping(w http.ResponseWriter, r *http.Request)
+	Healthz(w http.ResponseWriter, r *http.Request)
+	Readyz(w http.ResponseWriter, r *http.Request)
 	APIGetOrCreateURLs(w http.ResponseWriter, r *http.Request)
 	APIGetUserURLs(w http.ResponseWriter, r *http.Request)
 	APIDeleteUserURLs(w http.ResponseWriter, r *http.Request)
+	APIGetDeletionJob(w http.ResponseWriter, r *http.Request)
+	APIRestoreUserURLs(w http.ResponseWriter, r *http.Request)
+	APIExportUserURLs(w http.ResponseWriter, r *http.Request)
+	APIImportUserURLs(w http.ResponseWriter, r *http.Request)
+	APIGetURLStats(w http.ResponseWriter, r *http.Request)
+	APIUpdateUserURL(w http.ResponseWriter, r *http.Request)
+	APIGetUserURLHistory(w http.ResponseWriter, r *http.Request)
+	APIGetStats(w http.ResponseWriter, r *http.Request)
+	APICompactStorage(w http.ResponseWriter, r *http.Request)
 }
 
 // Middlewares used middlewares.
 type Middlewares struct {
+	Metrics                func(http.Handler) http.Handler
+	Tracing                func(http.Handler) http.Handler
 	RequestLogger          func(http.Handler) http.Handler
 	GzipMiddleware         func(http.Handler) http.Handler
 	Authenticate           func(http.Handler) http.Handler
 	AuthenticateOrRegister func(http.Handler) http.Handler
+	TrustedSubnet          func(http.Handler) http.Handler
 }
 
 func shortenerRouter(
@@ -122,7 +150,7 @@ func shortenerRouter(
 	}
 
 	r := chi.NewRouter()
-	r.Use(middlewares.RequestLogger)
+	r.Use(middlewares.Metrics, middlewares.Tracing, middlewares.RequestLogger)
 
 	api.SwaggerInfo.Host = baseURL.Host
 	api.SwaggerInfo.Schemes = []string{"http", "https"}
@@ -131,6 +159,9 @@ func shortenerRouter(
 	redirectPathPrefix := strings.TrimPrefix(baseURL.Path, "/")
 	r.Get(`/`+redirectPathPrefix+`{id}`, appHandler.RedirectToURL)
 	r.Get(`/ping`, appHandler.Ping)
+	r.Get(`/healthz`, appHandler.Healthz)
+	r.Get(`/readyz`, appHandler.Readyz)
+	r.Method(http.MethodGet, `/metrics`, metrics.Handler())
 	r.Route(`/`, func(r chi.Router) {
 		r.Use(middlewares.GzipMiddleware, middlewares.AuthenticateOrRegister)
 		r.Post(`/`, appHandler.GetOrCreateURL)
@@ -141,23 +172,63 @@ func shortenerRouter(
 		r.Use(middlewares.Authenticate)
 		r.Get(`/`, appHandler.APIGetUserURLs)
 		r.Delete(`/`, appHandler.APIDeleteUserURLs)
+		r.Get(`/delete/{job_id}`, appHandler.APIGetDeletionJob)
+		r.Post(`/restore`, appHandler.APIRestoreUserURLs)
+		r.Get(`/export`, appHandler.APIExportUserURLs)
+		r.Post(`/import`, appHandler.APIImportUserURLs)
+		r.Get(`/{id}/stats`, appHandler.APIGetURLStats)
+		r.Patch(`/{id}`, appHandler.APIUpdateUserURL)
+		r.Get(`/{id}/history`, appHandler.APIGetUserURLHistory)
+	})
+	r.Route(`/api/internal`, func(r chi.Router) {
+		r.Use(middlewares.TrustedSubnet)
+		r.Get(`/stats`, appHandler.APIGetStats)
+		r.Post(`/storage/compact`, appHandler.APICompactStorage)
 	})
 
 	return r, nil
 }
 
-func connectPostgres(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("pgx", dsn)
+// reservedIDs returns first path segments of routes after base path of short URLs.
+// Short URLs with these IDs are handled by routes instead of redirect.
+func reservedIDs(r chi.Routes, baseURL *url.URL) ([]string, error) {
+	prefix := "/" + strings.TrimPrefix(baseURL.Path, "/")
+	ids := []string{}
+	seen := make(map[string]struct{})
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, prefix) {
+			return nil
+		}
+		id, _, _ := strings.Cut(strings.TrimPrefix(route, prefix), "/")
+		if id == "" || id == "*" || strings.HasPrefix(id, "{") {
+			return nil
+		}
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return ids, nil
+}
+
+// connectDB opens PostgreSQL or SQLite DB selected by DSN and returns DB and its driver name.
+func connectDB(dsn string) (*sql.DB, string, error) {
+	db, driver, err := database.Open(dsn)
+	if err != nil {
+		return nil, "", err
+	}
 	err = db.Ping()
 	if err != nil {
-		logger.Log.Error("Failed to ping DB Postgres",
+		logger.Log.Error("Failed to ping DB",
+			zap.String("driver", driver),
 			zap.Error(err),
 		)
 	}
-	return db, nil
+	return db, driver, nil
 }
 
 func runServer(server *http.Server, enableHTTPS bool) {
@@ -200,6 +271,44 @@ func runServer(server *http.Server, enableHTTPS bool) {
 	}
 }
 
+// shortenerGRPCServer creates gRPC server with app handlers.
+func shortenerGRPCServer(appServer pb.ShortenerServer, userUsecase *userUsecaseInternal.UserUsecase) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			tracing.UnaryServerInterceptor,
+			logger.RequestLoggerInterceptor,
+			userUsecase.AuthenticateOrRegisterInterceptor(
+				pb.Shortener_GetOrCreateURL_FullMethodName,
+				pb.Shortener_GetOrCreateURLs_FullMethodName,
+			),
+			userUsecase.AuthenticateInterceptor(
+				pb.Shortener_GetUserURLs_FullMethodName,
+				pb.Shortener_DeleteUserURLs_FullMethodName,
+				pb.Shortener_GetDeletionJob_FullMethodName,
+				pb.Shortener_GetURLStats_FullMethodName,
+			),
+		),
+	)
+	pb.RegisterShortenerServer(s, appServer)
+	return s
+}
+
+func runGRPCServer(server *grpc.Server, addr string) {
+	listen, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Log.Fatal("Failed to listen gRPC server address",
+			zap.Error(err),
+		)
+	}
+
+	err = server.Serve(listen)
+	if err != nil && err != grpc.ErrServerStopped {
+		logger.Log.Fatal("Failed to start gRPC server",
+			zap.Error(err),
+		)
+	}
+}
+
 func main() {
 	printBuildInfo()
 
@@ -217,92 +326,132 @@ func main() {
 		zap.Any(ConfigKey, config),
 	)
 
+	if args := flag.Args(); len(args) > 0 {
+		switch {
+		case len(args) == 2 && args[0] == MigrateCommand:
+			err = migrate(context.Background(), config.DatabaseDSN, args[1])
+			if err != nil {
+				logger.Log.Fatal("Failed to run migrations",
+					zap.String("command", args[1]),
+					zap.Error(err),
+				)
+			}
+		case args[0] == StorageCommand:
+			err = runStorageCommand(context.Background(), config, args[1:])
+			if err != nil {
+				logger.Log.Fatal("Failed to run storage command",
+					zap.Strings("args", args[1:]),
+					zap.Error(err),
+				)
+			}
+		default:
+			logger.Log.Fatal("Unknown command, usage: shortener [flags] migrate up|down|status|version "+
+				"or shortener [flags] storage copy --from SOURCE --to TARGET [--dry-run] [--checkpoint FILE] [--batch-size N]",
+				zap.Strings("args", args),
+			)
+		}
+		return
+	}
+
+	err = config.CheckFileStoragePaths()
+	if err != nil {
+		logger.Log.Fatal("Invalid file storage config",
+			zap.Error(err),
+		)
+	}
+
+	shutdownTracing, err := tracing.Initialize(context.Background(), config.TraceExporter, config.TraceEndpoint, config.TraceFile)
+	if err != nil {
+		logger.Log.Fatal("Failed to init tracing",
+			zap.Error(err),
+		)
+	}
+
 	var db *sql.DB
+	var dbDriver string
 
 	if config.DatabaseDSN != "" {
-		logger.Log.Info("Applying migrations")
-		err = migrate(config.DatabaseDSN)
+		if config.SkipMigrations {
+			logger.Log.Info("Skipping migrations")
+		} else {
+			logger.Log.Info("Applying migrations")
+			err = migrate(context.Background(), config.DatabaseDSN, MigrateUp)
+			if err != nil {
+				logger.Log.Fatal("Failed to apply migrations",
+					zap.Error(err),
+				)
+			}
+		}
+
+		db, dbDriver, err = connectDB(config.DatabaseDSN)
 		if err != nil {
-			logger.Log.Fatal("Failed to apply migrations",
+			logger.Log.Fatal("Failed to connect to DB",
 				zap.Error(err),
 			)
 		}
-
-		db, err = connectPostgres(config.DatabaseDSN)
+		err = metrics.RegisterDBStats(db)
 		if err != nil {
-			logger.Log.Fatal("Failed to connect to Postgres",
+			logger.Log.Fatal("Failed to register DB stats metrics",
 				zap.Error(err),
 			)
 		}
-		defer func() {
-			err = db.Close()
-			if err != nil {
-				logger.Log.Fatal("Failed to close Postgres",
-					zap.Error(err),
-				)
-			}
-		}()
+	}
+
+	syncPolicy := filestorage.SyncPolicy{
+		Mode:     config.FileStorageSync,
+		Interval: config.FileStorageSyncInterval,
 	}
 
 	appRepo, err := appRepoInternal.NewAppRepo(
 		db,
+		dbDriver,
+		config.QueryTimeout,
 		config.FileStoragePath,
-		DeletedURLsFileStoragePath,
+		config.DeletedURLsFileStoragePath,
+		config.ClicksFileStoragePath,
+		config.DeletionJobsFileStoragePath,
+		syncPolicy,
+		config.URLUniqueness,
 	)
 	if err != nil {
 		logger.Log.Fatal("Failed to create appRepo",
 			zap.Error(err),
 		)
 	}
-	defer func() {
-		err = appRepo.Close()
-		if err != nil {
-			logger.Log.Fatal("Failed to close appRepo",
-				zap.Error(err),
-			)
-		}
-	}()
 
-	userRepo, err := userRepoInternal.NewUserRepo(db, UsersFileStoragePath)
+	userRepo, err := userRepoInternal.NewUserRepo(db, dbDriver, config.QueryTimeout, config.UsersFileStoragePath, syncPolicy)
 	if err != nil {
 		logger.Log.Fatal("Failed to create userRepo",
 			zap.Error(err),
 		)
 	}
-	defer func() {
-		err = userRepo.Close()
-		if err != nil {
-			logger.Log.Fatal("Failed to close userRepo",
-				zap.Error(err),
-			)
-		}
-	}()
-
-	appUsecase, err := appUsecaseInternal.NewAppUsecase(
-		appRepo,
-		config.BaseURL,
-		CountRegenerationsForLengthID,
-		LengthID,
-		MaxLengthID,
-		db,
-		DeleteURLsChanSize,
-		DeleteURLsWaitingTime,
-	)
+
+	appUsecase, err := appUsecaseInternal.NewAppUsecase(appRepo, appUsecaseInternal.AppUsecaseConfig{
+		BaseURL:                       config.BaseURL,
+		CountRegenerationsForLengthID: CountRegenerationsForLengthID,
+		LengthID:                      LengthID,
+		MaxLengthID:                   MaxLengthID,
+		URLUniqueness:                 config.URLUniqueness,
+		DeleteURLsBatchSize:           DeleteURLsBatchSize,
+		DeleteURLsWaitingTime:         DeleteURLsWaitingTime,
+		DeleteURLsMaxAttempts:         DeleteURLsMaxAttempts,
+		DeleteURLsRetryBaseDelay:      DeleteURLsRetryBaseDelay,
+		DeleteURLsRetryMaxDelay:       DeleteURLsRetryMaxDelay,
+		DeleteExpiredURLsWaitingTime:  DeleteExpiredURLsWaitingTime,
+		PurgeDeletedURLsWaitingTime:   PurgeDeletedURLsWaitingTime,
+		DeletedURLsGracePeriod:        config.DeletedURLsGracePeriod,
+		PurgeDeletionJobsWaitingTime:  config.DeletionJobsPurgeInterval,
+		DeletionJobsRetention:         config.DeletionJobsRetention,
+		ClicksChanSize:                ClicksChanSize,
+		ClicksWaitingTime:             ClicksWaitingTime,
+		CompactStorageWaitingTime:     config.FileStorageCompactionInterval,
+	})
 	if err != nil {
 		logger.Log.Fatal("Failed to create appUsecase",
 			zap.Error(err),
 		)
 	}
 
-	defer func() {
-		err = appUsecase.Close()
-		if err != nil {
-			logger.Log.Fatal("Failed to close userRepoInmem",
-				zap.Error(err),
-			)
-		}
-	}()
-
 	userUsecase, err := userUsecaseInternal.NewUserUsecase(
 		userRepo,
 		SecretKey,
@@ -314,7 +463,7 @@ func main() {
 		)
 	}
 
-	appHandler := appDeliveryInternal.NewAppHandler(appUsecase)
+	appHandler := appDeliveryInternal.NewAppHandler(appUsecase, userUsecase)
 
 	u, err := url.ParseRequestURI(config.BaseURL)
 	if err != nil {
@@ -323,11 +472,21 @@ func main() {
 		)
 	}
 
+	trustedSubnetMiddleware, err := trustedsubnet.NewTrustedSubnetMiddleware(config.TrustedSubnet)
+	if err != nil {
+		logger.Log.Fatal("Failed to create trusted subnet middleware",
+			zap.Error(err),
+		)
+	}
+
 	middlewares := &Middlewares{
+		Metrics:                metrics.Middleware,
+		Tracing:                tracing.Middleware,
 		RequestLogger:          logger.RequestLogger,
 		GzipMiddleware:         gzip.GzipMiddleware,
 		AuthenticateOrRegister: userUsecase.AuthenticateOrRegister,
 		Authenticate:           userUsecase.Authenticate,
+		TrustedSubnet:          trustedSubnetMiddleware,
 	}
 
 	r, err := shortenerRouter(appHandler, u, middlewares)
@@ -337,6 +496,14 @@ func main() {
 		)
 	}
 
+	ids, err := reservedIDs(r, u)
+	if err != nil {
+		logger.Log.Fatal("Failed to get reserved IDs",
+			zap.Error(err),
+		)
+	}
+	appUsecase.ReserveIDs(ids...)
+
 	logger.Log.Info("Server running",
 		zap.String(AddrKey, config.ServerAddress),
 	)
@@ -348,13 +515,48 @@ func main() {
 
 	go runServer(server, config.EnableHTTPS)
 
+	logger.Log.Info("gRPC server running",
+		zap.String(AddrKey, config.GRPCServerAddress),
+	)
+
+	grpcServer := shortenerGRPCServer(appDeliveryInternal.NewAppGRPCServer(appUsecase), userUsecase)
+
+	go runGRPCServer(grpcServer, config.GRPCServerAddress)
+
 	exitChan := make(chan os.Signal, 1)
 	signal.Notify(exitChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
 	exitSyg := <-exitChan
-	logger.Log.Info("terminating: via signal", zap.Any("signal", exitSyg))
-	err = server.Shutdown(context.Background())
-	if err != nil {
-		logger.Log.Fatal("Failed to HTTP server shutdown", zap.Error(err))
+	logger.Log.Info("terminating: via signal",
+		zap.Any("signal", exitSyg),
+		zap.Duration("shutdown_timeout", config.ShutdownTimeout),
+	)
+	// readiness probe fails from now, so balancer stops sending new requests
+	appUsecase.StartShutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+
+	steps := []shutdownStep{
+		shutdownHTTPServer(server),
+		shutdownGRPCServer(grpcServer),
+		{name: "app usecase", run: appUsecase.Shutdown, drain: true},
+		closeAfterDrainStep("user repo", userRepo.Close),
+		closeAfterDrainStep("app repo", appRepo.Close),
+	}
+	if db != nil {
+		steps = append(steps, closeAfterDrainStep("database", db.Close))
 	}
+	steps = append(steps, shutdownStep{
+		name: "tracing",
+		run: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, TracingShutdownTimeout)
+			defer cancel()
+			return shutdownTracing(ctx)
+		},
+	})
+
+	exitCode := gracefulShutdown(ctx, steps...)
+	cancel()
+
+	exit(exitCode)
 }
@@ -9,15 +9,21 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app/delivery/mocks"
 	"github.com/MisterMaks/go-yandex-shortener/internal/gzip"
 	"github.com/MisterMaks/go-yandex-shortener/internal/logger"
+	"github.com/MisterMaks/go-yandex-shortener/internal/metrics"
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
 	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
 	"github.com/golang/mock/gomock"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
 	appDeliveryInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/delivery"
+	appRepoInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/repo"
+	appUsecaseInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -26,6 +32,7 @@ const (
 	TestValidURL   string = "http://valid_url.ru/"
 	TestInvalidURL string = "invalid_url"
 	TestID         string = "1"
+	TestJobID      string = "job"
 	ContentTypeKey string = "Content-Type"
 	TextPlainKey   string = "text/plain"
 	TestUserID     uint   = 1
@@ -58,18 +65,19 @@ func TestRouter(t *testing.T) {
 
 	// гарантируем, что заглушка
 	// при вызове с аргументом "Key" вернёт "Value"
-	m.EXPECT().GetOrCreateURL(TestValidURL, gomock.Any()).Return(&app.URL{
+	m.EXPECT().GetOrCreateURL(gomock.Any(), TestValidURL, gomock.Any(), gomock.Any(), gomock.Any()).Return(&app.URL{
 		ID:     TestID,
 		URL:    TestValidURL,
 		UserID: TestUserID,
 	}, false, nil).AnyTimes()
-	m.EXPECT().GetOrCreateURL(gomock.Any(), gomock.Any()).Return(nil, false, ErrTestInvalidURL).AnyTimes()
+	m.EXPECT().GetOrCreateURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, false, ErrTestInvalidURL).AnyTimes()
 
-	m.EXPECT().GetURL(TestID).Return(&app.URL{
+	m.EXPECT().GetURL(gomock.Any(), TestID).Return(&app.URL{
 		ID:  TestID,
 		URL: TestValidURL,
 	}, nil).AnyTimes()
-	m.EXPECT().GetURL(gomock.Any()).Return(nil, ErrTestIDNotFound).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), gomock.Any()).Return(nil, ErrTestIDNotFound).AnyTimes()
+	m.EXPECT().SendURLClickInChan(TestID).AnyTimes()
 
 	m.EXPECT().GenerateShortURL(gomock.Any()).DoAndReturn(
 		func(id string) string {
@@ -77,9 +85,11 @@ func TestRouter(t *testing.T) {
 		},
 	)
 
-	appHandler := appDeliveryInternal.NewAppHandler(m)
+	appHandler := appDeliveryInternal.NewAppHandler(m, nil)
 
 	middlewares := &Middlewares{
+		Metrics:        metrics.Middleware,
+		Tracing:        tracing.Middleware,
 		RequestLogger:  logger.RequestLogger,
 		GzipMiddleware: gzip.GzipMiddleware,
 		AuthenticateOrRegister: func(h http.Handler) http.Handler {
@@ -88,7 +98,8 @@ func TestRouter(t *testing.T) {
 				h.ServeHTTP(w, r.WithContext(ctx))
 			})
 		},
-		Authenticate: func(h http.Handler) http.Handler { return h },
+		Authenticate:  func(h http.Handler) http.Handler { return h },
+		TrustedSubnet: func(h http.Handler) http.Handler { return h },
 	}
 
 	u, err := url.ParseRequestURI(ResultAddrPrefix)
@@ -232,3 +243,107 @@ func TestRouter(t *testing.T) {
 		}
 	}
 }
+
+func TestReservedIDs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	appHandler := appDeliveryInternal.NewAppHandler(mocks.NewMockAppUsecaseInterface(ctrl), nil)
+	noop := func(h http.Handler) http.Handler { return h }
+	middlewares := &Middlewares{
+		Metrics:                noop,
+		Tracing:                noop,
+		RequestLogger:          noop,
+		GzipMiddleware:         noop,
+		Authenticate:           noop,
+		AuthenticateOrRegister: noop,
+		TrustedSubnet:          noop,
+	}
+
+	tests := []struct {
+		name    string
+		baseURL string
+		want    []string
+	}{
+		{
+			name:    "root base path",
+			baseURL: "http://localhost:8080/",
+			want:    []string{"api", "healthz", "metrics", "ping", "readyz", "swagger"},
+		},
+		{
+			name:    "base path without routes",
+			baseURL: "http://localhost:8080/r/",
+			want:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.ParseRequestURI(tt.baseURL)
+			require.NoError(t, err)
+			r, err := shortenerRouter(appHandler, u, middlewares)
+			require.NoError(t, err)
+
+			ids, err := reservedIDs(r, u)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, ids)
+		})
+	}
+}
+
+func TestRouter_ReservedAlias(t *testing.T) {
+	appRepo, err := appRepoInternal.NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	appUsecase, err := appUsecaseInternal.NewAppUsecase(appRepo, appUsecaseInternal.AppUsecaseConfig{
+		BaseURL:                       "http://localhost:8080/",
+		CountRegenerationsForLengthID: 1,
+		LengthID:                      5,
+		MaxLengthID:                   20,
+		DeleteURLsWaitingTime:         time.Hour,
+		DeleteExpiredURLsWaitingTime:  time.Hour,
+		DeletedURLsGracePeriod:        -1,
+		DeletionJobsRetention:         -1,
+		ClicksWaitingTime:             time.Hour,
+	})
+	require.NoError(t, err)
+	defer func() { err = appUsecase.Close(); require.NoError(t, err) }()
+
+	noop := func(h http.Handler) http.Handler { return h }
+	middlewares := &Middlewares{
+		Metrics:        noop,
+		Tracing:        noop,
+		RequestLogger:  noop,
+		GzipMiddleware: noop,
+		AuthenticateOrRegister: func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx := context.WithValue(r.Context(), usecase.UserIDKey, TestUserID)
+				h.ServeHTTP(w, r.WithContext(ctx))
+			})
+		},
+		Authenticate:  noop,
+		TrustedSubnet: noop,
+	}
+
+	u, err := url.ParseRequestURI(appUsecase.BaseURL)
+	require.NoError(t, err)
+	r, err := shortenerRouter(appDeliveryInternal.NewAppHandler(appUsecase, nil), u, middlewares)
+	require.NoError(t, err)
+	ids, err := reservedIDs(r, u)
+	require.NoError(t, err)
+	appUsecase.ReserveIDs(ids...)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	for _, alias := range []string{"ping", "healthz", "readyz", "metrics", "swagger"} {
+		t.Run(alias, func(t *testing.T) {
+			body := []byte(`{"url": "` + TestValidURL + `", "alias": "` + alias + `"}`)
+			res, err := ts.Client().Post(ts.URL+"/api/shorten", "application/json", bytes.NewReader(body))
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+			// short URL with alias would be handled by route instead of redirect
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/database"
+	"github.com/MisterMaks/go-yandex-shortener/migrations"
+)
+
+// Migrate subcommand constants.
+const (
+	MigrateCommand string = "migrate" // subcommand name: shortener [flags] migrate up|down|status|version
+
+	MigrateUp      string = "up"      // apply all new migrations
+	MigrateDown    string = "down"    // roll back last migration
+	MigrateStatus  string = "status"  // print status of all migrations
+	MigrateVersion string = "version" // print current version of DB
+)
+
+// Errors for migrations.
+var (
+	ErrUnknownMigrateCommand = errors.New("unknown migrate command")
+	ErrEmptyDatabaseDSN      = errors.New("empty database DSN")
+)
+
+// migrationsDialect returns goose dialect and migrations directory of DB driver.
+func migrationsDialect(driver string) (string, string) {
+	if driver == database.DriverSQLite {
+		return "sqlite3", migrations.SQLiteDir
+	}
+	return "postgres", migrations.PostgresDir
+}
+
+// migrate runs goose command with migrations embedded in binary.
+// Migrations of PostgreSQL or SQLite are selected by DSN.
+func migrate(ctx context.Context, dsn, command string) (err error) {
+	switch command {
+	case MigrateUp, MigrateDown, MigrateStatus, MigrateVersion:
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownMigrateCommand, command)
+	}
+	if dsn == "" {
+		return ErrEmptyDatabaseDSN
+	}
+
+	db, driver, err := database.Open(dsn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	dialect, dir := migrationsDialect(driver)
+	goose.SetBaseFS(migrations.FS)
+	err = goose.SetDialect(dialect)
+	if err != nil {
+		return err
+	}
+
+	return goose.RunContext(ctx, command, db, dir)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MisterMaks/go-yandex-shortener/migrations"
+)
+
+func TestMigrate(t *testing.T) {
+	err := migrate(context.Background(), "postgres://localhost/db", "unknown")
+	assert.ErrorIs(t, err, ErrUnknownMigrateCommand)
+
+	err = migrate(context.Background(), "", MigrateUp)
+	assert.ErrorIs(t, err, ErrEmptyDatabaseDSN)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	goose.SetBaseFS(migrations.FS)
+	defer goose.SetBaseFS(nil)
+
+	ms, err := goose.CollectMigrations(".", 0, goose.MaxVersion)
+	require.NoError(t, err)
+	assert.NotEmpty(t, ms)
+}
@@ -34,6 +34,18 @@ func (m *MockAppHandlerInterface) EXPECT() *MockAppHandlerInterfaceMockRecorder
 	return m.recorder
 }
 
+// APICompactStorage mocks base method.
+func (m *MockAppHandlerInterface) APICompactStorage(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "APICompactStorage", w, r)
+}
+
+// APICompactStorage indicates an expected call of APICompactStorage.
+func (mr *MockAppHandlerInterfaceMockRecorder) APICompactStorage(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APICompactStorage", reflect.TypeOf((*MockAppHandlerInterface)(nil).APICompactStorage), w, r)
+}
+
 // APIDeleteUserURLs mocks base method.
 func (m *MockAppHandlerInterface) APIDeleteUserURLs(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -46,6 +58,30 @@ func (mr *MockAppHandlerInterfaceMockRecorder) APIDeleteUserURLs(w, r interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIDeleteUserURLs", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIDeleteUserURLs), w, r)
 }
 
+// APIExportUserURLs mocks base method.
+func (m *MockAppHandlerInterface) APIExportUserURLs(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "APIExportUserURLs", w, r)
+}
+
+// APIExportUserURLs indicates an expected call of APIExportUserURLs.
+func (mr *MockAppHandlerInterfaceMockRecorder) APIExportUserURLs(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIExportUserURLs", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIExportUserURLs), w, r)
+}
+
+// APIGetDeletionJob mocks base method.
+func (m *MockAppHandlerInterface) APIGetDeletionJob(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "APIGetDeletionJob", w, r)
+}
+
+// APIGetDeletionJob indicates an expected call of APIGetDeletionJob.
+func (mr *MockAppHandlerInterfaceMockRecorder) APIGetDeletionJob(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIGetDeletionJob", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIGetDeletionJob), w, r)
+}
+
 // APIGetOrCreateURL mocks base method.
 func (m *MockAppHandlerInterface) APIGetOrCreateURL(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -70,6 +106,42 @@ func (mr *MockAppHandlerInterfaceMockRecorder) APIGetOrCreateURLs(w, r interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIGetOrCreateURLs", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIGetOrCreateURLs), w, r)
 }
 
+// APIGetStats mocks base method.
+func (m *MockAppHandlerInterface) APIGetStats(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "APIGetStats", w, r)
+}
+
+// APIGetStats indicates an expected call of APIGetStats.
+func (mr *MockAppHandlerInterfaceMockRecorder) APIGetStats(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIGetStats", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIGetStats), w, r)
+}
+
+// APIGetURLStats mocks base method.
+func (m *MockAppHandlerInterface) APIGetURLStats(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "APIGetURLStats", w, r)
+}
+
+// APIGetURLStats indicates an expected call of APIGetURLStats.
+func (mr *MockAppHandlerInterfaceMockRecorder) APIGetURLStats(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIGetURLStats", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIGetURLStats), w, r)
+}
+
+// APIGetUserURLHistory mocks base method.
+func (m *MockAppHandlerInterface) APIGetUserURLHistory(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "APIGetUserURLHistory", w, r)
+}
+
+// APIGetUserURLHistory indicates an expected call of APIGetUserURLHistory.
+func (mr *MockAppHandlerInterfaceMockRecorder) APIGetUserURLHistory(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIGetUserURLHistory", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIGetUserURLHistory), w, r)
+}
+
 // APIGetUserURLs mocks base method.
 func (m *MockAppHandlerInterface) APIGetUserURLs(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -82,6 +154,42 @@ func (mr *MockAppHandlerInterfaceMockRecorder) APIGetUserURLs(w, r interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIGetUserURLs", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIGetUserURLs), w, r)
 }
 
+// APIImportUserURLs mocks base method.
+func (m *MockAppHandlerInterface) APIImportUserURLs(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "APIImportUserURLs", w, r)
+}
+
+// APIImportUserURLs indicates an expected call of APIImportUserURLs.
+func (mr *MockAppHandlerInterfaceMockRecorder) APIImportUserURLs(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIImportUserURLs", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIImportUserURLs), w, r)
+}
+
+// APIRestoreUserURLs mocks base method.
+func (m *MockAppHandlerInterface) APIRestoreUserURLs(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "APIRestoreUserURLs", w, r)
+}
+
+// APIRestoreUserURLs indicates an expected call of APIRestoreUserURLs.
+func (mr *MockAppHandlerInterfaceMockRecorder) APIRestoreUserURLs(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIRestoreUserURLs", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIRestoreUserURLs), w, r)
+}
+
+// APIUpdateUserURL mocks base method.
+func (m *MockAppHandlerInterface) APIUpdateUserURL(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "APIUpdateUserURL", w, r)
+}
+
+// APIUpdateUserURL indicates an expected call of APIUpdateUserURL.
+func (mr *MockAppHandlerInterfaceMockRecorder) APIUpdateUserURL(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIUpdateUserURL", reflect.TypeOf((*MockAppHandlerInterface)(nil).APIUpdateUserURL), w, r)
+}
+
 // GetOrCreateURL mocks base method.
 func (m *MockAppHandlerInterface) GetOrCreateURL(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -94,6 +202,18 @@ func (mr *MockAppHandlerInterfaceMockRecorder) GetOrCreateURL(w, r interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrCreateURL", reflect.TypeOf((*MockAppHandlerInterface)(nil).GetOrCreateURL), w, r)
 }
 
+// Healthz mocks base method.
+func (m *MockAppHandlerInterface) Healthz(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Healthz", w, r)
+}
+
+// Healthz indicates an expected call of Healthz.
+func (mr *MockAppHandlerInterfaceMockRecorder) Healthz(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Healthz", reflect.TypeOf((*MockAppHandlerInterface)(nil).Healthz), w, r)
+}
+
 // Ping mocks base method.
 func (m *MockAppHandlerInterface) Ping(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -106,6 +226,18 @@ func (mr *MockAppHandlerInterfaceMockRecorder) Ping(w, r interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockAppHandlerInterface)(nil).Ping), w, r)
 }
 
+// Readyz mocks base method.
+func (m *MockAppHandlerInterface) Readyz(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Readyz", w, r)
+}
+
+// Readyz indicates an expected call of Readyz.
+func (mr *MockAppHandlerInterfaceMockRecorder) Readyz(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Readyz", reflect.TypeOf((*MockAppHandlerInterface)(nil).Readyz), w, r)
+}
+
 // RedirectToURL mocks base method.
 func (m *MockAppHandlerInterface) RedirectToURL(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/logger"
+)
+
+// Exit codes of app.
+const (
+	ExitCodeOK              = 0 // all in-flight work is drained and resources are closed
+	ExitCodeShutdownFailed  = 2 // some resources failed to flush or close
+	ExitCodeShutdownTimeout = 3 // shutdown deadline exceeded, in-flight work may be lost
+)
+
+// shutdownStep is named step of graceful shutdown.
+type shutdownStep struct {
+	name       string
+	run        func(ctx context.Context) error
+	drain      bool // step waits for in-flight work which uses resources closed by next steps
+	afterDrain bool // step closes resource which is used by in-flight work, it is skipped if drain failed
+}
+
+// gracefulShutdown runs steps in order. Every step is run even if previous one failed,
+// so resources are closed after failed close of another resource.
+// Steps after drain are skipped if drain failed or timed out, because not finished work still uses their resources.
+// Func returns exit code of app.
+func gracefulShutdown(ctx context.Context, steps ...shutdownStep) int {
+	code := ExitCodeOK
+	drained := true
+	for _, step := range steps {
+		if step.afterDrain && !drained {
+			logger.Log.Warn("Shutdown step skipped, in-flight work is not drained",
+				zap.String("step", step.name),
+			)
+			continue
+		}
+
+		start := time.Now()
+		err := step.run(ctx)
+		if err != nil && step.drain {
+			drained = false
+		}
+		if err == nil {
+			logger.Log.Info("Shutdown step finished",
+				zap.String("step", step.name),
+				zap.Duration("duration", time.Since(start)),
+			)
+			continue
+		}
+
+		logger.Log.Error("Shutdown step failed",
+			zap.String("step", step.name),
+			zap.Duration("duration", time.Since(start)),
+			zap.Error(err),
+		)
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			code = ExitCodeShutdownTimeout
+		case code == ExitCodeOK:
+			code = ExitCodeShutdownFailed
+		}
+	}
+	return code
+}
+
+// shutdownHTTPServer stops accepting connections and waits for active requests till ctx is done.
+// Not finished connections are closed after deadline.
+func shutdownHTTPServer(server *http.Server) shutdownStep {
+	return shutdownStep{
+		name:  "http server",
+		drain: true,
+		run: func(ctx context.Context) error {
+			err := server.Shutdown(ctx)
+			if err != nil {
+				return errors.Join(err, server.Close())
+			}
+			return nil
+		},
+	}
+}
+
+// shutdownGRPCServer stops accepting connections and waits for active RPCs till ctx is done.
+// Not finished RPCs are canceled after deadline.
+func shutdownGRPCServer(server *grpc.Server) shutdownStep {
+	return shutdownStep{
+		name:  "grpc server",
+		drain: true,
+		run: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				server.GracefulStop()
+				close(stopped)
+			}()
+
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				server.Stop()
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// closeStep creates shutdown step which closes resource without deadline.
+func closeStep(name string, closeFunc func() error) shutdownStep {
+	return shutdownStep{
+		name: name,
+		run: func(context.Context) error {
+			return closeFunc()
+		},
+	}
+}
+
+// closeAfterDrainStep creates shutdown step which closes resource used by in-flight work.
+// Step is skipped if in-flight work is not drained.
+func closeAfterDrainStep(name string, closeFunc func() error) shutdownStep {
+	step := closeStep(name, closeFunc)
+	step.afterDrain = true
+	return step
+}
+
+// exit syncs logger and terminates app with exit code of shutdown.
+// It must be called after all resources are closed, because deferred funcs are not run.
+func exit(code int) {
+	logger.Log.Info("Shutdown finished",
+		zap.Int("exit_code", code),
+	)
+
+	err := logger.Sync()
+	if err != nil {
+		log.Println("ERROR\tFailed to sync logger. Error:", err)
+		if code == ExitCodeOK {
+			code = ExitCodeShutdownFailed
+		}
+	}
+
+	os.Exit(code)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	appUsecaseInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
+	appUsecaseMocks "github.com/MisterMaks/go-yandex-shortener/internal/app/usecase/mocks"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	errClose := errors.New("close error")
+
+	tests := []struct {
+		name     string
+		errs     []error
+		wantCode int
+	}{
+		{
+			name:     "all steps finished",
+			errs:     []error{nil, nil, nil},
+			wantCode: ExitCodeOK,
+		},
+		{
+			name:     "step failed",
+			errs:     []error{nil, errClose, nil},
+			wantCode: ExitCodeShutdownFailed,
+		},
+		{
+			name:     "deadline exceeded",
+			errs:     []error{errClose, context.DeadlineExceeded, nil},
+			wantCode: ExitCodeShutdownTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := []int{}
+			steps := make([]shutdownStep, 0, len(tt.errs))
+			for i, err := range tt.errs {
+				steps = append(steps, shutdownStep{
+					name: "step",
+					run: func(context.Context) error {
+						order = append(order, i)
+						return err
+					},
+				})
+			}
+
+			code := gracefulShutdown(context.Background(), steps...)
+			assert.Equal(t, tt.wantCode, code)
+			// every step is run in order even if previous one failed
+			assert.Equal(t, []int{0, 1, 2}, order)
+		})
+	}
+}
+
+func TestGracefulShutdown_WorkerOutlivesDeadline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+
+	m := appUsecaseMocks.NewMockAppRepoInterface(ctrl)
+	// first run of deletion worker is stuck in storage till the end of test
+	m.EXPECT().GetDueDeletionJobs(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(context.Context, time.Time, uint) ([]*app.DeletionJob, error) {
+			once.Do(func() {
+				close(started)
+				<-release
+			})
+			return nil, nil
+		},
+	).AnyTimes()
+	m.EXPECT().CountPendingDeletionJobs(gomock.Any()).Return(uint(0), nil).AnyTimes()
+
+	appUsecase, err := appUsecaseInternal.NewAppUsecase(m, appUsecaseInternal.AppUsecaseConfig{
+		BaseURL:                      "http://example.com/",
+		LengthID:                     1,
+		MaxLengthID:                  1,
+		DeleteURLsWaitingTime:        time.Millisecond,
+		DeleteExpiredURLsWaitingTime: time.Hour,
+		DeletedURLsGracePeriod:       -1,
+		DeletionJobsRetention:        -1,
+		ClicksWaitingTime:            time.Hour,
+	})
+	require.NoError(t, err)
+	<-started
+
+	repoClosed := false
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	code := gracefulShutdown(ctx,
+		shutdownStep{name: "app usecase", run: appUsecase.Shutdown, drain: true},
+		closeAfterDrainStep("app repo", func() error {
+			repoClosed = true
+			return nil
+		}),
+	)
+	assert.Equal(t, ExitCodeShutdownTimeout, code)
+	// worker still uses repo, so repo is not closed
+	assert.False(t, repoClosed)
+
+	close(release)
+	err = appUsecase.Shutdown(context.Background())
+	require.NoError(t, err)
+}
+
+func TestShutdownHTTPServer(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	go func() {
+		res, err := ts.Client().Get(ts.URL)
+		if err == nil {
+			_ = res.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := shutdownHTTPServer(ts.Config).run(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	appRepoInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/repo"
+	appUsecaseInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
+	"github.com/MisterMaks/go-yandex-shortener/internal/logger"
+	userRepoInternal "github.com/MisterMaks/go-yandex-shortener/internal/user/repo"
+	userUsecaseInternal "github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
+)
+
+// Storage subcommand constants.
+const (
+	StorageCommand     string = "storage" // subcommand name: shortener [flags] storage copy --from SOURCE --to TARGET
+	StorageCopyCommand string = "copy"    // copy users, URLs and deletion marks from one storage to another
+
+	// Prefix of file storage, rest of storage is path to URLs file. Other files are searched in its dir.
+	// Example: file:/var/lib/shortener/short-url-db.json
+	FileStorageScheme string = "file:"
+
+	StorageCopyBatchSize uint = 100 // default count of users or URLs copied at once
+)
+
+// Errors for storage subcommand.
+var (
+	ErrUnknownStorageCommand = errors.New("unknown storage command")
+	ErrEmptyStorage          = errors.New("empty storage")                                           // source or target is not set
+	ErrSameStorage           = errors.New("source and target storages are the same")                 // storage can not be copied to itself
+	ErrInvalidBatchSize      = errors.New("batch size must be positive")                             // copy would never finish
+	ErrStorageCopyConflict   = errors.New("URL is saved in target storage with another ID or owner") // target is not empty
+)
+
+// storageRepos are repos of one storage.
+type storageRepos struct {
+	appRepo  appUsecaseInternal.AppRepoInterface
+	userRepo userUsecaseInternal.UserRepoInterface
+}
+
+func (sr *storageRepos) close() error {
+	return errors.Join(sr.appRepo.Close(), sr.userRepo.Close())
+}
+
+// openStorage opens repos of file storage or DB selected by storage.
+// Migrations are applied to DB if migrateDB is true.
+func openStorage(
+	ctx context.Context,
+	storage string,
+	migrateDB bool,
+	queryTimeout time.Duration,
+	syncPolicy filestorage.SyncPolicy,
+	urlUniqueness string,
+) (*storageRepos, error) {
+	if filename, ok := strings.CutPrefix(storage, FileStorageScheme); ok {
+		if filename == "" {
+			return nil, ErrEmptyStorage
+		}
+		dir := filepath.Dir(filename)
+		appRepo, err := appRepoInternal.NewAppRepoInmem(
+			filename,
+			filepath.Join(dir, DeletedURLsFileStorageName),
+			filepath.Join(dir, ClicksFileStorageName),
+			filepath.Join(dir, DeletionJobsFileStorageName),
+			syncPolicy,
+			urlUniqueness,
+		)
+		if err != nil {
+			return nil, err
+		}
+		userRepo, err := userRepoInternal.NewUserRepoInmem(filepath.Join(dir, UsersFileStorageName), syncPolicy)
+		if err != nil {
+			return nil, errors.Join(err, appRepo.Close())
+		}
+		return &storageRepos{appRepo: appRepo, userRepo: userRepo}, nil
+	}
+
+	if migrateDB {
+		err := migrate(ctx, storage, MigrateUp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	db, driver, err := connectDB(storage)
+	if err != nil {
+		return nil, err
+	}
+	appRepo, err := appRepoInternal.NewAppRepo(db, driver, queryTimeout, "", "", "", "", syncPolicy, urlUniqueness)
+	if err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	userRepo, err := userRepoInternal.NewUserRepo(db, driver, queryTimeout, "", syncPolicy)
+	if err != nil {
+		return nil, errors.Join(err, appRepo.Close())
+	}
+	return &storageRepos{appRepo: appRepo, userRepo: userRepo}, nil
+}
+
+// storageCopyOptions are options of storage copy.
+type storageCopyOptions struct {
+	batchSize      uint   // count of users or URLs copied at once
+	dryRun         bool   // source is read, but target is not written
+	checkpointFile string // progress is saved after every batch and copy is resumed from it, empty means without resume
+}
+
+// storageCopyStats are counts of records read from source.
+type storageCopyStats struct {
+	users       uint
+	urls        uint
+	deletedURLs uint
+}
+
+// storageCopyCheckpoint is position of storage copy.
+// Users are copied in order of IDs, URLs of every user are copied in order of creation.
+type storageCopyCheckpoint struct {
+	UserID uint           `json:"user_id"`         // users with lower IDs are copied with their URLs
+	After  *app.URLCursor `json:"after,omitempty"` // URLs of user with UserID till cursor are copied
+}
+
+// readStorageCopyCheckpoint reads saved position of copy. Zero position is returned if file does not exist.
+func readStorageCopyCheckpoint(filename string) (*storageCopyCheckpoint, error) {
+	checkpoint := &storageCopyCheckpoint{}
+	if filename == "" {
+		return checkpoint, nil
+	}
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+
+	err := filestorage.ReadRecords(filename, func(data []byte) error {
+		return json.Unmarshal(data, checkpoint)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// storageCopier copies users, URLs and deletion marks between storages.
+// Short IDs, owners, deletion marks, creation times, statistics and history of changes of URLs are preserved.
+type storageCopier struct {
+	source  *storageRepos
+	target  *storageRepos // nil if copy is dry run
+	options storageCopyOptions
+	stats   storageCopyStats
+}
+
+// saveCheckpoint saves position of copy if copy is resumable.
+func (sc *storageCopier) saveCheckpoint(checkpoint *storageCopyCheckpoint) error {
+	if sc.options.checkpointFile == "" || sc.target == nil {
+		return nil
+	}
+	return filestorage.WriteFileAtomically(sc.options.checkpointFile, []interface{}{checkpoint})
+}
+
+// copy copies users page by page after resumed position, URLs of every user are copied after user.
+func (sc *storageCopier) copy(ctx context.Context, resumed *storageCopyCheckpoint) error {
+	// user of checkpoint is copied again, saving of existing user is skipped
+	afterID := resumed.UserID
+	if afterID > 0 {
+		afterID--
+	}
+
+	for {
+		users, err := sc.source.userRepo.GetUsers(ctx, afterID, sc.options.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+
+		if sc.target != nil {
+			err = sc.target.userRepo.AddUsers(ctx, users)
+			if err != nil {
+				return err
+			}
+		}
+		sc.stats.users += uint(len(users))
+
+		for _, u := range users {
+			var after *app.URLCursor
+			if u.ID == resumed.UserID {
+				after = resumed.After
+			}
+			err = sc.copyUserURLs(ctx, u.ID, after)
+			if err != nil {
+				return err
+			}
+			err = sc.saveCheckpoint(&storageCopyCheckpoint{UserID: u.ID + 1})
+			if err != nil {
+				return err
+			}
+		}
+		afterID = users[len(users)-1].ID
+	}
+}
+
+// copyUserURLs copies URLs of user created after cursor page by page.
+func (sc *storageCopier) copyUserURLs(ctx context.Context, userID uint, after *app.URLCursor) error {
+	filter := &app.UserURLsFilter{Limit: sc.options.batchSize, After: after, IncludeDeleted: true}
+	for {
+		urls, err := sc.source.appRepo.GetUserURLs(ctx, userID, filter)
+		if err != nil {
+			return err
+		}
+		if len(urls) == 0 {
+			return nil
+		}
+		edits, err := readURLEdits(ctx, sc.source.appRepo, urls)
+		if err != nil {
+			return err
+		}
+
+		if sc.target != nil {
+			err = copyURLs(ctx, sc.target.appRepo, urls, edits)
+			if err != nil {
+				return err
+			}
+		}
+		sc.stats.urls += uint(len(urls))
+		for _, url := range urls {
+			if url.IsDeleted {
+				sc.stats.deletedURLs++
+			}
+		}
+
+		last := urls[len(urls)-1]
+		filter.After = &app.URLCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		err = sc.saveCheckpoint(&storageCopyCheckpoint{UserID: userID, After: filter.After})
+		if err != nil {
+			return err
+		}
+		if uint(len(urls)) < sc.options.batchSize {
+			return nil
+		}
+	}
+}
+
+// readURLEdits reads history of changes of original URLs of urls from source.
+func readURLEdits(ctx context.Context, source appUsecaseInternal.AppRepoInterface, urls []*app.URL) ([]*app.URLEdit, error) {
+	edits := []*app.URLEdit{}
+	for _, url := range urls {
+		urlEdits, err := source.GetURLEdits(ctx, url.ID)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, urlEdits...)
+	}
+	return edits, nil
+}
+
+// copyURLs saves URLs in target with all their fields and history of changes of original URLs.
+// URLs which are already copied are skipped, already copied URLs which are deleted in source are marked as deleted.
+func copyURLs(ctx context.Context, target appUsecaseInternal.AppRepoInterface, urls []*app.URL, edits []*app.URLEdit) error {
+	savedURLs, err := target.ImportURLs(ctx, urls, edits)
+	if errors.Is(err, app.ErrURLIDExists) {
+		return fmt.Errorf("%w: %v", ErrStorageCopyConflict, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	deletedURLs := []*app.URL{}
+	for i, url := range urls {
+		savedURL := savedURLs[i]
+		if savedURL.ID != url.ID || savedURL.UserID != url.UserID {
+			return fmt.Errorf("%w: URL %s with ID %s of user %d is saved with ID %s of user %d",
+				ErrStorageCopyConflict, url.URL, url.ID, url.UserID, savedURL.ID, savedURL.UserID,
+			)
+		}
+		if url.IsDeleted && !savedURL.IsDeleted {
+			deletedURLs = append(deletedURLs, savedURL)
+		}
+	}
+
+	return target.DeleteUserURLs(ctx, deletedURLs)
+}
+
+// copyStorage copies users, URLs and deletion marks from source to target.
+// Target is not written if copy is dry run. Checkpoint is removed after copy is finished.
+func copyStorage(ctx context.Context, source, target *storageRepos, options storageCopyOptions) (storageCopyStats, error) {
+	if options.batchSize == 0 {
+		return storageCopyStats{}, ErrInvalidBatchSize
+	}
+
+	checkpoint, err := readStorageCopyCheckpoint(options.checkpointFile)
+	if err != nil {
+		return storageCopyStats{}, err
+	}
+	if checkpoint.UserID > 0 {
+		logger.Log.Info("Resuming storage copy",
+			zap.Uint("user_id", checkpoint.UserID),
+			zap.Any("after", checkpoint.After),
+		)
+	}
+
+	sc := &storageCopier{source: source, target: target, options: options}
+	err = sc.copy(ctx, checkpoint)
+	if err != nil {
+		return sc.stats, err
+	}
+
+	if options.checkpointFile != "" && target != nil {
+		err = os.Remove(options.checkpointFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return sc.stats, err
+		}
+	}
+	return sc.stats, nil
+}
+
+// runStorageCommand runs storage subcommand with its args:
+// copy --from SOURCE --to TARGET [--dry-run] [--checkpoint FILE] [--batch-size N].
+// Storage is file:PATH or DB DSN. Migrations are applied to target DB unless they are skipped by config.
+func runStorageCommand(ctx context.Context, config *Config, args []string) (err error) {
+	if len(args) == 0 || args[0] != StorageCopyCommand {
+		return fmt.Errorf("%w: %s", ErrUnknownStorageCommand, strings.Join(args, " "))
+	}
+
+	var from, to string
+	options := storageCopyOptions{}
+	fs := flag.NewFlagSet(StorageCommand+" "+StorageCopyCommand, flag.ContinueOnError)
+	fs.StringVar(&from, "from", "", "Source storage: file:PATH or database DSN")
+	fs.StringVar(&to, "to", "", "Target storage: file:PATH or database DSN")
+	fs.BoolVar(&options.dryRun, "dry-run", false, "Read source storage without writing target storage")
+	fs.StringVar(&options.checkpointFile, "checkpoint", "", "File of copy progress, interrupted copy is resumed from it")
+	fs.UintVar(&options.batchSize, "batch-size", StorageCopyBatchSize, "Count of users or URLs copied at once")
+	err = fs.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if from == "" || to == "" {
+		return ErrEmptyStorage
+	}
+	if from == to {
+		return ErrSameStorage
+	}
+
+	syncPolicy := filestorage.SyncPolicy{
+		Mode:     config.FileStorageSync,
+		Interval: config.FileStorageSyncInterval,
+	}
+
+	source, err := openStorage(ctx, from, false, config.QueryTimeout, syncPolicy, config.URLUniqueness)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, source.close())
+	}()
+
+	var target *storageRepos
+	if !options.dryRun {
+		target, err = openStorage(ctx, to, !config.SkipMigrations, config.QueryTimeout, syncPolicy, config.URLUniqueness)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = errors.Join(err, target.close())
+		}()
+	}
+
+	stats, err := copyStorage(ctx, source, target, options)
+	logger.Log.Info("Storage copy finished",
+		zap.Bool("dry_run", options.dryRun),
+		zap.Uint("users", stats.users),
+		zap.Uint("urls", stats.urls),
+		zap.Uint("deleted_urls", stats.deletedURLs),
+		zap.Error(err),
+	)
+	return err
+}
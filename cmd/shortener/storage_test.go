@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
+)
+
+// newTestFileStorage opens file storage with two users and their URLs, URL "2" is deleted.
+func newTestFileStorage(t *testing.T) *storageRepos {
+	ctx := context.Background()
+	source, err := openStorage(ctx, FileStorageScheme+filepath.Join(t.TempDir(), "urls.json"), false, 0, filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := source.close()
+		assert.NoError(t, err)
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err = source.userRepo.CreateUser(ctx)
+		require.NoError(t, err)
+	}
+	_, err = source.appRepo.GetOrCreateURLs(ctx, []*app.URL{
+		{ID: "1", URL: "https://1.example.com", UserID: 1},
+		{ID: "2", URL: "https://2.example.com", UserID: 1},
+		{ID: "3", URL: "https://3.example.com", UserID: 2},
+	})
+	require.NoError(t, err)
+	err = source.appRepo.DeleteUserURLs(ctx, []*app.URL{{ID: "2", UserID: 1}})
+	require.NoError(t, err)
+
+	return source
+}
+
+// newTestSQLiteStorage opens empty SQLite storage with applied migrations.
+func newTestSQLiteStorage(t *testing.T) *storageRepos {
+	target, err := openStorage(context.Background(), "sqlite://"+filepath.Join(t.TempDir(), "shortener.db"), true, 0, filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := target.close()
+		assert.NoError(t, err)
+	})
+	return target
+}
+
+// assertCopiedURL checks ID, owner and deletion mark of copied URL.
+func assertCopiedURL(t *testing.T, r *storageRepos, id, rawURL string, userID uint, isDeleted bool) {
+	url, err := r.appRepo.GetURL(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, rawURL, url.URL)
+	assert.Equal(t, userID, url.UserID)
+	assert.Equal(t, isDeleted, url.IsDeleted)
+}
+
+func TestCopyStorage(t *testing.T) {
+	ctx := context.Background()
+	source := newTestFileStorage(t)
+	target := newTestSQLiteStorage(t)
+
+	// batch is smaller than count of URLs of user
+	stats, err := copyStorage(ctx, source, target, storageCopyOptions{batchSize: 1})
+	require.NoError(t, err)
+	assert.Equal(t, storageCopyStats{users: 2, urls: 3, deletedURLs: 1}, stats)
+
+	assertCopiedURL(t, target, "1", "https://1.example.com", 1, false)
+	assertCopiedURL(t, target, "2", "https://2.example.com", 1, true)
+	assertCopiedURL(t, target, "3", "https://3.example.com", 2, false)
+
+	// new users of target do not get IDs of copied users
+	u, err := target.userRepo.CreateUser(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), u.ID)
+
+	// copy is repeated without changes
+	_, err = copyStorage(ctx, source, target, storageCopyOptions{batchSize: 10})
+	require.NoError(t, err)
+	count, err := target.appRepo.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), count)
+}
+
+func TestCopyStorage_URLFields(t *testing.T) {
+	ctx := context.Background()
+	source := newTestFileStorage(t)
+	target := newTestSQLiteStorage(t)
+	lastAccessedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	editedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	err := source.appRepo.AddURLsClicks(ctx, []*app.URLClicks{{ID: "1", Count: 5, LastAccessedAt: lastAccessedAt}})
+	require.NoError(t, err)
+	_, err = source.appRepo.UpdateURL(ctx, "3", 2, "https://3-2.example.com", editedAt)
+	require.NoError(t, err)
+	_, err = source.appRepo.UpdateURL(ctx, "3", 2, "https://3-3.example.com", editedAt.Add(time.Second))
+	require.NoError(t, err)
+
+	_, err = copyStorage(ctx, source, target, storageCopyOptions{batchSize: 10})
+	require.NoError(t, err)
+
+	for _, id := range []string{"1", "2", "3"} {
+		sourceURL, err := source.appRepo.GetURL(ctx, id)
+		require.NoError(t, err)
+		targetURL, err := target.appRepo.GetURL(ctx, id)
+		require.NoError(t, err)
+
+		assert.Equal(t, sourceURL.URL, targetURL.URL, id)
+		assert.Equal(t, sourceURL.UserID, targetURL.UserID, id)
+		assert.Equal(t, sourceURL.IsDeleted, targetURL.IsDeleted, id)
+		assert.Equal(t, sourceURL.Clicks, targetURL.Clicks, id)
+		assert.True(t, sourceURL.CreatedAt.Equal(targetURL.CreatedAt), "%s CreatedAt: %s != %s", id, sourceURL.CreatedAt, targetURL.CreatedAt)
+		for _, times := range [][2]*time.Time{
+			{sourceURL.DeletedAt, targetURL.DeletedAt},
+			{sourceURL.LastAccessedAt, targetURL.LastAccessedAt},
+		} {
+			if times[0] == nil {
+				assert.Nil(t, times[1], id)
+				continue
+			}
+			require.NotNil(t, times[1], id)
+			assert.True(t, times[0].Equal(*times[1]), "%s: %s != %s", id, times[0], times[1])
+		}
+
+		sourceEdits, err := source.appRepo.GetURLEdits(ctx, id)
+		require.NoError(t, err)
+		targetEdits, err := target.appRepo.GetURLEdits(ctx, id)
+		require.NoError(t, err)
+		require.Len(t, targetEdits, len(sourceEdits), id)
+		for i, edit := range sourceEdits {
+			assert.Equal(t, edit.OldURL, targetEdits[i].OldURL, id)
+			assert.Equal(t, edit.NewURL, targetEdits[i].NewURL, id)
+			assert.True(t, edit.EditedAt.Equal(targetEdits[i].EditedAt), "%s EditedAt: %s != %s", id, edit.EditedAt, targetEdits[i].EditedAt)
+		}
+	}
+
+	url, err := target.appRepo.GetURL(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), url.Clicks)
+	edits, err := target.appRepo.GetURLEdits(ctx, "3")
+	require.NoError(t, err)
+	assert.Len(t, edits, 2)
+}
+
+func TestCopyStorage_DryRun(t *testing.T) {
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	stats, err := copyStorage(context.Background(), newTestFileStorage(t), nil, storageCopyOptions{
+		batchSize:      10,
+		dryRun:         true,
+		checkpointFile: checkpointFile,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, storageCopyStats{users: 2, urls: 3, deletedURLs: 1}, stats)
+	assert.NoFileExists(t, checkpointFile)
+}
+
+func TestCopyStorage_Resume(t *testing.T) {
+	ctx := context.Background()
+	source := newTestFileStorage(t)
+	target := newTestSQLiteStorage(t)
+
+	// copy is interrupted after the first URL of the first user
+	url, err := source.appRepo.GetURL(ctx, "1")
+	require.NoError(t, err)
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+	err = filestorage.WriteFileAtomically(checkpointFile, []interface{}{
+		&storageCopyCheckpoint{UserID: 1, After: &app.URLCursor{CreatedAt: url.CreatedAt, ID: url.ID}},
+	})
+	require.NoError(t, err)
+
+	stats, err := copyStorage(ctx, source, target, storageCopyOptions{batchSize: 10, checkpointFile: checkpointFile})
+	require.NoError(t, err)
+	assert.Equal(t, storageCopyStats{users: 2, urls: 2, deletedURLs: 1}, stats)
+	assert.NoFileExists(t, checkpointFile)
+
+	_, err = target.appRepo.GetURL(ctx, "1")
+	assert.ErrorIs(t, err, app.ErrURLNotFound)
+	assertCopiedURL(t, target, "2", "https://2.example.com", 1, true)
+	assertCopiedURL(t, target, "3", "https://3.example.com", 2, false)
+}
+
+func TestCopyStorage_Checkpoint(t *testing.T) {
+	ctx := context.Background()
+	source := newTestFileStorage(t)
+	target := newTestSQLiteStorage(t)
+
+	// URL of the second user is taken by another URL in target
+	_, err := target.userRepo.CreateUser(ctx)
+	require.NoError(t, err)
+	_, err = target.appRepo.GetOrCreateURL(ctx, "3", "https://4.example.com", 1, nil)
+	require.NoError(t, err)
+
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+	_, err = copyStorage(ctx, source, target, storageCopyOptions{batchSize: 10, checkpointFile: checkpointFile})
+	require.ErrorIs(t, err, ErrStorageCopyConflict)
+
+	// copy is resumed from the second user
+	checkpoint, err := readStorageCopyCheckpoint(checkpointFile)
+	require.NoError(t, err)
+	assert.Equal(t, &storageCopyCheckpoint{UserID: 2}, checkpoint)
+}
+
+func TestRunStorageCommand(t *testing.T) {
+	storage := FileStorageScheme + filepath.Join(t.TempDir(), "urls.json")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{name: "unknown command", args: []string{"move"}, wantErr: ErrUnknownStorageCommand},
+		{name: "without command", args: []string{}, wantErr: ErrUnknownStorageCommand},
+		{name: "without target", args: []string{"copy", "--from", storage}, wantErr: ErrEmptyStorage},
+		{name: "the same storage", args: []string{"copy", "--from", storage, "--to", storage}, wantErr: ErrSameStorage},
+		{
+			name:    "zero batch size",
+			args:    []string{"copy", "--from", storage, "--to", storage + ".copy", "--batch-size", "0"},
+			wantErr: ErrInvalidBatchSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runStorageCommand(context.Background(), &Config{QueryTimeout: time.Second}, tt.args)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		target := filepath.Join(t.TempDir(), "target", "urls.json")
+		err := runStorageCommand(context.Background(), &Config{}, []string{
+			"copy", "--from", storage, "--to", FileStorageScheme + target, "--dry-run",
+		})
+		require.NoError(t, err)
+		_, err = os.Stat(filepath.Dir(target))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -1,17 +1,132 @@
 package app
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Errors for app.
+var (
+	ErrURLIDExists = errors.New("url ID exists") // URL ID is already used by another URL
+	ErrURLNotFound = errors.New("url not found") // URL with ID does not exist
+	ErrURLExists   = errors.New("url exists")    // original URL is already shortened with another URL ID
+
+	ErrUnknownURLUniqueness = errors.New("unknown url uniqueness") // uniqueness is not global or user
+
+	ErrDeletionJobNotFound = errors.New("deletion job not found") // deletion job with ID does not exist
+)
+
+// Scopes in which original URL is shortened only once.
+const (
+	URLUniquenessGlobal string = "global" // all users get the same short URL for original URL
+	URLUniquenessUser   string = "user"   // every user gets own short URL for original URL
+)
+
+// ValidateURLUniqueness returns error if uniqueness is unknown.
+func ValidateURLUniqueness(uniqueness string) error {
+	if uniqueness != URLUniquenessGlobal && uniqueness != URLUniquenessUser {
+		return fmt.Errorf("%w: %s", ErrUnknownURLUniqueness, uniqueness)
+	}
+	return nil
+}
+
 // URL struct for URL.
 type URL struct {
 	ID        string
 	URL       string
 	UserID    uint
 	IsDeleted bool
+	DeletedAt *time.Time `json:",omitempty"` // time of deletion, nil if URL is not deleted
+	ExpiresAt *time.Time `json:",omitempty"` // nil if URL never expires
+	CreatedAt time.Time  // zero for URLs saved before creation time was stored
+
+	Clicks         uint64     `json:",omitempty"` // count of redirects
+	LastAccessedAt *time.Time `json:",omitempty"` // time of last redirect
+}
+
+// URLEdit is change of original URL of short URL by its user.
+type URLEdit struct {
+	URLID    string
+	OldURL   string
+	NewURL   string
+	EditedAt time.Time
+}
+
+// URLClicks struct for redirects to URL.
+type URLClicks struct {
+	ID             string
+	Count          uint64
+	LastAccessedAt time.Time
+}
+
+// Statuses of deletion job.
+const (
+	DeletionJobPending string = "pending" // waiting for first attempt or retry
+	DeletionJobDone    string = "done"    // URLs are deleted
+	DeletionJobFailed  string = "failed"  // dead letter: attempts are exhausted
+
+	DeletionURLIgnored string = "ignored" // status of URL in done deletion job: URL did not exist or belonged to another user
+)
+
+// Statuses of URL in restore request.
+const (
+	RestoreURLRestored string = "restored" // URL is not deleted anymore
+	RestoreURLIgnored  string = "ignored"  // URL does not exist, is not deleted, is expired, is purged or belongs to another user
+)
+
+// DeletionJob is persistent request of user to delete URLs.
+type DeletionJob struct {
+	ID            string
+	UserID        uint
+	URLIDs        []string
+	Status        string
+	Attempts      uint      // count of failed attempts
+	NextAttemptAt time.Time // job is not processed before this time
+	LastError     string    `json:",omitempty"` // error of last failed attempt
+	CreatedAt     time.Time
+	FinishedAt    *time.Time `json:",omitempty"` // time when job is done or moved to dead letter, nil if job is pending
+	IgnoredURLIDs []string   `json:",omitempty"` // URLs which did not exist or belonged to another user when job was done
+}
+
+// URLCursor is position of URL in user URLs sorted by creation time.
+type URLCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"` // breaks ties between URLs created at the same time
+}
+
+// UserURLsFilter filter and page of user URLs for storage.
+// URLs are sorted by CreatedAt and then by ID.
+type UserURLsFilter struct {
+	Limit          uint       // max count of URLs, 0 means without limit
+	After          *URLCursor // return URLs after cursor in sort order, nil means from the beginning
+	Search         string     // substring of original URL
+	IncludeDeleted bool       // return deleted URLs too
+	Desc           bool       // sort in descending order
+}
+
+// Compare compares cursor c with position of URL with createdAt and id in ascending order.
+// It returns -1 if c is before URL, 0 if c points to URL and +1 if c is after URL.
+func (c *URLCursor) Compare(createdAt time.Time, id string) int {
+	if cmp := c.CreatedAt.Compare(createdAt); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(c.ID, id)
+}
+
+// IsExpired checks URL expiration at the moment now.
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
 }
 
 // RequestBatchURL struct for APIGetOrCreateURLs handler.
 type RequestBatchURL struct {
-	CorrelationID string `json:"correlation_id"` // ID for connect OriginalURL with ShortURL in ResponseBatchURL
-	OriginalURL   string `json:"original_url"`
+	CorrelationID string     `json:"correlation_id"` // ID for connect OriginalURL with ShortURL in ResponseBatchURL
+	OriginalURL   string     `json:"original_url"`
+	Alias         string     `json:"alias,omitempty"`      // custom URL ID, generated if empty
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // absolute expiration time, can't be used with TTL
+	TTL           int64      `json:"ttl,omitempty"`        // lifetime in seconds, can't be used with ExpiresAt
 }
 
 // ResponseBatchURL struct for APIGetOrCreateURLs handler.
@@ -20,8 +135,130 @@ type ResponseBatchURL struct {
 	ShortURL      string `json:"short_url"`
 }
 
+// UserURLsParams struct for parameters of APIGetUserURLs handler.
+type UserURLsParams struct {
+	Limit          uint   // max count of URLs in page, 0 means all URLs
+	Cursor         string // cursor returned with previous page
+	Search         string // substring of original URL
+	IncludeDeleted bool   // return deleted URLs too
+	Desc           bool   // sort by creation time in descending order
+}
+
 // ResponseUserURL struct for APIGetUserURLs handler.
 type ResponseUserURL struct {
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	IsDeleted   bool       `json:"is_deleted,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"` // deleted URL is purged after grace period since this time
+}
+
+// ResponseURLStats struct for APIGetURLStats handler.
+type ResponseURLStats struct {
+	ShortURL       string     `json:"short_url"`
+	OriginalURL    string     `json:"original_url"`
+	Clicks         uint64     `json:"clicks"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	IsDeleted      bool       `json:"is_deleted"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// ResponseURLEdit struct for change of original URL in history of user URL.
+type ResponseURLEdit struct {
+	OldURL   string    `json:"old_url"`
+	NewURL   string    `json:"new_url"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+// ResponseUpdateUserURL struct for APIUpdateUserURL handler.
+type ResponseUpdateUserURL struct {
+	ShortURL    string            `json:"short_url"`
+	OriginalURL string            `json:"original_url"`
+	History     []ResponseURLEdit `json:"history"` // changes of original URL sorted by time
+}
+
+// ResponseStats struct for APIGetStats handler.
+type ResponseStats struct {
+	URLs  uint `json:"urls"`  // count of short URLs
+	Users uint `json:"users"` // count of users
+}
+
+// ResponseDeleteUserURLs struct for APIDeleteUserURLs handler.
+type ResponseDeleteUserURLs struct {
+	JobID string `json:"job_id"` // ID of deletion job
+}
+
+// ResponseDeletionJobURL struct for status of URL in deletion job.
+type ResponseDeletionJobURL struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // pending, done, failed or ignored
+}
+
+// ResponseRestoreUserURL struct for status of URL in APIRestoreUserURLs handler.
+type ResponseRestoreUserURL struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // restored or ignored
+}
+
+// ResponseDeletionJob struct for APIGetDeletionJob handler.
+type ResponseDeletionJob struct {
+	JobID     string                   `json:"job_id"`
+	Status    string                   `json:"status"`   // pending, done or failed
+	Attempts  uint                     `json:"attempts"` // count of failed attempts
+	CreatedAt time.Time                `json:"created_at"`
+	URLs      []ResponseDeletionJobURL `json:"urls"`
+}
+
+// Statuses and names of health checks.
+const (
+	HealthStatusOK   string = "ok"   // check passed
+	HealthStatusFail string = "fail" // check failed
+
+	ReadinessStorage        string = "storage"         // active storage is available
+	ReadinessDeletionWorker string = "deletion_worker" // background deletion of URLs is running
+	ReadinessShutdown       string = "shutdown"        // app is not shutting down
+)
+
+// ResponseHealthCheck struct for result of health check.
+type ResponseHealthCheck struct {
+	Status string `json:"status"`          // ok or fail
+	Error  string `json:"error,omitempty"` // reason of failed check
+}
+
+// ResponseReadiness struct for Readyz handler.
+type ResponseReadiness struct {
+	Status string                         `json:"status"` // ok if all checks passed, otherwise fail
+	Checks map[string]ResponseHealthCheck `json:"checks"`
+}
+
+// Formats of exported and imported user URLs.
+const (
+	FormatCSV    string = "csv"    // header and row of comma separated values for every URL
+	FormatNDJSON string = "ndjson" // JSON object on separate line for every URL
+)
+
+// Statuses of imported user URL.
+const (
+	ImportStatusImported   string = "imported"    // URL is saved with short ID of row or URL of row is already saved with this ID
+	ImportStatusExists     string = "exists"      // original URL is already saved with another short ID or row without short ID
+	ImportStatusAliasTaken string = "alias_taken" // short ID of row is used by another URL
+	ImportStatusSkipped    string = "skipped"     // deleted URL is not imported
+	ImportStatusInvalid    string = "invalid"     // original URL or short URL of row is invalid
+)
+
+// UserURLRecord struct for row of exported or imported user URLs.
+type UserURLRecord struct {
+	ShortURL    string     `json:"short_url"` // short ID is last path segment, new ID is generated if empty
+	OriginalURL string     `json:"original_url"`
+	IsDeleted   bool       `json:"is_deleted"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"` // ignored by import
+}
+
+// ResponseImportUserURL struct for outcome of imported row in APIImportUserURLs handler.
+type ResponseImportUserURL struct {
+	Row      int    `json:"row"`                 // number of row starting from 1, header of CSV is not counted
+	ShortURL string `json:"short_url,omitempty"` // short URL of saved URL
+	Status   string `json:"status"`              // imported, exists, alias_taken, skipped or invalid
+	Error    string `json:"error,omitempty"`     // reason of invalid row
 }
@@ -0,0 +1,211 @@
+package delivery
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"time"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+)
+
+// Errors for formats of user URLs.
+var (
+	ErrUnknownFormat    = errors.New("unknown format")                   // format is not csv or ndjson
+	ErrInvalidCSVHeader = errors.New("invalid CSV header")               // header has no original_url column or has duplicate columns
+	ErrInvalidCSVRow    = errors.New("invalid CSV row")                  // value of row can not be parsed
+	ErrInvalidNDJSONRow = errors.New("invalid NDJSON row")               // row is not JSON object of user URL
+	ErrTooManyRows      = errors.New("too many rows to import")          // rows exceed max count of imported URLs
+	ErrNoImportFormat   = errors.New("format of imported URLs is empty") // neither format query nor known Content-Type
+)
+
+// Columns of CSV with user URLs.
+const (
+	CSVShortURLColumn    string = "short_url"
+	CSVOriginalURLColumn string = "original_url"
+	CSVIsDeletedColumn   string = "is_deleted"
+	CSVCreatedAtColumn   string = "created_at"
+)
+
+// csvHeader is header of exported CSV.
+var csvHeader = []string{CSVShortURLColumn, CSVOriginalURLColumn, CSVIsDeletedColumn, CSVCreatedAtColumn}
+
+// formatContentTypes maps format of user URLs to its Content-Type.
+var formatContentTypes = map[string]string{
+	app.FormatCSV:    TextCSVKey,
+	app.FormatNDJSON: ApplicationNDJSONKey,
+}
+
+// parseExportFormat returns format from query parameter, NDJSON is default format.
+func parseExportFormat(format string) (string, error) {
+	if format == "" {
+		return app.FormatNDJSON, nil
+	}
+	if _, ok := formatContentTypes[format]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	return format, nil
+}
+
+// parseImportFormat returns format from query parameter or from Content-Type of request.
+func parseImportFormat(format, contentType string) (string, error) {
+	if format != "" {
+		return parseExportFormat(format)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for f, ct := range formatContentTypes {
+		if mediaType == ct {
+			return f, nil
+		}
+	}
+	return "", ErrNoImportFormat
+}
+
+// userURLsEncoder writes records of user URLs to writer in format page by page.
+type userURLsEncoder struct {
+	format  string
+	json    *json.Encoder
+	csv     *csv.Writer
+	started bool // something is written to writer
+}
+
+// newUserURLsEncoder creates *userURLsEncoder which writes records to w in format.
+func newUserURLsEncoder(w io.Writer, format string) *userURLsEncoder {
+	return &userURLsEncoder{format: format, json: json.NewEncoder(w), csv: csv.NewWriter(w)}
+}
+
+// Started reports whether something is written to writer.
+func (e *userURLsEncoder) Started() bool {
+	return e.started
+}
+
+// Encode writes page of records, CSV header is written before the first page.
+func (e *userURLsEncoder) Encode(records []app.UserURLRecord) error {
+	if e.format != app.FormatCSV {
+		e.started = true
+		for _, record := range records {
+			if err := e.json.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !e.started {
+		e.started = true
+		if err := e.csv.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	for _, record := range records {
+		var createdAt string
+		if record.CreatedAt != nil {
+			createdAt = record.CreatedAt.Format(time.RFC3339Nano)
+		}
+		row := []string{record.ShortURL, record.OriginalURL, strconv.FormatBool(record.IsDeleted), createdAt}
+		if err := e.csv.Write(row); err != nil {
+			return err
+		}
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// Flush finishes writing of records, CSV header is written even if there are no records.
+func (e *userURLsEncoder) Flush() error {
+	if e.format == app.FormatCSV && !e.started {
+		return e.Encode(nil)
+	}
+	return nil
+}
+
+// decodeUserURLs reads at most maxRows records in format from r.
+func decodeUserURLs(r io.Reader, format string, maxRows int) ([]app.UserURLRecord, error) {
+	if format == app.FormatCSV {
+		return decodeUserURLsCSV(r, maxRows)
+	}
+
+	records := []app.UserURLRecord{}
+	dec := json.NewDecoder(r)
+	for row := 1; ; row++ {
+		record := app.UserURLRecord{}
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w %d: %w", ErrInvalidNDJSONRow, row, err)
+		}
+		if row > maxRows {
+			return nil, ErrTooManyRows
+		}
+		records = append(records, record)
+	}
+}
+
+// decodeUserURLsCSV reads CSV with header. Columns are found by header, so their order does not matter,
+// only original_url column is required.
+func decodeUserURLsCSV(r io.Reader, maxRows int) ([]app.UserURLRecord, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return []app.UserURLRecord{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		if _, ok := columns[column]; ok {
+			return nil, fmt.Errorf("%w: duplicate column %s", ErrInvalidCSVHeader, column)
+		}
+		columns[column] = i
+	}
+	if _, ok := columns[CSVOriginalURLColumn]; !ok {
+		return nil, fmt.Errorf("%w: no column %s", ErrInvalidCSVHeader, CSVOriginalURLColumn)
+	}
+	value := func(row []string, column string) string {
+		if i, ok := columns[column]; ok {
+			return row[i]
+		}
+		return ""
+	}
+
+	records := []app.UserURLRecord{}
+	for i := 1; ; i++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if i > maxRows {
+			return nil, ErrTooManyRows
+		}
+
+		record := app.UserURLRecord{
+			ShortURL:    value(row, CSVShortURLColumn),
+			OriginalURL: value(row, CSVOriginalURLColumn),
+		}
+		if isDeleted := value(row, CSVIsDeletedColumn); isDeleted != "" {
+			record.IsDeleted, err = strconv.ParseBool(isDeleted)
+			if err != nil {
+				return nil, fmt.Errorf("%w %d: %s: %w", ErrInvalidCSVRow, i, CSVIsDeletedColumn, err)
+			}
+		}
+		if createdAt := value(row, CSVCreatedAtColumn); createdAt != "" {
+			t, err := time.Parse(time.RFC3339Nano, createdAt)
+			if err != nil {
+				return nil, fmt.Errorf("%w %d: %s: %w", ErrInvalidCSVRow, i, CSVCreatedAtColumn, err)
+			}
+			record.CreatedAt = &t
+		}
+		records = append(records, record)
+	}
+}
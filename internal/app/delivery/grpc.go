@@ -0,0 +1,377 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/MisterMaks/go-yandex-shortener/api/proto"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	appUsecaseInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
+	"github.com/MisterMaks/go-yandex-shortener/internal/logger"
+	"github.com/MisterMaks/go-yandex-shortener/internal/metrics"
+	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AppGRPCServer gRPC handlers struct.
+type AppGRPCServer struct {
+	pb.UnimplementedShortenerServer
+
+	AppUsecase AppUsecaseInterface
+}
+
+// NewAppGRPCServer creates *AppGRPCServer.
+func NewAppGRPCServer(appUsecase AppUsecaseInterface) *AppGRPCServer {
+	return &AppGRPCServer{AppUsecase: appUsecase}
+}
+
+func timestampToTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func timeToTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// GetOrCreateURL get (if URL existed) or create short URL.
+func (s *AppGRPCServer) GetOrCreateURL(ctx context.Context, in *pb.GetOrCreateURLRequest) (*pb.GetOrCreateURLResponse, error) {
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Creating or getting URL using gRPC")
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	expiresAt, err := appUsecaseInternal.GetExpirationTime(timestampToTime(in.GetExpiresAt()), in.GetTtl(), time.Now())
+	if err != nil {
+		handlerLogger.Warn("Invalid expiration",
+			zap.Any(RequestBodyKey, in),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	url, exists, err := s.AppUsecase.GetOrCreateURL(ctx, in.GetUrl(), in.GetAlias(), expiresAt, userID)
+	if errors.Is(err, appUsecaseInternal.ErrAliasTaken) {
+		handlerLogger.Warn("Alias is already taken",
+			zap.String(URLKey, in.GetUrl()),
+			zap.String(AliasKey, in.GetAlias()),
+		)
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if errors.Is(err, appUsecaseInternal.ErrURLDeleted) {
+		handlerLogger.Warn("URL is deleted or expired",
+			zap.String(URLKey, in.GetUrl()),
+		)
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.String(URLKey, in.GetUrl()),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &pb.GetOrCreateURLResponse{
+		Result: s.AppUsecase.GenerateShortURL(url.ID),
+		Exists: exists,
+	}, nil
+}
+
+// GetOrCreateURLs get (if URLs existed) or create short URLs.
+func (s *AppGRPCServer) GetOrCreateURLs(ctx context.Context, in *pb.GetOrCreateURLsRequest) (*pb.GetOrCreateURLsResponse, error) {
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Creating or getting URLs batch using gRPC")
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	requestBatchURLs := make([]app.RequestBatchURL, 0, len(in.GetUrls()))
+	for _, u := range in.GetUrls() {
+		requestBatchURLs = append(requestBatchURLs, app.RequestBatchURL{
+			CorrelationID: u.GetCorrelationId(),
+			OriginalURL:   u.GetOriginalUrl(),
+			Alias:         u.GetAlias(),
+			ExpiresAt:     timestampToTime(u.GetExpiresAt()),
+			TTL:           u.GetTtl(),
+		})
+	}
+
+	responseBatchURLs, err := s.AppUsecase.GetOrCreateURLs(ctx, requestBatchURLs, userID)
+	if errors.Is(err, appUsecaseInternal.ErrAliasTaken) {
+		handlerLogger.Warn("Alias is already taken",
+			zap.Any(URLsKey, requestBatchURLs),
+		)
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if errors.Is(err, appUsecaseInternal.ErrURLDeleted) {
+		handlerLogger.Warn("URL is deleted or expired",
+			zap.Any(URLsKey, requestBatchURLs),
+		)
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(URLsKey, requestBatchURLs),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &pb.GetOrCreateURLsResponse{Urls: make([]*pb.ResponseBatchURL, 0, len(responseBatchURLs))}
+	for _, u := range responseBatchURLs {
+		resp.Urls = append(resp.Urls, &pb.ResponseBatchURL{
+			CorrelationId: u.CorrelationID,
+			ShortUrl:      u.ShortURL,
+		})
+	}
+
+	return resp, nil
+}
+
+// GetURL get original URL for short URL ID.
+func (s *AppGRPCServer) GetURL(ctx context.Context, in *pb.GetURLRequest) (*pb.GetURLResponse, error) {
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Getting URL using gRPC")
+
+	if in.GetId() == "" {
+		handlerLogger.Warn("Bad request",
+			zap.String(RequestPathIDKey, in.GetId()),
+		)
+		return nil, status.Error(codes.InvalidArgument, "empty id")
+	}
+
+	url, err := s.AppUsecase.GetURL(ctx, in.GetId())
+	switch {
+	case errors.Is(err, app.ErrURLNotFound):
+		handlerLogger.Warn("URL not found",
+			zap.String(RequestPathIDKey, in.GetId()),
+		)
+		metrics.RedirectsTotal.WithLabelValues(metrics.RedirectMiss).Inc()
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		handlerLogger.Error("Failed to get URL",
+			zap.String(RequestPathIDKey, in.GetId()),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	handlerLogger.Info("Found URL",
+		zap.Any(URLKey, url),
+	)
+
+	if url.IsDeleted || url.IsExpired(time.Now()) {
+		metrics.RedirectsTotal.WithLabelValues(metrics.RedirectMiss).Inc()
+		return nil, status.Error(codes.FailedPrecondition, appUsecaseInternal.ErrURLDeleted.Error())
+	}
+
+	metrics.RedirectsTotal.WithLabelValues(metrics.RedirectHit).Inc()
+
+	s.AppUsecase.SendURLClickInChan(url.ID)
+
+	return &pb.GetURLResponse{Url: url.URL}, nil
+}
+
+// GetUserURLs get short and original URLs of user.
+func (s *AppGRPCServer) GetUserURLs(ctx context.Context, in *pb.GetUserURLsRequest) (*pb.GetUserURLsResponse, error) {
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Getting user URLs using gRPC")
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	userURLs, nextCursor, err := s.AppUsecase.GetUserURLs(ctx, userID, app.UserURLsParams{
+		Limit:          uint(in.GetLimit()),
+		Cursor:         in.GetCursor(),
+		Search:         in.GetSearch(),
+		IncludeDeleted: in.GetIncludeDeleted(),
+		Desc:           in.GetDesc(),
+	})
+	if err != nil {
+		handlerLogger.Warn("Bad request", zap.Error(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &pb.GetUserURLsResponse{
+		Urls:       make([]*pb.ResponseUserURL, 0, len(userURLs)),
+		NextCursor: nextCursor,
+	}
+	for _, u := range userURLs {
+		resp.Urls = append(resp.Urls, &pb.ResponseUserURL{
+			ShortUrl:    u.ShortURL,
+			OriginalUrl: u.OriginalURL,
+			ExpiresAt:   timeToTimestamp(u.ExpiresAt),
+			CreatedAt:   timeToTimestamp(u.CreatedAt),
+			IsDeleted:   u.IsDeleted,
+		})
+	}
+
+	return resp, nil
+}
+
+// DeleteUserURLs save job to delete user URLs in persistent queue.
+func (s *AppGRPCServer) DeleteUserURLs(ctx context.Context, in *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Deleting user URLs using gRPC")
+
+	handlerLogger.Debug("Request data", zap.Any("url_ids", in.GetIds()))
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	jobID, err := s.AppUsecase.EnqueueDeleteUserURLs(ctx, userID, in.GetIds())
+	if err != nil {
+		handlerLogger.Error("Failed to enqueue deletion of user URLs",
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.DeleteUserURLsResponse{JobId: jobID}, nil
+}
+
+// GetDeletionJob get status of user deletion job.
+func (s *AppGRPCServer) GetDeletionJob(ctx context.Context, in *pb.GetDeletionJobRequest) (*pb.GetDeletionJobResponse, error) {
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Getting deletion job using gRPC")
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	job, err := s.AppUsecase.GetDeletionJob(ctx, in.GetJobId(), userID)
+	switch {
+	case errors.Is(err, app.ErrDeletionJobNotFound):
+		handlerLogger.Warn("Deletion job not found",
+			zap.String(RequestPathIDKey, in.GetJobId()),
+		)
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, appUsecaseInternal.ErrForbidden):
+		handlerLogger.Warn("Deletion job belongs to another user",
+			zap.String(RequestPathIDKey, in.GetJobId()),
+		)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		handlerLogger.Error("Failed to get deletion job",
+			zap.String(RequestPathIDKey, in.GetJobId()),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &pb.GetDeletionJobResponse{
+		JobId:     job.JobID,
+		Status:    job.Status,
+		Attempts:  uint32(job.Attempts),
+		CreatedAt: timestamppb.New(job.CreatedAt),
+		Urls:      make([]*pb.DeletionJobURL, 0, len(job.URLs)),
+	}
+	for _, u := range job.URLs {
+		resp.Urls = append(resp.Urls, &pb.DeletionJobURL{Id: u.ID, Status: u.Status})
+	}
+
+	return resp, nil
+}
+
+// GetURLStats get statistics of user URL.
+func (s *AppGRPCServer) GetURLStats(ctx context.Context, in *pb.GetURLStatsRequest) (*pb.GetURLStatsResponse, error) {
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Getting user URL statistics using gRPC")
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	stats, err := s.AppUsecase.GetURLStats(ctx, in.GetId(), userID)
+	switch {
+	case errors.Is(err, app.ErrURLNotFound):
+		handlerLogger.Warn("URL not found",
+			zap.String(RequestPathIDKey, in.GetId()),
+		)
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, appUsecaseInternal.ErrForbidden):
+		handlerLogger.Warn("URL belongs to another user",
+			zap.String(RequestPathIDKey, in.GetId()),
+		)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		handlerLogger.Warn("Bad request",
+			zap.String(RequestPathIDKey, in.GetId()),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &pb.GetURLStatsResponse{
+		ShortUrl:       stats.ShortURL,
+		OriginalUrl:    stats.OriginalURL,
+		Clicks:         stats.Clicks,
+		LastAccessedAt: timeToTimestamp(stats.LastAccessedAt),
+		IsDeleted:      stats.IsDeleted,
+		ExpiresAt:      timeToTimestamp(stats.ExpiresAt),
+	}, nil
+}
+
+// Ping ping database.
+func (s *AppGRPCServer) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Ping DB")
+
+	err := s.AppUsecase.Ping(ctx)
+	if err != nil {
+		handlerLogger.Error("Failed to ping DB",
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.PingResponse{}, nil
+}
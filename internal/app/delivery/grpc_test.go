@@ -0,0 +1,316 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/MisterMaks/go-yandex-shortener/api/proto"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app/delivery/mocks"
+	appUsecaseInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
+	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAppGRPCServer_GetOrCreateURL(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), usecase.UserIDKey, TestUserID)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		request  *pb.GetOrCreateURLRequest
+		want     *pb.GetOrCreateURLResponse
+		wantCode codes.Code
+	}{
+		{
+			name:     "valid URL",
+			ctx:      userCtx,
+			request:  &pb.GetOrCreateURLRequest{Url: TestValidURL},
+			want:     &pb.GetOrCreateURLResponse{Result: TestHost + "/" + TestID},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "invalid URL",
+			ctx:      userCtx,
+			request:  &pb.GetOrCreateURLRequest{Url: TestInvalidURL},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "taken alias",
+			ctx:      userCtx,
+			request:  &pb.GetOrCreateURLRequest{Url: TestValidURL, Alias: TestTakenAlias},
+			wantCode: codes.AlreadyExists,
+		},
+		{
+			name:     "invalid expiration",
+			ctx:      userCtx,
+			request:  &pb.GetOrCreateURLRequest{Url: TestValidURL, Ttl: -1},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "unauthorized user",
+			ctx:      context.Background(),
+			request:  &pb.GetOrCreateURLRequest{Url: TestValidURL},
+			wantCode: codes.Unauthenticated,
+		},
+	}
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().GetOrCreateURL(gomock.Any(), TestValidURL, "", nil, TestUserID).Return(&app.URL{
+		ID:     TestID,
+		URL:    TestValidURL,
+		UserID: TestUserID,
+	}, false, nil).AnyTimes()
+	m.EXPECT().GetOrCreateURL(gomock.Any(), TestValidURL, TestTakenAlias, nil, TestUserID).
+		Return(nil, false, appUsecaseInternal.ErrAliasTaken).AnyTimes()
+	m.EXPECT().GetOrCreateURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, false, ErrTestInvalidURL).AnyTimes()
+	m.EXPECT().GenerateShortURL(TestID).Return(TestHost + "/" + TestID).AnyTimes()
+
+	s := NewAppGRPCServer(m)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.GetOrCreateURL(tt.ctx, tt.request)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode == codes.OK {
+				assert.Equal(t, tt.want.GetResult(), resp.GetResult())
+				assert.Equal(t, tt.want.GetExists(), resp.GetExists())
+			}
+		})
+	}
+}
+
+func TestAppGRPCServer_GetURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		want     string
+		wantCode codes.Code
+	}{
+		{
+			name:     "valid ID",
+			id:       TestID,
+			want:     TestValidURL,
+			wantCode: codes.OK,
+		},
+		{
+			name:     "expired ID",
+			id:       TestExpiredID,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "deleted ID",
+			id:       TestDeletedID,
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "not found",
+			id:       "not_found",
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "storage error",
+			id:       TestErrorID,
+			wantCode: codes.Internal,
+		},
+		{
+			name:     "empty ID",
+			id:       "",
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().GetURL(gomock.Any(), TestID).Return(&app.URL{
+		ID:  TestID,
+		URL: TestValidURL,
+	}, nil).AnyTimes()
+	expiresAt := time.Now().Add(-time.Second)
+	m.EXPECT().GetURL(gomock.Any(), TestExpiredID).Return(&app.URL{
+		ID:        TestExpiredID,
+		URL:       TestValidURL,
+		ExpiresAt: &expiresAt,
+	}, nil).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), TestDeletedID).Return(&app.URL{
+		ID:        TestDeletedID,
+		URL:       TestValidURL,
+		IsDeleted: true,
+	}, nil).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), TestErrorID).Return(nil, errors.New("storage is unavailable")).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), gomock.Any()).Return(nil, app.ErrURLNotFound).AnyTimes()
+	m.EXPECT().SendURLClickInChan(TestID).Times(1)
+
+	s := NewAppGRPCServer(m)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.GetURL(context.Background(), &pb.GetURLRequest{Id: tt.id})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.want, resp.GetUrl())
+		})
+	}
+}
+
+func TestAppGRPCServer_GetOrCreateURLs(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), usecase.UserIDKey, TestUserID)
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().GetOrCreateURLs(gomock.Any(), []app.RequestBatchURL{
+		{CorrelationID: TestID, OriginalURL: TestValidURL},
+	}, TestUserID).Return([]app.ResponseBatchURL{
+		{CorrelationID: TestID, ShortURL: TestHost + "/" + TestID},
+	}, nil)
+
+	s := NewAppGRPCServer(m)
+
+	resp, err := s.GetOrCreateURLs(userCtx, &pb.GetOrCreateURLsRequest{
+		Urls: []*pb.RequestBatchURL{{CorrelationId: TestID, OriginalUrl: TestValidURL}},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetUrls(), 1)
+	assert.Equal(t, TestID, resp.GetUrls()[0].GetCorrelationId())
+	assert.Equal(t, TestHost+"/"+TestID, resp.GetUrls()[0].GetShortUrl())
+
+	_, err = s.GetOrCreateURLs(context.Background(), &pb.GetOrCreateURLsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAppGRPCServer_GetUserURLs(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), usecase.UserIDKey, TestUserID)
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().GetUserURLs(gomock.Any(), TestUserID, app.UserURLsParams{Limit: 1, Search: "valid", Desc: true}).
+		Return([]app.ResponseUserURL{
+			{ShortURL: TestHost + "/" + TestID, OriginalURL: TestValidURL},
+		}, "next", nil)
+
+	s := NewAppGRPCServer(m)
+
+	resp, err := s.GetUserURLs(userCtx, &pb.GetUserURLsRequest{Limit: 1, Search: "valid", Desc: true})
+	require.NoError(t, err)
+	assert.Equal(t, "next", resp.GetNextCursor())
+	require.Len(t, resp.GetUrls(), 1)
+	assert.Equal(t, TestHost+"/"+TestID, resp.GetUrls()[0].GetShortUrl())
+	assert.Equal(t, TestValidURL, resp.GetUrls()[0].GetOriginalUrl())
+	assert.Nil(t, resp.GetUrls()[0].GetExpiresAt())
+
+	_, err = s.GetUserURLs(context.Background(), &pb.GetUserURLsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAppGRPCServer_DeleteUserURLs(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), usecase.UserIDKey, TestUserID)
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().EnqueueDeleteUserURLs(gomock.Any(), TestUserID, []string{TestID}).Return(TestJobID, nil).Times(1)
+	m.EXPECT().EnqueueDeleteUserURLs(gomock.Any(), TestUserID, []string{"789"}).Return("", errors.New("queue error")).Times(1)
+
+	s := NewAppGRPCServer(m)
+
+	resp, err := s.DeleteUserURLs(userCtx, &pb.DeleteUserURLsRequest{Ids: []string{TestID}})
+	require.NoError(t, err)
+	assert.Equal(t, TestJobID, resp.GetJobId())
+
+	_, err = s.DeleteUserURLs(userCtx, &pb.DeleteUserURLsRequest{Ids: []string{"789"}})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = s.DeleteUserURLs(context.Background(), &pb.DeleteUserURLsRequest{Ids: []string{TestID}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAppGRPCServer_GetDeletionJob(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), usecase.UserIDKey, TestUserID)
+	createdAt := time.Date(2024, 10, 16, 15, 0, 0, 0, time.UTC)
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().GetDeletionJob(gomock.Any(), TestJobID, TestUserID).Return(&app.ResponseDeletionJob{
+		JobID:     TestJobID,
+		Status:    app.DeletionJobPending,
+		Attempts:  1,
+		CreatedAt: createdAt,
+		URLs: []app.ResponseDeletionJobURL{
+			{ID: TestID, Status: app.DeletionJobPending},
+			{ID: "other", Status: app.DeletionURLIgnored},
+		},
+	}, nil).Times(1)
+	m.EXPECT().GetDeletionJob(gomock.Any(), "not_found", TestUserID).Return(nil, app.ErrDeletionJobNotFound).Times(1)
+	m.EXPECT().GetDeletionJob(gomock.Any(), "other", TestUserID).Return(nil, appUsecaseInternal.ErrForbidden).Times(1)
+
+	s := NewAppGRPCServer(m)
+
+	resp, err := s.GetDeletionJob(userCtx, &pb.GetDeletionJobRequest{JobId: TestJobID})
+	require.NoError(t, err)
+	assert.Equal(t, TestJobID, resp.GetJobId())
+	assert.Equal(t, app.DeletionJobPending, resp.GetStatus())
+	assert.Equal(t, uint32(1), resp.GetAttempts())
+	assert.True(t, createdAt.Equal(resp.GetCreatedAt().AsTime()))
+	require.Len(t, resp.GetUrls(), 2)
+	assert.Equal(t, app.DeletionURLIgnored, resp.GetUrls()[1].GetStatus())
+
+	_, err = s.GetDeletionJob(userCtx, &pb.GetDeletionJobRequest{JobId: "not_found"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.GetDeletionJob(userCtx, &pb.GetDeletionJobRequest{JobId: "other"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = s.GetDeletionJob(context.Background(), &pb.GetDeletionJobRequest{JobId: TestJobID})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAppGRPCServer_Ping(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	gomock.InOrder(
+		m.EXPECT().Ping(gomock.Any()).Return(nil),
+		m.EXPECT().Ping(gomock.Any()).Return(ErrTestIDNotFound),
+	)
+
+	s := NewAppGRPCServer(m)
+
+	_, err := s.Ping(context.Background(), &pb.PingRequest{})
+	require.NoError(t, err)
+
+	_, err = s.Ping(context.Background(), &pb.PingRequest{})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
@@ -1,14 +1,22 @@
 package delivery
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	appUsecaseInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
 	"github.com/MisterMaks/go-yandex-shortener/internal/logger"
+	"github.com/MisterMaks/go-yandex-shortener/internal/metrics"
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
 	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
@@ -20,37 +28,108 @@ const (
 	TextPlainKey       string = "text/plain"
 	ApplicationJSONKey string = "application/json"
 
+	TextCSVKey            string = "text/csv"
+	ApplicationNDJSONKey  string = "application/x-ndjson"
+	ContentDispositionKey string = "Content-Disposition"
+
 	MethodKey         string = "method"
 	HeaderKey         string = "header"
 	RequestBodyKey    string = "request_body"
 	RequestBodyStrKey string = "request_body_str"
 	URLIDKey          string = "url_id"
 	URLKey            string = "url"
+	AliasKey          string = "alias"
 	URLsKey           string = "urls"
 	ShortURLKey       string = "short_url"
 	RequestPathIDKey  string = "request_path_id"
 	ResponseKey       string = "response"
+	QueryKey          string = "query"
+
+	LimitQueryKey          string = "limit"
+	CursorQueryKey         string = "cursor"
+	SearchQueryKey         string = "search"
+	IncludeDeletedQueryKey string = "include_deleted"
+	SortQueryKey           string = "sort"
+	SortCreatedAtAsc       string = "created_at"  // sort by creation time in ascending order
+	SortCreatedAtDesc      string = "-created_at" // sort by creation time in descending order
+	NextCursorKey          string = "X-Next-Cursor"
+	FormatQueryKey         string = "format"
+	ExportFilename         string = "urls" // name of exported file without extension
 )
 
+// ErrInvalidSort is error for unknown sort query parameter.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// parseUserURLsParams parses query parameters of APIGetUserURLs request.
+func parseUserURLsParams(query url.Values) (app.UserURLsParams, error) {
+	params := app.UserURLsParams{
+		Cursor: query.Get(CursorQueryKey),
+		Search: query.Get(SearchQueryKey),
+	}
+
+	if limit := query.Get(LimitQueryKey); limit != "" {
+		l, err := strconv.ParseUint(limit, 10, 0)
+		if err != nil {
+			return params, err
+		}
+		params.Limit = uint(l)
+	}
+
+	if includeDeleted := query.Get(IncludeDeletedQueryKey); includeDeleted != "" {
+		b, err := strconv.ParseBool(includeDeleted)
+		if err != nil {
+			return params, err
+		}
+		params.IncludeDeleted = b
+	}
+
+	switch query.Get(SortQueryKey) {
+	case "", SortCreatedAtAsc:
+	case SortCreatedAtDesc:
+		params.Desc = true
+	default:
+		return params, ErrInvalidSort
+	}
+
+	return params, nil
+}
+
 // AppUsecaseInterface contains the necessary functions for the business logic of app.
 type AppUsecaseInterface interface {
-	GetOrCreateURL(rawURL string, userID uint) (*app.URL, bool, error)                                   // get created or create short URL for request URL
-	GetURL(id string) (*app.URL, error)                                                                  // get original URL for short URL
-	GenerateShortURL(id string) string                                                                   // generate short URL
-	Ping() error                                                                                         // ping database
-	GetOrCreateURLs(requestBatchURLs []app.RequestBatchURL, userID uint) ([]app.ResponseBatchURL, error) // get created or create short URLs for request batch URLs
-	GetUserURLs(userID uint) ([]app.ResponseUserURL, error)                                              // get short and original URLs for user
-	SendDeleteUserURLsInChan(userID uint, urlIDs []string)                                               // send urls in delete chan
+	GetOrCreateURL(ctx context.Context, rawURL, alias string, expiresAt *time.Time, userID uint) (*app.URL, bool, error)      // get created or create short URL for request URL
+	GetURL(ctx context.Context, id string) (*app.URL, error)                                                                  // get original URL for short URL
+	GenerateShortURL(id string) string                                                                                        // generate short URL
+	Ping(ctx context.Context) error                                                                                           // ping database
+	GetOrCreateURLs(ctx context.Context, requestBatchURLs []app.RequestBatchURL, userID uint) ([]app.ResponseBatchURL, error) // get created or create short URLs for request batch URLs
+	GetUserURLs(ctx context.Context, userID uint, params app.UserURLsParams) ([]app.ResponseUserURL, string, error)           // get page of short and original URLs for user
+	EnqueueDeleteUserURLs(ctx context.Context, userID uint, urlIDs []string) (string, error)                                  // save job to delete user URLs in persistent queue
+	GetDeletionJob(ctx context.Context, jobID string, userID uint) (*app.ResponseDeletionJob, error)                          // get status of user deletion job
+	RestoreUserURLs(ctx context.Context, userID uint, urlIDs []string) ([]app.ResponseRestoreUserURL, error)                  // cancel deletion of user URLs
+	UpdateUserURL(ctx context.Context, id string, userID uint, rawURL string) (*app.ResponseUpdateUserURL, error)             // change original URL of user URL
+	GetUserURLHistory(ctx context.Context, id string, userID uint) ([]app.ResponseURLEdit, error)                             // get changes of original URL of user URL
+	SendURLClickInChan(urlID string)                                                                                          // send redirect to URL in clicks chan
+	GetURLStats(ctx context.Context, id string, userID uint) (*app.ResponseURLStats, error)                                   // get statistics of user URL
+	CountURLs(ctx context.Context) (uint, error)                                                                              // get count of short URLs
+	CheckReadiness(ctx context.Context) *app.ResponseReadiness                                                                // check storage, background workers and shutdown state
+	CompactStorage(ctx context.Context) error                                                                                 // rewrite log of storage to snapshot
+	ExportUserURLs(ctx context.Context, userID uint, write func(records []app.UserURLRecord) error) error                     // pass all URLs of user including deleted to write page by page
+	ImportUserURLs(ctx context.Context, userID uint, records []app.UserURLRecord) ([]app.ResponseImportUserURL, error)        // save URLs of user and get outcome of every record
+}
+
+// UserUsecaseInterface contains the necessary functions for the business logic of users.
+type UserUsecaseInterface interface {
+	CountUsers(ctx context.Context) (uint, error) // get count of users
 }
 
 // AppHandler handlers struct.
 type AppHandler struct {
-	AppUsecase AppUsecaseInterface
+	AppUsecase  AppUsecaseInterface
+	UserUsecase UserUsecaseInterface
 }
 
 // NewAppHandler creates *AppHandler
-func NewAppHandler(appUsecase AppUsecaseInterface) *AppHandler {
-	return &AppHandler{AppUsecase: appUsecase}
+func NewAppHandler(appUsecase AppUsecaseInterface, userUsecase UserUsecaseInterface) *AppHandler {
+	return &AppHandler{AppUsecase: appUsecase, UserUsecase: userUsecase}
 }
 
 // GetOrCreateURL Get (if URL existed) or create URL.
@@ -61,12 +140,16 @@ func NewAppHandler(appUsecase AppUsecaseInterface) *AppHandler {
 //	@Param		url	body		string	true	"URL"	example(https://test.org)
 //	@Success	201	{string}	string	"URL created"
 //	@Success	409	{string}	string	"URL exists"
+//	@Failure	410	{string}	string	"URL is deleted or expired"
 //	@Failure	405	{string}	string	"Method not allowed"
 //	@Failure	400	{string}	string	"Bad request"
 //	@Failure	401	{string}	string	"Unauthorized"
 //	@Router		/ [post]
 func (ah *AppHandler) GetOrCreateURL(w http.ResponseWriter, r *http.Request) {
-	handlerLogger := logger.GetContextLogger(r.Context())
+	ctx, span := tracing.Start(r.Context(), "AppHandler.GetOrCreateURL")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
 
 	handlerLogger.Info("Creating or getting URL")
 
@@ -98,7 +181,7 @@ func (ah *AppHandler) GetOrCreateURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := usecase.GetContextUserID(r.Context())
+	userID, err := usecase.GetContextUserID(ctx)
 	if err != nil {
 		handlerLogger.Warn("No user ID",
 			zap.Any(RequestBodyKey, r.Body),
@@ -110,7 +193,14 @@ func (ah *AppHandler) GetOrCreateURL(w http.ResponseWriter, r *http.Request) {
 
 	bodyStr := string(body)
 
-	url, exists, err := ah.AppUsecase.GetOrCreateURL(bodyStr, userID)
+	url, exists, err := ah.AppUsecase.GetOrCreateURL(ctx, bodyStr, "", nil, userID)
+	if errors.Is(err, appUsecaseInternal.ErrURLDeleted) {
+		handlerLogger.Warn("URL is deleted or expired",
+			zap.String(RequestBodyStrKey, bodyStr),
+		)
+		w.WriteHeader(http.StatusGone)
+		return
+	}
 	if err != nil {
 		handlerLogger.Warn("Bad request",
 			zap.String(RequestBodyStrKey, bodyStr),
@@ -144,12 +234,17 @@ func (ah *AppHandler) GetOrCreateURL(w http.ResponseWriter, r *http.Request) {
 //	@Param		url	body		delivery.APIGetOrCreateURL.Request	true	"URL"
 //	@Success	201	{object}	delivery.APIGetOrCreateURL.Response	"URL created"
 //	@Success	409	{object}	delivery.APIGetOrCreateURL.Response	"URL exists"
+//	@Failure	409	{string}	string								"Alias is already taken"
+//	@Failure	410	{string}	string								"URL is deleted or expired"
 //	@Failure	405	{string}	string								"Method not allowed"
 //	@Failure	400	{string}	string								"Bad request"
 //	@Failure	401	{string}	string								"Unauthorized"
 //	@Router		/api/shorten [post]
 func (ah *AppHandler) APIGetOrCreateURL(w http.ResponseWriter, r *http.Request) {
-	handlerLogger := logger.GetContextLogger(r.Context())
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIGetOrCreateURL")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
 
 	handlerLogger.Info("Creating or getting URL using API")
 
@@ -172,7 +267,10 @@ func (ah *AppHandler) APIGetOrCreateURL(w http.ResponseWriter, r *http.Request)
 	}
 
 	type Request struct {
-		URL string `json:"url"`
+		URL       string     `json:"url"`
+		Alias     string     `json:"alias,omitempty"`      // custom URL ID, generated if empty
+		ExpiresAt *time.Time `json:"expires_at,omitempty"` // absolute expiration time, can't be used with TTL
+		TTL       int64      `json:"ttl,omitempty"`        // lifetime in seconds, can't be used with ExpiresAt
 	}
 
 	var req Request
@@ -187,7 +285,7 @@ func (ah *AppHandler) APIGetOrCreateURL(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	userID, err := usecase.GetContextUserID(r.Context())
+	userID, err := usecase.GetContextUserID(ctx)
 	if err != nil {
 		handlerLogger.Warn("No user ID",
 			zap.Any(RequestBodyKey, r.Body),
@@ -197,7 +295,32 @@ func (ah *AppHandler) APIGetOrCreateURL(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	url, exists, err := ah.AppUsecase.GetOrCreateURL(req.URL, userID)
+	expiresAt, err := appUsecaseInternal.GetExpirationTime(req.ExpiresAt, req.TTL, time.Now())
+	if err != nil {
+		handlerLogger.Warn("Invalid expiration",
+			zap.Any(RequestBodyKey, req),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	url, exists, err := ah.AppUsecase.GetOrCreateURL(ctx, req.URL, req.Alias, expiresAt, userID)
+	if errors.Is(err, appUsecaseInternal.ErrAliasTaken) {
+		handlerLogger.Warn("Alias is already taken",
+			zap.String(URLKey, req.URL),
+			zap.String(AliasKey, req.Alias),
+		)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if errors.Is(err, appUsecaseInternal.ErrURLDeleted) {
+		handlerLogger.Warn("URL is deleted or expired",
+			zap.String(URLKey, req.URL),
+		)
+		w.WriteHeader(http.StatusGone)
+		return
+	}
 	if err != nil {
 		handlerLogger.Warn("Bad request",
 			zap.String(URLKey, req.URL),
@@ -242,7 +365,10 @@ func (ah *AppHandler) APIGetOrCreateURL(w http.ResponseWriter, r *http.Request)
 //	@Failure	410		{string}	string	"Gone"
 //	@Router		/{url_id} [get]
 func (ah *AppHandler) RedirectToURL(w http.ResponseWriter, r *http.Request) {
-	handlerLogger := logger.GetContextLogger(r.Context())
+	ctx, span := tracing.Start(r.Context(), "AppHandler.RedirectToURL")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
 
 	handlerLogger.Info("Redirecting to URL")
 
@@ -263,12 +389,13 @@ func (ah *AppHandler) RedirectToURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	url, err := ah.AppUsecase.GetURL(id)
+	url, err := ah.AppUsecase.GetURL(ctx, id)
 	if err != nil {
 		handlerLogger.Warn("Bad request",
 			zap.String(RequestPathIDKey, id),
 			zap.Error(err),
 		)
+		metrics.RedirectsTotal.WithLabelValues(metrics.RedirectMiss).Inc()
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -277,11 +404,16 @@ func (ah *AppHandler) RedirectToURL(w http.ResponseWriter, r *http.Request) {
 		zap.Any(URLKey, url),
 	)
 
-	if url.IsDeleted {
+	if url.IsDeleted || url.IsExpired(time.Now()) {
+		metrics.RedirectsTotal.WithLabelValues(metrics.RedirectMiss).Inc()
 		w.WriteHeader(http.StatusGone)
 		return
 	}
 
+	metrics.RedirectsTotal.WithLabelValues(metrics.RedirectHit).Inc()
+
+	ah.AppUsecase.SendURLClickInChan(url.ID)
+
 	http.Redirect(w, r, url.URL, http.StatusTemporaryRedirect)
 }
 
@@ -294,7 +426,10 @@ func (ah *AppHandler) RedirectToURL(w http.ResponseWriter, r *http.Request) {
 //	@Failure	500	{string}	string	"Internal server error"
 //	@Router		/ping [get]
 func (ah *AppHandler) Ping(w http.ResponseWriter, r *http.Request) {
-	handlerLogger := logger.GetContextLogger(r.Context())
+	ctx, span := tracing.Start(r.Context(), "AppHandler.Ping")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
 
 	handlerLogger.Info("Ping DB")
 
@@ -306,17 +441,98 @@ func (ah *AppHandler) Ping(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := ah.AppUsecase.Ping()
+	err := ah.AppUsecase.Ping(ctx)
 	if err != nil {
 		handlerLogger.Error("Failed to ping DB",
 			zap.Error(err),
 		)
 		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// Healthz Check that app process is alive.
+//
+//	@Summary	Liveness probe
+//	@Produce	json
+//	@Success	200	{object}	app.ResponseHealthCheck	"Process is alive"
+//	@Failure	405	{string}	string					"Method not allowed"
+//	@Router		/healthz [get]
+func (ah *AppHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.Healthz")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	if r.Method != http.MethodGet {
+		handlerLogger.Warn("Request method is not GET",
+			zap.String(MethodKey, r.Method),
+		)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := app.ResponseHealthCheck{Status: app.HealthStatusOK}
+
+	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
+
+	enc := json.NewEncoder(w)
+	err := enc.Encode(resp)
+	if err != nil {
+		handlerLogger.Warn("Failed to encode response",
+			zap.Any(ResponseKey, resp),
+			zap.Error(err),
+		)
+		return
+	}
+}
+
+// Readyz Check that app is ready to serve requests: storage is available,
+// deletion worker is running and app is not shutting down.
+//
+//	@Summary	Readiness probe
+//	@Produce	json
+//	@Success	200	{object}	app.ResponseReadiness	"App is ready"
+//	@Failure	405	{string}	string					"Method not allowed"
+//	@Failure	503	{object}	app.ResponseReadiness	"App is not ready"
+//	@Router		/readyz [get]
+func (ah *AppHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.Readyz")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	if r.Method != http.MethodGet {
+		handlerLogger.Warn("Request method is not GET",
+			zap.String(MethodKey, r.Method),
+		)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := ah.AppUsecase.CheckReadiness(ctx)
+
+	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
+	if resp.Status != app.HealthStatusOK {
+		handlerLogger.Warn("App is not ready",
+			zap.Any(ResponseKey, resp),
+		)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	enc := json.NewEncoder(w)
+	err := enc.Encode(resp)
+	if err != nil {
+		handlerLogger.Warn("Failed to encode response",
+			zap.Any(ResponseKey, resp),
+			zap.Error(err),
+		)
+		return
+	}
+}
+
 // APIGetOrCreateURLs Get (if URLs existed) or create URLs in JSON format.
 //
 //	@Summary	Get (if URLs existed) or create URLs in JSON format
@@ -324,12 +540,17 @@ func (ah *AppHandler) Ping(w http.ResponseWriter, r *http.Request) {
 //	@Produce	json
 //	@Param		url	body		[]app.RequestBatchURL	true	"URL"
 //	@Success	201	{object}	[]app.ResponseBatchURL	"URLs created"
+//	@Failure	409	{string}	string					"Alias is already taken"
+//	@Failure	410	{string}	string					"URL is deleted or expired"
 //	@Failure	405	{string}	string					"Method not allowed"
 //	@Failure	400	{string}	string					"Bad request"
 //	@Failure	401	{string}	string					"Unauthorized"
 //	@Router		/api/shorten/batch [post]
 func (ah *AppHandler) APIGetOrCreateURLs(w http.ResponseWriter, r *http.Request) {
-	handlerLogger := logger.GetContextLogger(r.Context())
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIGetOrCreateURLs")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
 
 	handlerLogger.Info("Creating or getting URLs batch using API")
 
@@ -363,7 +584,7 @@ func (ah *AppHandler) APIGetOrCreateURLs(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	userID, err := usecase.GetContextUserID(r.Context())
+	userID, err := usecase.GetContextUserID(ctx)
 	if err != nil {
 		handlerLogger.Warn("No user ID",
 			zap.Any(RequestBodyKey, r.Body),
@@ -373,7 +594,21 @@ func (ah *AppHandler) APIGetOrCreateURLs(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	resp, err := ah.AppUsecase.GetOrCreateURLs(req, userID)
+	resp, err := ah.AppUsecase.GetOrCreateURLs(ctx, req, userID)
+	if errors.Is(err, appUsecaseInternal.ErrAliasTaken) {
+		handlerLogger.Warn("Alias is already taken",
+			zap.Any(URLsKey, req),
+		)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if errors.Is(err, appUsecaseInternal.ErrURLDeleted) {
+		handlerLogger.Warn("URL is deleted or expired",
+			zap.Any(URLsKey, req),
+		)
+		w.WriteHeader(http.StatusGone)
+		return
+	}
 	if err != nil {
 		handlerLogger.Warn("Bad request",
 			zap.Any(URLsKey, req),
@@ -397,18 +632,28 @@ func (ah *AppHandler) APIGetOrCreateURLs(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// APIGetUserURLs Get user URLs in JSON format.
+// APIGetUserURLs Get page of user URLs in JSON format.
+// Cursor of next page is returned in X-Next-Cursor header.
 //
-//	@Summary	Get user URLs in JSON format
+//	@Summary	Get page of user URLs in JSON format
 //	@Produce	json
-//	@Success	200	{object}	[]app.ResponseUserURL	"URLs created"
+//	@Param		limit			query		int						false	"Max count of URLs, 100 if empty"	maximum(1000)
+//	@Param		cursor			query		string					false	"Cursor from X-Next-Cursor header of previous page"
+//	@Param		search			query		string					false	"Substring of original URL"
+//	@Param		include_deleted	query		bool					false	"Return deleted URLs too"
+//	@Param		sort			query		string					false	"Sort by creation time"	Enums(created_at, -created_at)
+//	@Success	200				{object}	[]app.ResponseUserURL	"URLs created"
+//	@Header		200				{string}	X-Next-Cursor			"Cursor of next page, empty if page is last"
 //	@Failure	405	{string}	string					"Method not allowed"
 //	@Failure	400	{string}	string					"Bad request"
 //	@Failure	401	{string}	string					"Unauthorized"
 //	@Failure	204	{string}	string					"No content"
 //	@Router		/api/user/urls [get]
 func (ah *AppHandler) APIGetUserURLs(w http.ResponseWriter, r *http.Request) {
-	handlerLogger := logger.GetContextLogger(r.Context())
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIGetUserURLs")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
 
 	handlerLogger.Info("Getting user URLs using API")
 
@@ -418,7 +663,7 @@ func (ah *AppHandler) APIGetUserURLs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := usecase.GetContextUserID(r.Context())
+	userID, err := usecase.GetContextUserID(ctx)
 	if err != nil {
 		handlerLogger.Warn("No user ID",
 			zap.Any(RequestBodyKey, r.Body),
@@ -428,7 +673,17 @@ func (ah *AppHandler) APIGetUserURLs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := ah.AppUsecase.GetUserURLs(userID)
+	params, err := parseUserURLsParams(r.URL.Query())
+	if err != nil {
+		handlerLogger.Warn("Invalid query parameters",
+			zap.String(QueryKey, r.URL.RawQuery),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp, nextCursor, err := ah.AppUsecase.GetUserURLs(ctx, userID, params)
 	if err != nil {
 		handlerLogger.Warn("Bad request", zap.Error(err))
 		w.WriteHeader(http.StatusBadRequest)
@@ -438,6 +693,173 @@ func (ah *AppHandler) APIGetUserURLs(w http.ResponseWriter, r *http.Request) {
 	if len(resp) == 0 {
 		handlerLogger.Warn("No content")
 		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if nextCursor != "" {
+		w.Header().Set(NextCursorKey, nextCursor)
+	}
+	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(resp)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(ResponseKey, resp),
+			zap.Error(err),
+		)
+		return
+	}
+}
+
+// APIExportUserURLs Export all user URLs including deleted in CSV or NDJSON format.
+//
+//	@Summary	Export all user URLs including deleted in CSV or NDJSON format
+//	@Produce	text/csv,application/x-ndjson
+//	@Param		format	query		string	false	"Format of URLs, ndjson if empty"	Enums(csv, ndjson)
+//	@Success	200		{object}	[]app.UserURLRecord	"User URLs, CSV has header short_url,original_url,is_deleted,created_at"
+//	@Failure	405		{string}	string				"Method not allowed"
+//	@Failure	400		{string}	string				"Bad request"
+//	@Failure	401		{string}	string				"Unauthorized"
+//	@Failure	500		{string}	string				"Internal server error"
+//	@Router		/api/user/urls/export [get]
+func (ah *AppHandler) APIExportUserURLs(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIExportUserURLs")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Exporting user URLs using API")
+
+	if r.Method != http.MethodGet {
+		handlerLogger.Warn("Request method is not GET", zap.String(MethodKey, r.Method))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	format, err := parseExportFormat(r.URL.Query().Get(FormatQueryKey))
+	if err != nil {
+		handlerLogger.Warn("Invalid query parameters",
+			zap.String(QueryKey, r.URL.RawQuery),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set(ContentTypeKey, formatContentTypes[format])
+	w.Header().Set(ContentDispositionKey, fmt.Sprintf(`attachment; filename="%s.%s"`, ExportFilename, format))
+
+	// pages are written as soon as they are read, status can be changed only before the first page
+	enc := newUserURLsEncoder(w, format)
+	var encodeErr error
+	err = ah.AppUsecase.ExportUserURLs(ctx, userID, func(records []app.UserURLRecord) error {
+		encodeErr = enc.Encode(records)
+		return encodeErr
+	})
+	if err == nil {
+		encodeErr = enc.Flush()
+	}
+	if encodeErr != nil {
+		handlerLogger.Warn("Failed to encode user URLs",
+			zap.Error(encodeErr),
+		)
+		return
+	}
+	if err != nil {
+		handlerLogger.Error("Failed to export user URLs", zap.Error(err))
+		if !enc.Started() {
+			w.Header().Del(ContentTypeKey)
+			w.Header().Del(ContentDispositionKey)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+}
+
+// APIImportUserURLs Import user URLs in CSV or NDJSON format.
+// Format is set by format query parameter or by Content-Type text/csv or application/x-ndjson.
+// Rows are saved like URLs of APIGetOrCreateURLs, short ID of row is used as alias.
+//
+//	@Summary	Import user URLs in CSV or NDJSON format
+//	@Accept		text/csv,application/x-ndjson
+//	@Produce	json
+//	@Param		format	query		string						false	"Format of URLs, Content-Type is used if empty"	Enums(csv, ndjson)
+//	@Param		urls	body		[]app.UserURLRecord			true	"User URLs, CSV must have header with original_url column"
+//	@Success	200		{object}	[]app.ResponseImportUserURL	"Outcome of every row"
+//	@Failure	405		{string}	string						"Method not allowed"
+//	@Failure	400		{string}	string						"Bad request"
+//	@Failure	401		{string}	string						"Unauthorized"
+//	@Failure	500		{string}	string						"Internal server error"
+//	@Router		/api/user/urls/import [post]
+func (ah *AppHandler) APIImportUserURLs(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIImportUserURLs")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Importing user URLs using API")
+
+	if r.Method != http.MethodPost {
+		handlerLogger.Warn("Request method is not POST",
+			zap.String(MethodKey, r.Method),
+		)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	format, err := parseImportFormat(r.URL.Query().Get(FormatQueryKey), r.Header.Get(ContentTypeKey))
+	if err != nil {
+		handlerLogger.Warn("Unknown format of imported URLs",
+			zap.String(QueryKey, r.URL.RawQuery),
+			zap.Any(HeaderKey, r.Header),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("Use '%s' query or '%s' header with '%s' or '%s'",
+			FormatQueryKey, ContentTypeKey, TextCSVKey, ApplicationNDJSONKey)))
+		return
+	}
+
+	records, err := decodeUserURLs(r.Body, format, appUsecaseInternal.MaxImportUserURLs)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.String("format", format),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	resp, err := ah.AppUsecase.ImportUserURLs(ctx, userID, records)
+	if errors.Is(err, appUsecaseInternal.ErrTooManyImportURLs) {
+		handlerLogger.Warn("Too many URLs to import", zap.Int(URLsKey, len(records)))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		handlerLogger.Error("Failed to import user URLs", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
@@ -457,16 +879,20 @@ func (ah *AppHandler) APIGetUserURLs(w http.ResponseWriter, r *http.Request) {
 //
 //	@Summary	Delete user URLs in JSON format
 //	@Accept		json
-//	@Produce	plain
-//	@Param		url_ids	body		[]string	true	"URL IDs"
-//	@Success	202		{string}	string		"Accepted"
-//	@Failure	405		{string}	string		"Method not allowed"
-//	@Failure	400		{string}	string		"Bad request"
-//	@Failure	401		{string}	string		"Unauthorized"
+//	@Produce	json
+//	@Param		url_ids	body		[]string					true	"URL IDs"
+//	@Success	202		{object}	app.ResponseDeleteUserURLs	"Accepted, deletion job ID"
+//	@Failure	405		{string}	string						"Method not allowed"
+//	@Failure	400		{string}	string						"Bad request"
+//	@Failure	401		{string}	string						"Unauthorized"
+//	@Failure	500		{string}	string						"Internal server error"
 //	@Security	ApiKeyAuth
 //	@Router		/api/user/urls [delete]
 func (ah *AppHandler) APIDeleteUserURLs(w http.ResponseWriter, r *http.Request) {
-	handlerLogger := logger.GetContextLogger(r.Context())
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIDeleteUserURLs")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
 
 	handlerLogger.Info("Deleting user URLs using API")
 
@@ -490,7 +916,7 @@ func (ah *AppHandler) APIDeleteUserURLs(w http.ResponseWriter, r *http.Request)
 
 	handlerLogger.Debug("Request data", zap.Any("url_ids", req))
 
-	userID, err := usecase.GetContextUserID(r.Context())
+	userID, err := usecase.GetContextUserID(ctx)
 	if err != nil {
 		handlerLogger.Warn("No user ID",
 			zap.Any(RequestBodyKey, r.Body),
@@ -500,7 +926,559 @@ func (ah *AppHandler) APIDeleteUserURLs(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	ah.AppUsecase.SendDeleteUserURLsInChan(userID, req)
+	jobID, err := ah.AppUsecase.EnqueueDeleteUserURLs(ctx, userID, req)
+	if err != nil {
+		handlerLogger.Error("Failed to enqueue deletion of user URLs",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := app.ResponseDeleteUserURLs{JobID: jobID}
 
+	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
 	w.WriteHeader(http.StatusAccepted)
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(resp)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(ResponseKey, resp),
+			zap.Error(err),
+		)
+		return
+	}
+}
+
+// APIRestoreUserURLs Restore deleted user URLs in JSON format.
+// URL can be restored till it is purged after grace period since its deletion.
+//
+//	@Summary	Restore deleted user URLs in JSON format
+//	@Accept		json
+//	@Produce	json
+//	@Param		url_ids	body		[]string						true	"URL IDs"
+//	@Success	200		{object}	[]app.ResponseRestoreUserURL	"Status of every URL: restored or ignored"
+//	@Failure	405		{string}	string							"Method not allowed"
+//	@Failure	400		{string}	string							"Bad request"
+//	@Failure	401		{string}	string							"Unauthorized"
+//	@Failure	500		{string}	string							"Internal server error"
+//	@Router		/api/user/urls/restore [post]
+func (ah *AppHandler) APIRestoreUserURLs(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIRestoreUserURLs")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Restoring user URLs using API")
+
+	if r.Method != http.MethodPost {
+		handlerLogger.Warn("Request method is not POST", zap.String(MethodKey, r.Method))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req []string
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&req)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(RequestBodyKey, r.Body),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	handlerLogger.Debug("Request data", zap.Any("url_ids", req))
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := ah.AppUsecase.RestoreUserURLs(ctx, userID, req)
+	if errors.Is(err, appUsecaseInternal.ErrTooManyRestoreURLs) {
+		handlerLogger.Warn("Too many URLs to restore", zap.Int(URLsKey, len(req)))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		handlerLogger.Error("Failed to restore user URLs",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(resp)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(ResponseKey, resp),
+			zap.Error(err),
+		)
+		return
+	}
+}
+
+// APIGetDeletionJob Get status of user deletion job in JSON format.
+//
+//	@Summary	Get status of user deletion job in JSON format
+//	@Produce	json
+//	@Param		job_id	path		string					true	"Deletion job ID"
+//	@Success	200		{object}	app.ResponseDeletionJob	"Status of job and of every URL in it"
+//	@Failure	405		{string}	string					"Method not allowed"
+//	@Failure	400		{string}	string					"Bad request"
+//	@Failure	401		{string}	string					"Unauthorized"
+//	@Failure	403		{string}	string					"Forbidden"
+//	@Failure	404		{string}	string					"Not found"
+//	@Failure	500		{string}	string					"Internal server error"
+//	@Security	ApiKeyAuth
+//	@Router		/api/user/urls/delete/{job_id} [get]
+func (ah *AppHandler) APIGetDeletionJob(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIGetDeletionJob")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Getting deletion job using API")
+
+	if r.Method != http.MethodGet {
+		handlerLogger.Warn("Request method is not GET", zap.String(MethodKey, r.Method))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := chi.URLParam(r, "job_id")
+	if jobID == "" {
+		handlerLogger.Warn("Bad request",
+			zap.String(RequestPathIDKey, jobID),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := ah.AppUsecase.GetDeletionJob(ctx, jobID, userID)
+	switch {
+	case errors.Is(err, app.ErrDeletionJobNotFound):
+		handlerLogger.Warn("Deletion job not found",
+			zap.String(RequestPathIDKey, jobID),
+		)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case errors.Is(err, appUsecaseInternal.ErrForbidden):
+		handlerLogger.Warn("Deletion job belongs to another user",
+			zap.String(RequestPathIDKey, jobID),
+		)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case err != nil:
+		handlerLogger.Error("Failed to get deletion job",
+			zap.String(RequestPathIDKey, jobID),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(resp)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(ResponseKey, resp),
+			zap.Error(err),
+		)
+		return
+	}
+}
+
+// APIGetURLStats Get user URL statistics in JSON format.
+//
+//	@Summary	Get user URL statistics in JSON format
+//	@Produce	json
+//	@Param		url_id	path		string					true	"URL ID"	example(qwerty)
+//	@Success	200		{object}	app.ResponseURLStats	"URL statistics"
+//	@Failure	405		{string}	string					"Method not allowed"
+//	@Failure	400		{string}	string					"Bad request"
+//	@Failure	401		{string}	string					"Unauthorized"
+//	@Failure	403		{string}	string					"Forbidden"
+//	@Failure	404		{string}	string					"Not found"
+//	@Router		/api/user/urls/{url_id}/stats [get]
+func (ah *AppHandler) APIGetURLStats(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIGetURLStats")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Getting user URL statistics using API")
+
+	if r.Method != http.MethodGet {
+		handlerLogger.Warn("Request method is not GET", zap.String(MethodKey, r.Method))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		handlerLogger.Warn("Bad request",
+			zap.String(RequestPathIDKey, id),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := ah.AppUsecase.GetURLStats(ctx, id, userID)
+	switch {
+	case errors.Is(err, app.ErrURLNotFound):
+		handlerLogger.Warn("URL not found",
+			zap.String(RequestPathIDKey, id),
+		)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case errors.Is(err, appUsecaseInternal.ErrForbidden):
+		handlerLogger.Warn("URL belongs to another user",
+			zap.String(RequestPathIDKey, id),
+		)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case err != nil:
+		handlerLogger.Warn("Bad request",
+			zap.String(RequestPathIDKey, id),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(resp)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(ResponseKey, resp),
+			zap.Error(err),
+		)
+		return
+	}
+}
+
+// APIUpdateUserURL Change original URL of user URL in JSON format, short URL is not changed.
+//
+//	@Summary	Change original URL of user URL in JSON format
+//	@Accept		json
+//	@Produce	json
+//	@Param		url_id	path		string							true	"URL ID"	example(qwerty)
+//	@Param		url		body		delivery.APIUpdateUserURL.Request	true	"New original URL"
+//	@Success	200		{object}	app.ResponseUpdateUserURL		"URL with history of changes"
+//	@Failure	405		{string}	string							"Method not allowed"
+//	@Failure	400		{string}	string							"Bad request"
+//	@Failure	401		{string}	string							"Unauthorized"
+//	@Failure	403		{string}	string							"Forbidden"
+//	@Failure	404		{string}	string							"Not found"
+//	@Failure	409		{string}	string							"URL is already shortened with another ID"
+//	@Failure	410		{string}	string							"Gone"
+//	@Router		/api/user/urls/{url_id} [patch]
+func (ah *AppHandler) APIUpdateUserURL(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIUpdateUserURL")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Changing user URL using API")
+
+	if r.Method != http.MethodPatch {
+		handlerLogger.Warn("Request method is not PATCH", zap.String(MethodKey, r.Method))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		handlerLogger.Warn("Bad request",
+			zap.String(RequestPathIDKey, id),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	type Request struct {
+		URL string `json:"url"`
+	}
+
+	var req Request
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&req)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(RequestBodyKey, r.Body),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := ah.AppUsecase.UpdateUserURL(ctx, id, userID, req.URL)
+	switch {
+	case errors.Is(err, app.ErrURLNotFound):
+		handlerLogger.Warn("URL not found",
+			zap.String(RequestPathIDKey, id),
+		)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case errors.Is(err, appUsecaseInternal.ErrForbidden):
+		handlerLogger.Warn("URL belongs to another user",
+			zap.String(RequestPathIDKey, id),
+		)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case errors.Is(err, appUsecaseInternal.ErrURLDeleted):
+		handlerLogger.Warn("URL is deleted",
+			zap.String(RequestPathIDKey, id),
+		)
+		w.WriteHeader(http.StatusGone)
+		return
+	case errors.Is(err, app.ErrURLExists):
+		handlerLogger.Warn("URL is already shortened with another ID",
+			zap.String(RequestPathIDKey, id),
+			zap.String(URLKey, req.URL),
+		)
+		w.WriteHeader(http.StatusConflict)
+		return
+	case err != nil:
+		handlerLogger.Warn("Bad request",
+			zap.String(RequestPathIDKey, id),
+			zap.String(URLKey, req.URL),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	handlerLogger.Info("User URL changed",
+		zap.String(URLIDKey, id),
+		zap.String(URLKey, resp.OriginalURL),
+	)
+
+	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(resp)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(ResponseKey, resp),
+			zap.Error(err),
+		)
+		return
+	}
+}
+
+// APIGetUserURLHistory Get changes of original URL of user URL in JSON format.
+//
+//	@Summary	Get changes of original URL of user URL in JSON format
+//	@Produce	json
+//	@Param		url_id	path		string					true	"URL ID"	example(qwerty)
+//	@Success	200		{array}		app.ResponseURLEdit		"Changes of original URL sorted by time"
+//	@Failure	405		{string}	string					"Method not allowed"
+//	@Failure	400		{string}	string					"Bad request"
+//	@Failure	401		{string}	string					"Unauthorized"
+//	@Failure	403		{string}	string					"Forbidden"
+//	@Failure	404		{string}	string					"Not found"
+//	@Router		/api/user/urls/{url_id}/history [get]
+func (ah *AppHandler) APIGetUserURLHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIGetUserURLHistory")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Getting history of user URL using API")
+
+	if r.Method != http.MethodGet {
+		handlerLogger.Warn("Request method is not GET", zap.String(MethodKey, r.Method))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		handlerLogger.Warn("Bad request",
+			zap.String(RequestPathIDKey, id),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	userID, err := usecase.GetContextUserID(ctx)
+	if err != nil {
+		handlerLogger.Warn("No user ID",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := ah.AppUsecase.GetUserURLHistory(ctx, id, userID)
+	switch {
+	case errors.Is(err, app.ErrURLNotFound):
+		handlerLogger.Warn("URL not found",
+			zap.String(RequestPathIDKey, id),
+		)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case errors.Is(err, appUsecaseInternal.ErrForbidden):
+		handlerLogger.Warn("URL belongs to another user",
+			zap.String(RequestPathIDKey, id),
+		)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case err != nil:
+		handlerLogger.Warn("Bad request",
+			zap.String(RequestPathIDKey, id),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(resp)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(ResponseKey, resp),
+			zap.Error(err),
+		)
+		return
+	}
+}
+
+// APIGetStats Get count of short URLs and users in JSON format.
+//
+//	@Summary	Get count of short URLs and users in JSON format
+//	@Produce	json
+//	@Success	200	{object}	app.ResponseStats	"Statistics"
+//	@Failure	405	{string}	string				"Method not allowed"
+//	@Failure	403	{string}	string				"Forbidden"
+//	@Failure	500	{string}	string				"Internal server error"
+//	@Router		/api/internal/stats [get]
+func (ah *AppHandler) APIGetStats(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APIGetStats")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Getting statistics using API")
+
+	if r.Method != http.MethodGet {
+		handlerLogger.Warn("Request method is not GET", zap.String(MethodKey, r.Method))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	countURLs, err := ah.AppUsecase.CountURLs(ctx)
+	if err != nil {
+		handlerLogger.Error("Failed to count URLs", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	countUsers, err := ah.UserUsecase.CountUsers(ctx)
+	if err != nil {
+		handlerLogger.Error("Failed to count users", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := app.ResponseStats{URLs: countURLs, Users: countUsers}
+
+	w.Header().Set(ContentTypeKey, ApplicationJSONKey)
+
+	enc := json.NewEncoder(w)
+	err = enc.Encode(resp)
+	if err != nil {
+		handlerLogger.Warn("Bad request",
+			zap.Any(ResponseKey, resp),
+			zap.Error(err),
+		)
+		return
+	}
+}
+
+// APICompactStorage Compact file storage to snapshot on demand.
+//
+//	@Summary	Compact file storage to snapshot on demand
+//	@Success	204	{string}	string	"Storage compacted"
+//	@Failure	405	{string}	string	"Method not allowed"
+//	@Failure	403	{string}	string	"Forbidden"
+//	@Failure	501	{string}	string	"Storage does not support compaction"
+//	@Failure	500	{string}	string	"Internal server error"
+//	@Router		/api/internal/storage/compact [post]
+func (ah *AppHandler) APICompactStorage(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AppHandler.APICompactStorage")
+	defer span.End()
+
+	handlerLogger := logger.GetContextLogger(ctx)
+
+	handlerLogger.Info("Compacting storage using API")
+
+	if r.Method != http.MethodPost {
+		handlerLogger.Warn("Request method is not POST",
+			zap.String(MethodKey, r.Method),
+		)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	start := time.Now()
+	err := ah.AppUsecase.CompactStorage(ctx)
+	if errors.Is(err, appUsecaseInternal.ErrCompactionNotSupported) {
+		handlerLogger.Warn("Storage does not support compaction")
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		handlerLogger.Error("Failed to compact storage", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	handlerLogger.Info("Storage compacted", zap.Duration("duration", time.Since(start)))
+	w.WriteHeader(http.StatusNoContent)
 }
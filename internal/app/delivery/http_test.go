@@ -8,9 +8,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app/delivery/mocks"
+	appUsecaseInternal "github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
 	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
 	"github.com/golang/mock/gomock"
 
@@ -24,8 +27,14 @@ const (
 	TestValidURL   string = "valid_url"
 	TestInvalidURL string = "invalid_url"
 	TestID         string = "1"
+	TestJobID      string = "job"
 	TestHost       string = "http://example.com"
 	TestUserID     uint   = 1
+	TestTakenAlias string = "taken"
+	TestExpiredID  string = "expired"
+	TestDeletedID  string = "deleted"
+	TestErrorID    string = "error"
+	TestGoneURL    string = "gone_url"
 )
 
 var (
@@ -112,12 +121,12 @@ func TestAppHandler_GetOrCreateURL(t *testing.T) {
 
 	// гарантируем, что заглушка
 	// при вызове с аргументом "Key" вернёт "Value"
-	m.EXPECT().GetOrCreateURL(TestValidURL, gomock.Any()).Return(&app.URL{
+	m.EXPECT().GetOrCreateURL(gomock.Any(), TestValidURL, gomock.Any(), gomock.Any(), gomock.Any()).Return(&app.URL{
 		ID:     TestID,
 		URL:    TestValidURL,
 		UserID: TestUserID,
 	}, false, nil).AnyTimes()
-	m.EXPECT().GetOrCreateURL(gomock.Any(), gomock.Any()).Return(nil, false, ErrTestInvalidURL).AnyTimes()
+	m.EXPECT().GetOrCreateURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, false, ErrTestInvalidURL).AnyTimes()
 
 	m.EXPECT().GenerateShortURL(gomock.Any()).DoAndReturn(
 		func(id string) string {
@@ -125,7 +134,7 @@ func TestAppHandler_GetOrCreateURL(t *testing.T) {
 		},
 	)
 
-	appHandler := NewAppHandler(m)
+	appHandler := NewAppHandler(m, nil)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -200,6 +209,30 @@ func TestAppHandler_APIGetOrCreateURL(t *testing.T) {
 				statusCode: http.StatusBadRequest,
 			},
 		},
+		{
+			name: "taken alias",
+			request: request{
+				method:      http.MethodPost,
+				contentType: ApplicationJSONKey,
+				url:         TestHost + "/api/shorten",
+				body:        []byte(`{"url": "` + TestValidURL + `", "alias": "` + TestTakenAlias + `"}`),
+			},
+			want: want{
+				statusCode: http.StatusConflict,
+			},
+		},
+		{
+			name: "deleted URL",
+			request: request{
+				method:      http.MethodPost,
+				contentType: ApplicationJSONKey,
+				url:         TestHost + "/api/shorten",
+				body:        []byte(`{"url": "` + TestGoneURL + `"}`),
+			},
+			want: want{
+				statusCode: http.StatusGone,
+			},
+		},
 		{
 			name: "invalid method",
 			request: request{
@@ -235,12 +268,14 @@ func TestAppHandler_APIGetOrCreateURL(t *testing.T) {
 
 	// гарантируем, что заглушка
 	// при вызове с аргументом "Key" вернёт "Value"
-	m.EXPECT().GetOrCreateURL(TestValidURL, gomock.Any()).Return(&app.URL{
+	m.EXPECT().GetOrCreateURL(gomock.Any(), TestValidURL, TestTakenAlias, gomock.Any(), gomock.Any()).Return(nil, false, appUsecaseInternal.ErrAliasTaken).AnyTimes()
+	m.EXPECT().GetOrCreateURL(gomock.Any(), TestGoneURL, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, false, appUsecaseInternal.ErrURLDeleted).AnyTimes()
+	m.EXPECT().GetOrCreateURL(gomock.Any(), TestValidURL, gomock.Any(), gomock.Any(), gomock.Any()).Return(&app.URL{
 		ID:     TestID,
 		URL:    TestValidURL,
 		UserID: TestUserID,
 	}, false, nil).AnyTimes()
-	m.EXPECT().GetOrCreateURL(gomock.Any(), gomock.Any()).Return(nil, false, ErrTestInvalidURL).AnyTimes()
+	m.EXPECT().GetOrCreateURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, false, ErrTestInvalidURL).AnyTimes()
 
 	m.EXPECT().GenerateShortURL(gomock.Any()).DoAndReturn(
 		func(id string) string {
@@ -248,7 +283,7 @@ func TestAppHandler_APIGetOrCreateURL(t *testing.T) {
 		},
 	)
 
-	appHandler := NewAppHandler(m)
+	appHandler := NewAppHandler(m, nil)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -318,6 +353,17 @@ func TestAppHandler_RedirectToURL(t *testing.T) {
 				statusCode: http.StatusBadRequest,
 			},
 		},
+		{
+			name: "expired URL",
+			request: request{
+				method: http.MethodGet,
+				url:    TestHost + "/",
+				id:     TestExpiredID,
+			},
+			want: want{
+				statusCode: http.StatusGone,
+			},
+		},
 		{
 			name: "invalid method",
 			request: request{
@@ -351,13 +397,20 @@ func TestAppHandler_RedirectToURL(t *testing.T) {
 
 	// гарантируем, что заглушка
 	// при вызове с аргументом "Key" вернёт "Value"
-	m.EXPECT().GetURL(TestID).Return(&app.URL{
+	m.EXPECT().GetURL(gomock.Any(), TestID).Return(&app.URL{
 		ID:  TestID,
 		URL: TestValidURL,
 	}, nil).AnyTimes()
-	m.EXPECT().GetURL(gomock.Any()).Return(nil, ErrTestIDNotFound).AnyTimes()
+	expiresAt := time.Now().Add(-time.Second)
+	m.EXPECT().GetURL(gomock.Any(), TestExpiredID).Return(&app.URL{
+		ID:        TestExpiredID,
+		URL:       TestValidURL,
+		ExpiresAt: &expiresAt,
+	}, nil).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), gomock.Any()).Return(nil, ErrTestIDNotFound).AnyTimes()
+	m.EXPECT().SendURLClickInChan(TestID).Times(1)
 
-	appHandler := NewAppHandler(m)
+	appHandler := NewAppHandler(m, nil)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -419,9 +472,9 @@ func TestAppHandler_Ping(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := mocks.NewMockAppUsecaseInterface(ctrl)
-			m.EXPECT().Ping().Return(tt.usecasePingError).AnyTimes()
+			m.EXPECT().Ping(gomock.Any()).Return(tt.usecasePingError).AnyTimes()
 
-			appHandler := NewAppHandler(m)
+			appHandler := NewAppHandler(m, nil)
 
 			req := httptest.NewRequest(tt.requestMethod, TestHost+"/ping", bytes.NewReader(nil))
 			w := httptest.NewRecorder()
@@ -434,6 +487,125 @@ func TestAppHandler_Ping(t *testing.T) {
 	}
 }
 
+func TestAppHandler_Healthz(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestMethod  string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "simple case",
+			requestMethod:  http.MethodGet,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"status": "ok"}`,
+		},
+		{
+			name:           "invalid method",
+			requestMethod:  http.MethodPost,
+			wantStatusCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appHandler := NewAppHandler(nil, nil)
+
+			req := httptest.NewRequest(tt.requestMethod, TestHost+"/healthz", nil)
+			w := httptest.NewRecorder()
+			appHandler.Healthz(w, req)
+			res := w.Result()
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode, "Invalid status code")
+
+			if res.StatusCode == http.StatusOK {
+				assert.JSONEq(t, tt.wantBody, string(resBody))
+			}
+		})
+	}
+}
+
+func TestAppHandler_Readyz(t *testing.T) {
+	readyResp := &app.ResponseReadiness{
+		Status: app.HealthStatusOK,
+		Checks: map[string]app.ResponseHealthCheck{
+			app.ReadinessStorage:        {Status: app.HealthStatusOK},
+			app.ReadinessDeletionWorker: {Status: app.HealthStatusOK},
+			app.ReadinessShutdown:       {Status: app.HealthStatusOK},
+		},
+	}
+	notReadyResp := &app.ResponseReadiness{
+		Status: app.HealthStatusFail,
+		Checks: map[string]app.ResponseHealthCheck{
+			app.ReadinessStorage:        {Status: app.HealthStatusOK},
+			app.ReadinessDeletionWorker: {Status: app.HealthStatusOK},
+			app.ReadinessShutdown:       {Status: app.HealthStatusFail, Error: "app is shutting down"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		requestMethod  string
+		readiness      *app.ResponseReadiness
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "ready",
+			requestMethod:  http.MethodGet,
+			readiness:      readyResp,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"status": "ok", "checks": {"storage": {"status": "ok"}, "deletion_worker": {"status": "ok"}, "shutdown": {"status": "ok"}}}`,
+		},
+		{
+			name:           "not ready",
+			requestMethod:  http.MethodGet,
+			readiness:      notReadyResp,
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantBody:       `{"status": "fail", "checks": {"storage": {"status": "ok"}, "deletion_worker": {"status": "ok"}, "shutdown": {"status": "fail", "error": "app is shutting down"}}}`,
+		},
+		{
+			name:           "invalid method",
+			requestMethod:  http.MethodPost,
+			readiness:      readyResp,
+			wantStatusCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mocks.NewMockAppUsecaseInterface(ctrl)
+			m.EXPECT().CheckReadiness(gomock.Any()).Return(tt.readiness).AnyTimes()
+
+			appHandler := NewAppHandler(m, nil)
+
+			req := httptest.NewRequest(tt.requestMethod, TestHost+"/readyz", nil)
+			w := httptest.NewRecorder()
+			appHandler.Readyz(w, req)
+			res := w.Result()
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode, "Invalid status code")
+
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, string(resBody))
+			}
+		})
+	}
+}
+
 func TestAppHandler_APIGetOrCreateURLs(t *testing.T) {
 	contextUserID := uint(1)
 
@@ -506,6 +678,19 @@ func TestAppHandler_APIGetOrCreateURLs(t *testing.T) {
 				body:       nil,
 			},
 		},
+		{
+			name: "deleted URL",
+			request: request{
+				method:      http.MethodPost,
+				body:        []byte(fmt.Sprintf(`[{"correlation_id": "%s", "original_url": "%s"}]`, TestID, TestGoneURL)),
+				contentType: ApplicationJSONKey,
+				ctx:         context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusGone,
+				body:       nil,
+			},
+		},
 		{
 			name: "unauthorized user",
 			request: request{
@@ -540,9 +725,11 @@ func TestAppHandler_APIGetOrCreateURLs(t *testing.T) {
 		},
 	}
 
-	m.EXPECT().GetOrCreateURLs(requestBatchURLs, contextUserID).Return(responseBatchURLs, nil).AnyTimes()
+	m.EXPECT().GetOrCreateURLs(gomock.Any(), requestBatchURLs, contextUserID).Return(responseBatchURLs, nil).AnyTimes()
+	m.EXPECT().GetOrCreateURLs(gomock.Any(), []app.RequestBatchURL{{CorrelationID: TestID, OriginalURL: TestGoneURL}}, contextUserID).
+		Return(nil, appUsecaseInternal.ErrURLDeleted).AnyTimes()
 
-	appHandler := NewAppHandler(m)
+	appHandler := NewAppHandler(m, nil)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -575,18 +762,22 @@ func TestAppHandler_APIGetUserURLs(t *testing.T) {
 
 	type request struct {
 		method string
+		query  string
 		ctx    context.Context
 	}
 
 	type usecaseGetUserURLsResponse struct {
-		userID   uint
-		userURLs []app.ResponseUserURL
-		err      error
+		userID     uint
+		params     app.UserURLsParams
+		userURLs   []app.ResponseUserURL
+		nextCursor string
+		err        error
 	}
 
 	type want struct {
 		statusCode int
 		body       []byte
+		nextCursor string
 	}
 
 	tests := []struct {
@@ -611,6 +802,74 @@ func TestAppHandler_APIGetUserURLs(t *testing.T) {
 				body:       []byte(fmt.Sprintf(`[{"original_url": "%s", "short_url": "%s"}]`, TestValidURL, TestHost+"/"+TestID)),
 			},
 		},
+		{
+			name: "default page",
+			request: request{
+				method: http.MethodGet,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			usecaseGetUserURLsResponse: usecaseGetUserURLsResponse{
+				userID:     contextUserID,
+				userURLs:   []app.ResponseUserURL{{OriginalURL: TestValidURL, ShortURL: TestHost + "/" + TestID}},
+				nextCursor: "next",
+				err:        nil,
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				body:       []byte(fmt.Sprintf(`[{"original_url": "%s", "short_url": "%s"}]`, TestValidURL, TestHost+"/"+TestID)),
+				nextCursor: "next",
+			},
+		},
+		{
+			name: "page",
+			request: request{
+				method: http.MethodGet,
+				query:  "?limit=1&cursor=abc&search=valid&include_deleted=true&sort=-created_at",
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			usecaseGetUserURLsResponse: usecaseGetUserURLsResponse{
+				userID: contextUserID,
+				params: app.UserURLsParams{
+					Limit:          1,
+					Cursor:         "abc",
+					Search:         "valid",
+					IncludeDeleted: true,
+					Desc:           true,
+				},
+				userURLs:   []app.ResponseUserURL{{OriginalURL: TestValidURL, ShortURL: TestHost + "/" + TestID}},
+				nextCursor: "next",
+				err:        nil,
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				body:       []byte(fmt.Sprintf(`[{"original_url": "%s", "short_url": "%s"}]`, TestValidURL, TestHost+"/"+TestID)),
+				nextCursor: "next",
+			},
+		},
+		{
+			name: "invalid limit",
+			request: request{
+				method: http.MethodGet,
+				query:  "?limit=-1",
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+				body:       nil,
+			},
+		},
+		{
+			name: "invalid sort",
+			request: request{
+				method: http.MethodGet,
+				query:  "?sort=url",
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+				body:       nil,
+			},
+		},
 		{
 			name: "no content",
 			request: request{
@@ -667,15 +926,16 @@ func TestAppHandler_APIGetUserURLs(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := mocks.NewMockAppUsecaseInterface(ctrl)
-			m.EXPECT().GetUserURLs(tt.usecaseGetUserURLsResponse.userID).
+			m.EXPECT().GetUserURLs(gomock.Any(), tt.usecaseGetUserURLsResponse.userID, tt.usecaseGetUserURLsResponse.params).
 				Return(
 					tt.usecaseGetUserURLsResponse.userURLs,
+					tt.usecaseGetUserURLsResponse.nextCursor,
 					tt.usecaseGetUserURLsResponse.err,
 				).AnyTimes()
 
-			appHandler := NewAppHandler(m)
+			appHandler := NewAppHandler(m, nil)
 
-			req := httptest.NewRequest(tt.request.method, TestHost+"/api/user/urls", nil)
+			req := httptest.NewRequest(tt.request.method, TestHost+"/api/user/urls"+tt.request.query, nil)
 			req = req.WithContext(tt.request.ctx)
 
 			w := httptest.NewRecorder()
@@ -690,10 +950,15 @@ func TestAppHandler_APIGetUserURLs(t *testing.T) {
 			require.NoError(t, err)
 
 			assert.Equal(t, tt.want.statusCode, res.StatusCode, "Invalid status code")
+			assert.Equal(t, tt.want.nextCursor, res.Header.Get(NextCursorKey))
 
-			if res.StatusCode == http.StatusCreated {
+			if res.StatusCode == http.StatusOK {
 				assert.JSONEq(t, string(tt.want.body), string(resBody))
 			}
+			if res.StatusCode == http.StatusNoContent {
+				assert.Empty(t, resBody)
+				assert.Empty(t, res.Header.Get(ContentTypeKey))
+			}
 		})
 	}
 }
@@ -707,6 +972,7 @@ func TestAppHandler_APIDeleteUserURLs(t *testing.T) {
 
 	type want struct {
 		statusCode int
+		body       string
 	}
 
 	tests := []struct {
@@ -723,6 +989,7 @@ func TestAppHandler_APIDeleteUserURLs(t *testing.T) {
 			},
 			want: want{
 				statusCode: http.StatusAccepted,
+				body:       fmt.Sprintf(`{"job_id": "%s"}`, TestJobID),
 			},
 		},
 		{
@@ -758,15 +1025,27 @@ func TestAppHandler_APIDeleteUserURLs(t *testing.T) {
 				statusCode: http.StatusUnauthorized,
 			},
 		},
+		{
+			name: "queue error",
+			request: request{
+				method: http.MethodDelete,
+				body:   bytes.NewReader([]byte(`["789"]`)),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, uint(1)),
+			},
+			want: want{
+				statusCode: http.StatusInternalServerError,
+			},
+		},
 	}
 
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	m := mocks.NewMockAppUsecaseInterface(ctrl)
-	m.EXPECT().SendDeleteUserURLsInChan(gomock.Any(), gomock.Any()).Return().AnyTimes()
+	m.EXPECT().EnqueueDeleteUserURLs(gomock.Any(), uint(1), []string{"123", "456"}).Return(TestJobID, nil).AnyTimes()
+	m.EXPECT().EnqueueDeleteUserURLs(gomock.Any(), uint(1), []string{"789"}).Return("", errors.New("queue error")).AnyTimes()
 
-	appHandler := NewAppHandler(m)
+	appHandler := NewAppHandler(m, nil)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -779,10 +1058,1206 @@ func TestAppHandler_APIDeleteUserURLs(t *testing.T) {
 
 			res := w.Result()
 
-			err := res.Body.Close()
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
 			require.NoError(t, err)
 
 			assert.Equal(t, tt.want.statusCode, res.StatusCode, "Invalid status code")
+
+			if res.StatusCode == http.StatusAccepted {
+				assert.JSONEq(t, tt.want.body, string(resBody))
+			}
+		})
+	}
+}
+
+func TestAppHandler_APIRestoreUserURLs(t *testing.T) {
+	type request struct {
+		method string
+		body   *bytes.Reader
+		ctx    context.Context
+	}
+
+	type want struct {
+		statusCode  int
+		contentType string
+		body        string
+	}
+
+	tests := []struct {
+		name    string
+		request request
+		want    want
+	}{
+		{
+			name: "simple",
+			request: request{
+				method: http.MethodPost,
+				body:   bytes.NewReader([]byte(`["123", "456"]`)),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, uint(1)),
+			},
+			want: want{
+				statusCode:  http.StatusOK,
+				contentType: ApplicationJSONKey,
+				body:        `[{"id": "123", "status": "restored"}, {"id": "456", "status": "ignored"}]`,
+			},
+		},
+		{
+			name: "invalid method",
+			request: request{
+				method: http.MethodGet,
+				body:   bytes.NewReader([]byte(`["123", "456"]`)),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, uint(1)),
+			},
+			want: want{
+				statusCode: http.StatusMethodNotAllowed,
+			},
+		},
+		{
+			name: "invalid body",
+			request: request{
+				method: http.MethodPost,
+				body:   bytes.NewReader([]byte(`123`)),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, uint(1)),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "invalid user ID",
+			request: request{
+				method: http.MethodPost,
+				body:   bytes.NewReader([]byte(`["123", "456"]`)),
+				ctx:    context.Background(),
+			},
+			want: want{
+				statusCode: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "too many URLs",
+			request: request{
+				method: http.MethodPost,
+				body:   bytes.NewReader([]byte(`["many"]`)),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, uint(1)),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "restore error",
+			request: request{
+				method: http.MethodPost,
+				body:   bytes.NewReader([]byte(`["789"]`)),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, uint(1)),
+			},
+			want: want{
+				statusCode: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().RestoreUserURLs(gomock.Any(), uint(1), []string{"123", "456"}).Return([]app.ResponseRestoreUserURL{
+		{ID: "123", Status: app.RestoreURLRestored},
+		{ID: "456", Status: app.RestoreURLIgnored},
+	}, nil).AnyTimes()
+	m.EXPECT().RestoreUserURLs(gomock.Any(), uint(1), []string{"many"}).Return(nil, appUsecaseInternal.ErrTooManyRestoreURLs).AnyTimes()
+	m.EXPECT().RestoreUserURLs(gomock.Any(), uint(1), []string{"789"}).Return(nil, errors.New("restore error")).AnyTimes()
+
+	appHandler := NewAppHandler(m, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.request.method, TestHost+"/api/user/urls/restore", tt.request.body)
+			req = req.WithContext(tt.request.ctx)
+
+			w := httptest.NewRecorder()
+
+			appHandler.APIRestoreUserURLs(w, req)
+
+			res := w.Result()
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want.statusCode, res.StatusCode, "Invalid status code")
+
+			if res.StatusCode == http.StatusOK {
+				assert.Equal(t, tt.want.contentType, res.Header.Get(ContentTypeKey))
+				assert.JSONEq(t, tt.want.body, string(resBody))
+			}
+		})
+	}
+}
+
+func TestAppHandler_APIGetURLStats(t *testing.T) {
+	contextUserID := uint(1)
+	otherUserID := uint(2)
+
+	lastAccessedAt := time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC)
+	stats := &app.ResponseURLStats{
+		ShortURL:       TestHost + "/" + TestID,
+		OriginalURL:    TestValidURL,
+		Clicks:         3,
+		LastAccessedAt: &lastAccessedAt,
+	}
+
+	type request struct {
+		method string
+		id     string
+		ctx    context.Context
+	}
+
+	type want struct {
+		statusCode int
+		body       string
+	}
+
+	tests := []struct {
+		name    string
+		request request
+		want    want
+	}{
+		{
+			name: "valid data",
+			request: request{
+				method: http.MethodGet,
+				id:     TestID,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				body: fmt.Sprintf(
+					`{"short_url": "%s", "original_url": "%s", "clicks": 3, "last_accessed_at": "2024-10-16T12:00:00Z", "is_deleted": false}`,
+					TestHost+"/"+TestID, TestValidURL,
+				),
+			},
+		},
+		{
+			name: "not found",
+			request: request{
+				method: http.MethodGet,
+				id:     "not_found",
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusNotFound,
+			},
+		},
+		{
+			name: "another user URL",
+			request: request{
+				method: http.MethodGet,
+				id:     TestID,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, otherUserID),
+			},
+			want: want{
+				statusCode: http.StatusForbidden,
+			},
+		},
+		{
+			name: "unauthorized user",
+			request: request{
+				method: http.MethodGet,
+				id:     TestID,
+				ctx:    context.Background(),
+			},
+			want: want{
+				statusCode: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "invalid method",
+			request: request{
+				method: http.MethodPost,
+				id:     TestID,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusMethodNotAllowed,
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().GetURLStats(gomock.Any(), TestID, contextUserID).Return(stats, nil).AnyTimes()
+	m.EXPECT().GetURLStats(gomock.Any(), TestID, otherUserID).Return(nil, appUsecaseInternal.ErrForbidden).AnyTimes()
+	m.EXPECT().GetURLStats(gomock.Any(), "not_found", gomock.Any()).Return(nil, app.ErrURLNotFound).AnyTimes()
+
+	appHandler := NewAppHandler(m, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.request.method, TestHost+"/api/user/urls/"+tt.request.id+"/stats", nil)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.request.id)
+
+			req = req.WithContext(context.WithValue(tt.request.ctx, chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+
+			appHandler.APIGetURLStats(w, req)
+
+			res := w.Result()
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want.statusCode, res.StatusCode, "Invalid status code")
+
+			if res.StatusCode == http.StatusOK {
+				assert.JSONEq(t, tt.want.body, string(resBody))
+			}
+		})
+	}
+}
+
+func TestAppHandler_APIUpdateUserURL(t *testing.T) {
+	contextUserID := uint(1)
+	otherUserID := uint(2)
+
+	editedAt := time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC)
+	updated := &app.ResponseUpdateUserURL{
+		ShortURL:    TestHost + "/" + TestID,
+		OriginalURL: TestValidURL,
+		History:     []app.ResponseURLEdit{{OldURL: "https://old.org", NewURL: TestValidURL, EditedAt: editedAt}},
+	}
+
+	type request struct {
+		method string
+		id     string
+		body   string
+		ctx    context.Context
+	}
+
+	type want struct {
+		statusCode  int
+		contentType string
+		body        string
+	}
+
+	tests := []struct {
+		name    string
+		request request
+		want    want
+	}{
+		{
+			name: "valid data",
+			request: request{
+				method: http.MethodPatch,
+				id:     TestID,
+				body:   fmt.Sprintf(`{"url": "%s"}`, TestValidURL),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode:  http.StatusOK,
+				contentType: ApplicationJSONKey,
+				body: fmt.Sprintf(
+					`{"short_url": "%s", "original_url": "%s", "history": [{"old_url": "https://old.org", "new_url": "%s", "edited_at": "2024-11-02T12:00:00Z"}]}`,
+					TestHost+"/"+TestID, TestValidURL, TestValidURL,
+				),
+			},
+		},
+		{
+			name: "URL exists",
+			request: request{
+				method: http.MethodPatch,
+				id:     TestID,
+				body:   `{"url": "https://exists.org"}`,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusConflict,
+			},
+		},
+		{
+			name: "deleted URL",
+			request: request{
+				method: http.MethodPatch,
+				id:     "deleted",
+				body:   fmt.Sprintf(`{"url": "%s"}`, TestValidURL),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusGone,
+			},
+		},
+		{
+			name: "not found",
+			request: request{
+				method: http.MethodPatch,
+				id:     "not_found",
+				body:   fmt.Sprintf(`{"url": "%s"}`, TestValidURL),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusNotFound,
+			},
+		},
+		{
+			name: "another user URL",
+			request: request{
+				method: http.MethodPatch,
+				id:     TestID,
+				body:   fmt.Sprintf(`{"url": "%s"}`, TestValidURL),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, otherUserID),
+			},
+			want: want{
+				statusCode: http.StatusForbidden,
+			},
+		},
+		{
+			name: "invalid URL",
+			request: request{
+				method: http.MethodPatch,
+				id:     TestID,
+				body:   fmt.Sprintf(`{"url": "%s"}`, TestInvalidURL),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "invalid body",
+			request: request{
+				method: http.MethodPatch,
+				id:     TestID,
+				body:   `url`,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "unauthorized user",
+			request: request{
+				method: http.MethodPatch,
+				id:     TestID,
+				body:   fmt.Sprintf(`{"url": "%s"}`, TestValidURL),
+				ctx:    context.Background(),
+			},
+			want: want{
+				statusCode: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "invalid method",
+			request: request{
+				method: http.MethodPut,
+				id:     TestID,
+				body:   fmt.Sprintf(`{"url": "%s"}`, TestValidURL),
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusMethodNotAllowed,
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().UpdateUserURL(gomock.Any(), TestID, contextUserID, TestValidURL).Return(updated, nil).AnyTimes()
+	m.EXPECT().UpdateUserURL(gomock.Any(), TestID, contextUserID, "https://exists.org").Return(nil, app.ErrURLExists).AnyTimes()
+	m.EXPECT().UpdateUserURL(gomock.Any(), TestID, contextUserID, TestInvalidURL).Return(nil, ErrTestInvalidURL).AnyTimes()
+	m.EXPECT().UpdateUserURL(gomock.Any(), TestID, otherUserID, gomock.Any()).Return(nil, appUsecaseInternal.ErrForbidden).AnyTimes()
+	m.EXPECT().UpdateUserURL(gomock.Any(), "deleted", gomock.Any(), gomock.Any()).Return(nil, appUsecaseInternal.ErrURLDeleted).AnyTimes()
+	m.EXPECT().UpdateUserURL(gomock.Any(), "not_found", gomock.Any(), gomock.Any()).Return(nil, app.ErrURLNotFound).AnyTimes()
+
+	appHandler := NewAppHandler(m, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.request.method, TestHost+"/api/user/urls/"+tt.request.id, strings.NewReader(tt.request.body))
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.request.id)
+
+			req = req.WithContext(context.WithValue(tt.request.ctx, chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+
+			appHandler.APIUpdateUserURL(w, req)
+
+			res := w.Result()
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want.statusCode, res.StatusCode, "Invalid status code")
+
+			if res.StatusCode == http.StatusOK {
+				assert.Equal(t, tt.want.contentType, res.Header.Get(ContentTypeKey))
+				assert.JSONEq(t, tt.want.body, string(resBody))
+			}
+		})
+	}
+}
+
+func TestAppHandler_APIGetUserURLHistory(t *testing.T) {
+	contextUserID := uint(1)
+	otherUserID := uint(2)
+
+	editedAt := time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC)
+	history := []app.ResponseURLEdit{{OldURL: "https://old.org", NewURL: TestValidURL, EditedAt: editedAt}}
+
+	type request struct {
+		method string
+		id     string
+		ctx    context.Context
+	}
+
+	type want struct {
+		statusCode int
+		body       string
+	}
+
+	tests := []struct {
+		name    string
+		request request
+		want    want
+	}{
+		{
+			name: "valid data",
+			request: request{
+				method: http.MethodGet,
+				id:     TestID,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				body:       fmt.Sprintf(`[{"old_url": "https://old.org", "new_url": "%s", "edited_at": "2024-11-02T12:00:00Z"}]`, TestValidURL),
+			},
+		},
+		{
+			name: "not found",
+			request: request{
+				method: http.MethodGet,
+				id:     "not_found",
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusNotFound,
+			},
+		},
+		{
+			name: "another user URL",
+			request: request{
+				method: http.MethodGet,
+				id:     TestID,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, otherUserID),
+			},
+			want: want{
+				statusCode: http.StatusForbidden,
+			},
+		},
+		{
+			name: "unauthorized user",
+			request: request{
+				method: http.MethodGet,
+				id:     TestID,
+				ctx:    context.Background(),
+			},
+			want: want{
+				statusCode: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "invalid method",
+			request: request{
+				method: http.MethodPost,
+				id:     TestID,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusMethodNotAllowed,
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().GetUserURLHistory(gomock.Any(), TestID, contextUserID).Return(history, nil).AnyTimes()
+	m.EXPECT().GetUserURLHistory(gomock.Any(), TestID, otherUserID).Return(nil, appUsecaseInternal.ErrForbidden).AnyTimes()
+	m.EXPECT().GetUserURLHistory(gomock.Any(), "not_found", gomock.Any()).Return(nil, app.ErrURLNotFound).AnyTimes()
+
+	appHandler := NewAppHandler(m, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.request.method, TestHost+"/api/user/urls/"+tt.request.id+"/history", nil)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.request.id)
+
+			req = req.WithContext(context.WithValue(tt.request.ctx, chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+
+			appHandler.APIGetUserURLHistory(w, req)
+
+			res := w.Result()
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want.statusCode, res.StatusCode, "Invalid status code")
+
+			if res.StatusCode == http.StatusOK {
+				assert.JSONEq(t, tt.want.body, string(resBody))
+			}
+		})
+	}
+}
+
+func TestAppHandler_APIGetDeletionJob(t *testing.T) {
+	contextUserID := uint(1)
+	otherUserID := uint(2)
+
+	job := &app.ResponseDeletionJob{
+		JobID:     TestJobID,
+		Status:    app.DeletionJobDone,
+		CreatedAt: time.Date(2024, 10, 16, 15, 0, 0, 0, time.UTC),
+		URLs: []app.ResponseDeletionJobURL{
+			{ID: TestID, Status: app.DeletionJobDone},
+			{ID: "other", Status: app.DeletionURLIgnored},
+		},
+	}
+
+	type request struct {
+		method string
+		jobID  string
+		ctx    context.Context
+	}
+
+	type want struct {
+		statusCode int
+		body       string
+	}
+
+	tests := []struct {
+		name    string
+		request request
+		want    want
+	}{
+		{
+			name: "valid data",
+			request: request{
+				method: http.MethodGet,
+				jobID:  TestJobID,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				body: fmt.Sprintf(
+					`{"job_id": "%s", "status": "done", "attempts": 0, "created_at": "2024-10-16T15:00:00Z", "urls": [{"id": "%s", "status": "done"}, {"id": "other", "status": "ignored"}]}`,
+					TestJobID, TestID,
+				),
+			},
+		},
+		{
+			name: "not found",
+			request: request{
+				method: http.MethodGet,
+				jobID:  "not_found",
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusNotFound,
+			},
+		},
+		{
+			name: "another user job",
+			request: request{
+				method: http.MethodGet,
+				jobID:  TestJobID,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, otherUserID),
+			},
+			want: want{
+				statusCode: http.StatusForbidden,
+			},
+		},
+		{
+			name: "storage error",
+			request: request{
+				method: http.MethodGet,
+				jobID:  "error",
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusInternalServerError,
+			},
+		},
+		{
+			name: "unauthorized user",
+			request: request{
+				method: http.MethodGet,
+				jobID:  TestJobID,
+				ctx:    context.Background(),
+			},
+			want: want{
+				statusCode: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "invalid method",
+			request: request{
+				method: http.MethodPost,
+				jobID:  TestJobID,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, contextUserID),
+			},
+			want: want{
+				statusCode: http.StatusMethodNotAllowed,
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().GetDeletionJob(gomock.Any(), TestJobID, contextUserID).Return(job, nil).AnyTimes()
+	m.EXPECT().GetDeletionJob(gomock.Any(), TestJobID, otherUserID).Return(nil, appUsecaseInternal.ErrForbidden).AnyTimes()
+	m.EXPECT().GetDeletionJob(gomock.Any(), "not_found", gomock.Any()).Return(nil, app.ErrDeletionJobNotFound).AnyTimes()
+	m.EXPECT().GetDeletionJob(gomock.Any(), "error", gomock.Any()).Return(nil, errors.New("storage error")).AnyTimes()
+
+	appHandler := NewAppHandler(m, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.request.method, TestHost+"/api/user/urls/delete/"+tt.request.jobID, nil)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("job_id", tt.request.jobID)
+
+			req = req.WithContext(context.WithValue(tt.request.ctx, chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+
+			appHandler.APIGetDeletionJob(w, req)
+
+			res := w.Result()
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want.statusCode, res.StatusCode, "Invalid status code")
+
+			if res.StatusCode == http.StatusOK {
+				assert.JSONEq(t, tt.want.body, string(resBody))
+			}
+		})
+	}
+}
+
+func TestAppHandler_APIGetStats(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		countURLsErr   error
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "valid data",
+			method:         http.MethodGet,
+			countURLsErr:   nil,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"urls": 3, "users": 2}`,
+		},
+		{
+			name:           "count error",
+			method:         http.MethodGet,
+			countURLsErr:   fmt.Errorf("internal server error"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "invalid method",
+			method:         http.MethodPost,
+			countURLsErr:   nil,
+			wantStatusCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mocks.NewMockAppUsecaseInterface(ctrl)
+			m.EXPECT().CountURLs(gomock.Any()).Return(uint(3), tt.countURLsErr).AnyTimes()
+
+			um := mocks.NewMockUserUsecaseInterface(ctrl)
+			um.EXPECT().CountUsers(gomock.Any()).Return(uint(2), nil).AnyTimes()
+
+			appHandler := NewAppHandler(m, um)
+
+			req := httptest.NewRequest(tt.method, TestHost+"/api/internal/stats", nil)
+
+			w := httptest.NewRecorder()
+
+			appHandler.APIGetStats(w, req)
+
+			res := w.Result()
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode, "Invalid status code")
+
+			if res.StatusCode == http.StatusOK {
+				assert.JSONEq(t, tt.wantBody, string(resBody))
+			}
+		})
+	}
+}
+
+func TestAppHandler_APICompactStorage(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		compactErr     error
+		wantStatusCode int
+	}{
+		{
+			name:           "storage compacted",
+			method:         http.MethodPost,
+			compactErr:     nil,
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "compaction not supported",
+			method:         http.MethodPost,
+			compactErr:     appUsecaseInternal.ErrCompactionNotSupported,
+			wantStatusCode: http.StatusNotImplemented,
+		},
+		{
+			name:           "compaction error",
+			method:         http.MethodPost,
+			compactErr:     fmt.Errorf("internal server error"),
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "invalid method",
+			method:         http.MethodGet,
+			compactErr:     nil,
+			wantStatusCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mocks.NewMockAppUsecaseInterface(ctrl)
+			m.EXPECT().CompactStorage(gomock.Any()).Return(tt.compactErr).AnyTimes()
+
+			appHandler := NewAppHandler(m, nil)
+
+			req := httptest.NewRequest(tt.method, TestHost+"/api/internal/storage/compact", nil)
+
+			w := httptest.NewRecorder()
+
+			appHandler.APICompactStorage(w, req)
+
+			res := w.Result()
+			err := res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode, "Invalid status code")
+		})
+	}
+}
+
+func TestAppHandler_APIExportUserURLs(t *testing.T) {
+	createdAt := time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC)
+	records := []app.UserURLRecord{
+		{ShortURL: TestHost + "/" + TestID, OriginalURL: TestValidURL, CreatedAt: &createdAt},
+		{ShortURL: TestHost + "/deleted", OriginalURL: "https://test.ru?a=1,b=2", IsDeleted: true},
+	}
+
+	type request struct {
+		method string
+		query  string
+		ctx    context.Context
+	}
+
+	type want struct {
+		statusCode  int
+		contentType string
+		body        string
+	}
+
+	tests := []struct {
+		name    string
+		request request
+		want    want
+	}{
+		{
+			name: "default NDJSON format",
+			request: request{
+				method: http.MethodGet,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode:  http.StatusOK,
+				contentType: ApplicationNDJSONKey,
+				body: fmt.Sprintf(`{"short_url":"%s","original_url":"%s","is_deleted":false,"created_at":"2024-10-16T12:00:00Z"}`+"\n"+
+					`{"short_url":"%s","original_url":"https://test.ru?a=1,b=2","is_deleted":true}`+"\n",
+					TestHost+"/"+TestID, TestValidURL, TestHost+"/deleted"),
+			},
+		},
+		{
+			name: "CSV format",
+			request: request{
+				method: http.MethodGet,
+				query:  "?format=csv",
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode:  http.StatusOK,
+				contentType: TextCSVKey,
+				body: "short_url,original_url,is_deleted,created_at\n" +
+					fmt.Sprintf("%s,%s,false,2024-10-16T12:00:00Z\n", TestHost+"/"+TestID, TestValidURL) +
+					fmt.Sprintf("%s,\"https://test.ru?a=1,b=2\",true,\n", TestHost+"/deleted"),
+			},
+		},
+		{
+			name: "unknown format",
+			request: request{
+				method: http.MethodGet,
+				query:  "?format=xml",
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "unauthorized user",
+			request: request{
+				method: http.MethodGet,
+				ctx:    context.Background(),
+			},
+			want: want{
+				statusCode: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "invalid method",
+			request: request{
+				method: http.MethodPost,
+				ctx:    context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode: http.StatusMethodNotAllowed,
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// records are written page by page
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().ExportUserURLs(gomock.Any(), TestUserID, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ uint, write func(records []app.UserURLRecord) error) error {
+			for _, record := range records {
+				if err := write([]app.UserURLRecord{record}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	).AnyTimes()
+
+	appHandler := NewAppHandler(m, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.request.method, TestHost+"/api/user/urls/export"+tt.request.query, nil)
+			req = req.WithContext(tt.request.ctx)
+
+			w := httptest.NewRecorder()
+
+			appHandler.APIExportUserURLs(w, req)
+
+			res := w.Result()
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want.statusCode, res.StatusCode, "Invalid status code")
+
+			if res.StatusCode == http.StatusOK {
+				assert.Equal(t, tt.want.contentType, res.Header.Get(ContentTypeKey))
+				assert.Contains(t, res.Header.Get(ContentDispositionKey), "attachment")
+				assert.Equal(t, tt.want.body, string(resBody))
+			}
+		})
+	}
+
+	t.Run("failed export", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		m := mocks.NewMockAppUsecaseInterface(ctrl)
+		m.EXPECT().ExportUserURLs(gomock.Any(), TestUserID, gomock.Any()).Return(errors.New("export error")).Times(1)
+
+		req := httptest.NewRequest(http.MethodGet, TestHost+"/api/user/urls/export?format=csv", nil)
+		req = req.WithContext(context.WithValue(context.Background(), usecase.UserIDKey, TestUserID))
+		w := httptest.NewRecorder()
+
+		NewAppHandler(m, nil).APIExportUserURLs(w, req)
+
+		res := w.Result()
+		err := res.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Empty(t, res.Header.Get(ContentDispositionKey))
+	})
+
+	t.Run("export failed after first page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		m := mocks.NewMockAppUsecaseInterface(ctrl)
+		m.EXPECT().ExportUserURLs(gomock.Any(), TestUserID, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ uint, write func(records []app.UserURLRecord) error) error {
+				if err := write(records[:1]); err != nil {
+					return err
+				}
+				return errors.New("export error")
+			},
+		).Times(1)
+
+		req := httptest.NewRequest(http.MethodGet, TestHost+"/api/user/urls/export", nil)
+		req = req.WithContext(context.WithValue(context.Background(), usecase.UserIDKey, TestUserID))
+		w := httptest.NewRecorder()
+
+		NewAppHandler(m, nil).APIExportUserURLs(w, req)
+
+		// status is already sent with the first page, response is cut
+		res := w.Result()
+		resBody, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		err = res.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, 1, strings.Count(string(resBody), "\n"))
+	})
+}
+
+func TestAppHandler_APIImportUserURLs(t *testing.T) {
+	wantRecords := []app.UserURLRecord{
+		{ShortURL: TestHost + "/" + TestID, OriginalURL: TestValidURL},
+		{OriginalURL: "https://test.ru", IsDeleted: true},
+	}
+	resp := []app.ResponseImportUserURL{
+		{Row: 1, ShortURL: TestHost + "/" + TestID, Status: app.ImportStatusImported},
+		{Row: 2, Status: app.ImportStatusSkipped},
+	}
+	respBody := fmt.Sprintf(`[{"row": 1, "short_url": "%s", "status": "imported"}, {"row": 2, "status": "skipped"}]`, TestHost+"/"+TestID)
+
+	csvBody := "original_url,short_url,is_deleted\n" +
+		fmt.Sprintf("%s,%s,\n", TestValidURL, TestHost+"/"+TestID) +
+		"https://test.ru,,true\n"
+	ndjsonBody := fmt.Sprintf(`{"short_url":"%s","original_url":"%s"}`, TestHost+"/"+TestID, TestValidURL) + "\n" +
+		`{"original_url":"https://test.ru","is_deleted":true}` + "\n"
+
+	type request struct {
+		method      string
+		query       string
+		contentType string
+		body        string
+		ctx         context.Context
+	}
+
+	type want struct {
+		statusCode int
+		body       string
+	}
+
+	tests := []struct {
+		name    string
+		request request
+		want    want
+	}{
+		{
+			name: "CSV by Content-Type",
+			request: request{
+				method:      http.MethodPost,
+				contentType: TextCSVKey + "; charset=utf-8",
+				body:        csvBody,
+				ctx:         context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				body:       respBody,
+			},
+		},
+		{
+			name: "NDJSON by Content-Type",
+			request: request{
+				method:      http.MethodPost,
+				contentType: ApplicationNDJSONKey,
+				body:        ndjsonBody,
+				ctx:         context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				body:       respBody,
+			},
+		},
+		{
+			name: "format by query",
+			request: request{
+				method:      http.MethodPost,
+				query:       "?format=csv",
+				contentType: TextPlainKey,
+				body:        csvBody,
+				ctx:         context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				body:       respBody,
+			},
+		},
+		{
+			name: "unknown format",
+			request: request{
+				method:      http.MethodPost,
+				contentType: ApplicationJSONKey,
+				body:        ndjsonBody,
+				ctx:         context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "CSV without original_url column",
+			request: request{
+				method:      http.MethodPost,
+				contentType: TextCSVKey,
+				body:        "short_url\n" + TestHost + "/" + TestID + "\n",
+				ctx:         context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "CSV with invalid deleted flag",
+			request: request{
+				method:      http.MethodPost,
+				contentType: TextCSVKey,
+				body:        "original_url,is_deleted\n" + TestValidURL + ",maybe\n",
+				ctx:         context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "invalid NDJSON",
+			request: request{
+				method:      http.MethodPost,
+				contentType: ApplicationNDJSONKey,
+				body:        ndjsonBody + "{",
+				ctx:         context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "unauthorized user",
+			request: request{
+				method:      http.MethodPost,
+				contentType: TextCSVKey,
+				body:        csvBody,
+				ctx:         context.Background(),
+			},
+			want: want{
+				statusCode: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "invalid method",
+			request: request{
+				method:      http.MethodGet,
+				contentType: TextCSVKey,
+				body:        csvBody,
+				ctx:         context.WithValue(context.Background(), usecase.UserIDKey, TestUserID),
+			},
+			want: want{
+				statusCode: http.StatusMethodNotAllowed,
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockAppUsecaseInterface(ctrl)
+	m.EXPECT().ImportUserURLs(gomock.Any(), TestUserID, wantRecords).Return(resp, nil).AnyTimes()
+
+	appHandler := NewAppHandler(m, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.request.method, TestHost+"/api/user/urls/import"+tt.request.query, bytes.NewBufferString(tt.request.body))
+			req.Header.Set(ContentTypeKey, tt.request.contentType)
+			req = req.WithContext(tt.request.ctx)
+
+			w := httptest.NewRecorder()
+
+			appHandler.APIImportUserURLs(w, req)
+
+			res := w.Result()
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want.statusCode, res.StatusCode, "Invalid status code")
+
+			if res.StatusCode == http.StatusOK {
+				assert.JSONEq(t, tt.want.body, string(resBody))
+			}
 		})
 	}
 }
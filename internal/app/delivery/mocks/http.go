@@ -5,7 +5,9 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	app "github.com/MisterMaks/go-yandex-shortener/internal/app"
 	gomock "github.com/golang/mock/gomock"
@@ -34,6 +36,78 @@ func (m *MockAppUsecaseInterface) EXPECT() *MockAppUsecaseInterfaceMockRecorder
 	return m.recorder
 }
 
+// CheckReadiness mocks base method.
+func (m *MockAppUsecaseInterface) CheckReadiness(ctx context.Context) *app.ResponseReadiness {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckReadiness", ctx)
+	ret0, _ := ret[0].(*app.ResponseReadiness)
+	return ret0
+}
+
+// CheckReadiness indicates an expected call of CheckReadiness.
+func (mr *MockAppUsecaseInterfaceMockRecorder) CheckReadiness(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckReadiness", reflect.TypeOf((*MockAppUsecaseInterface)(nil).CheckReadiness), ctx)
+}
+
+// CompactStorage mocks base method.
+func (m *MockAppUsecaseInterface) CompactStorage(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompactStorage", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompactStorage indicates an expected call of CompactStorage.
+func (mr *MockAppUsecaseInterfaceMockRecorder) CompactStorage(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompactStorage", reflect.TypeOf((*MockAppUsecaseInterface)(nil).CompactStorage), ctx)
+}
+
+// CountURLs mocks base method.
+func (m *MockAppUsecaseInterface) CountURLs(ctx context.Context) (uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountURLs", ctx)
+	ret0, _ := ret[0].(uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountURLs indicates an expected call of CountURLs.
+func (mr *MockAppUsecaseInterfaceMockRecorder) CountURLs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountURLs", reflect.TypeOf((*MockAppUsecaseInterface)(nil).CountURLs), ctx)
+}
+
+// EnqueueDeleteUserURLs mocks base method.
+func (m *MockAppUsecaseInterface) EnqueueDeleteUserURLs(ctx context.Context, userID uint, urlIDs []string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueDeleteUserURLs", ctx, userID, urlIDs)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnqueueDeleteUserURLs indicates an expected call of EnqueueDeleteUserURLs.
+func (mr *MockAppUsecaseInterfaceMockRecorder) EnqueueDeleteUserURLs(ctx, userID, urlIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueDeleteUserURLs", reflect.TypeOf((*MockAppUsecaseInterface)(nil).EnqueueDeleteUserURLs), ctx, userID, urlIDs)
+}
+
+// ExportUserURLs mocks base method.
+func (m *MockAppUsecaseInterface) ExportUserURLs(ctx context.Context, userID uint, write func([]app.UserURLRecord) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportUserURLs", ctx, userID, write)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportUserURLs indicates an expected call of ExportUserURLs.
+func (mr *MockAppUsecaseInterfaceMockRecorder) ExportUserURLs(ctx, userID, write interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportUserURLs", reflect.TypeOf((*MockAppUsecaseInterface)(nil).ExportUserURLs), ctx, userID, write)
+}
+
 // GenerateShortURL mocks base method.
 func (m *MockAppUsecaseInterface) GenerateShortURL(id string) string {
 	m.ctrl.T.Helper()
@@ -48,10 +122,25 @@ func (mr *MockAppUsecaseInterfaceMockRecorder) GenerateShortURL(id interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateShortURL", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GenerateShortURL), id)
 }
 
+// GetDeletionJob mocks base method.
+func (m *MockAppUsecaseInterface) GetDeletionJob(ctx context.Context, jobID string, userID uint) (*app.ResponseDeletionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletionJob", ctx, jobID, userID)
+	ret0, _ := ret[0].(*app.ResponseDeletionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletionJob indicates an expected call of GetDeletionJob.
+func (mr *MockAppUsecaseInterfaceMockRecorder) GetDeletionJob(ctx, jobID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletionJob", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetDeletionJob), ctx, jobID, userID)
+}
+
 // GetOrCreateURL mocks base method.
-func (m *MockAppUsecaseInterface) GetOrCreateURL(rawURL string, userID uint) (*app.URL, bool, error) {
+func (m *MockAppUsecaseInterface) GetOrCreateURL(ctx context.Context, rawURL, alias string, expiresAt *time.Time, userID uint) (*app.URL, bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrCreateURL", rawURL, userID)
+	ret := m.ctrl.Call(m, "GetOrCreateURL", ctx, rawURL, alias, expiresAt, userID)
 	ret0, _ := ret[0].(*app.URL)
 	ret1, _ := ret[1].(bool)
 	ret2, _ := ret[2].(error)
@@ -59,78 +148,192 @@ func (m *MockAppUsecaseInterface) GetOrCreateURL(rawURL string, userID uint) (*a
 }
 
 // GetOrCreateURL indicates an expected call of GetOrCreateURL.
-func (mr *MockAppUsecaseInterfaceMockRecorder) GetOrCreateURL(rawURL, userID interface{}) *gomock.Call {
+func (mr *MockAppUsecaseInterfaceMockRecorder) GetOrCreateURL(ctx, rawURL, alias, expiresAt, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrCreateURL", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetOrCreateURL), rawURL, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrCreateURL", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetOrCreateURL), ctx, rawURL, alias, expiresAt, userID)
 }
 
 // GetOrCreateURLs mocks base method.
-func (m *MockAppUsecaseInterface) GetOrCreateURLs(requestBatchURLs []app.RequestBatchURL, userID uint) ([]app.ResponseBatchURL, error) {
+func (m *MockAppUsecaseInterface) GetOrCreateURLs(ctx context.Context, requestBatchURLs []app.RequestBatchURL, userID uint) ([]app.ResponseBatchURL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrCreateURLs", requestBatchURLs, userID)
+	ret := m.ctrl.Call(m, "GetOrCreateURLs", ctx, requestBatchURLs, userID)
 	ret0, _ := ret[0].([]app.ResponseBatchURL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOrCreateURLs indicates an expected call of GetOrCreateURLs.
-func (mr *MockAppUsecaseInterfaceMockRecorder) GetOrCreateURLs(requestBatchURLs, userID interface{}) *gomock.Call {
+func (mr *MockAppUsecaseInterfaceMockRecorder) GetOrCreateURLs(ctx, requestBatchURLs, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrCreateURLs", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetOrCreateURLs), requestBatchURLs, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrCreateURLs", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetOrCreateURLs), ctx, requestBatchURLs, userID)
 }
 
 // GetURL mocks base method.
-func (m *MockAppUsecaseInterface) GetURL(id string) (*app.URL, error) {
+func (m *MockAppUsecaseInterface) GetURL(ctx context.Context, id string) (*app.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetURL", id)
+	ret := m.ctrl.Call(m, "GetURL", ctx, id)
 	ret0, _ := ret[0].(*app.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetURL indicates an expected call of GetURL.
-func (mr *MockAppUsecaseInterfaceMockRecorder) GetURL(id interface{}) *gomock.Call {
+func (mr *MockAppUsecaseInterfaceMockRecorder) GetURL(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetURL), ctx, id)
+}
+
+// GetURLStats mocks base method.
+func (m *MockAppUsecaseInterface) GetURLStats(ctx context.Context, id string, userID uint) (*app.ResponseURLStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetURLStats", ctx, id, userID)
+	ret0, _ := ret[0].(*app.ResponseURLStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetURLStats indicates an expected call of GetURLStats.
+func (mr *MockAppUsecaseInterfaceMockRecorder) GetURLStats(ctx, id, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURLStats", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetURLStats), ctx, id, userID)
+}
+
+// GetUserURLHistory mocks base method.
+func (m *MockAppUsecaseInterface) GetUserURLHistory(ctx context.Context, id string, userID uint) ([]app.ResponseURLEdit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserURLHistory", ctx, id, userID)
+	ret0, _ := ret[0].([]app.ResponseURLEdit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserURLHistory indicates an expected call of GetUserURLHistory.
+func (mr *MockAppUsecaseInterfaceMockRecorder) GetUserURLHistory(ctx, id, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetURL), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserURLHistory", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetUserURLHistory), ctx, id, userID)
 }
 
 // GetUserURLs mocks base method.
-func (m *MockAppUsecaseInterface) GetUserURLs(userID uint) ([]app.ResponseUserURL, error) {
+func (m *MockAppUsecaseInterface) GetUserURLs(ctx context.Context, userID uint, params app.UserURLsParams) ([]app.ResponseUserURL, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserURLs", userID)
+	ret := m.ctrl.Call(m, "GetUserURLs", ctx, userID, params)
 	ret0, _ := ret[0].([]app.ResponseUserURL)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetUserURLs indicates an expected call of GetUserURLs.
+func (mr *MockAppUsecaseInterfaceMockRecorder) GetUserURLs(ctx, userID, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserURLs", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetUserURLs), ctx, userID, params)
+}
+
+// ImportUserURLs mocks base method.
+func (m *MockAppUsecaseInterface) ImportUserURLs(ctx context.Context, userID uint, records []app.UserURLRecord) ([]app.ResponseImportUserURL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportUserURLs", ctx, userID, records)
+	ret0, _ := ret[0].([]app.ResponseImportUserURL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserURLs indicates an expected call of GetUserURLs.
-func (mr *MockAppUsecaseInterfaceMockRecorder) GetUserURLs(userID interface{}) *gomock.Call {
+// ImportUserURLs indicates an expected call of ImportUserURLs.
+func (mr *MockAppUsecaseInterfaceMockRecorder) ImportUserURLs(ctx, userID, records interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserURLs", reflect.TypeOf((*MockAppUsecaseInterface)(nil).GetUserURLs), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportUserURLs", reflect.TypeOf((*MockAppUsecaseInterface)(nil).ImportUserURLs), ctx, userID, records)
 }
 
 // Ping mocks base method.
-func (m *MockAppUsecaseInterface) Ping() error {
+func (m *MockAppUsecaseInterface) Ping(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping")
+	ret := m.ctrl.Call(m, "Ping", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Ping indicates an expected call of Ping.
-func (mr *MockAppUsecaseInterfaceMockRecorder) Ping() *gomock.Call {
+func (mr *MockAppUsecaseInterfaceMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockAppUsecaseInterface)(nil).Ping), ctx)
+}
+
+// RestoreUserURLs mocks base method.
+func (m *MockAppUsecaseInterface) RestoreUserURLs(ctx context.Context, userID uint, urlIDs []string) ([]app.ResponseRestoreUserURL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUserURLs", ctx, userID, urlIDs)
+	ret0, _ := ret[0].([]app.ResponseRestoreUserURL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUserURLs indicates an expected call of RestoreUserURLs.
+func (mr *MockAppUsecaseInterfaceMockRecorder) RestoreUserURLs(ctx, userID, urlIDs interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockAppUsecaseInterface)(nil).Ping))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUserURLs", reflect.TypeOf((*MockAppUsecaseInterface)(nil).RestoreUserURLs), ctx, userID, urlIDs)
 }
 
-// SendDeleteUserURLsInChan mocks base method.
-func (m *MockAppUsecaseInterface) SendDeleteUserURLsInChan(userID uint, urlIDs []string) {
+// SendURLClickInChan mocks base method.
+func (m *MockAppUsecaseInterface) SendURLClickInChan(urlID string) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SendDeleteUserURLsInChan", userID, urlIDs)
+	m.ctrl.Call(m, "SendURLClickInChan", urlID)
+}
+
+// SendURLClickInChan indicates an expected call of SendURLClickInChan.
+func (mr *MockAppUsecaseInterfaceMockRecorder) SendURLClickInChan(urlID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendURLClickInChan", reflect.TypeOf((*MockAppUsecaseInterface)(nil).SendURLClickInChan), urlID)
+}
+
+// UpdateUserURL mocks base method.
+func (m *MockAppUsecaseInterface) UpdateUserURL(ctx context.Context, id string, userID uint, rawURL string) (*app.ResponseUpdateUserURL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserURL", ctx, id, userID, rawURL)
+	ret0, _ := ret[0].(*app.ResponseUpdateUserURL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserURL indicates an expected call of UpdateUserURL.
+func (mr *MockAppUsecaseInterfaceMockRecorder) UpdateUserURL(ctx, id, userID, rawURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserURL", reflect.TypeOf((*MockAppUsecaseInterface)(nil).UpdateUserURL), ctx, id, userID, rawURL)
+}
+
+// MockUserUsecaseInterface is a mock of UserUsecaseInterface interface.
+type MockUserUsecaseInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserUsecaseInterfaceMockRecorder
+}
+
+// MockUserUsecaseInterfaceMockRecorder is the mock recorder for MockUserUsecaseInterface.
+type MockUserUsecaseInterfaceMockRecorder struct {
+	mock *MockUserUsecaseInterface
+}
+
+// NewMockUserUsecaseInterface creates a new mock instance.
+func NewMockUserUsecaseInterface(ctrl *gomock.Controller) *MockUserUsecaseInterface {
+	mock := &MockUserUsecaseInterface{ctrl: ctrl}
+	mock.recorder = &MockUserUsecaseInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserUsecaseInterface) EXPECT() *MockUserUsecaseInterfaceMockRecorder {
+	return m.recorder
+}
+
+// CountUsers mocks base method.
+func (m *MockUserUsecaseInterface) CountUsers(ctx context.Context) (uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsers", ctx)
+	ret0, _ := ret[0].(uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// SendDeleteUserURLsInChan indicates an expected call of SendDeleteUserURLsInChan.
-func (mr *MockAppUsecaseInterfaceMockRecorder) SendDeleteUserURLsInChan(userID, urlIDs interface{}) *gomock.Call {
+// CountUsers indicates an expected call of CountUsers.
+func (mr *MockUserUsecaseInterfaceMockRecorder) CountUsers(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendDeleteUserURLsInChan", reflect.TypeOf((*MockAppUsecaseInterface)(nil).SendDeleteUserURLsInChan), userID, urlIDs)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsers", reflect.TypeOf((*MockUserUsecaseInterface)(nil).CountUsers), ctx)
 }
@@ -1,97 +1,326 @@
 package repo
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"os"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 )
 
 type producer struct {
-	file *os.File
-	// добавляем Writer в Producer
-	writer *bufio.Writer
+	writer      *filestorage.Writer
+	startHeader *logHeader // header of log which is not started again after compaction yet, nil if log is started
 }
 
-func newProducer(filename string) (*producer, error) {
-	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+func newProducer(filename string, syncPolicy filestorage.SyncPolicy) (*producer, error) {
+	writer, err := filestorage.NewWriter(filename, syncPolicy)
 	if err != nil {
 		return nil, err
 	}
 
 	return &producer{
-		file: file,
-		// создаём новый Writer
-		writer: bufio.NewWriter(file),
+		writer: writer,
 	}, nil
 }
 
 func (p *producer) close() error {
-	// закрываем файл
-	return p.file.Close()
+	return p.writer.Close()
 }
 
-func (p *producer) writeURL(url *app.URL) error {
-	data, err := json.Marshal(&url)
+// checkWritable checks that file of producer still exists and can be opened for writing.
+func (p *producer) checkWritable() error {
+	file, err := os.OpenFile(p.writer.Name(), os.O_WRONLY|os.O_APPEND, 0)
 	if err != nil {
 		return err
 	}
+	return file.Close()
+}
 
-	// записываем событие в буфер
-	if _, err := p.writer.Write(data); err != nil {
+// write appends record to file. Log which is not started again after compaction is started first,
+// otherwise record would be written to log of previous generation which is skipped on next start.
+func (p *producer) write(v interface{}) error {
+	if err := p.start(); err != nil {
 		return err
 	}
+	return p.writer.Write(v)
+}
+
+func (p *producer) writeURL(url *app.URL) error {
+	return p.write(url)
+}
+
+func (p *producer) writeDeletedURL(record *deletedURLRecord) error {
+	return p.write(record)
+}
+
+func (p *producer) writeURLClicks(urlClicks *app.URLClicks) error {
+	return p.write(urlClicks)
+}
+
+func (p *producer) writeDeletionJob(job *app.DeletionJob) error {
+	return p.write(job)
+}
+
+func (p *producer) writePurge(ids []string) error {
+	return p.write(&purgeRecord{Purged: ids})
+}
+
+// replace atomically replaces file of producer with records and reopens it for appending.
+func (p *producer) replace(records ...interface{}) error {
+	return p.writer.Replace(records...)
+}
 
-	// добавляем перенос строки
-	if err := p.writer.WriteByte('\n'); err != nil {
+// startLog starts log again with header. If log can not be started now,
+// it is started before its next record and the record is not written till then.
+func (p *producer) startLog(header *logHeader) error {
+	p.startHeader = header
+	return p.start()
+}
+
+// start starts log with header of pending start.
+func (p *producer) start() error {
+	if p.startHeader == nil {
+		return nil
+	}
+	if err := p.replace(p.startHeader); err != nil {
 		return err
 	}
+	p.startHeader = nil
+	return nil
+}
 
-	// записываем буфер в файл
-	return p.writer.Flush()
+// logHeader is first record of log started by compaction.
+// Records of log are applied on top of snapshot with the same generation,
+// log without header has zero generation.
+type logHeader struct {
+	Generation *uint64 `json:"generation"`
 }
 
-type consumer struct {
-	file *os.File
-	// заменяем Reader на Scanner
-	scanner *bufio.Scanner
+// parseLogHeader parses record data as log header.
+func parseLogHeader(data []byte) (uint64, bool) {
+	header := logHeader{}
+	if err := json.Unmarshal(data, &header); err != nil || header.Generation == nil {
+		return 0, false
+	}
+	return *header.Generation, true
+}
+
+// newLogHeader creates header of log with generation.
+func newLogHeader(generation uint64) *logHeader {
+	return &logHeader{Generation: &generation}
+}
+
+// purgeRecord is record of log which removes purged URLs. Records of purged URLs written before it
+// belong to removed URLs, so they are not applied to URLs created later with the same IDs.
+// Purge records are dropped by compaction together with records of purged URLs.
+type purgeRecord struct {
+	Purged []string
+}
+
+// purgeRecordPrefix is start of marshalled purge record, so other records are not unmarshalled twice.
+var purgeRecordPrefix = []byte(`{"Purged":`)
+
+// parsePurgeRecord parses record data as purge record and returns set of IDs of purged URLs.
+func parsePurgeRecord(data []byte) (map[string]struct{}, bool) {
+	if !bytes.HasPrefix(data, purgeRecordPrefix) {
+		return nil, false
+	}
+	record := purgeRecord{}
+	if err := json.Unmarshal(data, &record); err != nil || len(record.Purged) == 0 {
+		return nil, false
+	}
+	ids := make(map[string]struct{}, len(record.Purged))
+	for _, id := range record.Purged {
+		ids[id] = struct{}{}
+	}
+	return ids, true
 }
 
-func newConsumer(filename string) (*consumer, error) {
-	file, err := os.OpenFile(filename, os.O_RDONLY|os.O_CREATE, 0666)
+// isPurgedID checks whether id is in set of IDs of purged URLs.
+func isPurgedID(ids map[string]struct{}, id string) bool {
+	_, ok := ids[id]
+	return ok
+}
+
+// deletePurgedURLs removes URLs with purged IDs.
+func deletePurgedURLs(urls []*app.URL, ids map[string]struct{}) []*app.URL {
+	return slices.DeleteFunc(urls, func(url *app.URL) bool { return isPurgedID(ids, url.ID) })
+}
+
+// deletePurgedURLEdits removes changes of original URLs of URLs with purged IDs.
+func deletePurgedURLEdits(edits []*app.URLEdit, ids map[string]struct{}) []*app.URLEdit {
+	return slices.DeleteFunc(edits, func(edit *app.URLEdit) bool { return isPurgedID(ids, edit.URLID) })
+}
+
+// readRecords reads records of file and passes them to decode.
+// Func returns generation from log header.
+func readRecords(filename string, decode func(data []byte) error) (uint64, error) {
+	var generation uint64
+	first := true
+	err := filestorage.ReadRecords(filename, func(data []byte) error {
+		if first {
+			first = false
+			if g, ok := parseLogHeader(data); ok {
+				generation = g
+				return nil
+			}
+		}
+		return decode(data)
+	})
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	return generation, nil
+}
 
-	return &consumer{
-		file: file,
-		// создаём новый scanner
-		scanner: bufio.NewScanner(file),
-	}, nil
+func (p *producer) writeURLEdit(edit *app.URLEdit) error {
+	return p.write(&urlRecord{Edit: edit})
+}
+
+// writeURLHistory writes change of original URL which is already applied to saved URL.
+func (p *producer) writeURLHistory(edit *app.URLEdit) error {
+	return p.write(&urlRecord{Edit: edit, History: true})
 }
 
-func (c *consumer) close() error {
-	return c.file.Close()
+// urlRecord is record of log of URLs: new URL or change of original URL of saved URL.
+// URL fields are written at the top level, so records of previous versions are read as new URLs.
+type urlRecord struct {
+	*app.URL
+	Edit    *app.URLEdit `json:",omitempty"`
+	History bool         `json:",omitempty"` // change is only kept in history, original URL of saved URL already has it
 }
 
-func (c *consumer) readURLs() ([]*app.URL, error) {
+// readURLs reads new URLs, changes of original URLs and history of changes of file in the order they were written.
+// Records of URLs purged after them are skipped. Func returns IDs of all purged URLs,
+// so URLs of snapshot are skipped too.
+func readURLs(filename string) ([]*app.URL, []*app.URLEdit, []*app.URLEdit, map[string]struct{}, uint64, error) {
 	urls := make([]*app.URL, 0, DefaultCountURLs)
+	edits := []*app.URLEdit{}
+	history := []*app.URLEdit{}
+	purged := make(map[string]struct{})
+	generation, err := readRecords(filename, func(data []byte) error {
+		if ids, ok := parsePurgeRecord(data); ok {
+			urls = deletePurgedURLs(urls, ids)
+			edits = deletePurgedURLEdits(edits, ids)
+			history = deletePurgedURLEdits(history, ids)
+			for id := range ids {
+				purged[id] = struct{}{}
+			}
+			return nil
+		}
 
-	bytes, err := os.ReadFile(c.file.Name())
+		record := &urlRecord{}
+		if err := json.Unmarshal(data, record); err != nil {
+			return err
+		}
+		if record.Edit != nil && record.History {
+			history = append(history, record.Edit)
+			return nil
+		}
+		if record.Edit != nil {
+			edits = append(edits, record.Edit)
+			return nil
+		}
+		if record.URL == nil {
+			record.URL = &app.URL{}
+		}
+		urls = append(urls, record.URL)
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, nil, 0, err
+	}
+	return urls, edits, history, purged, generation, nil
+}
+
+// deletedURLRecord is record of log of deleted URLs: URL is deleted or restored by its user.
+// Records written before restore was supported have only ID and UserID and mark URL as deleted.
+type deletedURLRecord struct {
+	ID        string
+	UserID    uint
+	DeletedAt *time.Time `json:",omitempty"` // time of deletion, nil for records of previous versions
+	Restored  bool       `json:",omitempty"` // deletion of URL is cancelled
+}
+
+// readDeletedURLs reads records of log of deleted URLs, records of URLs purged after them are skipped.
+func readDeletedURLs(filename string) ([]*deletedURLRecord, uint64, error) {
+	records := []*deletedURLRecord{}
+	generation, err := readRecords(filename, func(data []byte) error {
+		if ids, ok := parsePurgeRecord(data); ok {
+			records = slices.DeleteFunc(records, func(record *deletedURLRecord) bool { return isPurgedID(ids, record.ID) })
+			return nil
+		}
+
+		record := &deletedURLRecord{}
+		if err := json.Unmarshal(data, record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
 	}
+	return records, generation, nil
+}
 
-	bytesStr := string(bytes)
-	bytesStrForJSON := "[" + strings.ReplaceAll(bytesStr, "}\n{", "},\n{") + "]"
-	bytes = []byte(bytesStrForJSON)
+// readURLsClicks reads redirects to URLs, redirects to URLs purged after them are skipped.
+func readURLsClicks(filename string) ([]*app.URLClicks, uint64, error) {
+	urlsClicks := []*app.URLClicks{}
+	generation, err := readRecords(filename, func(data []byte) error {
+		if ids, ok := parsePurgeRecord(data); ok {
+			urlsClicks = slices.DeleteFunc(urlsClicks, func(urlClicks *app.URLClicks) bool { return isPurgedID(ids, urlClicks.ID) })
+			return nil
+		}
 
-	err = json.Unmarshal(bytes, &urls)
+		urlClicks := &app.URLClicks{}
+		if err := json.Unmarshal(data, urlClicks); err != nil {
+			return err
+		}
+		urlsClicks = append(urlsClicks, urlClicks)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return urlsClicks, generation, nil
+}
+
+func readDeletionJobs(filename string) ([]*app.DeletionJob, error) {
+	jobs := []*app.DeletionJob{}
+	_, err := readRecords(filename, func(data []byte) error {
+		job := &app.DeletionJob{}
+		if err := json.Unmarshal(data, job); err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return jobs, nil
+}
+
+// readSnapshot reads generation, URLs and history of changes of original URLs from snapshot file.
+// Func returns zero generation if snapshot does not exist.
+func readSnapshot(filename string) ([]*app.URL, []*app.URLEdit, uint64, error) {
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		return nil, nil, 0, nil
+	}
 
-	return urls, nil
+	urls, edits, history, _, generation, err := readURLs(filename)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if generation == 0 {
+		return nil, nil, 0, ErrInvalidSnapshot
+	}
+	// all changes of snapshot are history, URLs of snapshot already have them
+	return urls, append(history, edits...), generation, nil
 }
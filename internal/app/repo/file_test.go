@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,7 +28,7 @@ func BenchmarkConsumerReadURLs(b *testing.B) {
 		tmpFile, err := os.CreateTemp("", TestFilenamePattern)
 		require.NoError(b, err)
 
-		producer, err := newProducer(tmpFile.Name())
+		producer, err := newProducer(tmpFile.Name(), filestorage.SyncPolicy{})
 		require.NoError(b, err)
 
 		for i := 0; i < CountURLs; i++ {
@@ -35,14 +36,12 @@ func BenchmarkConsumerReadURLs(b *testing.B) {
 			require.NoError(b, err)
 		}
 
-		consumer, err := newConsumer(tmpFile.Name())
-		require.NoError(b, err)
+		producer.close()
 
 		b.StartTimer() // возобновляем таймер
-		consumer.readURLs()
+		readURLs(tmpFile.Name())
 		b.StopTimer() // останавливаем таймер
 
-		consumer.close()
 		os.Remove(tmpFile.Name())
 	}
 }
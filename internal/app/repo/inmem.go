@@ -1,98 +1,395 @@
 package repo
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
 )
 
 // ErrURLNotFound is error for not found URL.
-var ErrURLNotFound = errors.New("url not found")
+var ErrURLNotFound = app.ErrURLNotFound
 
-// DefaultCountURLs constant for in-mem repo.
-const DefaultCountURLs = 256
+// ErrDeletionJobNotFound is error for not found deletion job.
+var ErrDeletionJobNotFound = app.ErrDeletionJobNotFound
+
+// Errors for file storage.
+var (
+	ErrInvalidSnapshot    = errors.New("snapshot has no header")     // snapshot file is not written by compaction
+	ErrLogAheadOfSnapshot = errors.New("log is newer than snapshot") // snapshot file is replaced or removed
+)
+
+// Constants for in-mem repo.
+const (
+	DefaultCountURLs   = 256         // initial capacity of URLs indexes
+	SnapshotFileSuffix = ".snapshot" // snapshot of URLs is saved next to log of URLs
+)
 
 // AppRepoInmem in-memory application data storage.
+// URLs are indexed by short ID, by original URL and by user ID,
+// so every lookup does not depend on the total count of URLs.
 type AppRepoInmem struct {
-	urls              []*app.URL
+	urlsByID          map[string]*app.URL
+	urlsByURL         map[string]*app.URL // URLs by urlKey of original URL
+	urlsByUserID      map[uint][]*app.URL
+	expiringURLs      map[string]*app.URL       // not deleted URLs with expiration time
+	urlEdits          map[string][]*app.URLEdit // history of changes of original URL by URL ID
 	mu                sync.RWMutex
 	producer          *producer
 	deleteURLProducer *producer
+	clicksProducer    *producer
+
+	deletionJobs         map[string]*app.DeletionJob
+	pendingDeletionJobs  []*app.DeletionJob // pending jobs sorted by compareDueDeletionJobs
+	deletionJobsProducer *producer          // journal of deletion jobs, last record of job is its actual state
+
+	snapshotFilename string // URLs with deletion marks and clicks at the moment of last compaction
+	generation       uint64 // generation of snapshot, logs are applied on top of snapshot with the same generation
+
+	userScoped bool // original URL is unique only among URLs of its user
+}
+
+func newAppRepoInmem(urls []*app.URL, p *producer, deleteURLProducer *producer, clicksProducer *producer, userScoped bool) *AppRepoInmem {
+	countURLs := len(urls)
+	if countURLs < DefaultCountURLs {
+		countURLs = DefaultCountURLs
+	}
+
+	ari := &AppRepoInmem{
+		urlsByID:          make(map[string]*app.URL, countURLs),
+		urlsByURL:         make(map[string]*app.URL, countURLs),
+		urlsByUserID:      make(map[uint][]*app.URL),
+		expiringURLs:      make(map[string]*app.URL),
+		urlEdits:          make(map[string][]*app.URLEdit),
+		mu:                sync.RWMutex{},
+		producer:          p,
+		deleteURLProducer: deleteURLProducer,
+		clicksProducer:    clicksProducer,
+		deletionJobs:      make(map[string]*app.DeletionJob),
+		userScoped:        userScoped,
+	}
+	for _, url := range urls {
+		ari.addURL(url)
+	}
+
+	return ari
+}
+
+// addURL adds URL in indexes. Caller must hold the lock.
+func (ari *AppRepoInmem) addURL(url *app.URL) {
+	ari.urlsByID[url.ID] = url
+	key := urlKey(ari.userScoped, url.UserID, url.URL)
+	if _, ok := ari.urlsByURL[key]; !ok {
+		ari.urlsByURL[key] = url
+	}
+	ari.urlsByUserID[url.UserID] = append(ari.urlsByUserID[url.UserID], url)
+	if url.ExpiresAt != nil && !url.IsDeleted {
+		ari.expiringURLs[url.ID] = url
+	}
+}
+
+// copyURL returns copy of stored URL, so caller can not change storage.
+func copyURL(url *app.URL) *app.URL {
+	urlCopy := *url
+	return &urlCopy
+}
+
+// markDeleted saves deletion marker in file and marks URL as deleted at the moment now.
+// URL is not changed if marker is not saved. Caller must hold the lock.
+func (ari *AppRepoInmem) markDeleted(url *app.URL, now time.Time) error {
+	if ari.deleteURLProducer != nil {
+		err := ari.deleteURLProducer.writeDeletedURL(&deletedURLRecord{ID: url.ID, UserID: url.UserID, DeletedAt: &now})
+		if err != nil {
+			return err
+		}
+	}
+
+	url.IsDeleted = true
+	url.DeletedAt = &now
+	delete(ari.expiringURLs, url.ID)
+	return nil
+}
+
+// markRestored saves restore marker in file and cancels deletion of URL.
+// URL is not changed if marker is not saved. Caller must hold the lock.
+func (ari *AppRepoInmem) markRestored(url *app.URL) error {
+	if ari.deleteURLProducer != nil {
+		err := ari.deleteURLProducer.writeDeletedURL(&deletedURLRecord{ID: url.ID, UserID: url.UserID, Restored: true})
+		if err != nil {
+			return err
+		}
+	}
+
+	url.IsDeleted = false
+	url.DeletedAt = nil
+	if url.ExpiresAt != nil {
+		ari.expiringURLs[url.ID] = url
+	}
+	return nil
+}
+
+// applyDeletedURL applies record of log of deleted URLs read at the moment now.
+// URL deleted by previous versions gets time of reading as time of deletion,
+// so its grace period starts after upgrade.
+func (ari *AppRepoInmem) applyDeletedURL(record *deletedURLRecord, now time.Time) {
+	url, ok := ari.urlsByID[record.ID]
+	if !ok || url.UserID != record.UserID {
+		return
+	}
+
+	if record.Restored {
+		url.IsDeleted = false
+		url.DeletedAt = nil
+		if url.ExpiresAt != nil {
+			ari.expiringURLs[url.ID] = url
+		}
+		return
+	}
+
+	deletedAt := record.DeletedAt
+	if deletedAt == nil {
+		deletedAt = &now
+	}
+	url.IsDeleted = true
+	url.DeletedAt = deletedAt
+	delete(ari.expiringURLs, url.ID)
+}
+
+// applyURLEdit changes original URL of URL and adds change in history of URL. Caller must hold the lock.
+// Index by original URL points to URL only if original URL is not used by another URL.
+func (ari *AppRepoInmem) applyURLEdit(edit *app.URLEdit) {
+	url, ok := ari.urlsByID[edit.URLID]
+	if !ok {
+		return
+	}
+
+	key := urlKey(ari.userScoped, url.UserID, url.URL)
+	if ari.urlsByURL[key] == url {
+		delete(ari.urlsByURL, key)
+	}
+	url.URL = edit.NewURL
+	key = urlKey(ari.userScoped, url.UserID, url.URL)
+	if _, ok = ari.urlsByURL[key]; !ok {
+		ari.urlsByURL[key] = url
+	}
+	ari.urlEdits[url.ID] = append(ari.urlEdits[url.ID], edit)
+}
+
+// addURLClicks adds redirects to URL statistics. Caller must hold the lock.
+func (ari *AppRepoInmem) addURLClicks(urlClicks *app.URLClicks) bool {
+	url, ok := ari.urlsByID[urlClicks.ID]
+	if !ok {
+		return false
+	}
+
+	url.Clicks += urlClicks.Count
+	if url.LastAccessedAt == nil || url.LastAccessedAt.Before(urlClicks.LastAccessedAt) {
+		lastAccessedAt := urlClicks.LastAccessedAt
+		url.LastAccessedAt = &lastAccessedAt
+	}
+
+	return true
+}
+
+// checkLogGeneration checks whether records of log should be applied on top of snapshot.
+// Log which is older than snapshot is already merged in it, it is left by interrupted compaction.
+func checkLogGeneration(filename string, logGeneration, generation uint64) (bool, error) {
+	if logGeneration > generation {
+		return false, fmt.Errorf("%w: %s", ErrLogAheadOfSnapshot, filename)
+	}
+	return logGeneration == generation, nil
 }
 
-// NewAppRepoInmem creates *AppRepoInmem and loads saved data from file.
-func NewAppRepoInmem(filename string, deletedURLsFilename string) (*AppRepoInmem, error) {
+// NewAppRepoInmem creates *AppRepoInmem and loads saved data from snapshot and tail of logs.
+// Clicks and deletion jobs are not saved if their filenames are empty.
+// Files are synced to disk by syncPolicy. Original URL is shortened once in scope of urlUniqueness.
+func NewAppRepoInmem(
+	filename string,
+	deletedURLsFilename string,
+	clicksFilename string,
+	deletionJobsFilename string,
+	syncPolicy filestorage.SyncPolicy,
+	urlUniqueness string,
+) (*AppRepoInmem, error) {
+	userScoped := urlUniqueness == app.URLUniquenessUser
 	if filename == "" {
-		return &AppRepoInmem{
-			urls:     make([]*app.URL, 0, DefaultCountURLs),
-			mu:       sync.RWMutex{},
-			producer: nil,
-		}, nil
+		return newAppRepoInmem(nil, nil, nil, nil, userScoped), nil
 	}
 
-	c, err := newConsumer(filename)
+	snapshotFilename := filename + SnapshotFileSuffix
+	urls, snapshotEdits, generation, err := readSnapshot(snapshotFilename)
 	if err != nil {
 		return nil, err
 	}
-	urls, err := c.readURLs()
+
+	logURLs, urlEdits, logHistory, purgedIDs, logGeneration, err := readURLs(filename)
 	if err != nil {
 		return nil, err
 	}
-	if err = c.close(); err != nil {
+	applyURLs, err := checkLogGeneration(filename, logGeneration, generation)
+	if err != nil {
 		return nil, err
 	}
+	if applyURLs {
+		// URLs of snapshot purged by log are removed before URLs created later with the same IDs are added
+		urls = append(deletePurgedURLs(urls, purgedIDs), logURLs...)
+		snapshotEdits = deletePurgedURLEdits(snapshotEdits, purgedIDs)
+		snapshotEdits = append(snapshotEdits, logHistory...)
+	} else {
+		urlEdits = nil
+	}
 
-	c, err = newConsumer(deletedURLsFilename)
+	deletedURLs, logGeneration, err := readDeletedURLs(deletedURLsFilename)
 	if err != nil {
 		return nil, err
 	}
-	deletedURLs, err := c.readURLs()
+	applyDeletedURLs, err := checkLogGeneration(deletedURLsFilename, logGeneration, generation)
 	if err != nil {
 		return nil, err
 	}
-	if err = c.close(); err != nil {
-		return nil, err
+	if !applyDeletedURLs {
+		deletedURLs = nil
 	}
 
-	for _, deletedURL := range deletedURLs {
-		for _, url := range urls {
-			if url.ID == deletedURL.URL {
-				url.IsDeleted = true
-				continue
-			}
+	urlsClicks := []*app.URLClicks{}
+	applyClicks := true
+	if clicksFilename != "" {
+		urlsClicks, logGeneration, err = readURLsClicks(clicksFilename)
+		if err != nil {
+			return nil, err
+		}
+		applyClicks, err = checkLogGeneration(clicksFilename, logGeneration, generation)
+		if err != nil {
+			return nil, err
+		}
+		if !applyClicks {
+			urlsClicks = nil
 		}
 	}
 
-	p, err := newProducer(filename)
+	deletionJobs := []*app.DeletionJob{}
+	if deletionJobsFilename != "" {
+		deletionJobs, err = readDeletionJobs(deletionJobsFilename)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p, err := newProducer(filename, syncPolicy)
 	if err != nil {
 		return nil, err
 	}
 
-	deleteURLProducer, err := newProducer(deletedURLsFilename)
+	deleteURLProducer, err := newProducer(deletedURLsFilename, syncPolicy)
 	if err != nil {
 		return nil, err
 	}
 
-	return &AppRepoInmem{
-		urls:              urls,
-		mu:                sync.RWMutex{},
-		producer:          p,
-		deleteURLProducer: deleteURLProducer,
-	}, nil
+	var clicksProducer *producer
+	if clicksFilename != "" {
+		clicksProducer, err = newProducer(clicksFilename, syncPolicy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var deletionJobsProducer *producer
+	if deletionJobsFilename != "" {
+		deletionJobsProducer, err = newProducer(deletionJobsFilename, syncPolicy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// stale logs are started again, otherwise their new records would be skipped on next start
+	if !applyURLs {
+		if err = p.replace(newLogHeader(generation)); err != nil {
+			return nil, err
+		}
+	}
+	if !applyDeletedURLs {
+		if err = deleteURLProducer.replace(newLogHeader(generation)); err != nil {
+			return nil, err
+		}
+	}
+	if !applyClicks {
+		if err = clicksProducer.replace(newLogHeader(generation)); err != nil {
+			return nil, err
+		}
+	}
+
+	ari := newAppRepoInmem(urls, p, deleteURLProducer, clicksProducer, userScoped)
+	ari.deletionJobsProducer = deletionJobsProducer
+	ari.snapshotFilename = snapshotFilename
+	ari.generation = generation
+
+	for _, job := range deletionJobs {
+		ari.deletionJobs[job.ID] = job
+	}
+	for _, job := range ari.deletionJobs {
+		switch {
+		case job.Status == app.DeletionJobPending:
+			ari.pendingDeletionJobs = append(ari.pendingDeletionJobs, job)
+		case job.FinishedAt == nil:
+			// retention of jobs finished by previous versions starts now
+			finishedAt := time.Now()
+			job.FinishedAt = &finishedAt
+		}
+	}
+	slices.SortFunc(ari.pendingDeletionJobs, compareDueDeletionJobs)
+
+	// URLs already have original URLs after changes of snapshot history and imported history of log
+	for _, edit := range snapshotEdits {
+		ari.urlEdits[edit.URLID] = append(ari.urlEdits[edit.URLID], edit)
+	}
+	for _, edit := range urlEdits {
+		ari.applyURLEdit(edit)
+	}
+
+	for _, urlClicks := range urlsClicks {
+		ari.addURLClicks(urlClicks)
+	}
+
+	now := time.Now()
+	for _, deletedURL := range deletedURLs {
+		ari.applyDeletedURL(deletedURL, now)
+	}
+	// snapshot of previous versions has deleted URLs without time of deletion
+	for _, url := range ari.urlsByID {
+		if url.IsDeleted && url.DeletedAt == nil {
+			url.DeletedAt = &now
+		}
+	}
+
+	return ari, nil
 }
 
 // GetOrCreateURL get saved URL or creates new URL and save it in file.
-func (ari *AppRepoInmem) GetOrCreateURL(id, rawURL string, userID uint) (*app.URL, error) {
+func (ari *AppRepoInmem) GetOrCreateURL(ctx context.Context, id, rawURL string, userID uint, expiresAt *time.Time) (*app.URL, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.GetOrCreateURL")
+	defer span.End()
+
 	ari.mu.Lock()
 	defer ari.mu.Unlock()
-	for _, url := range ari.urls {
-		if rawURL == url.URL {
-			return url, nil
-		}
+
+	if url, ok := ari.urlsByURL[urlKey(ari.userScoped, userID, rawURL)]; ok {
+		return copyURL(url), nil
+	}
+
+	if _, ok := ari.urlsByID[id]; ok {
+		return nil, app.ErrURLIDExists
 	}
-	url := &app.URL{ID: id, URL: rawURL, UserID: userID}
-	ari.urls = append(ari.urls, url)
+
+	url := &app.URL{ID: id, URL: rawURL, UserID: userID, ExpiresAt: expiresAt, CreatedAt: time.Now()}
+	ari.addURL(url)
 
 	if ari.producer != nil {
 		if err := ari.producer.writeURL(url); err != nil {
@@ -100,31 +397,63 @@ func (ari *AppRepoInmem) GetOrCreateURL(id, rawURL string, userID uint) (*app.UR
 		}
 	}
 
-	return url, nil
+	return copyURL(url), nil
 }
 
 // GetURL get URL with ID.
-func (ari *AppRepoInmem) GetURL(id string) (*app.URL, error) {
+func (ari *AppRepoInmem) GetURL(ctx context.Context, id string) (*app.URL, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.GetURL")
+	defer span.End()
+
 	ari.mu.RLock()
 	defer ari.mu.RUnlock()
-	for _, url := range ari.urls {
-		if id == url.ID {
-			return url, nil
-		}
+
+	url, ok := ari.urlsByID[id]
+	if !ok {
+		return nil, ErrURLNotFound
 	}
-	return nil, ErrURLNotFound
+	return copyURL(url), nil
 }
 
 // CheckIDExistence check URL ID existence.
-func (ari *AppRepoInmem) CheckIDExistence(id string) (bool, error) {
+func (ari *AppRepoInmem) CheckIDExistence(ctx context.Context, id string) (bool, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.CheckIDExistence")
+	defer span.End()
+
 	ari.mu.RLock()
 	defer ari.mu.RUnlock()
-	for _, url := range ari.urls {
-		if id == url.ID {
-			return true, nil
+
+	_, ok := ari.urlsByID[id]
+	return ok, nil
+}
+
+// CountURLs returns count of URLs.
+func (ari *AppRepoInmem) CountURLs(ctx context.Context) (uint, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.CountURLs")
+	defer span.End()
+
+	ari.mu.RLock()
+	defer ari.mu.RUnlock()
+
+	return uint(len(ari.urlsByID)), nil
+}
+
+// Ping checks that storage files are writable.
+func (ari *AppRepoInmem) Ping(ctx context.Context) error {
+	_, span := tracing.Start(ctx, "AppRepoInmem.Ping")
+	defer span.End()
+
+	for _, p := range []*producer{ari.producer, ari.deleteURLProducer, ari.clicksProducer, ari.deletionJobsProducer} {
+		if p == nil {
+			continue
+		}
+		err := p.checkWritable()
+		if err != nil {
+			tracing.RecordError(span, err)
+			return err
 		}
 	}
-	return false, nil
+	return nil
 }
 
 // Close finishes working with the file.
@@ -143,72 +472,656 @@ func (ari *AppRepoInmem) Close() error {
 		err = ari.deleteURLProducer.close()
 	}
 
+	if err != nil {
+		return err
+	}
+
+	if ari.clicksProducer != nil {
+		err = ari.clicksProducer.close()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if ari.deletionJobsProducer != nil {
+		err = ari.deletionJobsProducer.close()
+	}
+
 	return err
 }
 
 // GetOrCreateURLs gets created URLs and saves new URLs and returns them.
-func (ari *AppRepoInmem) GetOrCreateURLs(urls []*app.URL) ([]*app.URL, error) {
+// Result contains saved URL for every URL of batch in the same order, URLs of batch are not changed.
+// Nothing is saved if ID of any new URL is already used.
+func (ari *AppRepoInmem) GetOrCreateURLs(ctx context.Context, urls []*app.URL) ([]*app.URL, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.GetOrCreateURLs")
+	defer span.End()
+
 	ari.mu.Lock()
 	defer ari.mu.Unlock()
 
-LOOP:
+	newURLIDs := make(map[string]string, len(urls))
+	newURLs := make(map[string]struct{}, len(urls))
+	for _, url := range urls {
+		key := urlKey(ari.userScoped, url.UserID, url.URL)
+		if _, ok := ari.urlsByURL[key]; ok {
+			continue
+		}
+		if _, ok := newURLs[key]; ok {
+			continue
+		}
+		if _, ok := ari.urlsByID[url.ID]; ok {
+			return nil, app.ErrURLIDExists
+		}
+		if _, ok := newURLIDs[url.ID]; ok {
+			return nil, app.ErrURLIDExists
+		}
+		newURLIDs[url.ID] = url.URL
+		newURLs[key] = struct{}{}
+	}
+
+	now := time.Now()
+	savedURLs := make([]*app.URL, 0, len(urls))
 	for _, url := range urls {
-		for _, ariURL := range ari.urls {
-			if url.URL == ariURL.URL {
-				url.ID = ariURL.ID
-				url.UserID = ariURL.UserID
-				continue LOOP
+		// repeated URL of batch is found here after its first occurrence is saved
+		if ariURL, ok := ari.urlsByURL[urlKey(ari.userScoped, url.UserID, url.URL)]; ok {
+			savedURLs = append(savedURLs, copyURL(ariURL))
+			continue
+		}
+
+		createdAt := url.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		url = &app.URL{ID: url.ID, URL: url.URL, UserID: url.UserID, ExpiresAt: url.ExpiresAt, CreatedAt: createdAt}
+		ari.addURL(url)
+
+		if ari.producer != nil {
+			if err := ari.producer.writeURL(url); err != nil {
+				return nil, err
 			}
 		}
+		savedURLs = append(savedURLs, copyURL(url))
+	}
+
+	return savedURLs, nil
+}
+
+// ImportURLs gets saved or saves URLs with all their fields like GetOrCreateURLs.
+// History of changes of original URL from edits is saved only for new URLs.
+func (ari *AppRepoInmem) ImportURLs(ctx context.Context, urls []*app.URL, edits []*app.URLEdit) ([]*app.URL, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.ImportURLs")
+	defer span.End()
+
+	ari.mu.Lock()
+	defer ari.mu.Unlock()
+
+	newURLIDs := make(map[string]struct{}, len(urls))
+	newURLs := make(map[string]struct{}, len(urls))
+	for _, url := range urls {
+		key := urlKey(ari.userScoped, url.UserID, url.URL)
+		if _, ok := ari.urlsByURL[key]; ok {
+			continue
+		}
+		if _, ok := newURLs[key]; ok {
+			continue
+		}
+		if _, ok := ari.urlsByID[url.ID]; ok {
+			return nil, app.ErrURLIDExists
+		}
+		if _, ok := newURLIDs[url.ID]; ok {
+			return nil, app.ErrURLIDExists
+		}
+		newURLIDs[url.ID] = struct{}{}
+		newURLs[key] = struct{}{}
+	}
+
+	urlEdits := groupURLEdits(edits)
 
-		url = &app.URL{ID: url.ID, URL: url.URL, UserID: url.UserID}
-		ari.urls = append(ari.urls, url)
+	now := time.Now()
+	savedURLs := make([]*app.URL, 0, len(urls))
+	for _, url := range urls {
+		// repeated URL of batch is found here after its first occurrence is saved
+		if ariURL, ok := ari.urlsByURL[urlKey(ari.userScoped, url.UserID, url.URL)]; ok {
+			savedURLs = append(savedURLs, copyURL(ariURL))
+			continue
+		}
 
+		url = copyURL(url)
+		if url.CreatedAt.IsZero() {
+			url.CreatedAt = now
+		}
 		if ari.producer != nil {
 			if err := ari.producer.writeURL(url); err != nil {
 				return nil, err
 			}
 		}
+		ari.addURL(url)
+
+		// history is kept in memory as far as it is saved in file
+		for _, edit := range urlEdits[url.ID] {
+			editCopy := *edit
+			if ari.producer != nil {
+				if err := ari.producer.writeURLHistory(&editCopy); err != nil {
+					return nil, err
+				}
+			}
+			ari.urlEdits[url.ID] = append(ari.urlEdits[url.ID], &editCopy)
+		}
+		savedURLs = append(savedURLs, copyURL(url))
 	}
 
-	return urls, nil
+	return savedURLs, nil
 }
 
-// GetUserURLs gets user URLs.
-func (ari *AppRepoInmem) GetUserURLs(userID uint) ([]*app.URL, error) {
+// GetUserURLs gets page of user URLs sorted by creation time.
+func (ari *AppRepoInmem) GetUserURLs(ctx context.Context, userID uint, filter *app.UserURLsFilter) ([]*app.URL, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.GetUserURLs")
+	defer span.End()
+
 	ari.mu.RLock()
 	defer ari.mu.RUnlock()
 
-	userURLs := []*app.URL{}
-	for _, url := range ari.urls {
-		if url.UserID == userID {
-			userURLs = append(userURLs, url)
+	userURLs := make([]*app.URL, 0, len(ari.urlsByUserID[userID]))
+	for _, url := range ari.urlsByUserID[userID] {
+		if url.IsDeleted && !filter.IncludeDeleted {
+			continue
+		}
+		if filter.Search != "" && !strings.Contains(url.URL, filter.Search) {
+			continue
+		}
+		if filter.After != nil {
+			cmp := filter.After.Compare(url.CreatedAt, url.ID)
+			if !filter.Desc && cmp >= 0 || filter.Desc && cmp <= 0 {
+				continue
+			}
+		}
+		userURLs = append(userURLs, copyURL(url))
+	}
+
+	sort.Slice(userURLs, func(i, j int) bool {
+		c := app.URLCursor{CreatedAt: userURLs[i].CreatedAt, ID: userURLs[i].ID}
+		cmp := c.Compare(userURLs[j].CreatedAt, userURLs[j].ID)
+		if filter.Desc {
+			return cmp > 0
 		}
+		return cmp < 0
+	})
+
+	if filter.Limit > 0 && uint(len(userURLs)) > filter.Limit {
+		userURLs = userURLs[:filter.Limit]
 	}
 
 	return userURLs, nil
 }
 
 // DeleteUserURLs delete user URLs.
-func (ari *AppRepoInmem) DeleteUserURLs(urls []*app.URL) error {
+func (ari *AppRepoInmem) DeleteUserURLs(ctx context.Context, urls []*app.URL) error {
+	_, span := tracing.Start(ctx, "AppRepoInmem.DeleteUserURLs")
+	defer span.End()
+
 	ari.mu.Lock()
 	defer ari.mu.Unlock()
 
+	now := time.Now()
 	for _, url := range urls {
-		for _, ariURL := range ari.urls {
-			if url.ID == ariURL.ID && url.UserID == ariURL.UserID {
-				ariURL.IsDeleted = true
-
-				if ari.deleteURLProducer != nil {
-					if err := ari.deleteURLProducer.writeURL(url); err != nil {
-						return err
-					}
-				}
+		ariURL, ok := ari.urlsByID[url.ID]
+		if !ok || ariURL.UserID != url.UserID || ariURL.IsDeleted {
+			continue
+		}
 
-				continue
+		if err := ari.markDeleted(ariURL, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpiredURLs marks URLs expired at the moment now as deleted.
+func (ari *AppRepoInmem) DeleteExpiredURLs(ctx context.Context, now time.Time) error {
+	_, span := tracing.Start(ctx, "AppRepoInmem.DeleteExpiredURLs")
+	defer span.End()
+
+	ari.mu.Lock()
+	defer ari.mu.Unlock()
+
+	for _, url := range ari.expiringURLs {
+		if !url.IsExpired(now) {
+			continue
+		}
+		if err := ari.markDeleted(url, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetUserURLIDs gets IDs of URLs among ids which belong to user.
+func (ari *AppRepoInmem) GetUserURLIDs(ctx context.Context, userID uint, ids []string) ([]string, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.GetUserURLIDs")
+	defer span.End()
+
+	ari.mu.RLock()
+	defer ari.mu.RUnlock()
+
+	userURLIDs := []string{}
+	for _, id := range ids {
+		if url, ok := ari.urlsByID[id]; ok && url.UserID == userID {
+			userURLIDs = append(userURLIDs, id)
+		}
+	}
+	return userURLIDs, nil
+}
+
+// RestoreUserURLs cancels deletion of user URLs which are not expired at the moment now
+// and saves restore markers in file of deleted URLs.
+func (ari *AppRepoInmem) RestoreUserURLs(ctx context.Context, userID uint, ids []string, now time.Time) ([]string, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.RestoreUserURLs")
+	defer span.End()
+
+	ari.mu.Lock()
+	defer ari.mu.Unlock()
+
+	restoredIDs := []string{}
+	for _, id := range ids {
+		url, ok := ari.urlsByID[id]
+		if !ok || url.UserID != userID || !url.IsDeleted || url.IsExpired(now) {
+			continue
+		}
+
+		if err := ari.markRestored(url); err != nil {
+			return nil, err
+		}
+		restoredIDs = append(restoredIDs, id)
+	}
+
+	return restoredIDs, nil
+}
+
+// isPurgeable checks whether URL is deleted before deletedBefore.
+func isPurgeable(url *app.URL, deletedBefore time.Time) bool {
+	return url.IsDeleted && url.DeletedAt != nil && url.DeletedAt.Before(deletedBefore)
+}
+
+// PurgeDeletedURLs removes URLs deleted before deletedBefore, so their IDs and original URLs can be used again.
+// Purge is appended to logs, records of purged URLs are skipped on next start and are dropped by next compaction.
+func (ari *AppRepoInmem) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (uint, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.PurgeDeletedURLs")
+	defer span.End()
+
+	// storage is locked for writing only if there are URLs to purge
+	ari.mu.RLock()
+	candidates := []*app.URL{}
+	for _, url := range ari.urlsByID {
+		if isPurgeable(url, deletedBefore) {
+			candidates = append(candidates, url)
+		}
+	}
+	ari.mu.RUnlock()
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	ari.mu.Lock()
+	defer ari.mu.Unlock()
+
+	// URL can be restored or purged after candidates are collected
+	purgedURLs := make(map[*app.URL]struct{}, len(candidates))
+	ids := make([]string, 0, len(candidates))
+	for _, url := range candidates {
+		if ari.urlsByID[url.ID] != url || !isPurgeable(url, deletedBefore) {
+			continue
+		}
+		purgedURLs[url] = struct{}{}
+		ids = append(ids, url.ID)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// purge is saved once it is appended to log of URLs, nothing is removed if it is not appended
+	if ari.producer != nil {
+		if err := ari.producer.writePurge(ids); err != nil {
+			tracing.RecordError(span, err)
+			return 0, err
+		}
+	}
+
+	userURLs := make(map[uint][]*app.URL)
+	for url := range purgedURLs {
+		delete(ari.urlsByID, url.ID)
+		delete(ari.urlEdits, url.ID)
+		if key := urlKey(ari.userScoped, url.UserID, url.URL); ari.urlsByURL[key] == url {
+			delete(ari.urlsByURL, key)
+		}
+		userURLs[url.UserID] = ari.urlsByUserID[url.UserID]
+	}
+	for userID, urls := range userURLs {
+		ari.urlsByUserID[userID] = slices.DeleteFunc(urls, func(url *app.URL) bool {
+			_, ok := purgedURLs[url]
+			return ok
+		})
+	}
+
+	// records of purged URLs in other logs would be applied to new URLs with the same IDs,
+	// so storage is compacted if purge is not appended to them
+	errs := make([]error, 0, 2)
+	for _, p := range []*producer{ari.deleteURLProducer, ari.clicksProducer} {
+		if p == nil {
+			continue
+		}
+		errs = append(errs, p.writePurge(ids))
+	}
+	if err := errors.Join(errs...); err != nil {
+		// saved snapshot is enough: logs of previous generation are skipped on next start
+		generation := ari.generation
+		if err = ari.compact(); err != nil && ari.generation == generation {
+			tracing.RecordError(span, err)
+			return 0, err
+		}
+	}
+
+	return uint(len(ids)), nil
+}
+
+// UpdateURL changes original URL of not deleted user URL and saves change in file.
+// Nothing is changed if URL already has rawURL.
+func (ari *AppRepoInmem) UpdateURL(ctx context.Context, id string, userID uint, rawURL string, editedAt time.Time) (*app.URL, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.UpdateURL")
+	defer span.End()
+
+	ari.mu.Lock()
+	defer ari.mu.Unlock()
+
+	url, ok := ari.urlsByID[id]
+	if !ok || url.UserID != userID || url.IsDeleted {
+		return nil, ErrURLNotFound
+	}
+	if url.URL == rawURL {
+		return copyURL(url), nil
+	}
+	if _, ok = ari.urlsByURL[urlKey(ari.userScoped, userID, rawURL)]; ok {
+		return nil, app.ErrURLExists
+	}
+
+	edit := &app.URLEdit{URLID: id, OldURL: url.URL, NewURL: rawURL, EditedAt: editedAt}
+	if ari.producer != nil {
+		if err := ari.producer.writeURLEdit(edit); err != nil {
+			return nil, err
+		}
+	}
+	ari.applyURLEdit(edit)
+
+	return copyURL(url), nil
+}
+
+// GetURLEdits gets history of changes of original URL of URL with ID.
+func (ari *AppRepoInmem) GetURLEdits(ctx context.Context, id string) ([]*app.URLEdit, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.GetURLEdits")
+	defer span.End()
+
+	ari.mu.RLock()
+	defer ari.mu.RUnlock()
+
+	edits := make([]*app.URLEdit, 0, len(ari.urlEdits[id]))
+	for _, edit := range ari.urlEdits[id] {
+		editCopy := *edit
+		edits = append(edits, &editCopy)
+	}
+	return edits, nil
+}
+
+// AddURLsClicks adds redirects to URLs statistics and saves them in file.
+func (ari *AppRepoInmem) AddURLsClicks(ctx context.Context, urlsClicks []*app.URLClicks) error {
+	_, span := tracing.Start(ctx, "AppRepoInmem.AddURLsClicks")
+	defer span.End()
+
+	ari.mu.Lock()
+	defer ari.mu.Unlock()
+
+	for _, urlClicks := range urlsClicks {
+		if !ari.addURLClicks(urlClicks) {
+			continue
+		}
+
+		if ari.clicksProducer != nil {
+			if err := ari.clicksProducer.writeURLClicks(urlClicks); err != nil {
+				return err
 			}
 		}
 	}
 
 	return nil
 }
+
+// compareDueDeletionJobs orders deletion jobs by time of next attempt and then by ID.
+func compareDueDeletionJobs(a, b *app.DeletionJob) int {
+	if c := a.NextAttemptAt.Compare(b.NextAttemptAt); c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// copyDeletionJob returns copy of stored deletion job, so caller can not change storage.
+func copyDeletionJob(job *app.DeletionJob) *app.DeletionJob {
+	jobCopy := *job
+	jobCopy.URLIDs = slices.Clone(job.URLIDs)
+	jobCopy.IgnoredURLIDs = slices.Clone(job.IgnoredURLIDs)
+	return &jobCopy
+}
+
+// saveDeletionJob saves copy of deletion job in memory and in file. Caller must hold the lock.
+func (ari *AppRepoInmem) saveDeletionJob(job *app.DeletionJob) error {
+	savedJob := copyDeletionJob(job)
+
+	if ari.deletionJobsProducer != nil {
+		if err := ari.deletionJobsProducer.writeDeletionJob(savedJob); err != nil {
+			return err
+		}
+	}
+
+	// index of pending jobs is kept sorted, so due jobs are its prefix
+	if oldJob, ok := ari.deletionJobs[savedJob.ID]; ok && oldJob.Status == app.DeletionJobPending {
+		if i, found := slices.BinarySearchFunc(ari.pendingDeletionJobs, oldJob, compareDueDeletionJobs); found {
+			ari.pendingDeletionJobs = slices.Delete(ari.pendingDeletionJobs, i, i+1)
+		}
+	}
+	if savedJob.Status == app.DeletionJobPending {
+		i, _ := slices.BinarySearchFunc(ari.pendingDeletionJobs, savedJob, compareDueDeletionJobs)
+		ari.pendingDeletionJobs = slices.Insert(ari.pendingDeletionJobs, i, savedJob)
+	}
+
+	ari.deletionJobs[savedJob.ID] = savedJob
+	return nil
+}
+
+// AddDeletionJob saves new deletion job in file.
+func (ari *AppRepoInmem) AddDeletionJob(ctx context.Context, job *app.DeletionJob) error {
+	_, span := tracing.Start(ctx, "AppRepoInmem.AddDeletionJob")
+	defer span.End()
+
+	ari.mu.Lock()
+	defer ari.mu.Unlock()
+
+	return ari.saveDeletionJob(job)
+}
+
+// GetDueDeletionJobs gets pending deletion jobs which should be attempted at the moment now.
+// Jobs are sorted by time of next attempt.
+func (ari *AppRepoInmem) GetDueDeletionJobs(ctx context.Context, now time.Time, limit uint) ([]*app.DeletionJob, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.GetDueDeletionJobs")
+	defer span.End()
+
+	ari.mu.RLock()
+	defer ari.mu.RUnlock()
+
+	jobs := []*app.DeletionJob{}
+	for _, job := range ari.pendingDeletionJobs {
+		if job.NextAttemptAt.After(now) || limit > 0 && uint(len(jobs)) >= limit {
+			break
+		}
+		jobs = append(jobs, copyDeletionJob(job))
+	}
+
+	return jobs, nil
+}
+
+// GetDeletionJob gets deletion job with ID.
+func (ari *AppRepoInmem) GetDeletionJob(ctx context.Context, id string) (*app.DeletionJob, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.GetDeletionJob")
+	defer span.End()
+
+	ari.mu.RLock()
+	defer ari.mu.RUnlock()
+
+	job, ok := ari.deletionJobs[id]
+	if !ok {
+		return nil, ErrDeletionJobNotFound
+	}
+
+	return copyDeletionJob(job), nil
+}
+
+// CountPendingDeletionJobs returns count of pending deletion jobs.
+func (ari *AppRepoInmem) CountPendingDeletionJobs(ctx context.Context) (uint, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.CountPendingDeletionJobs")
+	defer span.End()
+
+	ari.mu.RLock()
+	defer ari.mu.RUnlock()
+
+	return uint(len(ari.pendingDeletionJobs)), nil
+}
+
+// UpdateDeletionJob saves status, attempts and outcome of URLs of deletion job in file.
+func (ari *AppRepoInmem) UpdateDeletionJob(ctx context.Context, job *app.DeletionJob) error {
+	_, span := tracing.Start(ctx, "AppRepoInmem.UpdateDeletionJob")
+	defer span.End()
+
+	ari.mu.Lock()
+	defer ari.mu.Unlock()
+
+	if _, ok := ari.deletionJobs[job.ID]; !ok {
+		return ErrDeletionJobNotFound
+	}
+
+	return ari.saveDeletionJob(job)
+}
+
+// PurgeDeletionJobs removes done and dead letter deletion jobs finished before finishedBefore.
+// Journal of deletion jobs is rewritten without removed jobs.
+func (ari *AppRepoInmem) PurgeDeletionJobs(ctx context.Context, finishedBefore time.Time) (uint, error) {
+	_, span := tracing.Start(ctx, "AppRepoInmem.PurgeDeletionJobs")
+	defer span.End()
+
+	ari.mu.Lock()
+	defer ari.mu.Unlock()
+
+	purgedJobs := make(map[string]*app.DeletionJob)
+	for id, job := range ari.deletionJobs {
+		if job.Status == app.DeletionJobPending || job.FinishedAt == nil || !job.FinishedAt.Before(finishedBefore) {
+			continue
+		}
+		purgedJobs[id] = job
+	}
+	if len(purgedJobs) == 0 {
+		return 0, nil
+	}
+
+	for id := range purgedJobs {
+		delete(ari.deletionJobs, id)
+	}
+	// removed jobs stay in memory if journal is not rewritten, so they are not restored on next start
+	if err := ari.replaceDeletionJobs(); err != nil {
+		for id, job := range purgedJobs {
+			ari.deletionJobs[id] = job
+		}
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	return uint(len(purgedJobs)), nil
+}
+
+// replaceDeletionJobs rewrites journal of deletion jobs with actual states of jobs. Caller must hold the lock.
+func (ari *AppRepoInmem) replaceDeletionJobs() error {
+	if ari.deletionJobsProducer == nil {
+		return nil
+	}
+
+	jobs := make([]interface{}, 0, len(ari.deletionJobs))
+	for _, job := range ari.deletionJobs {
+		jobs = append(jobs, job)
+	}
+	return ari.deletionJobsProducer.replace(jobs...)
+}
+
+// Compact rewrites all URLs with their deletion marks and clicks to new snapshot
+// and starts new logs of URLs, deleted URLs and clicks, so startup reads snapshot and short tail of logs.
+// Journal of deletion jobs is rewritten with actual states of jobs.
+// Storage is locked for writing while compaction is in progress.
+func (ari *AppRepoInmem) Compact(ctx context.Context) error {
+	_, span := tracing.Start(ctx, "AppRepoInmem.Compact")
+	defer span.End()
+
+	ari.mu.Lock()
+	defer ari.mu.Unlock()
+
+	err := ari.compact()
+	tracing.RecordError(span, err)
+	return err
+}
+
+// compact rewrites URLs to new snapshot and starts new logs. Caller must hold the lock.
+func (ari *AppRepoInmem) compact() error {
+	// nothing to compact if data is not saved in file
+	if ari.producer == nil {
+		return nil
+	}
+
+	urls := make([]*app.URL, 0, len(ari.urlsByID))
+	for _, url := range ari.urlsByID {
+		urls = append(urls, url)
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		c := app.URLCursor{CreatedAt: urls[i].CreatedAt, ID: urls[i].ID}
+		return c.Compare(urls[j].CreatedAt, urls[j].ID) < 0
+	})
+
+	generation := ari.generation + 1
+	records := make([]interface{}, 0, len(urls)+1)
+	records = append(records, newLogHeader(generation))
+	for _, url := range urls {
+		records = append(records, url)
+	}
+	// history is saved after all URLs, so it is not applied to URLs again
+	for _, url := range urls {
+		for _, edit := range ari.urlEdits[url.ID] {
+			records = append(records, &urlRecord{Edit: edit})
+		}
+	}
+
+	// logs are started again only after snapshot is saved, logs of previous generation are skipped after crash
+	err := filestorage.WriteFileAtomically(ari.snapshotFilename, records)
+	if err != nil {
+		return err
+	}
+	// generation of saved snapshot is never used again, otherwise records of logs could be applied twice
+	ari.generation = generation
+
+	// every log gets header of new generation before its next record, even if it can not be started now,
+	// so records are not appended to log which is skipped on next start
+	errs := make([]error, 0, 4)
+	for _, p := range []*producer{ari.producer, ari.deleteURLProducer, ari.clicksProducer} {
+		if p == nil {
+			continue
+		}
+		errs = append(errs, p.startLog(newLogHeader(generation)))
+	}
+	errs = append(errs, ari.replaceDeletionJobs())
+
+	return errors.Join(errs...)
+}
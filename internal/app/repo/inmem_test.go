@@ -1,12 +1,19 @@
 package repo
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
-	"sync"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app/repo/repotest"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -23,7 +30,7 @@ func TestNewAppRepoInmem(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpFile.Name())
+	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 	assert.NoError(t, err)
 	assert.NotNil(t, appRepoInMem)
 }
@@ -39,6 +46,7 @@ func TestAppRepoInmem_GetOrCreateURL(t *testing.T) {
 	}
 	type want struct {
 		url     *app.URL
+		created bool
 		wantErr bool
 	}
 
@@ -64,6 +72,7 @@ func TestAppRepoInmem_GetOrCreateURL(t *testing.T) {
 					URL:    "yandex.ru",
 					UserID: 1,
 				},
+				created: true,
 				wantErr: false,
 			},
 		},
@@ -97,23 +106,27 @@ func TestAppRepoInmem_GetOrCreateURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			producer, err := newProducer(tmpFile.Name())
+			producer, err := newProducer(tmpFile.Name(), filestorage.SyncPolicy{})
 			if err != nil {
 				t.Fatalf("CRITICAL\tUnexpected error. Error: %v\n", err)
 			}
-			ari := &AppRepoInmem{
-				urls:     tt.fields.urls,
-				mu:       sync.RWMutex{},
-				producer: producer,
-			}
-			url, err := ari.GetOrCreateURL(tt.args.id, tt.args.rawURL, tt.args.userID)
+			ari := newAppRepoInmem(tt.fields.urls, producer, nil, nil, false)
+			url, err := ari.GetOrCreateURL(context.Background(), tt.args.id, tt.args.rawURL, tt.args.userID, nil)
 			if tt.want.wantErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
 			}
+			// returned URL is copy of stored URL
+			assert.NotSame(t, ari.urlsByID[url.ID], url)
+			assert.Equal(t, url, ari.urlsByID[url.ID])
+			assert.Equal(t, url, ari.urlsByURL[url.URL])
+			assert.Contains(t, ari.urlsByUserID[url.UserID], url)
+			if tt.want.created {
+				assert.WithinDuration(t, time.Now(), url.CreatedAt, time.Minute)
+				url.CreatedAt = time.Time{}
+			}
 			assert.Equal(t, tt.want.url, url)
-			assert.Contains(t, ari.urls, url)
 		})
 	}
 }
@@ -169,11 +182,8 @@ func TestAppRepoInmem_GetURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ari := &AppRepoInmem{
-				urls: tt.fields.urls,
-				mu:   sync.RWMutex{},
-			}
-			url, err := ari.GetURL(tt.args.id)
+			ari := newAppRepoInmem(tt.fields.urls, nil, nil, nil, false)
+			url, err := ari.GetURL(context.Background(), tt.args.id)
 			if tt.want.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -239,16 +249,12 @@ func TestAppRepoInmem_CheckIDExistence(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			producer, err := newProducer(tmpFile.Name())
+			producer, err := newProducer(tmpFile.Name(), filestorage.SyncPolicy{})
 			if err != nil {
 				t.Fatalf("CRITICAL\tUnexpected error. Error: %v\n", err)
 			}
-			ari := &AppRepoInmem{
-				urls:     tt.fields.urls,
-				mu:       sync.RWMutex{},
-				producer: producer,
-			}
-			checked, err := ari.CheckIDExistence(tt.args.id)
+			ari := newAppRepoInmem(tt.fields.urls, producer, nil, nil, false)
+			checked, err := ari.CheckIDExistence(context.Background(), tt.args.id)
 			if tt.want.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -285,6 +291,26 @@ func generateTestURLs(countUsers, countUserURLs uint) []*app.URL {
 	return urls
 }
 
+func TestAppRepoInmem_CountURLs(t *testing.T) {
+	appRepoInMem, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	defer func() { err = appRepoInMem.Close(); require.NoError(t, err) }()
+
+	count, err := appRepoInMem.CountURLs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), count)
+
+	_, err = appRepoInMem.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "1", URL: "test1", UserID: uint(1)},
+		{ID: "2", URL: "test2", UserID: uint(2)},
+	})
+	require.NoError(t, err)
+
+	count, err = appRepoInMem.CountURLs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
 func TestAppRepoInmem_Close(t *testing.T) {
 	tmpFile, err := os.CreateTemp("", TestFilenamePattern)
 	require.NoError(t, err)
@@ -293,7 +319,7 @@ func TestAppRepoInmem_Close(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpFile.Name())
+	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 	require.NoError(t, err)
 	assert.NotNil(t, appRepoInMem)
 
@@ -301,6 +327,33 @@ func TestAppRepoInmem_Close(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestAppRepoInmem_Ping(t *testing.T) {
+	appRepoInMem, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+
+	err = appRepoInMem.Ping(context.Background())
+	assert.NoError(t, err)
+
+	err = appRepoInMem.Close()
+	require.NoError(t, err)
+
+	tmpFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+
+	appRepoInMem, err = NewAppRepoInmem(tmpFile.Name(), tmpFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	defer func() { err = appRepoInMem.Close(); require.NoError(t, err) }()
+
+	err = appRepoInMem.Ping(context.Background())
+	assert.NoError(t, err)
+
+	err = os.Remove(tmpFile.Name())
+	require.NoError(t, err)
+
+	err = appRepoInMem.Ping(context.Background())
+	assert.Error(t, err)
+}
+
 func TestAppRepoInmem_GetOrCreateURLs(t *testing.T) {
 	urls := []*app.URL{
 		{
@@ -351,14 +404,15 @@ func TestAppRepoInmem_GetOrCreateURLs(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpFile.Name())
+	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 	require.NoError(t, err)
 	assert.NotNil(t, appRepoInMem)
 
 	defer func() { err = appRepoInMem.Close(); require.NoError(t, err) }()
 
-	actualURLs, err := appRepoInMem.GetOrCreateURLs(urls)
+	actualURLs, err := appRepoInMem.GetOrCreateURLs(context.Background(), urls)
 	require.NoError(t, err)
+	resetCreatedAt(t, actualURLs...)
 	assert.Equal(t, expectedURLs, actualURLs)
 }
 
@@ -370,38 +424,53 @@ func TestAppRepoInmem_GetUserURLs(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpFile.Name())
+	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 	require.NoError(t, err)
 	assert.NotNil(t, appRepoInMem)
 
 	defer func() { err = appRepoInMem.Close(); require.NoError(t, err) }()
 
+	createdAt := time.Date(2024, 10, 16, 14, 0, 0, 0, time.UTC)
+
 	urls := []*app.URL{
 		{
 			ID:        "1",
 			URL:       "test1",
 			UserID:    uint(1),
 			IsDeleted: false,
+			CreatedAt: createdAt,
 		},
 		{
 			ID:        "2",
 			URL:       "test2",
 			UserID:    uint(1),
 			IsDeleted: false,
+			CreatedAt: createdAt.Add(time.Second),
 		},
 		{
 			ID:        "3",
 			URL:       "test1",
 			UserID:    uint(2),
 			IsDeleted: false,
+			CreatedAt: createdAt,
+		},
+		{
+			ID:        "4",
+			URL:       "example",
+			UserID:    uint(1),
+			IsDeleted: false,
+			CreatedAt: createdAt,
 		},
 	}
 
-	_, err = appRepoInMem.GetOrCreateURLs(urls)
+	_, err = appRepoInMem.GetOrCreateURLs(context.Background(), urls)
 	require.NoError(t, err)
 
-	actualURLs, err := appRepoInMem.GetUserURLs(uint(1))
+	err = appRepoInMem.DeleteUserURLs(context.Background(), []*app.URL{{ID: "4", UserID: uint(1)}})
 	require.NoError(t, err)
+	deletedURL, err := appRepoInMem.GetURL(context.Background(), "4")
+	require.NoError(t, err)
+	require.NotNil(t, deletedURL.DeletedAt)
 
 	expectedURLs := []*app.URL{
 		{
@@ -409,16 +478,81 @@ func TestAppRepoInmem_GetUserURLs(t *testing.T) {
 			URL:       "test1",
 			UserID:    uint(1),
 			IsDeleted: false,
+			CreatedAt: createdAt,
+		},
+		{
+			ID:        "4",
+			URL:       "example",
+			UserID:    uint(1),
+			IsDeleted: true,
+			DeletedAt: deletedURL.DeletedAt,
+			CreatedAt: createdAt,
 		},
 		{
 			ID:        "2",
 			URL:       "test2",
 			UserID:    uint(1),
 			IsDeleted: false,
+			CreatedAt: createdAt.Add(time.Second),
 		},
 	}
 
-	assert.Equal(t, expectedURLs, actualURLs)
+	tests := []struct {
+		name   string
+		filter *app.UserURLsFilter
+		want   []*app.URL
+	}{
+		{
+			name:   "all not deleted",
+			filter: &app.UserURLsFilter{},
+			want:   []*app.URL{expectedURLs[0], expectedURLs[2]},
+		},
+		{
+			name:   "include deleted",
+			filter: &app.UserURLsFilter{IncludeDeleted: true},
+			want:   expectedURLs,
+		},
+		{
+			name:   "desc",
+			filter: &app.UserURLsFilter{IncludeDeleted: true, Desc: true},
+			want:   []*app.URL{expectedURLs[2], expectedURLs[1], expectedURLs[0]},
+		},
+		{
+			name:   "limit",
+			filter: &app.UserURLsFilter{Limit: 2, IncludeDeleted: true},
+			want:   expectedURLs[:2],
+		},
+		{
+			name: "after cursor",
+			filter: &app.UserURLsFilter{
+				After:          &app.URLCursor{CreatedAt: createdAt, ID: "1"},
+				IncludeDeleted: true,
+			},
+			want: expectedURLs[1:],
+		},
+		{
+			name: "after cursor desc",
+			filter: &app.UserURLsFilter{
+				After:          &app.URLCursor{CreatedAt: createdAt, ID: "4"},
+				IncludeDeleted: true,
+				Desc:           true,
+			},
+			want: expectedURLs[:1],
+		},
+		{
+			name:   "search",
+			filter: &app.UserURLsFilter{Search: "test2"},
+			want:   expectedURLs[2:],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actualURLs, err := appRepoInMem.GetUserURLs(context.Background(), uint(1), tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, actualURLs)
+		})
+	}
 }
 
 func TestAppRepoInmem_DeleteUserURLs(t *testing.T) {
@@ -429,34 +563,39 @@ func TestAppRepoInmem_DeleteUserURLs(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpFile.Name())
+	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 	require.NoError(t, err)
 	assert.NotNil(t, appRepoInMem)
 
 	defer func() { err = appRepoInMem.Close(); require.NoError(t, err) }()
 
+	createdAt := time.Date(2024, 10, 16, 14, 0, 0, 0, time.UTC)
+
 	urls := []*app.URL{
 		{
 			ID:        "1",
 			URL:       "test1",
 			UserID:    uint(1),
 			IsDeleted: false,
+			CreatedAt: createdAt,
 		},
 		{
 			ID:        "2",
 			URL:       "test2",
 			UserID:    uint(1),
 			IsDeleted: false,
+			CreatedAt: createdAt,
 		},
 		{
 			ID:        "3",
 			URL:       "test3",
 			UserID:    uint(2),
 			IsDeleted: false,
+			CreatedAt: createdAt,
 		},
 	}
 
-	_, err = appRepoInMem.GetOrCreateURLs(urls)
+	_, err = appRepoInMem.GetOrCreateURLs(context.Background(), urls)
 	require.NoError(t, err)
 
 	urlsForDeletion := []*app.URL{
@@ -474,32 +613,42 @@ func TestAppRepoInmem_DeleteUserURLs(t *testing.T) {
 		},
 	}
 
-	err = appRepoInMem.DeleteUserURLs(urlsForDeletion)
+	before := time.Now()
+	err = appRepoInMem.DeleteUserURLs(context.Background(), urlsForDeletion)
 	require.NoError(t, err)
 
 	appRepoInMem.mu.RLock()
 	defer appRepoInMem.mu.RUnlock()
 
-	assert.Equal(t, []*app.URL{
-		{
+	deletedAt := appRepoInMem.urlsByID["1"].DeletedAt
+	require.NotNil(t, deletedAt)
+	assert.False(t, deletedAt.Before(before))
+
+	assert.Equal(t, map[string]*app.URL{
+		"1": {
 			ID:        "1",
 			URL:       "test1",
 			UserID:    uint(1),
 			IsDeleted: true,
+			DeletedAt: deletedAt,
+			CreatedAt: createdAt,
 		},
-		{
+		"2": {
 			ID:        "2",
 			URL:       "test2",
 			UserID:    uint(1),
 			IsDeleted: true,
+			DeletedAt: deletedAt,
+			CreatedAt: createdAt,
 		},
-		{
+		"3": {
 			ID:        "3",
 			URL:       "test3",
 			UserID:    uint(2),
 			IsDeleted: false,
+			CreatedAt: createdAt,
 		},
-	}, appRepoInMem.urls)
+	}, appRepoInMem.urlsByID)
 }
 
 func BenchmarkAppRepoInmem_GetOrCreateURL(b *testing.B) {
@@ -509,19 +658,19 @@ func BenchmarkAppRepoInmem_GetOrCreateURL(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
 
-		appRepoInmem, err := NewAppRepoInmem("", "")
+		appRepoInmem, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 		require.NoError(b, err)
 
 		for _, url := range urls {
 			b.StartTimer()
-			_, err = appRepoInmem.GetOrCreateURL(url.ID, url.URL, url.UserID)
+			_, err = appRepoInmem.GetOrCreateURL(context.Background(), url.ID, url.URL, url.UserID, nil)
 			b.StopTimer()
 			require.NoError(b, err)
 		}
 
 		for _, url := range urls[1 : len(urls)-2] {
 			b.StartTimer()
-			_, err = appRepoInmem.GetOrCreateURL(url.ID, url.URL, url.UserID)
+			_, err = appRepoInmem.GetOrCreateURL(context.Background(), url.ID, url.URL, url.UserID, nil)
 			b.StopTimer()
 			require.NoError(b, err)
 		}
@@ -538,12 +687,12 @@ func BenchmarkAppRepoInmem_GetOrCreateURLs(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
 
-		appRepoInmem, err := NewAppRepoInmem("", "")
+		appRepoInmem, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 		require.NoError(b, err)
 
 		b.StartTimer()
-		_, err = appRepoInmem.GetOrCreateURLs(urls)
-		_, err2 := appRepoInmem.GetOrCreateURLs(urls[1 : len(urls)-2])
+		_, err = appRepoInmem.GetOrCreateURLs(context.Background(), urls)
+		_, err2 := appRepoInmem.GetOrCreateURLs(context.Background(), urls[1:len(urls)-2])
 		b.StopTimer()
 
 		require.NoError(b, err)
@@ -561,15 +710,15 @@ func BenchmarkAppRepoInmem_CheckIDExistence(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
 
-		appRepoInmem, err := NewAppRepoInmem("", "")
+		appRepoInmem, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 		require.NoError(b, err)
 
-		_, err = appRepoInmem.GetOrCreateURLs(urls)
+		_, err = appRepoInmem.GetOrCreateURLs(context.Background(), urls)
 		require.NoError(b, err)
 
 		b.StartTimer()
-		_, err = appRepoInmem.CheckIDExistence(urls[3].ID)
-		_, err2 := appRepoInmem.CheckIDExistence("aaa")
+		_, err = appRepoInmem.CheckIDExistence(context.Background(), urls[3].ID)
+		_, err2 := appRepoInmem.CheckIDExistence(context.Background(), "aaa")
 		b.StopTimer()
 
 		require.NoError(b, err)
@@ -587,15 +736,15 @@ func BenchmarkAppRepoInmem_GetUserURLs(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
 
-		appRepoInmem, err := NewAppRepoInmem("", "")
+		appRepoInmem, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 		require.NoError(b, err)
 
-		_, err = appRepoInmem.GetOrCreateURLs(urls)
+		_, err = appRepoInmem.GetOrCreateURLs(context.Background(), urls)
 		require.NoError(b, err)
 
 		b.StartTimer()
-		_, err = appRepoInmem.GetUserURLs(urls[len(urls)/2].UserID)
-		_, err2 := appRepoInmem.GetUserURLs(uint(len(urls)) * 2)
+		_, err = appRepoInmem.GetUserURLs(context.Background(), urls[len(urls)/2].UserID, &app.UserURLsFilter{})
+		_, err2 := appRepoInmem.GetUserURLs(context.Background(), uint(len(urls))*2, &app.UserURLsFilter{})
 		b.StopTimer()
 
 		require.NoError(b, err)
@@ -613,15 +762,15 @@ func BenchmarkAppRepoInmem_DeleteUserURLs(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
 
-		appRepoInmem, err := NewAppRepoInmem("", "")
+		appRepoInmem, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 		require.NoError(b, err)
 
-		_, err = appRepoInmem.GetOrCreateURLs(urls)
+		_, err = appRepoInmem.GetOrCreateURLs(context.Background(), urls)
 		require.NoError(b, err)
 
 		b.StartTimer()
-		err = appRepoInmem.DeleteUserURLs(urls[3 : len(urls)-5])
-		err2 := appRepoInmem.DeleteUserURLs([]*app.URL{{ID: "aaa", UserID: uint(len(urls)) * 2}})
+		err = appRepoInmem.DeleteUserURLs(context.Background(), urls[3:len(urls)-5])
+		err2 := appRepoInmem.DeleteUserURLs(context.Background(), []*app.URL{{ID: "aaa", UserID: uint(len(urls)) * 2}})
 		b.StopTimer()
 
 		require.NoError(b, err)
@@ -631,3 +780,1085 @@ func BenchmarkAppRepoInmem_DeleteUserURLs(b *testing.B) {
 		require.NoError(b, err)
 	}
 }
+
+func TestNewAppRepoInmem_LoadDeletedURLs(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpFile.Name())
+		require.NoError(t, err)
+	}()
+
+	tmpDeletedURLsFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpDeletedURLsFile.Name())
+		require.NoError(t, err)
+	}()
+
+	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+
+	_, err = appRepoInMem.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "1", URL: "test1", UserID: uint(1)},
+		{ID: "2", URL: "test2", UserID: uint(2)},
+	})
+	require.NoError(t, err)
+
+	err = appRepoInMem.DeleteUserURLs(context.Background(), []*app.URL{
+		{ID: "1", UserID: uint(1)},
+		{ID: "2", UserID: uint(1)},
+	})
+	require.NoError(t, err)
+
+	err = appRepoInMem.Close()
+	require.NoError(t, err)
+
+	appRepoInMem, err = NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	defer func() { err = appRepoInMem.Close(); require.NoError(t, err) }()
+
+	url, err := appRepoInMem.GetURL(context.Background(), "1")
+	require.NoError(t, err)
+	assert.True(t, url.IsDeleted)
+
+	url, err = appRepoInMem.GetURL(context.Background(), "2")
+	require.NoError(t, err)
+	assert.False(t, url.IsDeleted)
+}
+
+func TestNewAppRepoInmem_LoadRestoredAndPurgedURLs(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpFile.Name())
+		require.NoError(t, err)
+	}()
+
+	tmpDeletedURLsFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpDeletedURLsFile.Name())
+		require.NoError(t, err)
+	}()
+
+	// deleted URL written before time of deletion was saved
+	_, err = tmpDeletedURLsFile.WriteString(`{"ID":"4","UserID":1}` + "\n")
+	require.NoError(t, err)
+	err = tmpDeletedURLsFile.Close()
+	require.NoError(t, err)
+
+	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+
+	_, err = appRepoInMem.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "1", URL: "test1", UserID: uint(1)},
+		{ID: "2", URL: "test2", UserID: uint(1)},
+		{ID: "3", URL: "test3", UserID: uint(1)},
+	})
+	require.NoError(t, err)
+
+	err = appRepoInMem.DeleteUserURLs(context.Background(), []*app.URL{
+		{ID: "1", UserID: uint(1)},
+		{ID: "2", UserID: uint(1)},
+		{ID: "3", UserID: uint(1)},
+	})
+	require.NoError(t, err)
+
+	restoredIDs, err := appRepoInMem.RestoreUserURLs(context.Background(), uint(1), []string{"1"}, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, restoredIDs)
+
+	err = appRepoInMem.Close()
+	require.NoError(t, err)
+
+	appRepoInMem, err = NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+
+	url, err := appRepoInMem.GetURL(context.Background(), "1")
+	require.NoError(t, err)
+	assert.False(t, url.IsDeleted)
+	assert.Nil(t, url.DeletedAt)
+
+	url, err = appRepoInMem.GetURL(context.Background(), "2")
+	require.NoError(t, err)
+	assert.True(t, url.IsDeleted)
+	assert.NotNil(t, url.DeletedAt)
+
+	count, err := appRepoInMem.PurgeDeletedURLs(context.Background(), time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+
+	err = appRepoInMem.Close()
+	require.NoError(t, err)
+
+	appRepoInMem, err = NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	defer func() { err = appRepoInMem.Close(); require.NoError(t, err) }()
+
+	_, err = appRepoInMem.GetURL(context.Background(), "1")
+	require.NoError(t, err)
+
+	_, err = appRepoInMem.GetURL(context.Background(), "2")
+	assert.ErrorIs(t, err, app.ErrURLNotFound)
+
+	_, err = appRepoInMem.GetURL(context.Background(), "3")
+	assert.ErrorIs(t, err, app.ErrURLNotFound)
+}
+
+func TestNewAppRepoInmem_LoadURLEdits(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "urls.json")
+	deletedURLsFilename := filepath.Join(dir, "deleted-urls.json")
+	editedAt := time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC)
+
+	appRepoInMem, err := NewAppRepoInmem(filename, deletedURLsFilename, "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+
+	_, err = appRepoInMem.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "1", URL: "test1", UserID: uint(1)},
+		{ID: "2", URL: "test2", UserID: uint(1)},
+	})
+	require.NoError(t, err)
+
+	_, err = appRepoInMem.UpdateURL(context.Background(), "1", uint(1), "test1-2", editedAt)
+	require.NoError(t, err)
+
+	err = appRepoInMem.Close()
+	require.NoError(t, err)
+
+	wantEdits := []*app.URLEdit{
+		{URLID: "1", OldURL: "test1", NewURL: "test1-2", EditedAt: editedAt},
+		{URLID: "1", OldURL: "test1-2", NewURL: "test1-3", EditedAt: editedAt.Add(time.Second)},
+	}
+
+	// changes are read from log, then from snapshot and tail of log
+	for _, compact := range []bool{false, true} {
+		appRepoInMem, err = NewAppRepoInmem(filename, deletedURLsFilename, "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+		require.NoError(t, err)
+
+		if compact {
+			err = appRepoInMem.Compact(context.Background())
+			require.NoError(t, err)
+		} else {
+			_, err = appRepoInMem.UpdateURL(context.Background(), "1", uint(1), "test1-3", editedAt.Add(time.Second))
+			require.NoError(t, err)
+		}
+
+		err = appRepoInMem.Close()
+		require.NoError(t, err)
+
+		appRepoInMem, err = NewAppRepoInmem(filename, deletedURLsFilename, "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+		require.NoError(t, err)
+
+		url, err := appRepoInMem.GetURL(context.Background(), "1")
+		require.NoError(t, err)
+		assert.Equal(t, "test1-3", url.URL)
+
+		edits, err := appRepoInMem.GetURLEdits(context.Background(), "1")
+		require.NoError(t, err)
+		assert.Equal(t, wantEdits, edits)
+
+		// index of original URLs is changed with URL
+		url, err = appRepoInMem.GetOrCreateURL(context.Background(), "3", "test1-3", uint(2), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "1", url.ID)
+		_, err = appRepoInMem.UpdateURL(context.Background(), "2", uint(1), "test1-3", editedAt)
+		assert.ErrorIs(t, err, app.ErrURLExists)
+
+		err = appRepoInMem.Close()
+		require.NoError(t, err)
+	}
+}
+
+func TestNewAppRepoInmem_LoadImportedURLs(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "urls.json")
+	deletedURLsFilename := filepath.Join(dir, "deleted-urls.json")
+	createdAt := time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := createdAt.Add(time.Hour)
+	editedAt := createdAt.Add(time.Minute)
+
+	appRepoInMem, err := NewAppRepoInmem(filename, deletedURLsFilename, "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+
+	wantURLs := []*app.URL{
+		{ID: "1", URL: "test1-2", UserID: uint(1), CreatedAt: createdAt, Clicks: 2, LastAccessedAt: &deletedAt},
+		{ID: "2", URL: "test1", UserID: uint(1), CreatedAt: createdAt, IsDeleted: true, DeletedAt: &deletedAt},
+	}
+	wantEdits := []*app.URLEdit{
+		{URLID: "1", OldURL: "test1", NewURL: "test1-2", EditedAt: editedAt},
+	}
+	_, err = appRepoInMem.ImportURLs(context.Background(), wantURLs, wantEdits)
+	require.NoError(t, err)
+
+	err = appRepoInMem.Close()
+	require.NoError(t, err)
+
+	// imported URLs are read from log, then from snapshot
+	for _, compact := range []bool{false, true} {
+		if compact {
+			appRepoInMem, err = NewAppRepoInmem(filename, deletedURLsFilename, "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+			require.NoError(t, err)
+			err = appRepoInMem.Compact(context.Background())
+			require.NoError(t, err)
+			err = appRepoInMem.Close()
+			require.NoError(t, err)
+		}
+
+		appRepoInMem, err = NewAppRepoInmem(filename, deletedURLsFilename, "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+		require.NoError(t, err)
+
+		for _, wantURL := range wantURLs {
+			url, err := appRepoInMem.GetURL(context.Background(), wantURL.ID)
+			require.NoError(t, err)
+			assert.Equal(t, wantURL, url)
+		}
+
+		// history is not applied to imported URL
+		edits, err := appRepoInMem.GetURLEdits(context.Background(), "1")
+		require.NoError(t, err)
+		assert.Equal(t, wantEdits, edits)
+		url, err := appRepoInMem.GetOrCreateURL(context.Background(), "3", "test1", uint(2), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "2", url.ID)
+
+		err = appRepoInMem.Close()
+		require.NoError(t, err)
+	}
+}
+
+func TestAppRepoInmem_AddURLsClicks(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpFile.Name())
+		require.NoError(t, err)
+	}()
+
+	tmpDeletedURLsFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpDeletedURLsFile.Name())
+		require.NoError(t, err)
+	}()
+
+	tmpClicksFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpClicksFile.Name())
+		require.NoError(t, err)
+	}()
+
+	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), tmpClicksFile.Name(), "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+
+	_, err = appRepoInMem.GetOrCreateURL(context.Background(), "1", "test1", uint(1), nil)
+	require.NoError(t, err)
+
+	lastAccessedAt := time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	err = appRepoInMem.AddURLsClicks(context.Background(), []*app.URLClicks{
+		{ID: "1", Count: 2, LastAccessedAt: lastAccessedAt.Add(-time.Hour)},
+		{ID: "unknown", Count: 1, LastAccessedAt: lastAccessedAt},
+	})
+	require.NoError(t, err)
+	err = appRepoInMem.AddURLsClicks(context.Background(), []*app.URLClicks{
+		{ID: "1", Count: 3, LastAccessedAt: lastAccessedAt},
+	})
+	require.NoError(t, err)
+
+	url, err := appRepoInMem.GetURL(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), url.Clicks)
+	require.NotNil(t, url.LastAccessedAt)
+	assert.True(t, lastAccessedAt.Equal(*url.LastAccessedAt))
+
+	err = appRepoInMem.Close()
+	require.NoError(t, err)
+
+	appRepoInMem, err = NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), tmpClicksFile.Name(), "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	defer func() { err = appRepoInMem.Close(); require.NoError(t, err) }()
+
+	url, err = appRepoInMem.GetURL(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), url.Clicks)
+	require.NotNil(t, url.LastAccessedAt)
+	assert.True(t, lastAccessedAt.Equal(*url.LastAccessedAt))
+}
+
+func TestAppRepoInmem_DeletionJobs(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpFile.Name())
+		require.NoError(t, err)
+	}()
+
+	tmpDeletedURLsFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpDeletedURLsFile.Name())
+		require.NoError(t, err)
+	}()
+
+	tmpDeletionJobsFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpDeletionJobsFile.Name())
+		require.NoError(t, err)
+	}()
+
+	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), "", tmpDeletionJobsFile.Name(), filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 10, 16, 15, 0, 0, 0, time.UTC)
+
+	jobs := []*app.DeletionJob{
+		{ID: "1", UserID: 1, URLIDs: []string{"a", "b"}, Status: app.DeletionJobPending, NextAttemptAt: now, CreatedAt: now},
+		{ID: "2", UserID: 1, URLIDs: []string{"c"}, Status: app.DeletionJobPending, NextAttemptAt: now.Add(-time.Second), CreatedAt: now},
+		{ID: "3", UserID: 2, URLIDs: []string{"d"}, Status: app.DeletionJobPending, NextAttemptAt: now.Add(time.Second), CreatedAt: now},
+	}
+	for _, job := range jobs {
+		err = appRepoInMem.AddDeletionJob(context.Background(), job)
+		require.NoError(t, err)
+	}
+
+	dueJobs, err := appRepoInMem.GetDueDeletionJobs(context.Background(), now, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []*app.DeletionJob{jobs[1], jobs[0]}, dueJobs)
+
+	dueJobs, err = appRepoInMem.GetDueDeletionJobs(context.Background(), now, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []*app.DeletionJob{jobs[1]}, dueJobs)
+
+	// returned jobs are copies
+	dueJobs[0].Status = app.DeletionJobDone
+	dueJobs, err = appRepoInMem.GetDueDeletionJobs(context.Background(), now, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []*app.DeletionJob{jobs[1]}, dueJobs)
+
+	dueJobs[0].Status = app.DeletionJobDone
+	err = appRepoInMem.UpdateDeletionJob(context.Background(), dueJobs[0])
+	require.NoError(t, err)
+
+	jobs[0].Attempts = 1
+	jobs[0].LastError = "test error"
+	jobs[0].NextAttemptAt = now.Add(time.Minute)
+	err = appRepoInMem.UpdateDeletionJob(context.Background(), jobs[0])
+	require.NoError(t, err)
+
+	err = appRepoInMem.UpdateDeletionJob(context.Background(), &app.DeletionJob{ID: "unknown"})
+	assert.ErrorIs(t, err, ErrDeletionJobNotFound)
+
+	job, err := appRepoInMem.GetDeletionJob(context.Background(), "2")
+	require.NoError(t, err)
+	assert.Equal(t, app.DeletionJobDone, job.Status)
+	assert.Equal(t, []string{"c"}, job.URLIDs)
+
+	_, err = appRepoInMem.GetDeletionJob(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrDeletionJobNotFound)
+
+	count, err := appRepoInMem.CountPendingDeletionJobs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+
+	err = appRepoInMem.Close()
+	require.NoError(t, err)
+
+	appRepoInMem, err = NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), "", tmpDeletionJobsFile.Name(), filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	defer func() { err = appRepoInMem.Close(); require.NoError(t, err) }()
+
+	dueJobs, err = appRepoInMem.GetDueDeletionJobs(context.Background(), now.Add(time.Minute), 0)
+	require.NoError(t, err)
+	require.Len(t, dueJobs, 2)
+	assert.Equal(t, "3", dueJobs[0].ID)
+	assert.Equal(t, "1", dueJobs[1].ID)
+	assert.Equal(t, uint(1), dueJobs[1].Attempts)
+	assert.Equal(t, "test error", dueJobs[1].LastError)
+	assert.Equal(t, []string{"a", "b"}, dueJobs[1].URLIDs)
+}
+
+func TestAppRepoInmem_PurgeDeletionJobs(t *testing.T) {
+	ctx := context.Background()
+	filenames := newTestFilenames(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	finishedAt := now.Add(-time.Hour)
+
+	r := filenames.open(t)
+	jobs := []*app.DeletionJob{
+		{ID: "done", UserID: 1, URLIDs: []string{"a"}, Status: app.DeletionJobDone, NextAttemptAt: now, CreatedAt: now, FinishedAt: &finishedAt},
+		{ID: "pending", UserID: 1, URLIDs: []string{"b"}, Status: app.DeletionJobPending, NextAttemptAt: now, CreatedAt: now},
+	}
+	for _, job := range jobs {
+		err := r.AddDeletionJob(ctx, job)
+		require.NoError(t, err)
+	}
+
+	count, err := r.PurgeDeletionJobs(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), count)
+
+	// journal is rewritten, so purged job is not loaded again
+	data, err := os.ReadFile(filenames.deletionJobs)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+
+	err = r.Close()
+	require.NoError(t, err)
+
+	r = filenames.open(t)
+	defer func() { err = r.Close(); require.NoError(t, err) }()
+
+	_, err = r.GetDeletionJob(ctx, "done")
+	assert.ErrorIs(t, err, ErrDeletionJobNotFound)
+	dueJobs, err := r.GetDueDeletionJobs(ctx, now, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []*app.DeletionJob{jobs[1]}, dueJobs)
+}
+
+// assertPurgedURLReused checks that URL created with ID and original URL of purged URL
+// does not get deletion mark and clicks of purged URL.
+func assertPurgedURLReused(t *testing.T, r *AppRepoInmem) {
+	ctx := context.Background()
+
+	url, err := r.GetURL(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, "new2", url.URL)
+	assert.False(t, url.IsDeleted)
+	assert.Zero(t, url.Clicks)
+	url, err = r.GetURL(ctx, "4")
+	require.NoError(t, err)
+	assert.Equal(t, "test2", url.URL)
+	urls, err := r.GetUserURLs(ctx, 1, &app.UserURLsFilter{IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Len(t, urls, 3)
+}
+
+// reusePurgedURL creates URLs with ID and original URL of purged URL "2".
+func reusePurgedURL(t *testing.T, r *AppRepoInmem) {
+	_, err := r.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "2", URL: "new2", UserID: 1},
+		{ID: "4", URL: "test2", UserID: 1},
+	})
+	require.NoError(t, err)
+}
+
+func TestAppRepoInmem_PurgeDeletedURLs(t *testing.T) {
+	ctx := context.Background()
+	filenames := newTestFilenames(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	r := filenames.open(t)
+	fillTestRepo(t, r, now)
+	err := r.AddURLsClicks(ctx, []*app.URLClicks{{ID: "2", Count: 1, LastAccessedAt: now}})
+	require.NoError(t, err)
+
+	count, err := r.PurgeDeletedURLs(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), count)
+
+	// purge is appended to logs, storage is not compacted
+	_, err = os.Stat(filenames.urls + SnapshotFileSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	reusePurgedURL(t, r)
+	assertPurgedURLReused(t, r)
+	err = r.Close()
+	require.NoError(t, err)
+
+	r = filenames.open(t)
+	assertPurgedURLReused(t, r)
+
+	err = r.Compact(ctx)
+	require.NoError(t, err)
+	err = r.Close()
+	require.NoError(t, err)
+
+	r = filenames.open(t)
+	defer func() { err = r.Close(); require.NoError(t, err) }()
+	assertPurgedURLReused(t, r)
+}
+
+func TestAppRepoInmem_PurgeDeletedURLsNotSaved(t *testing.T) {
+	ctx := context.Background()
+	filenames := newTestFilenames(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	r := filenames.open(t)
+	defer func() { err := r.Close(); require.NoError(t, err) }()
+	fillTestRepo(t, r, now)
+
+	// log of URLs can not be written: it is started again and its temp file is dir
+	tmpDir := filenames.urls + filestorage.TempFileSuffix
+	err := os.Mkdir(tmpDir, 0755)
+	require.NoError(t, err)
+	r.producer.startHeader = newLogHeader(r.generation)
+
+	_, err = r.PurgeDeletedURLs(ctx, time.Now().Add(time.Minute))
+	require.Error(t, err)
+
+	// purge is not saved, so deleted URL stays in memory
+	url, err := r.GetURL(ctx, "2")
+	require.NoError(t, err)
+	assert.True(t, url.IsDeleted)
+	urls, err := r.GetUserURLs(ctx, 1, &app.UserURLsFilter{IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	err = os.Remove(tmpDir)
+	require.NoError(t, err)
+
+	count, err := r.PurgeDeletedURLs(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), count)
+	_, err = r.GetURL(ctx, "2")
+	assert.ErrorIs(t, err, ErrURLNotFound)
+}
+
+func TestAppRepoInmem_PurgeDeletedURLsCompactedIfNotAppendedToLogs(t *testing.T) {
+	ctx := context.Background()
+	filenames := newTestFilenames(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	r := filenames.open(t)
+	fillTestRepo(t, r, now)
+
+	// log of deleted URLs can not be written: it is started again and its temp file is dir
+	tmpDir := filenames.deletedURLs + filestorage.TempFileSuffix
+	err := os.Mkdir(tmpDir, 0755)
+	require.NoError(t, err)
+	r.deleteURLProducer.startHeader = newLogHeader(r.generation)
+
+	count, err := r.PurgeDeletedURLs(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), count)
+
+	// deletion mark of purged URL is dropped by snapshot
+	_, err = os.Stat(filenames.urls + SnapshotFileSuffix)
+	require.NoError(t, err)
+
+	reusePurgedURL(t, r)
+	err = r.Close()
+	require.NoError(t, err)
+	err = os.Remove(tmpDir)
+	require.NoError(t, err)
+
+	r = filenames.open(t)
+	defer func() { err = r.Close(); require.NoError(t, err) }()
+	assertPurgedURLReused(t, r)
+}
+
+// benchmarkSizes are counts of users and URLs per user.
+// Lookup time must not grow with the total count of URLs.
+var benchmarkSizes = []struct {
+	countUsers    uint
+	countUserURLs uint
+}{
+	{countUsers: 10, countUserURLs: 10},
+	{countUsers: 100, countUserURLs: 100},
+	{countUsers: 1000, countUserURLs: 100},
+}
+
+func newBenchmarkAppRepoInmem(b *testing.B, countUsers, countUserURLs uint) (*AppRepoInmem, []*app.URL) {
+	appRepoInmem, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(b, err)
+
+	urls := make([]*app.URL, 0, countUsers*countUserURLs)
+	for i := uint(0); i < countUsers; i++ {
+		for j := uint(0); j < countUserURLs; j++ {
+			id := generateTestURLID(j, i)
+			urls = append(urls, &app.URL{ID: id, URL: "test_url_" + id, UserID: i})
+		}
+	}
+
+	_, err = appRepoInmem.GetOrCreateURLs(context.Background(), urls)
+	require.NoError(b, err)
+
+	return appRepoInmem, urls
+}
+
+func BenchmarkAppRepoInmem_GetURL_Sizes(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("urls_%d", size.countUsers*size.countUserURLs), func(b *testing.B) {
+			appRepoInmem, urls := newBenchmarkAppRepoInmem(b, size.countUsers, size.countUserURLs)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := appRepoInmem.GetURL(context.Background(), urls[i%len(urls)].ID)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAppRepoInmem_CheckIDExistence_Sizes(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("urls_%d", size.countUsers*size.countUserURLs), func(b *testing.B) {
+			appRepoInmem, _ := newBenchmarkAppRepoInmem(b, size.countUsers, size.countUserURLs)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := appRepoInmem.CheckIDExistence(context.Background(), "aaa")
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAppRepoInmem_GetOrCreateURL_Sizes(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("urls_%d", size.countUsers*size.countUserURLs), func(b *testing.B) {
+			appRepoInmem, urls := newBenchmarkAppRepoInmem(b, size.countUsers, size.countUserURLs)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				url := urls[i%len(urls)]
+				_, err := appRepoInmem.GetOrCreateURL(context.Background(), url.ID, url.URL, url.UserID, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAppRepoInmem_DeleteUserURLs_Sizes(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("urls_%d", size.countUsers*size.countUserURLs), func(b *testing.B) {
+			appRepoInmem, urls := newBenchmarkAppRepoInmem(b, size.countUsers, size.countUserURLs)
+			urlsForDeletion := urls[:10]
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := appRepoInmem.DeleteUserURLs(context.Background(), urlsForDeletion)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestAppRepoInmem_URLIDExists(t *testing.T) {
+	appRepoInMem, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+
+	_, err = appRepoInMem.GetOrCreateURL(context.Background(), "spring-sale", "test1", uint(1), nil)
+	require.NoError(t, err)
+
+	_, err = appRepoInMem.GetOrCreateURL(context.Background(), "spring-sale", "test2", uint(1), nil)
+	assert.ErrorIs(t, err, app.ErrURLIDExists)
+
+	_, err = appRepoInMem.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "new", URL: "test3", UserID: uint(1)},
+		{ID: "spring-sale", URL: "test4", UserID: uint(1)},
+	})
+	assert.ErrorIs(t, err, app.ErrURLIDExists)
+
+	_, err = appRepoInMem.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "same", URL: "test5", UserID: uint(1)},
+		{ID: "same", URL: "test6", UserID: uint(1)},
+	})
+	assert.ErrorIs(t, err, app.ErrURLIDExists)
+
+	ok, err := appRepoInMem.CheckIDExistence(context.Background(), "new")
+	require.NoError(t, err)
+	assert.False(t, ok, "URLs from failed batch must not be saved")
+}
+
+func TestAppRepoInmem_DeleteExpiredURLs(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpFile.Name())
+		require.NoError(t, err)
+	}()
+
+	tmpDeletedURLsFile, err := os.CreateTemp("", TestFilenamePattern)
+	require.NoError(t, err)
+	defer func() {
+		err = os.Remove(tmpDeletedURLsFile.Name())
+		require.NoError(t, err)
+	}()
+
+	appRepoInMem, err := NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Hour)
+
+	_, err = appRepoInMem.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "1", URL: "test1", UserID: uint(1), ExpiresAt: &past},
+		{ID: "2", URL: "test2", UserID: uint(1), ExpiresAt: &future},
+		{ID: "3", URL: "test3", UserID: uint(1)},
+	})
+	require.NoError(t, err)
+
+	err = appRepoInMem.DeleteExpiredURLs(context.Background(), now)
+	require.NoError(t, err)
+
+	err = appRepoInMem.Close()
+	require.NoError(t, err)
+
+	appRepoInMem, err = NewAppRepoInmem(tmpFile.Name(), tmpDeletedURLsFile.Name(), "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	defer func() { err = appRepoInMem.Close(); require.NoError(t, err) }()
+
+	for id, isDeleted := range map[string]bool{"1": true, "2": false, "3": false} {
+		url, err := appRepoInMem.GetURL(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, isDeleted, url.IsDeleted, "URL ID: %s", id)
+	}
+
+	url, err := appRepoInMem.GetURL(context.Background(), "2")
+	require.NoError(t, err)
+	require.NotNil(t, url.ExpiresAt)
+	assert.True(t, future.Equal(*url.ExpiresAt))
+	assert.NotContains(t, appRepoInMem.expiringURLs, "1")
+}
+
+func TestAppRepoInmem_Conformance(t *testing.T) {
+	repotest.RunAppRepoSuite(t, func(t *testing.T, countUsers int) (usecase.AppRepoInterface, []uint) {
+		r, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+		require.NoError(t, err)
+
+		userIDs := make([]uint, 0, countUsers)
+		for i := 1; i <= countUsers; i++ {
+			userIDs = append(userIDs, uint(i))
+		}
+		return r, userIDs
+	})
+}
+
+func TestAppRepoInmem_Conformance_File(t *testing.T) {
+	repotest.RunAppRepoSuite(t, func(t *testing.T, countUsers int) (usecase.AppRepoInterface, []uint) {
+		dir := t.TempDir()
+		r, err := NewAppRepoInmem(
+			filepath.Join(dir, "urls.json"),
+			filepath.Join(dir, "deleted_urls.json"),
+			filepath.Join(dir, "clicks.json"),
+			filepath.Join(dir, "deletion_jobs.json"),
+			filestorage.SyncPolicy{Mode: filestorage.SyncAlways},
+			app.URLUniquenessGlobal,
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			err := r.Close()
+			assert.NoError(t, err)
+		})
+
+		userIDs := make([]uint, 0, countUsers)
+		for i := 1; i <= countUsers; i++ {
+			userIDs = append(userIDs, uint(i))
+		}
+		return r, userIDs
+	})
+}
+
+func TestAppRepoInmem_Conformance_UserScoped(t *testing.T) {
+	repotest.RunAppRepoUserScopedSuite(t, func(t *testing.T, countUsers int) (usecase.AppRepoInterface, []uint) {
+		r, err := NewAppRepoInmem("", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessUser)
+		require.NoError(t, err)
+
+		userIDs := make([]uint, 0, countUsers)
+		for i := 1; i <= countUsers; i++ {
+			userIDs = append(userIDs, uint(i))
+		}
+		return r, userIDs
+	})
+}
+
+// testFilenames are files of in-memory repo in temp dir.
+type testFilenames struct {
+	urls, deletedURLs, clicks, deletionJobs string
+}
+
+func newTestFilenames(t *testing.T) testFilenames {
+	dir := t.TempDir()
+	return testFilenames{
+		urls:         filepath.Join(dir, "urls.json"),
+		deletedURLs:  filepath.Join(dir, "deleted_urls.json"),
+		clicks:       filepath.Join(dir, "clicks.json"),
+		deletionJobs: filepath.Join(dir, "deletion_jobs.json"),
+	}
+}
+
+func (f testFilenames) open(t *testing.T) *AppRepoInmem {
+	r, err := NewAppRepoInmem(f.urls, f.deletedURLs, f.clicks, f.deletionJobs, filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.NoError(t, err)
+	return r
+}
+
+// fillTestRepo saves URLs, deletion marks, clicks and deletion job in repo.
+func fillTestRepo(t *testing.T, r *AppRepoInmem, now time.Time) {
+	ctx := context.Background()
+
+	_, err := r.GetOrCreateURLs(ctx, []*app.URL{
+		{ID: "1", URL: "test1", UserID: 1},
+		{ID: "2", URL: "test2", UserID: 1},
+		{ID: "3", URL: "test3", UserID: 2},
+	})
+	require.NoError(t, err)
+
+	err = r.DeleteUserURLs(ctx, []*app.URL{{ID: "2", UserID: 1}})
+	require.NoError(t, err)
+
+	err = r.AddURLsClicks(ctx, []*app.URLClicks{{ID: "1", Count: 2, LastAccessedAt: now}})
+	require.NoError(t, err)
+
+	job := &app.DeletionJob{ID: "job", UserID: 1, URLIDs: []string{"2"}, Status: app.DeletionJobPending, NextAttemptAt: now, CreatedAt: now}
+	err = r.AddDeletionJob(ctx, job)
+	require.NoError(t, err)
+	job.Status = app.DeletionJobDone
+	err = r.UpdateDeletionJob(ctx, job)
+	require.NoError(t, err)
+}
+
+// assertTestRepo checks data saved by fillTestRepo.
+func assertTestRepo(t *testing.T, r *AppRepoInmem) {
+	ctx := context.Background()
+
+	url, err := r.GetURL(ctx, "1")
+	require.NoError(t, err)
+	assert.False(t, url.IsDeleted)
+	assert.Equal(t, uint64(2), url.Clicks)
+
+	url, err = r.GetURL(ctx, "2")
+	require.NoError(t, err)
+	assert.True(t, url.IsDeleted)
+
+	// URLs are not duplicated in indexes
+	urls, err := r.GetUserURLs(ctx, 1, &app.UserURLsFilter{IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	job, err := r.GetDeletionJob(ctx, "job")
+	require.NoError(t, err)
+	assert.Equal(t, app.DeletionJobDone, job.Status)
+}
+
+func TestAppRepoInmem_Compact(t *testing.T) {
+	ctx := context.Background()
+	filenames := newTestFilenames(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	r := filenames.open(t)
+	fillTestRepo(t, r, now)
+
+	err := r.Compact(ctx)
+	require.NoError(t, err)
+	assertTestRepo(t, r)
+	count, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), count)
+
+	// logs are started again with header of snapshot generation
+	header, err := filestorage.MarshalRecord(newLogHeader(1))
+	require.NoError(t, err)
+	for _, filename := range []string{filenames.urls, filenames.deletedURLs, filenames.clicks} {
+		data, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, string(header), string(data))
+	}
+	data, err := os.ReadFile(filenames.urls + SnapshotFileSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(string(data), "\n"))
+	_, err = os.Stat(filenames.urls + SnapshotFileSuffix + filestorage.TempFileSuffix)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// startup reads snapshot and tail of logs
+	_, err = r.GetOrCreateURL(ctx, "4", "test4", 2, nil)
+	require.NoError(t, err)
+	err = r.DeleteUserURLs(ctx, []*app.URL{{ID: "3", UserID: 2}})
+	require.NoError(t, err)
+	err = r.Close()
+	require.NoError(t, err)
+
+	r = filenames.open(t)
+	assertTestRepo(t, r)
+	url, err := r.GetURL(ctx, "3")
+	require.NoError(t, err)
+	assert.True(t, url.IsDeleted)
+	_, err = r.GetURL(ctx, "4")
+	require.NoError(t, err)
+
+	// next compaction merges tail of logs in snapshot
+	err = r.Compact(ctx)
+	require.NoError(t, err)
+	err = r.Close()
+	require.NoError(t, err)
+
+	r = filenames.open(t)
+	defer func() { err = r.Close(); require.NoError(t, err) }()
+	assertTestRepo(t, r)
+	assert.Equal(t, uint64(2), r.generation)
+	count, err = r.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(4), count)
+}
+
+func TestNewAppRepoInmem_InterruptedCompaction(t *testing.T) {
+	ctx := context.Background()
+	filenames := newTestFilenames(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	r := filenames.open(t)
+	fillTestRepo(t, r, now)
+
+	logs := map[string][]byte{}
+	for _, filename := range []string{filenames.urls, filenames.deletedURLs, filenames.clicks} {
+		data, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		logs[filename] = data
+	}
+
+	err := r.Compact(ctx)
+	require.NoError(t, err)
+	err = r.Close()
+	require.NoError(t, err)
+
+	// crash after snapshot is saved, but before logs are started again
+	for filename, data := range logs {
+		err = os.WriteFile(filename, data, 0666)
+		require.NoError(t, err)
+	}
+
+	// records of stale logs are already in snapshot, clicks are not counted twice
+	r = filenames.open(t)
+	assertTestRepo(t, r)
+	count, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), count)
+
+	_, err = r.GetOrCreateURL(ctx, "4", "test4", 2, nil)
+	require.NoError(t, err)
+	err = r.Close()
+	require.NoError(t, err)
+
+	// new records are appended to logs started again at startup
+	r = filenames.open(t)
+	defer func() { err = r.Close(); require.NoError(t, err) }()
+	assertTestRepo(t, r)
+	_, err = r.GetURL(ctx, "4")
+	require.NoError(t, err)
+}
+
+func TestAppRepoInmem_CompactLogNotStarted(t *testing.T) {
+	ctx := context.Background()
+	filenames := newTestFilenames(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	r := filenames.open(t)
+	fillTestRepo(t, r, now)
+
+	// log of deleted URLs can not be replaced: its temp file is dir
+	tmpDir := filenames.deletedURLs + filestorage.TempFileSuffix
+	err := os.Mkdir(tmpDir, 0755)
+	require.NoError(t, err)
+
+	err = r.Compact(ctx)
+	require.Error(t, err)
+
+	// record is not appended to log of previous generation, URL is not changed
+	err = r.DeleteUserURLs(ctx, []*app.URL{{ID: "3", UserID: 2}})
+	require.Error(t, err)
+	url, err := r.GetURL(ctx, "3")
+	require.NoError(t, err)
+	assert.False(t, url.IsDeleted)
+
+	err = os.Remove(tmpDir)
+	require.NoError(t, err)
+
+	// log is started before next record
+	err = r.DeleteUserURLs(ctx, []*app.URL{{ID: "3", UserID: 2}})
+	require.NoError(t, err)
+	err = r.Close()
+	require.NoError(t, err)
+
+	r = filenames.open(t)
+	defer func() { err = r.Close(); require.NoError(t, err) }()
+	assertTestRepo(t, r)
+	url, err = r.GetURL(ctx, "3")
+	require.NoError(t, err)
+	assert.True(t, url.IsDeleted)
+}
+
+func TestNewAppRepoInmem_LogAheadOfSnapshot(t *testing.T) {
+	filenames := newTestFilenames(t)
+
+	r := filenames.open(t)
+	fillTestRepo(t, r, time.Now())
+	err := r.Compact(context.Background())
+	require.NoError(t, err)
+	err = r.Close()
+	require.NoError(t, err)
+
+	err = os.Remove(filenames.urls + SnapshotFileSuffix)
+	require.NoError(t, err)
+
+	_, err = NewAppRepoInmem(filenames.urls, filenames.deletedURLs, filenames.clicks, filenames.deletionJobs, filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.ErrorIs(t, err, ErrLogAheadOfSnapshot)
+}
+
+func TestNewAppRepoInmem_TornTail(t *testing.T) {
+	ctx := context.Background()
+	filenames := newTestFilenames(t)
+
+	r := filenames.open(t)
+	fillTestRepo(t, r, time.Now())
+	err := r.Close()
+	require.NoError(t, err)
+
+	// crash while records were written
+	for _, filename := range []string{filenames.urls, filenames.deletedURLs, filenames.clicks, filenames.deletionJobs} {
+		file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"ID":"5","UR`)
+		require.NoError(t, err)
+		err = file.Close()
+		require.NoError(t, err)
+	}
+
+	r = filenames.open(t)
+	assertTestRepo(t, r)
+	_, err = r.GetOrCreateURL(ctx, "4", "test4", 2, nil)
+	require.NoError(t, err)
+	err = r.Close()
+	require.NoError(t, err)
+
+	// new records are appended after truncated tail
+	r = filenames.open(t)
+	defer func() { err = r.Close(); require.NoError(t, err) }()
+	assertTestRepo(t, r)
+	_, err = r.GetURL(ctx, "4")
+	require.NoError(t, err)
+	_, err = r.GetURL(ctx, "5")
+	require.ErrorIs(t, err, ErrURLNotFound)
+}
+
+func TestNewAppRepoInmem_CorruptedRecord(t *testing.T) {
+	filenames := newTestFilenames(t)
+
+	r := filenames.open(t)
+	fillTestRepo(t, r, time.Now())
+	err := r.Close()
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filenames.urls)
+	require.NoError(t, err)
+	// the first record is changed, but records after it are whole
+	data = []byte(strings.Replace(string(data), "test1", "test9", 1))
+	err = os.WriteFile(filenames.urls, data, 0666)
+	require.NoError(t, err)
+
+	_, err = NewAppRepoInmem(filenames.urls, filenames.deletedURLs, filenames.clicks, filenames.deletionJobs, filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	require.ErrorIs(t, err, filestorage.ErrChecksumMismatch)
+}
@@ -1,132 +1,448 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
+	"github.com/jackc/pgx/v5/pgconn"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Constants for Postgres errors.
+const (
+	UniqueViolationCode string = "23505"          // unique_violation error code
+	URLIDConstraintName string = "url_url_id_key" // unique constraint of url.url_id
+	URLConstraintName   string = "url_url_key"    // unique index of url.url of URLs which are not user scoped
+
+	URLUserIDConstraintName string = "url_user_id_url_key" // unique constraint of url.user_id and url.url
+)
+
+// convertError converts Postgres errors to app errors.
+func convertError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != UniqueViolationCode {
+		return err
+	}
+	return convertConstraintError(pgErr.ConstraintName, err)
+}
+
+// convertConstraintError converts unique violation err of constraint or index with name to app error.
+func convertConstraintError(name string, err error) error {
+	switch name {
+	case URLIDConstraintName:
+		return app.ErrURLIDExists
+	case URLConstraintName, URLUserIDConstraintName:
+		return app.ErrURLExists
+	}
+	return err
+}
+
+// startQuerySpan creates span of DB query executed by repo method operation.
+func startQuerySpan(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "AppRepoPostgres."+operation,
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(query),
+	)
+}
+
+// withQueryTimeout returns ctx which is canceled after timeout, zero timeout means no timeout.
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 // AppRepoPostgres application data storage in PostgreSQL.
 type AppRepoPostgres struct {
-	db *sql.DB
+	db           *sql.DB
+	queryTimeout time.Duration // max duration of one query
+	userScoped   bool          // original URL is unique only among URLs of its user
 }
 
 // NewAppRepoPostgres creates *AppRepoPostgres.
-func NewAppRepoPostgres(db *sql.DB) (*AppRepoPostgres, error) {
-	return &AppRepoPostgres{db: db}, nil
+func NewAppRepoPostgres(db *sql.DB, queryTimeout time.Duration, urlUniqueness string) (*AppRepoPostgres, error) {
+	return &AppRepoPostgres{
+		db:           db,
+		queryTimeout: queryTimeout,
+		userScoped:   urlUniqueness == app.URLUniquenessUser,
+	}, nil
 }
 
 // GetOrCreateURL insert new URL in DB or get existed URL.
-func (arp *AppRepoPostgres) GetOrCreateURL(id, rawURL string, userID uint) (*app.URL, error) {
-	query := `INSERT INTO url (url, url_id, user_id) 
-VALUES ($1, $2, $3) 
-ON CONFLICT (url) DO UPDATE SET url = EXCLUDED.url, user_id = COALESCE(url.user_id, EXCLUDED.user_id) 
-RETURNING url_id, user_id;`
-	err := arp.db.QueryRow(query, rawURL, id, userID).Scan(&id, &userID)
+func (arp *AppRepoPostgres) GetOrCreateURL(ctx context.Context, id, rawURL string, userID uint, expiresAt *time.Time) (*app.URL, error) {
+	query := `INSERT INTO url (url, url_id, user_id, user_scoped, expires_at) 
+VALUES ($1, $2, $3, $4, $5) 
+ON CONFLICT ` + urlConflictTarget(arp.userScoped) + ` DO UPDATE SET url = EXCLUDED.url, user_id = COALESCE(url.user_id, EXCLUDED.user_id) 
+RETURNING url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at;`
+	ctx, span := startQuerySpan(ctx, "GetOrCreateURL", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	url := &app.URL{URL: rawURL}
+	err := arp.db.QueryRowContext(ctx, query, rawURL, id, userID, arp.userScoped, expiresAt).Scan(
+		&url.ID, &url.UserID, &url.IsDeleted, &url.DeletedAt, &url.ExpiresAt, &url.CreatedAt, &url.Clicks, &url.LastAccessedAt,
+	)
+	if err != nil {
+		tracing.RecordError(span, err)
+		err = convertError(err)
+	}
+	if errors.Is(err, app.ErrURLExists) && !arp.userScoped {
+		// URL saved by user scoped repo before uniqueness is changed is not conflict target, it is returned to its owner
+		savedURLs, err := arp.getUserScopedURLs(ctx, arp.db, []*app.URL{{URL: rawURL, UserID: userID}})
+		if err != nil {
+			return nil, err
+		}
+		if savedURL, ok := savedURLs[urlKey(arp.userScoped, userID, rawURL)]; ok {
+			return savedURL, nil
+		}
+		return nil, app.ErrURLExists
+	}
 	if err != nil {
 		return nil, err
 	}
-	url := &app.URL{ID: id, URL: rawURL, UserID: userID}
 	return url, nil
 }
 
+// getUserScopedURLs gets URLs saved by user scoped repo for the same users and original URLs as urls from DB.
+func (arp *AppRepoPostgres) getUserScopedURLs(ctx context.Context, q queryer, urls []*app.URL) (map[string]*app.URL, error) {
+	query := `SELECT url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at 
+FROM url WHERE user_scoped AND (user_id, url) IN (`
+	args := make([]interface{}, 0, len(urls)*2)
+	for i, url := range urls {
+		if i > 0 {
+			query += ", "
+		}
+		query += fmt.Sprintf("($%d, $%d)", i*2+1, i*2+2)
+		args = append(args, url.UserID, url.URL)
+	}
+	query += `);`
+
+	ctx, span := startQuerySpan(ctx, "getUserScopedURLs", query)
+	defer span.End()
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	savedURLs, err := scanURLs(rows, arp.userScoped)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return savedURLs, nil
+}
+
 // GetURL get URL from DB.
-func (arp *AppRepoPostgres) GetURL(id string) (*app.URL, error) {
-	query := `SELECT url, url_id, user_id, is_deleted FROM url WHERE url_id = $1;`
+func (arp *AppRepoPostgres) GetURL(ctx context.Context, id string) (*app.URL, error) {
+	query := `SELECT url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at FROM url WHERE url_id = $1;`
 	url := &app.URL{}
-	err := arp.db.QueryRow(query, id).Scan(&url.URL, &url.ID, &url.UserID, &url.IsDeleted)
+	ctx, span := startQuerySpan(ctx, "GetURL", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	err := arp.db.QueryRowContext(ctx, query, id).Scan(
+		&url.URL, &url.ID, &url.UserID, &url.IsDeleted, &url.DeletedAt, &url.ExpiresAt, &url.CreatedAt, &url.Clicks, &url.LastAccessedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, app.ErrURLNotFound
+	}
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 	return url, nil
 }
 
 // CheckIDExistence check ID existence in DB.
-func (arp *AppRepoPostgres) CheckIDExistence(id string) (bool, error) {
+func (arp *AppRepoPostgres) CheckIDExistence(ctx context.Context, id string) (bool, error) {
 	query := `SELECT true FROM url WHERE url_id = $1;`
 	var exists bool
-	err := arp.db.QueryRow(query, id).Scan(&exists)
+	ctx, span := startQuerySpan(ctx, "CheckIDExistence", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	err := arp.db.QueryRowContext(ctx, query, id).Scan(&exists)
 	if err == sql.ErrNoRows {
 		return false, nil
 	}
 	if err != nil {
+		tracing.RecordError(span, err)
 		return false, err
 	}
 	return true, nil
 }
 
+// CountURLs returns count of URLs in DB.
+func (arp *AppRepoPostgres) CountURLs(ctx context.Context) (uint, error) {
+	query := `SELECT COUNT(*) FROM url;`
+	var count uint
+	ctx, span := startQuerySpan(ctx, "CountURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	err := arp.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+	return count, nil
+}
+
 // Ping ping DB.
-func (arp *AppRepoPostgres) Ping() error {
-	return arp.db.Ping()
+func (arp *AppRepoPostgres) Ping(ctx context.Context) error {
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	return arp.db.PingContext(ctx)
 }
 
 // GetOrCreateURLs insert batch URLs or get existed URLs from DB.
-func (arp *AppRepoPostgres) GetOrCreateURLs(urls []*app.URL) ([]*app.URL, error) {
-	query := `INSERT INTO url (url, url_id, user_id) VALUES `
-	args := make([]interface{}, 0, len(urls)*3)
-	lenURLs := len(urls)
+// Result contains saved URL for every URL of batch in the same order.
+func (arp *AppRepoPostgres) GetOrCreateURLs(ctx context.Context, urls []*app.URL) ([]*app.URL, error) {
+	if len(urls) == 0 {
+		return []*app.URL{}, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "AppRepoPostgres.GetOrCreateURLs")
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	save := func(urls []*app.URL) (map[string]*app.URL, error) {
+		return arp.saveURLs(ctx, urls)
+	}
+	getUserScoped := func(urls []*app.URL) (map[string]*app.URL, error) {
+		return arp.getUserScopedURLs(ctx, arp.db, urls)
+	}
+
+	savedURLs, err := saveURLsOrGetUserScoped(uniqueURLs(urls, arp.userScoped), arp.userScoped, save, getUserScoped)
+	if err != nil {
+		return nil, err
+	}
+	return orderURLs(urls, savedURLs, arp.userScoped)
+}
+
+// saveURLs inserts new URLs of batch without repeated original URLs in DB or gets existed URLs.
+func (arp *AppRepoPostgres) saveURLs(ctx context.Context, urls []*app.URL) (map[string]*app.URL, error) {
+	query := `INSERT INTO url (url, url_id, user_id, user_scoped, expires_at) VALUES `
+	args := make([]interface{}, 0, len(urls)*5)
 	for i, url := range urls {
-		query += fmt.Sprintf("($%d, $%d, $%d)", i*3+1, i*3+2, i*3+3)
-		args = append(args, url.URL, url.ID, url.UserID)
-		if i < lenURLs-1 {
+		if i > 0 {
 			query += ", "
 		}
+		query += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", i*5+1, i*5+2, i*5+3, i*5+4, i*5+5)
+		args = append(args, url.URL, url.ID, url.UserID, arp.userScoped, url.ExpiresAt)
 	}
-	query += ` ON CONFLICT (url) 
+	query += ` ON CONFLICT ` + urlConflictTarget(arp.userScoped) + ` 
 DO UPDATE SET url = EXCLUDED.url, user_id = COALESCE(url.user_id, EXCLUDED.user_id) 
-RETURNING url, url_id, user_id;`
+RETURNING url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at;`
+
+	ctx, span := startQuerySpan(ctx, "saveURLs", query)
+	defer span.End()
 
-	rows, err := arp.db.Query(query, args...)
+	rows, err := arp.db.QueryContext(ctx, query, args...)
 	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, convertError(err)
+	}
+	savedURLs, err := scanURLs(rows, arp.userScoped)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, convertError(err)
+	}
+	return savedURLs, nil
+}
+
+// ImportURLs gets saved or saves URLs with all their fields like GetOrCreateURLs in DB.
+// History of changes of original URL from edits is saved only for new URLs.
+func (arp *AppRepoPostgres) ImportURLs(ctx context.Context, urls []*app.URL, edits []*app.URLEdit) ([]*app.URL, error) {
+	if len(urls) == 0 {
+		return []*app.URL{}, nil
+	}
+
+	newURLs := uniqueURLs(urls, arp.userScoped)
+	selectQuery := `SELECT url_id FROM url WHERE url_id IN (`
+	selectArgs := make([]interface{}, 0, len(newURLs))
+	for i, url := range newURLs {
+		if i > 0 {
+			selectQuery += ", "
+		}
+		selectQuery += fmt.Sprintf("$%d", i+1)
+		selectArgs = append(selectArgs, url.ID)
+	}
+	selectQuery += `);`
+	editQuery := `INSERT INTO url_edit (url_id, old_url, new_url, edited_at) VALUES ($1, $2, $3, $4);`
+
+	ctx, span := tracing.Start(ctx, "AppRepoPostgres.ImportURLs")
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	tx, err := arp.db.BeginTx(ctx, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	urls = nil
-	for rows.Next() {
-		var (
-			id     string
-			url    string
-			userID uint
-		)
-		err = rows.Scan(&url, &id, &userID)
+	// URL is new if its ID is not saved before insert
+	savedIDs, err := queryURLIDs(ctx, tx, selectQuery, selectArgs)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	// user scoped URLs are got before insert, because transaction can not be used after failed insert
+	savedURLs := map[string]*app.URL{}
+	if !arp.userScoped {
+		savedURLs, err = arp.getUserScopedURLs(ctx, tx, newURLs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if insertURLs := unsavedURLs(newURLs, savedURLs, arp.userScoped); len(insertURLs) > 0 {
+		insertedURLs, err := arp.insertImportedURLs(ctx, tx, insertURLs)
 		if err != nil {
 			return nil, err
 		}
-		urls = append(urls, &app.URL{ID: id, URL: url, UserID: userID})
+		maps.Copy(savedURLs, insertedURLs)
 	}
 
-	err = rows.Err()
+	urlEdits := groupURLEdits(edits)
+	for _, url := range savedURLs {
+		if _, ok := savedIDs[url.ID]; ok {
+			continue
+		}
+		for _, edit := range urlEdits[url.ID] {
+			_, err = tx.ExecContext(ctx, editQuery, edit.URLID, edit.OldURL, edit.NewURL, edit.EditedAt)
+			if err != nil {
+				tracing.RecordError(span, err)
+				return nil, err
+			}
+		}
+	}
+
+	err = tx.Commit()
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
+	return orderURLs(urls, savedURLs, arp.userScoped)
+}
 
-	return urls, err
+// insertImportedURLs inserts URLs of batch without repeated original URLs with all their fields in DB or gets existed URLs.
+func (arp *AppRepoPostgres) insertImportedURLs(ctx context.Context, q queryer, urls []*app.URL) (map[string]*app.URL, error) {
+	now := time.Now()
+	query := `INSERT INTO url (url, url_id, user_id, user_scoped, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at) VALUES `
+	args := make([]interface{}, 0, len(urls)*10)
+	for i, url := range urls {
+		if i > 0 {
+			query += ", "
+		}
+		n := i * 10
+		query += fmt.Sprintf(
+			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10,
+		)
+		createdAt := url.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		args = append(
+			args, url.URL, url.ID, url.UserID, arp.userScoped, url.IsDeleted, url.DeletedAt, url.ExpiresAt, createdAt, url.Clicks, url.LastAccessedAt,
+		)
+	}
+	query += ` ON CONFLICT ` + urlConflictTarget(arp.userScoped) + ` 
+DO UPDATE SET url = EXCLUDED.url, user_id = COALESCE(url.user_id, EXCLUDED.user_id) 
+RETURNING url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at;`
+
+	ctx, span := startQuerySpan(ctx, "insertImportedURLs", query)
+	defer span.End()
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, convertError(err)
+	}
+	savedURLs, err := scanURLs(rows, arp.userScoped)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, convertError(err)
+	}
+	return savedURLs, nil
 }
 
-// GetUserURLs get user URLs from DB.
-func (arp *AppRepoPostgres) GetUserURLs(userID uint) ([]*app.URL, error) {
-	query := `SELECT url, url_id, user_id, is_deleted FROM url WHERE user_id = $1;`
+// GetUserURLs get page of user URLs sorted by creation time from DB.
+func (arp *AppRepoPostgres) GetUserURLs(ctx context.Context, userID uint, filter *app.UserURLsFilter) ([]*app.URL, error) {
+	query := `SELECT url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at 
+FROM url WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	if !filter.IncludeDeleted {
+		query += ` AND NOT is_deleted`
+	}
+	if filter.Search != "" {
+		args = append(args, filter.Search)
+		query += fmt.Sprintf(` AND strpos(url, $%d) > 0`, len(args))
+	}
+
+	order := "ASC"
+	comparison := ">"
+	if filter.Desc {
+		order = "DESC"
+		comparison = "<"
+	}
+
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		query += fmt.Sprintf(` AND (created_at, url_id COLLATE "C") %s ($%d, $%d)`, comparison, len(args)-1, len(args))
+	}
+
+	// url_id is compared byte-wise like in other storages
+	query += fmt.Sprintf(` ORDER BY created_at %s, url_id COLLATE "C" %s`, order, order)
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	query += ";"
+
+	ctx, span := startQuerySpan(ctx, "GetUserURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
 
-	rows, err := arp.db.Query(query, userID)
+	rows, err := arp.db.QueryContext(ctx, query, args...)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	urls := []*app.URL{}
 	for rows.Next() {
-		var (
-			id        string
-			url       string
-			isDeleted bool
+		url := &app.URL{}
+		err = rows.Scan(
+			&url.URL, &url.ID, &url.UserID, &url.IsDeleted, &url.DeletedAt, &url.ExpiresAt, &url.CreatedAt, &url.Clicks, &url.LastAccessedAt,
 		)
-		err = rows.Scan(&url, &id, &userID, &isDeleted)
 		if err != nil {
 			return nil, err
 		}
-		urls = append(urls, &app.URL{ID: id, URL: url, UserID: userID, IsDeleted: isDeleted})
+		urls = append(urls, url)
 	}
 
 	err = rows.Err()
@@ -138,8 +454,13 @@ func (arp *AppRepoPostgres) GetUserURLs(userID uint) ([]*app.URL, error) {
 }
 
 // DeleteUserURLs delete user URLs from DB.
-func (arp *AppRepoPostgres) DeleteUserURLs(urls []*app.URL) error {
-	query := `UPDATE url SET is_deleted = true WHERE `
+func (arp *AppRepoPostgres) DeleteUserURLs(ctx context.Context, urls []*app.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	// time of deletion of already deleted URL is not changed
+	query := `UPDATE url SET is_deleted = true, deleted_at = now() WHERE NOT is_deleted AND (`
 	args := make([]interface{}, 0, len(urls)*2)
 	lenURLs := len(urls)
 	for i, url := range urls {
@@ -149,13 +470,454 @@ func (arp *AppRepoPostgres) DeleteUserURLs(urls []*app.URL) error {
 			query += " OR "
 		}
 	}
-	query += ";"
+	query += ");"
+
+	ctx, span := startQuerySpan(ctx, "DeleteUserURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	_, err := arp.db.ExecContext(ctx, query, args...)
+	tracing.RecordError(span, err)
 
-	_, err := arp.db.Exec(query, args...)
+	return err
+}
+
+// DeleteExpiredURLs marks URLs expired at the moment now as deleted in DB.
+func (arp *AppRepoPostgres) DeleteExpiredURLs(ctx context.Context, now time.Time) error {
+	query := `UPDATE url SET is_deleted = true, deleted_at = $1 WHERE NOT is_deleted AND expires_at <= $1;`
+	ctx, span := startQuerySpan(ctx, "DeleteExpiredURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
 
+	_, err := arp.db.ExecContext(ctx, query, now)
+	tracing.RecordError(span, err)
 	return err
 }
 
+// GetUserURLIDs gets IDs of URLs among ids which belong to user from DB.
+func (arp *AppRepoPostgres) GetUserURLIDs(ctx context.Context, userID uint, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	query := `SELECT url_id FROM url WHERE user_id = $1 AND url_id IN (`
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, userID)
+	for i, id := range ids {
+		if i > 0 {
+			query += ", "
+		}
+		query += fmt.Sprintf("$%d", i+2)
+		args = append(args, id)
+	}
+	query += `);`
+
+	ctx, span := startQuerySpan(ctx, "GetUserURLIDs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	rows, err := arp.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	userURLIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userURLIDs = append(userURLIDs, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return userURLIDs, nil
+}
+
+// RestoreUserURLs cancels deletion of user URLs which are not expired at the moment now in DB.
+func (arp *AppRepoPostgres) RestoreUserURLs(ctx context.Context, userID uint, ids []string, now time.Time) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	query := `UPDATE url SET is_deleted = false, deleted_at = NULL 
+WHERE user_id = $1 AND is_deleted AND (expires_at IS NULL OR expires_at > $2) AND url_id IN (`
+	args := make([]interface{}, 0, len(ids)+2)
+	args = append(args, userID, now)
+	for i, id := range ids {
+		if i > 0 {
+			query += ", "
+		}
+		query += fmt.Sprintf("$%d", i+3)
+		args = append(args, id)
+	}
+	query += `) RETURNING url_id;`
+
+	ctx, span := startQuerySpan(ctx, "RestoreUserURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	rows, err := arp.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	restoredIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		restoredIDs = append(restoredIDs, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return restoredIDs, nil
+}
+
+// PurgeDeletedURLs removes URLs deleted before deletedBefore from DB.
+func (arp *AppRepoPostgres) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (uint, error) {
+	query := `DELETE FROM url WHERE is_deleted AND deleted_at < $1;`
+	ctx, span := startQuerySpan(ctx, "PurgeDeletedURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	result, err := arp.db.ExecContext(ctx, query, deletedBefore)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return uint(count), nil
+}
+
+// UpdateURL changes original URL of not deleted user URL and saves change in history of URL in one transaction.
+// Nothing is changed if URL already has rawURL.
+func (arp *AppRepoPostgres) UpdateURL(ctx context.Context, id string, userID uint, rawURL string, editedAt time.Time) (*app.URL, error) {
+	// row is locked, so concurrent changes of URL are saved in history one after another
+	selectQuery := `SELECT url FROM url WHERE url_id = $1 AND user_id = $2 AND NOT is_deleted FOR UPDATE;`
+	// URL changed by user scoped repo becomes user scoped, so it does not conflict with the same URL of other users
+	updateQuery := `UPDATE url SET url = $1, user_scoped = user_scoped OR $3 WHERE url_id = $2 
+RETURNING url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at;`
+	insertQuery := `INSERT INTO url_edit (url_id, old_url, new_url, edited_at) VALUES ($1, $2, $3, $4);`
+	ctx, span := startQuerySpan(ctx, "UpdateURL", updateQuery)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	tx, err := arp.db.BeginTx(ctx, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var oldURL string
+	err = tx.QueryRowContext(ctx, selectQuery, id, userID).Scan(&oldURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, app.ErrURLNotFound
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	url := &app.URL{}
+	err = tx.QueryRowContext(ctx, updateQuery, rawURL, id, arp.userScoped).Scan(
+		&url.URL, &url.ID, &url.UserID, &url.IsDeleted, &url.DeletedAt, &url.ExpiresAt, &url.CreatedAt, &url.Clicks, &url.LastAccessedAt,
+	)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, convertError(err)
+	}
+	if oldURL == rawURL {
+		return url, nil
+	}
+
+	_, err = tx.ExecContext(ctx, insertQuery, id, oldURL, rawURL, editedAt)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return url, nil
+}
+
+// GetURLEdits gets history of changes of original URL of URL with ID from DB.
+func (arp *AppRepoPostgres) GetURLEdits(ctx context.Context, id string) ([]*app.URLEdit, error) {
+	query := `SELECT url_id, old_url, new_url, edited_at FROM url_edit WHERE url_id = $1 ORDER BY edited_at, id;`
+	ctx, span := startQuerySpan(ctx, "GetURLEdits", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	rows, err := arp.db.QueryContext(ctx, query, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []*app.URLEdit{}
+	for rows.Next() {
+		edit := &app.URLEdit{}
+		err = rows.Scan(&edit.URLID, &edit.OldURL, &edit.NewURL, &edit.EditedAt)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return edits, nil
+}
+
+// AddURLsClicks adds redirects to URLs statistics in DB.
+func (arp *AppRepoPostgres) AddURLsClicks(ctx context.Context, urlsClicks []*app.URLClicks) error {
+	if len(urlsClicks) == 0 {
+		return nil
+	}
+
+	query := `UPDATE url SET clicks = url.clicks + v.clicks, 
+last_accessed_at = GREATEST(url.last_accessed_at, v.last_accessed_at) 
+FROM (VALUES `
+	args := make([]interface{}, 0, len(urlsClicks)*3)
+	lenURLsClicks := len(urlsClicks)
+	for i, urlClicks := range urlsClicks {
+		query += fmt.Sprintf("($%d, $%d::bigint, $%d::timestamptz)", i*3+1, i*3+2, i*3+3)
+		args = append(args, urlClicks.ID, int64(urlClicks.Count), urlClicks.LastAccessedAt)
+		if i < lenURLsClicks-1 {
+			query += ", "
+		}
+	}
+	query += `) AS v (url_id, clicks, last_accessed_at) 
+WHERE url.url_id = v.url_id;`
+
+	ctx, span := startQuerySpan(ctx, "AddURLsClicks", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	_, err := arp.db.ExecContext(ctx, query, args...)
+	tracing.RecordError(span, err)
+
+	return err
+}
+
+// AddDeletionJob inserts new deletion job in DB.
+func (arp *AppRepoPostgres) AddDeletionJob(ctx context.Context, job *app.DeletionJob) error {
+	urlIDs, err := json.Marshal(job.URLIDs)
+	if err != nil {
+		return err
+	}
+	ignoredURLIDs, err := json.Marshal(job.IgnoredURLIDs)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO deletion_job (id, user_id, url_ids, status, attempts, next_attempt_at, last_error, created_at, finished_at, ignored_url_ids) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`
+	ctx, span := startQuerySpan(ctx, "AddDeletionJob", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	_, err = arp.db.ExecContext(ctx, query,
+		job.ID, job.UserID, string(urlIDs), job.Status, job.Attempts, job.NextAttemptAt, job.LastError, job.CreatedAt, job.FinishedAt,
+		string(ignoredURLIDs),
+	)
+	tracing.RecordError(span, err)
+	return err
+}
+
+// GetDueDeletionJobs gets pending deletion jobs which should be attempted at the moment now from DB.
+// Jobs are sorted by time of next attempt.
+func (arp *AppRepoPostgres) GetDueDeletionJobs(ctx context.Context, now time.Time, limit uint) ([]*app.DeletionJob, error) {
+	query := `SELECT id, user_id, url_ids, status, attempts, next_attempt_at, last_error, created_at, finished_at, ignored_url_ids 
+FROM deletion_job WHERE status = $1 AND next_attempt_at <= $2 
+ORDER BY next_attempt_at, id`
+	args := []interface{}{app.DeletionJobPending, now}
+	if limit > 0 {
+		args = append(args, limit)
+		query += ` LIMIT $3`
+	}
+	query += ";"
+
+	ctx, span := startQuerySpan(ctx, "GetDueDeletionJobs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	rows, err := arp.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*app.DeletionJob{}
+	for rows.Next() {
+		job := &app.DeletionJob{}
+		var urlIDs, ignoredURLIDs []byte
+		err = rows.Scan(
+			&job.ID, &job.UserID, &urlIDs, &job.Status, &job.Attempts, &job.NextAttemptAt, &job.LastError, &job.CreatedAt, &job.FinishedAt,
+			&ignoredURLIDs,
+		)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(urlIDs, &job.URLIDs)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(ignoredURLIDs, &job.IgnoredURLIDs)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// GetDeletionJob gets deletion job with ID from DB.
+func (arp *AppRepoPostgres) GetDeletionJob(ctx context.Context, id string) (*app.DeletionJob, error) {
+	query := `SELECT id, user_id, url_ids, status, attempts, next_attempt_at, last_error, created_at, finished_at, ignored_url_ids 
+FROM deletion_job WHERE id = $1;`
+	job := &app.DeletionJob{}
+	var urlIDs, ignoredURLIDs []byte
+	ctx, span := startQuerySpan(ctx, "GetDeletionJob", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	err := arp.db.QueryRowContext(ctx, query, id).Scan(
+		&job.ID, &job.UserID, &urlIDs, &job.Status, &job.Attempts, &job.NextAttemptAt, &job.LastError, &job.CreatedAt, &job.FinishedAt,
+		&ignoredURLIDs,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, app.ErrDeletionJobNotFound
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	err = json.Unmarshal(urlIDs, &job.URLIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(ignoredURLIDs, &job.IgnoredURLIDs)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// CountPendingDeletionJobs returns count of pending deletion jobs in DB.
+func (arp *AppRepoPostgres) CountPendingDeletionJobs(ctx context.Context) (uint, error) {
+	query := `SELECT COUNT(*) FROM deletion_job WHERE status = $1;`
+	var count uint
+	ctx, span := startQuerySpan(ctx, "CountPendingDeletionJobs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	err := arp.db.QueryRowContext(ctx, query, app.DeletionJobPending).Scan(&count)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// UpdateDeletionJob updates status, attempts and outcome of URLs of deletion job in DB.
+func (arp *AppRepoPostgres) UpdateDeletionJob(ctx context.Context, job *app.DeletionJob) error {
+	ignoredURLIDs, err := json.Marshal(job.IgnoredURLIDs)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE deletion_job SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, finished_at = $6, ignored_url_ids = $7 
+WHERE id = $1;`
+	ctx, span := startQuerySpan(ctx, "UpdateDeletionJob", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	result, err := arp.db.ExecContext(ctx, query,
+		job.ID, job.Status, job.Attempts, job.NextAttemptAt, job.LastError, job.FinishedAt, string(ignoredURLIDs),
+	)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return app.ErrDeletionJobNotFound
+	}
+	return nil
+}
+
+// PurgeDeletionJobs removes done and dead letter deletion jobs finished before finishedBefore from DB.
+func (arp *AppRepoPostgres) PurgeDeletionJobs(ctx context.Context, finishedBefore time.Time) (uint, error) {
+	// status is not passed as argument, so partial index of finished jobs is used
+	query := `DELETE FROM deletion_job WHERE status <> 'pending' AND finished_at < $1;`
+	ctx, span := startQuerySpan(ctx, "PurgeDeletionJobs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, arp.queryTimeout)
+	defer cancel()
+
+	result, err := arp.db.ExecContext(ctx, query, finishedBefore)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return uint(count), nil
+}
+
 // Close finishes working with the db.
 func (arp *AppRepoPostgres) Close() error {
 	return arp.db.Close()
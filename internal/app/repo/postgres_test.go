@@ -5,8 +5,11 @@ import (
 	"database/sql"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app/repo/repotest"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
 	userRepoInternal "github.com/MisterMaks/go-yandex-shortener/internal/user/repo"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
@@ -16,6 +19,9 @@ import (
 
 var DSN = os.Getenv("TEST_DATABASE_URI")
 
+// TestQueryTimeout is timeout of queries in tests.
+const TestQueryTimeout = 5 * time.Second
+
 func upMigrations(dsn string) error {
 	db, err := goose.OpenDBWithDriver("postgres", dsn)
 	if err != nil {
@@ -79,11 +85,19 @@ func (te *TestEnvironment) clean() {
 	require.NoError(te.T, err, "Failed to roll back migrations")
 }
 
+// resetCreatedAt checks that creation time of URLs is set by DB and resets it for comparison.
+func resetCreatedAt(t *testing.T, urls ...*app.URL) {
+	for _, u := range urls {
+		assert.False(t, u.CreatedAt.IsZero(), "Empty created_at")
+		u.CreatedAt = time.Time{}
+	}
+}
+
 func TestNewAppRepoPostgres(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	_, err := NewAppRepoPostgres(te.DB)
+	_, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
 	assert.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 }
 
@@ -91,13 +105,13 @@ func TestAppRepoPostgres_GetOrCreateURL(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	r, err := NewAppRepoPostgres(te.DB)
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB)
+	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB, TestQueryTimeout)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	user, err := ur.CreateUser()
+	user, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
 	testID := "1"
@@ -106,32 +120,34 @@ func TestAppRepoPostgres_GetOrCreateURL(t *testing.T) {
 
 	testURL := &app.URL{ID: testID, URL: testURLStr, UserID: testUserID, IsDeleted: false}
 
-	actualURL, err := r.GetOrCreateURL(testID, testURLStr, testUserID)
+	actualURL, err := r.GetOrCreateURL(context.Background(), testID, testURLStr, testUserID, nil)
 	require.NoError(t, err)
+	resetCreatedAt(t, actualURL)
 	assert.Equal(t, testURL, actualURL)
 
-	user2, err := ur.CreateUser()
+	user2, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
-	actualURL, err = r.GetOrCreateURL("2", testURLStr, user2.ID)
+	actualURL, err = r.GetOrCreateURL(context.Background(), "2", testURLStr, user2.ID, nil)
 	require.NoError(t, err)
+	resetCreatedAt(t, actualURL)
 	assert.Equal(t, testURL, actualURL)
 
-	_, err = r.GetOrCreateURL("1", "https://test2.ru", user2.ID)
-	require.Error(t, err)
+	_, err = r.GetOrCreateURL(context.Background(), "1", "https://test2.ru", user2.ID, nil)
+	require.ErrorIs(t, err, app.ErrURLIDExists)
 }
 
 func TestAppRepoPostgres_GetURL(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	r, err := NewAppRepoPostgres(te.DB)
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB)
+	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB, TestQueryTimeout)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	user, err := ur.CreateUser()
+	user, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
 	testID := "1"
@@ -140,14 +156,15 @@ func TestAppRepoPostgres_GetURL(t *testing.T) {
 
 	testURL := &app.URL{ID: testID, URL: testURLStr, UserID: testUserID, IsDeleted: false}
 
-	_, err = r.GetOrCreateURL(testID, testURLStr, testUserID)
+	_, err = r.GetOrCreateURL(context.Background(), testID, testURLStr, testUserID, nil)
 	require.NoError(t, err)
 
-	actualURL, err := r.GetURL(testID)
+	actualURL, err := r.GetURL(context.Background(), testID)
 	require.NoError(t, err)
+	resetCreatedAt(t, actualURL)
 	assert.Equal(t, testURL, actualURL)
 
-	_, err = r.GetURL("2")
+	_, err = r.GetURL(context.Background(), "2")
 	require.Error(t, err)
 }
 
@@ -155,27 +172,27 @@ func TestAppRepoPostgres_CheckIDExistence(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	r, err := NewAppRepoPostgres(te.DB)
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB)
+	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB, TestQueryTimeout)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	user, err := ur.CreateUser()
+	user, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
 	testID := "1"
 	testURLStr := "https://test.ru"
 	testUserID := user.ID
 
-	_, err = r.GetOrCreateURL(testID, testURLStr, testUserID)
+	_, err = r.GetOrCreateURL(context.Background(), testID, testURLStr, testUserID, nil)
 	require.NoError(t, err)
 
-	ok, err := r.CheckIDExistence(testID)
+	ok, err := r.CheckIDExistence(context.Background(), testID)
 	require.NoError(t, err)
 	require.True(t, ok)
 
-	ok, err = r.CheckIDExistence("2")
+	ok, err = r.CheckIDExistence(context.Background(), "2")
 	require.NoError(t, err)
 	require.False(t, ok)
 }
@@ -184,16 +201,16 @@ func TestAppRepoPostgres_Ping(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	r, err := NewAppRepoPostgres(te.DB)
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	err = r.Ping()
+	err = r.Ping(context.Background())
 	require.NoError(t, err)
 
 	err = te.DB.Close()
 	require.NoError(t, err)
 
-	err = r.Ping()
+	err = r.Ping(context.Background())
 	require.Error(t, err)
 }
 
@@ -201,19 +218,19 @@ func TestAppRepoPostgres_GetOrCreateURLs(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	r, err := NewAppRepoPostgres(te.DB)
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB)
+	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB, TestQueryTimeout)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	user, err := ur.CreateUser()
+	user, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
-	user2, err := ur.CreateUser()
+	user2, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
-	user3, err := ur.CreateUser()
+	user3, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
 	testURLs := []*app.URL{
@@ -222,8 +239,9 @@ func TestAppRepoPostgres_GetOrCreateURLs(t *testing.T) {
 		{ID: "3", URL: "https://test3.ru", UserID: user2.ID, IsDeleted: false},
 	}
 
-	actualURLs, err := r.GetOrCreateURLs(testURLs)
+	actualURLs, err := r.GetOrCreateURLs(context.Background(), testURLs)
 	require.NoError(t, err)
+	resetCreatedAt(t, actualURLs...)
 	assert.Equal(t, testURLs, actualURLs)
 
 	testURLs2 := []*app.URL{
@@ -232,25 +250,31 @@ func TestAppRepoPostgres_GetOrCreateURLs(t *testing.T) {
 		{ID: "6", URL: "https://test3.ru", UserID: user3.ID, IsDeleted: false},
 	}
 
-	actualURLs, err = r.GetOrCreateURLs(testURLs2)
+	actualURLs, err = r.GetOrCreateURLs(context.Background(), testURLs2)
 	require.NoError(t, err)
+	resetCreatedAt(t, actualURLs...)
 	assert.Equal(t, testURLs, actualURLs)
+
+	_, err = r.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "1", URL: "https://test4.ru", UserID: user3.ID, IsDeleted: false},
+	})
+	require.ErrorIs(t, err, app.ErrURLIDExists)
 }
 
 func TestAppRepoPostgres_GetUserURLs(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	r, err := NewAppRepoPostgres(te.DB)
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB)
+	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB, TestQueryTimeout)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	user, err := ur.CreateUser()
+	user, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
-	user2, err := ur.CreateUser()
+	user2, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
 	testURLs := []*app.URL{
@@ -259,30 +283,61 @@ func TestAppRepoPostgres_GetUserURLs(t *testing.T) {
 		{ID: "3", URL: "https://test3.ru", UserID: user2.ID, IsDeleted: false},
 	}
 
-	actualURLs, err := r.GetOrCreateURLs(testURLs)
+	actualURLs, err := r.GetOrCreateURLs(context.Background(), testURLs)
 	require.NoError(t, err)
+	resetCreatedAt(t, actualURLs...)
 	assert.Equal(t, testURLs, actualURLs)
 
-	userURLs, err := r.GetUserURLs(user.ID)
+	userURLs, err := r.GetUserURLs(context.Background(), user.ID, &app.UserURLsFilter{})
 	require.NoError(t, err)
+	resetCreatedAt(t, userURLs...)
 	assert.Equal(t, testURLs[:2], userURLs)
 
-	user2URLs, err := r.GetUserURLs(user2.ID)
+	user2URLs, err := r.GetUserURLs(context.Background(), user2.ID, &app.UserURLsFilter{})
 	require.NoError(t, err)
+	resetCreatedAt(t, user2URLs...)
 	assert.Equal(t, testURLs[2:], user2URLs)
+
+	err = r.DeleteUserURLs(context.Background(), testURLs[:1])
+	require.NoError(t, err)
+
+	userURLs, err = r.GetUserURLs(context.Background(), user.ID, &app.UserURLsFilter{})
+	require.NoError(t, err)
+	require.Len(t, userURLs, 1)
+	assert.Equal(t, testURLs[1].ID, userURLs[0].ID)
+
+	userURLs, err = r.GetUserURLs(context.Background(), user.ID, &app.UserURLsFilter{Limit: 1, IncludeDeleted: true, Desc: true})
+	require.NoError(t, err)
+	require.Len(t, userURLs, 1)
+	assert.Equal(t, testURLs[1].ID, userURLs[0].ID)
+
+	userURLs, err = r.GetUserURLs(context.Background(), user.ID, &app.UserURLsFilter{
+		After:          &app.URLCursor{CreatedAt: userURLs[0].CreatedAt, ID: userURLs[0].ID},
+		IncludeDeleted: true,
+		Desc:           true,
+	})
+	require.NoError(t, err)
+	require.Len(t, userURLs, 1)
+	assert.Equal(t, testURLs[0].ID, userURLs[0].ID)
+	assert.True(t, userURLs[0].IsDeleted)
+
+	userURLs, err = r.GetUserURLs(context.Background(), user.ID, &app.UserURLsFilter{Search: "test2"})
+	require.NoError(t, err)
+	require.Len(t, userURLs, 1)
+	assert.Equal(t, testURLs[1].ID, userURLs[0].ID)
 }
 
 func TestAppRepoPostgres_DeleteUserURLs(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	r, err := NewAppRepoPostgres(te.DB)
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB)
+	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB, TestQueryTimeout)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
-	user, err := ur.CreateUser()
+	user, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
 	testURLs := []*app.URL{
@@ -290,29 +345,230 @@ func TestAppRepoPostgres_DeleteUserURLs(t *testing.T) {
 		{ID: "2", URL: "https://test2.ru", UserID: user.ID, IsDeleted: false},
 	}
 
-	actualURLs, err := r.GetOrCreateURLs(testURLs)
+	actualURLs, err := r.GetOrCreateURLs(context.Background(), testURLs)
 	require.NoError(t, err)
+	resetCreatedAt(t, actualURLs...)
 	assert.Equal(t, testURLs, actualURLs)
 
-	err = r.DeleteUserURLs(testURLs[:1])
+	err = r.DeleteUserURLs(context.Background(), testURLs[:1])
+	require.NoError(t, err)
+
+	u, err := r.GetURL(context.Background(), testURLs[0].ID)
+	require.NoError(t, err)
+	assert.True(t, u.IsDeleted)
+
+	u, err = r.GetURL(context.Background(), testURLs[1].ID)
+	require.NoError(t, err)
+	assert.False(t, u.IsDeleted)
+}
+
+func TestAppRepoPostgres_DeleteExpiredURLs(t *testing.T) {
+	te := newTestEnvironment(DSN, t)
+	defer te.clean()
+
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
+	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
+
+	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB, TestQueryTimeout)
+	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
+
+	user, err := ur.CreateUser(context.Background())
 	require.NoError(t, err)
 
-	u, err := r.GetURL(testURLs[0].ID)
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Hour)
+
+	_, err = r.GetOrCreateURL(context.Background(), "1", "https://test.ru", user.ID, &past)
+	require.NoError(t, err)
+	_, err = r.GetOrCreateURL(context.Background(), "2", "https://test2.ru", user.ID, &future)
+	require.NoError(t, err)
+
+	err = r.DeleteExpiredURLs(context.Background(), now)
+	require.NoError(t, err)
+
+	u, err := r.GetURL(context.Background(), "1")
 	require.NoError(t, err)
 	assert.True(t, u.IsDeleted)
 
-	u, err = r.GetURL(testURLs[1].ID)
+	u, err = r.GetURL(context.Background(), "2")
 	require.NoError(t, err)
 	assert.False(t, u.IsDeleted)
+	require.NotNil(t, u.ExpiresAt)
+	assert.True(t, future.Round(time.Microsecond).Equal(*u.ExpiresAt))
+}
+
+func TestAppRepoPostgres_AddURLsClicks(t *testing.T) {
+	te := newTestEnvironment(DSN, t)
+	defer te.clean()
+
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
+	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
+
+	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB, TestQueryTimeout)
+	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
+
+	user, err := ur.CreateUser(context.Background())
+	require.NoError(t, err)
+
+	_, err = r.GetOrCreateURL(context.Background(), "1", "https://test.ru", user.ID, nil)
+	require.NoError(t, err)
+
+	lastAccessedAt := time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	err = r.AddURLsClicks(context.Background(), []*app.URLClicks{
+		{ID: "1", Count: 2, LastAccessedAt: lastAccessedAt},
+		{ID: "2", Count: 1, LastAccessedAt: lastAccessedAt},
+	})
+	require.NoError(t, err)
+	err = r.AddURLsClicks(context.Background(), []*app.URLClicks{
+		{ID: "1", Count: 3, LastAccessedAt: lastAccessedAt.Add(-time.Hour)},
+	})
+	require.NoError(t, err)
+
+	u, err := r.GetURL(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), u.Clicks)
+	require.NotNil(t, u.LastAccessedAt)
+	assert.True(t, lastAccessedAt.Equal(*u.LastAccessedAt))
+}
+
+func TestAppRepoPostgres_CountURLs(t *testing.T) {
+	te := newTestEnvironment(DSN, t)
+	defer te.clean()
+
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
+	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
+
+	ur, err := userRepoInternal.NewUserRepoPostgres(te.DB, TestQueryTimeout)
+	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
+
+	user, err := ur.CreateUser(context.Background())
+	require.NoError(t, err)
+
+	_, err = r.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "1", URL: "https://test.ru", UserID: user.ID},
+		{ID: "2", URL: "https://test2.ru", UserID: user.ID},
+	})
+	require.NoError(t, err)
+
+	count, err := r.CountURLs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
+func TestAppRepoPostgres_DeletionJobs(t *testing.T) {
+	te := newTestEnvironment(DSN, t)
+	defer te.clean()
+
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
+	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
+
+	now := time.Date(2024, 10, 16, 15, 0, 0, 0, time.UTC)
+
+	jobs := []*app.DeletionJob{
+		{ID: "1", UserID: 1, URLIDs: []string{"a", "b"}, Status: app.DeletionJobPending, NextAttemptAt: now, CreatedAt: now},
+		{ID: "2", UserID: 1, URLIDs: []string{"c"}, Status: app.DeletionJobPending, NextAttemptAt: now.Add(-time.Second), CreatedAt: now},
+		{ID: "3", UserID: 2, URLIDs: []string{"d"}, Status: app.DeletionJobPending, NextAttemptAt: now.Add(time.Second), CreatedAt: now},
+	}
+	for _, job := range jobs {
+		err = r.AddDeletionJob(context.Background(), job)
+		require.NoError(t, err)
+	}
+
+	dueJobs, err := r.GetDueDeletionJobs(context.Background(), now, 0)
+	require.NoError(t, err)
+	require.Len(t, dueJobs, 2)
+	assert.Equal(t, "2", dueJobs[0].ID)
+	assert.Equal(t, "1", dueJobs[1].ID)
+	assert.Equal(t, []string{"a", "b"}, dueJobs[1].URLIDs)
+
+	dueJobs, err = r.GetDueDeletionJobs(context.Background(), now, 1)
+	require.NoError(t, err)
+	require.Len(t, dueJobs, 1)
+	assert.Equal(t, "2", dueJobs[0].ID)
+
+	dueJobs[0].Status = app.DeletionJobDone
+	err = r.UpdateDeletionJob(context.Background(), dueJobs[0])
+	require.NoError(t, err)
+
+	jobs[0].Attempts = 1
+	jobs[0].LastError = "test error"
+	jobs[0].NextAttemptAt = now.Add(time.Minute)
+	err = r.UpdateDeletionJob(context.Background(), jobs[0])
+	require.NoError(t, err)
+
+	err = r.UpdateDeletionJob(context.Background(), &app.DeletionJob{ID: "unknown"})
+	assert.ErrorIs(t, err, app.ErrDeletionJobNotFound)
+
+	job, err := r.GetDeletionJob(context.Background(), "2")
+	require.NoError(t, err)
+	assert.Equal(t, app.DeletionJobDone, job.Status)
+	assert.Equal(t, []string{"c"}, job.URLIDs)
+
+	_, err = r.GetDeletionJob(context.Background(), "unknown")
+	assert.ErrorIs(t, err, app.ErrDeletionJobNotFound)
+
+	count, err := r.CountPendingDeletionJobs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+
+	dueJobs, err = r.GetDueDeletionJobs(context.Background(), now.Add(time.Minute), 0)
+	require.NoError(t, err)
+	require.Len(t, dueJobs, 2)
+	assert.Equal(t, "3", dueJobs[0].ID)
+	assert.Equal(t, "1", dueJobs[1].ID)
+	assert.Equal(t, uint(1), dueJobs[1].Attempts)
+	assert.Equal(t, "test error", dueJobs[1].LastError)
 }
 
 func TestAppRepoPostgres_Close(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	r, err := NewAppRepoPostgres(te.DB)
+	r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, app.URLUniquenessGlobal)
 	require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
 
 	err = r.Close()
 	assert.NoError(t, err)
 }
+
+func TestAppRepoPostgres_Conformance(t *testing.T) {
+	repotest.RunAppRepoSuite(t, newPostgresConformanceRepo(app.URLUniquenessGlobal))
+}
+
+func TestAppRepoPostgres_Conformance_UserScoped(t *testing.T) {
+	repotest.RunAppRepoUserScopedSuite(t, newPostgresConformanceRepo(app.URLUniquenessUser))
+}
+
+// URLs saved in different scopes of uniqueness are in the same table, so mode of app can be changed.
+func TestAppRepoPostgres_ChangeURLUniqueness(t *testing.T) {
+	repotest.RunAppRepoChangeURLUniquenessSuite(t, func(t *testing.T, countUsers int) (usecase.AppRepoInterface, usecase.AppRepoInterface, []uint) {
+		globalRepo, userIDs := newPostgresConformanceRepo(app.URLUniquenessGlobal)(t, countUsers)
+		userRepo, err := NewAppRepoPostgres(globalRepo.(*AppRepoPostgres).db, TestQueryTimeout, app.URLUniquenessUser)
+		require.NoError(t, err)
+		return globalRepo, userRepo, userIDs
+	})
+}
+
+// newPostgresConformanceRepo returns func which creates app repo with urlUniqueness for conformance suite.
+func newPostgresConformanceRepo(urlUniqueness string) repotest.NewAppRepoFunc {
+	return func(t *testing.T, countUsers int) (usecase.AppRepoInterface, []uint) {
+		te := newTestEnvironment(DSN, t)
+		t.Cleanup(te.clean)
+
+		r, err := NewAppRepoPostgres(te.DB, TestQueryTimeout, urlUniqueness)
+		require.NoError(t, err, "Failed to run NewAppRepoPostgres()")
+
+		ur, err := userRepoInternal.NewUserRepoPostgres(te.DB, TestQueryTimeout)
+		require.NoError(t, err, "Failed to run NewUserRepoPostgres()")
+
+		userIDs := make([]uint, 0, countUsers)
+		for i := 0; i < countUsers; i++ {
+			user, err := ur.CreateUser(context.Background())
+			require.NoError(t, err)
+			userIDs = append(userIDs, user.ID)
+		}
+		return r, userIDs
+	}
+}
@@ -1,28 +1,49 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"maps"
+	"strconv"
+	"time"
 
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
 	"github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
+	"github.com/MisterMaks/go-yandex-shortener/internal/database"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 )
 
 // NewAppRepo init repo.
+// Repo is in-memory with file storage if db is nil, otherwise repo is selected by DB driver.
+// Original URL is shortened once in scope of urlUniqueness: for all users or for every user.
 func NewAppRepo(
 	db *sql.DB,
+	driver string,
+	queryTimeout time.Duration,
 	filename string,
 	deletedURLsFilename string,
+	clicksFilename string,
+	deletionJobsFilename string,
+	syncPolicy filestorage.SyncPolicy,
+	urlUniqueness string,
 ) (usecase.AppRepoInterface, error) {
 	var appRepo usecase.AppRepoInterface
 	var err error
 
-	switch db {
-	case nil:
-		appRepo, err = NewAppRepoInmem(filename, deletedURLsFilename)
+	switch {
+	case db == nil:
+		appRepo, err = NewAppRepoInmem(filename, deletedURLsFilename, clicksFilename, deletionJobsFilename, syncPolicy, urlUniqueness)
+		if err != nil {
+			return nil, err
+		}
+	case driver == database.DriverSQLite:
+		appRepo, err = NewAppRepoSQLite(db, queryTimeout, urlUniqueness)
 		if err != nil {
 			return nil, err
 		}
 	default:
-		appRepo, err = NewAppRepoPostgres(db)
+		appRepo, err = NewAppRepoPostgres(db, queryTimeout, urlUniqueness)
 		if err != nil {
 			return nil, err
 		}
@@ -30,3 +51,151 @@ func NewAppRepo(
 
 	return appRepo, nil
 }
+
+// urlKey returns key of original URL which is unique in repo.
+// Original URL of user scoped repo is unique only among URLs of its user.
+func urlKey(userScoped bool, userID uint, rawURL string) string {
+	if !userScoped {
+		return rawURL
+	}
+	return strconv.FormatUint(uint64(userID), 10) + " " + rawURL
+}
+
+// urlConflictTarget returns conflict target of inserted URL for the same SQL in Postgres and SQLite.
+// Original URL of URL saved by user scoped repo is unique only among URLs of its user,
+// otherwise it is unique among URLs which are not user scoped.
+func urlConflictTarget(userScoped bool) string {
+	if userScoped {
+		return "(user_id, url)"
+	}
+	return "(url) WHERE NOT user_scoped"
+}
+
+// uniqueURLs returns URLs of batch without repeated original URLs, the first URL wins.
+// DB can not insert or update the same row twice in one query.
+func uniqueURLs(urls []*app.URL, userScoped bool) []*app.URL {
+	seen := make(map[string]struct{}, len(urls))
+	unique := make([]*app.URL, 0, len(urls))
+	for _, url := range urls {
+		key := urlKey(userScoped, url.UserID, url.URL)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		unique = append(unique, url)
+	}
+	return unique
+}
+
+// orderURLs returns copy of saved URL for every URL of batch in the same order.
+// savedURLs are indexed by urlKey.
+func orderURLs(urls []*app.URL, savedURLs map[string]*app.URL, userScoped bool) ([]*app.URL, error) {
+	ordered := make([]*app.URL, 0, len(urls))
+	for _, url := range urls {
+		savedURL, ok := savedURLs[urlKey(userScoped, url.UserID, url.URL)]
+		if !ok {
+			return nil, ErrURLNotFound
+		}
+		orderedURL := *savedURL
+		ordered = append(ordered, &orderedURL)
+	}
+	return ordered, nil
+}
+
+// groupURLEdits returns changes of original URLs by URL ID in the same order.
+func groupURLEdits(edits []*app.URLEdit) map[string][]*app.URLEdit {
+	grouped := make(map[string][]*app.URLEdit)
+	for _, edit := range edits {
+		grouped[edit.URLID] = append(grouped[edit.URLID], edit)
+	}
+	return grouped
+}
+
+// queryURLIDs returns set of URL IDs selected by query in transaction of Postgres or SQLite.
+func queryURLIDs(ctx context.Context, tx *sql.Tx, query string, args []interface{}) (map[string]struct{}, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]struct{}, len(args))
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = struct{}{}
+	}
+	return ids, rows.Err()
+}
+
+// scanURLs reads and closes rows of URLs returned by Postgres or SQLite, saved URLs are indexed by urlKey.
+func scanURLs(rows *sql.Rows, userScoped bool) (map[string]*app.URL, error) {
+	defer rows.Close()
+
+	savedURLs := make(map[string]*app.URL)
+	for rows.Next() {
+		url := &app.URL{}
+		err := rows.Scan(
+			&url.URL, &url.ID, &url.UserID, &url.IsDeleted, &url.DeletedAt, &url.ExpiresAt, &url.CreatedAt, &url.Clicks, &url.LastAccessedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		savedURLs[urlKey(userScoped, url.UserID, url.URL)] = url
+	}
+	return savedURLs, rows.Err()
+}
+
+// queryer runs query in DB or in transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// saveURLsOrGetUserScoped saves unique URLs of batch by save and returns saved URLs indexed by urlKey.
+// Original URL saved by user scoped repo is not conflict target of repo with global uniqueness,
+// so after mode is switched its unique constraint with owner is violated. URLs of this conflict
+// are got by getUserScoped, other URLs are saved again.
+func saveURLsOrGetUserScoped(
+	urls []*app.URL,
+	userScoped bool,
+	save func(urls []*app.URL) (map[string]*app.URL, error),
+	getUserScoped func(urls []*app.URL) (map[string]*app.URL, error),
+) (map[string]*app.URL, error) {
+	savedURLs, err := save(urls)
+	if userScoped || !errors.Is(err, app.ErrURLExists) {
+		return savedURLs, err
+	}
+
+	savedURLs, err = getUserScoped(urls)
+	if err != nil {
+		return nil, err
+	}
+	restURLs := unsavedURLs(urls, savedURLs, userScoped)
+	// conflict is not caused by user scoped URLs
+	if len(restURLs) == len(urls) {
+		return nil, app.ErrURLExists
+	}
+	if len(restURLs) == 0 {
+		return savedURLs, nil
+	}
+
+	restSavedURLs, err := save(restURLs)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(savedURLs, restSavedURLs)
+	return savedURLs, nil
+}
+
+// unsavedURLs returns URLs of batch which are not in savedURLs indexed by urlKey.
+func unsavedURLs(urls []*app.URL, savedURLs map[string]*app.URL, userScoped bool) []*app.URL {
+	unsaved := make([]*app.URL, 0, len(urls))
+	for _, url := range urls {
+		if _, ok := savedURLs[urlKey(userScoped, url.UserID, url.URL)]; !ok {
+			unsaved = append(unsaved, url)
+		}
+	}
+	return unsaved
+}
@@ -1,14 +1,20 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/database"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 )
 
 func TestNewAppRepo(t *testing.T) {
-	r, err := NewAppRepo(nil, "", "")
+	r, err := NewAppRepo(nil, "", 0, "", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 	assert.NoError(t, err)
 	assert.NotNil(t, r)
 
@@ -16,10 +22,32 @@ func TestNewAppRepo(t *testing.T) {
 	assert.True(t, ok)
 
 	db := &sql.DB{}
-	r, err = NewAppRepo(db, "", "")
+	r, err = NewAppRepo(db, database.DriverPostgres, 0, "", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
 	assert.NoError(t, err)
 	assert.NotNil(t, r)
 
 	_, ok = r.(*AppRepoPostgres)
 	assert.True(t, ok)
+
+	r, err = NewAppRepo(db, database.DriverSQLite, 0, "", "", "", "", filestorage.SyncPolicy{}, app.URLUniquenessGlobal)
+	assert.NoError(t, err)
+	assert.NotNil(t, r)
+
+	_, ok = r.(*AppRepoSQLite)
+	assert.True(t, ok)
+}
+
+func TestWithQueryTimeout(t *testing.T) {
+	ctx, cancel := withQueryTimeout(context.Background(), 0)
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	cancel()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	ctx, cancel = withQueryTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.True(t, ok)
+	<-ctx.Done()
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
 }
@@ -0,0 +1,1088 @@
+// Package repotest provides conformance suite for implementations of usecase.AppRepoInterface.
+// Suite codifies behaviour which usecase relies on, so every storage must pass it.
+package repotest
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
+)
+
+// NewAppRepoFunc creates empty storage for one test and countUsers users which can own URLs.
+// Func must release storage with t.Cleanup.
+type NewAppRepoFunc func(t *testing.T, countUsers int) (usecase.AppRepoInterface, []uint)
+
+// RunAppRepoSuite runs conformance tests of app storage.
+func RunAppRepoSuite(t *testing.T, newRepo NewAppRepoFunc) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, newRepo NewAppRepoFunc)
+	}{
+		{name: "GetOrCreateURL", run: testGetOrCreateURL},
+		{name: "GetURL", run: testGetURL},
+		{name: "GetOrCreateURLs", run: testGetOrCreateURLs},
+		{name: "ImportURLs", run: testImportURLs},
+		{name: "GetUserURLs", run: testGetUserURLs},
+		{name: "DeleteUserURLs", run: testDeleteUserURLs},
+		{name: "DeleteExpiredURLs", run: testDeleteExpiredURLs},
+		{name: "GetUserURLIDs", run: testGetUserURLIDs},
+		{name: "RestoreUserURLs", run: testRestoreUserURLs},
+		{name: "PurgeDeletedURLs", run: testPurgeDeletedURLs},
+		{name: "UpdateURL", run: testUpdateURL},
+		{name: "AddURLsClicks", run: testAddURLsClicks},
+		{name: "ReturnedURLsAreCopies", run: testReturnedURLsAreCopies},
+		{name: "DeletionJobs", run: testDeletionJobs},
+		{name: "PurgeDeletionJobs", run: testPurgeDeletionJobs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo)
+		})
+	}
+}
+
+// RunAppRepoUserScopedSuite runs conformance tests of app storage
+// in which original URL is unique only among URLs of its user.
+func RunAppRepoUserScopedSuite(t *testing.T, newRepo NewAppRepoFunc) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, newRepo NewAppRepoFunc)
+	}{
+		{name: "GetOrCreateURL", run: testUserScopedGetOrCreateURL},
+		{name: "GetOrCreateURLs", run: testUserScopedGetOrCreateURLs},
+		{name: "UpdateURL", run: testUserScopedUpdateURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo)
+		})
+	}
+}
+
+// NewAppReposFunc creates empty storage for one test and countUsers users which can own URLs.
+// Func returns repos of the same storage in which original URL is unique for all users and for every user,
+// so mode of app is switched between them with existing data. Func must release storage with t.Cleanup.
+type NewAppReposFunc func(t *testing.T, countUsers int) (usecase.AppRepoInterface, usecase.AppRepoInterface, []uint)
+
+// RunAppRepoChangeURLUniquenessSuite runs conformance tests of app storage
+// in which scope of unique original URLs is changed with existing data.
+func RunAppRepoChangeURLUniquenessSuite(t *testing.T, newRepos NewAppReposFunc) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, newRepos NewAppReposFunc)
+	}{
+		{name: "GlobalToUser", run: testChangeURLUniquenessGlobalToUser},
+		{name: "UserToGlobal", run: testChangeURLUniquenessUserToGlobal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepos)
+		})
+	}
+}
+
+// assertURL checks stored fields of URL.
+// Times are compared as instants, because storages may return them in different locations.
+func assertURL(t *testing.T, expected, actual *app.URL) {
+	t.Helper()
+
+	require.NotNil(t, actual)
+	assert.Equal(t, expected.ID, actual.ID, "ID")
+	assert.Equal(t, expected.URL, actual.URL, "URL")
+	assert.Equal(t, expected.UserID, actual.UserID, "UserID")
+	assert.Equal(t, expected.IsDeleted, actual.IsDeleted, "IsDeleted")
+	assert.Equal(t, expected.Clicks, actual.Clicks, "Clicks")
+	assertTime(t, expected.ExpiresAt, actual.ExpiresAt, "ExpiresAt")
+	assertTime(t, expected.LastAccessedAt, actual.LastAccessedAt, "LastAccessedAt")
+	assert.False(t, actual.CreatedAt.IsZero(), "CreatedAt is not set")
+}
+
+// assertTime checks that optional times are equal instants.
+func assertTime(t *testing.T, expected, actual *time.Time, field string) {
+	t.Helper()
+
+	if expected == nil {
+		assert.Nil(t, actual, field)
+		return
+	}
+	if assert.NotNil(t, actual, field) {
+		assert.True(t, expected.Equal(*actual), "%s: expected %s, actual %s", field, expected, actual)
+	}
+}
+
+// urlIDs returns IDs of URLs in the same order.
+func urlIDs(urls []*app.URL) []string {
+	ids := make([]string, 0, len(urls))
+	for _, url := range urls {
+		ids = append(ids, url.ID)
+	}
+	return ids
+}
+
+// mustCreateURL creates URL and fails test on error.
+func mustCreateURL(t *testing.T, r usecase.AppRepoInterface, id, rawURL string, userID uint, expiresAt *time.Time) *app.URL {
+	t.Helper()
+
+	url, err := r.GetOrCreateURL(context.Background(), id, rawURL, userID, expiresAt)
+	require.NoError(t, err)
+	require.Equal(t, id, url.ID)
+	return url
+}
+
+func testGetOrCreateURL(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	url, err := r.GetOrCreateURL(ctx, "a1", "https://a.ru", userIDs[0], &expiresAt)
+	require.NoError(t, err)
+	expected := &app.URL{ID: "a1", URL: "https://a.ru", UserID: userIDs[0], ExpiresAt: &expiresAt}
+	assertURL(t, expected, url)
+
+	// saved URL keeps its ID, owner and expiration time
+	url, err = r.GetOrCreateURL(ctx, "b1", "https://a.ru", userIDs[1], nil)
+	require.NoError(t, err)
+	assertURL(t, expected, url)
+
+	// saved URL is returned even if new ID is used by another URL
+	mustCreateURL(t, r, "c1", "https://c.ru", userIDs[1], nil)
+	url, err = r.GetOrCreateURL(ctx, "c1", "https://a.ru", userIDs[1], nil)
+	require.NoError(t, err)
+	assertURL(t, expected, url)
+
+	_, err = r.GetOrCreateURL(ctx, "a1", "https://d.ru", userIDs[1], nil)
+	require.ErrorIs(t, err, app.ErrURLIDExists)
+
+	count, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
+func testGetURL(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 1)
+
+	created := mustCreateURL(t, r, "a1", "https://a.ru", userIDs[0], nil)
+
+	url, err := r.GetURL(ctx, "a1")
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "a1", URL: "https://a.ru", UserID: userIDs[0]}, url)
+	assert.True(t, created.CreatedAt.Equal(url.CreatedAt))
+
+	_, err = r.GetURL(ctx, "unknown")
+	require.ErrorIs(t, err, app.ErrURLNotFound)
+
+	exists, err := r.CheckIDExistence(ctx, "a1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = r.CheckIDExistence(ctx, "unknown")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func testGetOrCreateURLs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	mustCreateURL(t, r, "e1", "https://existing.ru", userIDs[1], nil)
+
+	input := []*app.URL{
+		{ID: "n1", URL: "https://new1.ru", UserID: userIDs[0], ExpiresAt: &expiresAt},
+		{ID: "n2", URL: "https://existing.ru", UserID: userIDs[0]},
+		{ID: "n3", URL: "https://new3.ru", UserID: userIDs[0]},
+		{ID: "n4", URL: "https://new1.ru", UserID: userIDs[0]}, // duplicate of first URL in batch
+	}
+	inputCopy := make([]app.URL, 0, len(input))
+	for _, url := range input {
+		inputCopy = append(inputCopy, *url)
+	}
+
+	// result contains URL for every input URL in the same order
+	urls, err := r.GetOrCreateURLs(ctx, input)
+	require.NoError(t, err)
+	require.Len(t, urls, len(input))
+	assertURL(t, &app.URL{ID: "n1", URL: "https://new1.ru", UserID: userIDs[0], ExpiresAt: &expiresAt}, urls[0])
+	assertURL(t, &app.URL{ID: "e1", URL: "https://existing.ru", UserID: userIDs[1]}, urls[1])
+	assertURL(t, &app.URL{ID: "n3", URL: "https://new3.ru", UserID: userIDs[0]}, urls[2])
+	assertURL(t, &app.URL{ID: "n1", URL: "https://new1.ru", UserID: userIDs[0], ExpiresAt: &expiresAt}, urls[3])
+
+	// input URLs are not mutated
+	for i, url := range input {
+		assert.Equal(t, inputCopy[i], *url)
+	}
+
+	count, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), count)
+
+	// nothing is saved if ID of any new URL is used
+	_, err = r.GetOrCreateURLs(ctx, []*app.URL{
+		{ID: "n5", URL: "https://new5.ru", UserID: userIDs[0]},
+		{ID: "e1", URL: "https://new6.ru", UserID: userIDs[0]},
+	})
+	require.ErrorIs(t, err, app.ErrURLIDExists)
+
+	_, err = r.GetOrCreateURLs(ctx, []*app.URL{
+		{ID: "n7", URL: "https://new7.ru", UserID: userIDs[0]},
+		{ID: "n7", URL: "https://new8.ru", UserID: userIDs[0]},
+	})
+	require.ErrorIs(t, err, app.ErrURLIDExists)
+
+	count, err = r.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), count)
+
+	urls, err = r.GetOrCreateURLs(ctx, []*app.URL{})
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func testImportURLs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+	createdAt := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	deletedAt := createdAt.Add(24 * time.Hour)
+	lastAccessedAt := createdAt.Add(time.Hour)
+	editedAt := createdAt.Add(time.Minute)
+
+	mustCreateURL(t, r, "e1", "https://existing.ru", userIDs[1], nil)
+
+	input := []*app.URL{
+		{ID: "i1", URL: "https://imported2.ru", UserID: userIDs[0], CreatedAt: createdAt, Clicks: 3, LastAccessedAt: &lastAccessedAt},
+		{ID: "i2", URL: "https://deleted.ru", UserID: userIDs[0], CreatedAt: createdAt, IsDeleted: true, DeletedAt: &deletedAt},
+		{ID: "i3", URL: "https://existing.ru", UserID: userIDs[0], CreatedAt: createdAt},
+	}
+	edits := []*app.URLEdit{
+		{URLID: "i1", OldURL: "https://imported.ru", NewURL: "https://imported1.ru", EditedAt: editedAt},
+		{URLID: "i1", OldURL: "https://imported1.ru", NewURL: "https://imported2.ru", EditedAt: editedAt.Add(time.Second)},
+		{URLID: "i3", OldURL: "https://old.ru", NewURL: "https://existing.ru", EditedAt: editedAt},
+	}
+
+	urls, err := r.ImportURLs(ctx, input, edits)
+	require.NoError(t, err)
+	require.Len(t, urls, len(input))
+	assertURL(t, input[0], urls[0])
+	assertURL(t, input[1], urls[1])
+	assertURL(t, &app.URL{ID: "e1", URL: "https://existing.ru", UserID: userIDs[1]}, urls[2])
+
+	// all fields of new URLs are saved
+	for _, expected := range input[:2] {
+		url, err := r.GetURL(ctx, expected.ID)
+		require.NoError(t, err)
+		assertURL(t, expected, url)
+		assert.True(t, expected.CreatedAt.Equal(url.CreatedAt), "CreatedAt: expected %s, actual %s", expected.CreatedAt, url.CreatedAt)
+		assertTime(t, expected.DeletedAt, url.DeletedAt, "DeletedAt")
+	}
+
+	// history is saved as is, original URL is not changed by it
+	urlEdits, err := r.GetURLEdits(ctx, "i1")
+	require.NoError(t, err)
+	require.Len(t, urlEdits, 2)
+	for i, edit := range urlEdits {
+		assert.Equal(t, edits[i].URLID, edit.URLID)
+		assert.Equal(t, edits[i].OldURL, edit.OldURL)
+		assert.Equal(t, edits[i].NewURL, edit.NewURL)
+		assertTime(t, &edits[i].EditedAt, &edit.EditedAt, "EditedAt")
+	}
+
+	// history of URL which is not saved is skipped
+	urlEdits, err = r.GetURLEdits(ctx, "i3")
+	require.NoError(t, err)
+	assert.Empty(t, urlEdits)
+	urlEdits, err = r.GetURLEdits(ctx, "e1")
+	require.NoError(t, err)
+	assert.Empty(t, urlEdits)
+
+	// imported URLs are skipped on repeated import, their history is not saved twice
+	urls, err = r.ImportURLs(ctx, input[:1], edits[:2])
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assertURL(t, input[0], urls[0])
+	urlEdits, err = r.GetURLEdits(ctx, "i1")
+	require.NoError(t, err)
+	assert.Len(t, urlEdits, 2)
+
+	// imported URL can be changed after its history
+	url, err := r.UpdateURL(ctx, "i1", userIDs[0], "https://imported3.ru", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "https://imported3.ru", url.URL)
+	urlEdits, err = r.GetURLEdits(ctx, "i1")
+	require.NoError(t, err)
+	require.Len(t, urlEdits, 3)
+	assert.Equal(t, "https://imported2.ru", urlEdits[2].OldURL)
+
+	// nothing is saved if ID of any new URL is used
+	_, err = r.ImportURLs(ctx, []*app.URL{
+		{ID: "i4", URL: "https://new4.ru", UserID: userIDs[0]},
+		{ID: "e1", URL: "https://new5.ru", UserID: userIDs[0]},
+	}, nil)
+	require.ErrorIs(t, err, app.ErrURLIDExists)
+	_, err = r.GetURL(ctx, "i4")
+	require.ErrorIs(t, err, app.ErrURLNotFound)
+
+	// URL without creation time gets current time
+	urls, err = r.ImportURLs(ctx, []*app.URL{{ID: "i5", URL: "https://new5.ru", UserID: userIDs[0]}}, nil)
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "i5", URL: "https://new5.ru", UserID: userIDs[0]}, urls[0])
+
+	urls, err = r.ImportURLs(ctx, []*app.URL{}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func testGetUserURLs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+
+	// URLs are created one by one, so their order by creation time is the order of IDs
+	u1 := mustCreateURL(t, r, "u1", "https://a.ru/1", userIDs[0], nil)
+	mustCreateURL(t, r, "u2", "https://b.ru/2", userIDs[0], nil)
+	u3 := mustCreateURL(t, r, "u3", "https://a.ru/3", userIDs[0], nil)
+	mustCreateURL(t, r, "o1", "https://a.ru/other", userIDs[1], nil)
+
+	err := r.DeleteUserURLs(ctx, []*app.URL{{ID: "u2", UserID: userIDs[0]}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		userID uint
+		filter *app.UserURLsFilter
+		want   []string
+	}{
+		{
+			name:   "deleted URLs are hidden",
+			userID: userIDs[0],
+			filter: &app.UserURLsFilter{},
+			want:   []string{"u1", "u3"},
+		},
+		{
+			name:   "include deleted",
+			userID: userIDs[0],
+			filter: &app.UserURLsFilter{IncludeDeleted: true},
+			want:   []string{"u1", "u2", "u3"},
+		},
+		{
+			name:   "desc",
+			userID: userIDs[0],
+			filter: &app.UserURLsFilter{Desc: true, IncludeDeleted: true},
+			want:   []string{"u3", "u2", "u1"},
+		},
+		{
+			name:   "search",
+			userID: userIDs[0],
+			filter: &app.UserURLsFilter{Search: "b.ru", IncludeDeleted: true},
+			want:   []string{"u2"},
+		},
+		{
+			name:   "limit",
+			userID: userIDs[0],
+			filter: &app.UserURLsFilter{Limit: 1},
+			want:   []string{"u1"},
+		},
+		{
+			name:   "after cursor",
+			userID: userIDs[0],
+			filter: &app.UserURLsFilter{After: &app.URLCursor{CreatedAt: u1.CreatedAt, ID: u1.ID}},
+			want:   []string{"u3"},
+		},
+		{
+			name:   "after cursor desc",
+			userID: userIDs[0],
+			filter: &app.UserURLsFilter{After: &app.URLCursor{CreatedAt: u3.CreatedAt, ID: u3.ID}, Desc: true},
+			want:   []string{"u1"},
+		},
+		{
+			name:   "another user",
+			userID: userIDs[1],
+			filter: &app.UserURLsFilter{},
+			want:   []string{"o1"},
+		},
+		{
+			name:   "user without URLs",
+			userID: userIDs[1] + 1000,
+			filter: &app.UserURLsFilter{},
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := r.GetUserURLs(ctx, tt.userID, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, urlIDs(urls))
+		})
+	}
+}
+
+func testDeleteUserURLs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+
+	mustCreateURL(t, r, "u1", "https://a.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u2", "https://b.ru", userIDs[1], nil)
+
+	// URLs of another user and unknown URLs are ignored
+	urlsToDelete := []*app.URL{
+		{ID: "u1", UserID: userIDs[0]},
+		{ID: "u2", UserID: userIDs[0]},
+		{ID: "unknown", UserID: userIDs[0]},
+	}
+	err := r.DeleteUserURLs(ctx, urlsToDelete)
+	require.NoError(t, err)
+	// deleting is idempotent
+	err = r.DeleteUserURLs(ctx, urlsToDelete)
+	require.NoError(t, err)
+	err = r.DeleteUserURLs(ctx, []*app.URL{})
+	require.NoError(t, err)
+
+	// URL is deleted softly: it is still found by ID
+	url, err := r.GetURL(ctx, "u1")
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u1", URL: "https://a.ru", UserID: userIDs[0], IsDeleted: true}, url)
+
+	url, err = r.GetURL(ctx, "u2")
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u2", URL: "https://b.ru", UserID: userIDs[1]}, url)
+
+	// deleted URL keeps its ID and original URL
+	url, err = r.GetOrCreateURL(ctx, "u3", "https://a.ru", userIDs[1], nil)
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u1", URL: "https://a.ru", UserID: userIDs[0], IsDeleted: true}, url)
+
+	_, err = r.GetOrCreateURL(ctx, "u1", "https://c.ru", userIDs[1], nil)
+	require.ErrorIs(t, err, app.ErrURLIDExists)
+
+	count, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
+func testDeleteExpiredURLs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 1)
+	now := time.Now().Truncate(time.Second)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	mustCreateURL(t, r, "past", "https://past.ru", userIDs[0], &past)
+	mustCreateURL(t, r, "now", "https://now.ru", userIDs[0], &now)
+	mustCreateURL(t, r, "future", "https://future.ru", userIDs[0], &future)
+	mustCreateURL(t, r, "never", "https://never.ru", userIDs[0], nil)
+
+	err := r.DeleteExpiredURLs(ctx, now)
+	require.NoError(t, err)
+
+	// URL expires at the moment of its expiration time
+	want := map[string]bool{"past": true, "now": true, "future": false, "never": false}
+	for id, isDeleted := range want {
+		url, err := r.GetURL(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, isDeleted, url.IsDeleted, id)
+	}
+}
+
+func testGetUserURLIDs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+
+	mustCreateURL(t, r, "u1", "https://a.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u2", "https://b.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u3", "https://c.ru", userIDs[1], nil)
+	err := r.DeleteUserURLs(ctx, []*app.URL{{ID: "u2", UserID: userIDs[0]}})
+	require.NoError(t, err)
+
+	// deleted URL still belongs to user
+	ids, err := r.GetUserURLIDs(ctx, userIDs[0], []string{"u1", "u2", "u3", "unknown"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"u1", "u2"}, ids)
+
+	ids, err = r.GetUserURLIDs(ctx, userIDs[0], []string{})
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func testRestoreUserURLs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+	now := time.Now().Truncate(time.Second)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	mustCreateURL(t, r, "u1", "https://a.ru", userIDs[0], &future)
+	mustCreateURL(t, r, "u2", "https://b.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u3", "https://c.ru", userIDs[1], nil)
+	mustCreateURL(t, r, "expired", "https://expired.ru", userIDs[0], &past)
+
+	before := time.Now().Add(-time.Second)
+	err := r.DeleteUserURLs(ctx, []*app.URL{{ID: "u1", UserID: userIDs[0]}, {ID: "u3", UserID: userIDs[1]}})
+	require.NoError(t, err)
+	err = r.DeleteExpiredURLs(ctx, now)
+	require.NoError(t, err)
+
+	// deleted URL has time of deletion
+	url, err := r.GetURL(ctx, "u1")
+	require.NoError(t, err)
+	require.True(t, url.IsDeleted)
+	require.NotNil(t, url.DeletedAt)
+	assert.True(t, url.DeletedAt.After(before), "DeletedAt: %s", url.DeletedAt)
+
+	// not deleted, expired, unknown URLs and URLs of another user are not restored
+	restoredIDs, err := r.RestoreUserURLs(ctx, userIDs[0], []string{"u1", "u2", "u3", "expired", "unknown"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u1"}, restoredIDs)
+
+	url, err = r.GetURL(ctx, "u1")
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u1", URL: "https://a.ru", UserID: userIDs[0], ExpiresAt: &future}, url)
+	assert.Nil(t, url.DeletedAt, "DeletedAt")
+
+	url, err = r.GetURL(ctx, "u3")
+	require.NoError(t, err)
+	assert.True(t, url.IsDeleted)
+
+	url, err = r.GetURL(ctx, "expired")
+	require.NoError(t, err)
+	assert.True(t, url.IsDeleted)
+
+	// restored URL expires again
+	err = r.DeleteExpiredURLs(ctx, future)
+	require.NoError(t, err)
+	url, err = r.GetURL(ctx, "u1")
+	require.NoError(t, err)
+	assert.True(t, url.IsDeleted)
+
+	restoredIDs, err = r.RestoreUserURLs(ctx, userIDs[0], []string{}, now)
+	require.NoError(t, err)
+	assert.Empty(t, restoredIDs)
+}
+
+func testPurgeDeletedURLs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 1)
+
+	mustCreateURL(t, r, "u1", "https://a.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u2", "https://b.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u3", "https://c.ru", userIDs[0], nil)
+
+	before := time.Now().Add(-time.Minute)
+	err := r.DeleteUserURLs(ctx, []*app.URL{{ID: "u1", UserID: userIDs[0]}, {ID: "u2", UserID: userIDs[0]}})
+	require.NoError(t, err)
+
+	// URLs deleted after time are not purged
+	count, err := r.PurgeDeletedURLs(ctx, before)
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), count)
+
+	count, err = r.PurgeDeletedURLs(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+
+	_, err = r.GetURL(ctx, "u1")
+	require.ErrorIs(t, err, app.ErrURLNotFound)
+	url, err := r.GetURL(ctx, "u3")
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u3", URL: "https://c.ru", UserID: userIDs[0]}, url)
+
+	urls, err := r.GetUserURLs(ctx, userIDs[0], &app.UserURLsFilter{IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u3"}, urlIDs(urls))
+
+	countURLs, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), countURLs)
+
+	// ID and original URL of purged URL can be used again
+	mustCreateURL(t, r, "u1", "https://new.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u4", "https://b.ru", userIDs[0], nil)
+
+	restoredIDs, err := r.RestoreUserURLs(ctx, userIDs[0], []string{"u2"}, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, restoredIDs)
+}
+
+func testUpdateURL(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+	editedAt := time.Now().Truncate(time.Second)
+
+	mustCreateURL(t, r, "u1", "https://a.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u2", "https://b.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u3", "https://c.ru", userIDs[1], nil)
+	mustCreateURL(t, r, "deleted", "https://deleted.ru", userIDs[0], nil)
+	err := r.DeleteUserURLs(ctx, []*app.URL{{ID: "deleted", UserID: userIDs[0]}})
+	require.NoError(t, err)
+
+	url, err := r.UpdateURL(ctx, "u1", userIDs[0], "https://a2.ru", editedAt)
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u1", URL: "https://a2.ru", UserID: userIDs[0]}, url)
+
+	url, err = r.UpdateURL(ctx, "u1", userIDs[0], "https://a3.ru", editedAt.Add(time.Second))
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u1", URL: "https://a3.ru", UserID: userIDs[0]}, url)
+
+	// unchanged URL is not saved in history
+	url, err = r.UpdateURL(ctx, "u1", userIDs[0], "https://a3.ru", editedAt.Add(2*time.Second))
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u1", URL: "https://a3.ru", UserID: userIDs[0]}, url)
+
+	url, err = r.GetURL(ctx, "u1")
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u1", URL: "https://a3.ru", UserID: userIDs[0]}, url)
+
+	edits, err := r.GetURLEdits(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, edits, 2)
+	assert.Equal(t, "u1", edits[0].URLID)
+	assert.Equal(t, "https://a.ru", edits[0].OldURL)
+	assert.Equal(t, "https://a2.ru", edits[0].NewURL)
+	assertTime(t, &editedAt, &edits[0].EditedAt, "EditedAt")
+	assert.Equal(t, "https://a2.ru", edits[1].OldURL)
+	assert.Equal(t, "https://a3.ru", edits[1].NewURL)
+
+	edits, err = r.GetURLEdits(ctx, "u2")
+	require.NoError(t, err)
+	assert.Empty(t, edits)
+
+	// original URL can not be changed to URL shortened with another ID, even by another user
+	_, err = r.UpdateURL(ctx, "u2", userIDs[0], "https://c.ru", editedAt)
+	require.ErrorIs(t, err, app.ErrURLExists)
+	url, err = r.GetURL(ctx, "u2")
+	require.NoError(t, err)
+	assert.Equal(t, "https://b.ru", url.URL)
+
+	// unknown, deleted URLs and URLs of another user are not changed
+	for _, id := range []string{"unknown", "deleted", "u3"} {
+		_, err = r.UpdateURL(ctx, id, userIDs[0], "https://new.ru", editedAt)
+		require.ErrorIs(t, err, app.ErrURLNotFound, id)
+	}
+
+	// previous original URL can be shortened again, new original URL is found by its new ID
+	mustCreateURL(t, r, "u4", "https://a.ru", userIDs[1], nil)
+	url, err = r.GetOrCreateURL(ctx, "u5", "https://a3.ru", userIDs[1], nil)
+	require.NoError(t, err)
+	assert.Equal(t, "u1", url.ID)
+
+	// history of purged URL is removed with URL
+	err = r.DeleteUserURLs(ctx, []*app.URL{{ID: "u1", UserID: userIDs[0]}})
+	require.NoError(t, err)
+	_, err = r.PurgeDeletedURLs(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	edits, err = r.GetURLEdits(ctx, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, edits)
+}
+
+func testAddURLsClicks(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 1)
+	now := time.Now().Truncate(time.Second)
+	earlier := now.Add(-time.Minute)
+
+	mustCreateURL(t, r, "u1", "https://a.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u2", "https://b.ru", userIDs[0], nil)
+
+	// clicks of unknown URLs are ignored
+	err := r.AddURLsClicks(ctx, []*app.URLClicks{
+		{ID: "u1", Count: 2, LastAccessedAt: now},
+		{ID: "unknown", Count: 1, LastAccessedAt: now},
+	})
+	require.NoError(t, err)
+
+	// time of last redirect does not go back
+	err = r.AddURLsClicks(ctx, []*app.URLClicks{{ID: "u1", Count: 3, LastAccessedAt: earlier}})
+	require.NoError(t, err)
+
+	err = r.AddURLsClicks(ctx, []*app.URLClicks{})
+	require.NoError(t, err)
+
+	url, err := r.GetURL(ctx, "u1")
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u1", URL: "https://a.ru", UserID: userIDs[0], Clicks: 5, LastAccessedAt: &now}, url)
+
+	url, err = r.GetURL(ctx, "u2")
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u2", URL: "https://b.ru", UserID: userIDs[0]}, url)
+}
+
+func testReturnedURLsAreCopies(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 1)
+	expected := &app.URL{ID: "u1", URL: "https://a.ru", UserID: userIDs[0]}
+
+	mutate := func(url *app.URL) {
+		url.URL = "https://changed.ru"
+		url.IsDeleted = true
+		url.Clicks = 100
+	}
+
+	url, err := r.GetOrCreateURL(ctx, "u1", "https://a.ru", userIDs[0], nil)
+	require.NoError(t, err)
+	mutate(url)
+
+	url, err = r.GetURL(ctx, "u1")
+	require.NoError(t, err)
+	assertURL(t, expected, url)
+	mutate(url)
+
+	urls, err := r.GetOrCreateURLs(ctx, []*app.URL{{ID: "u2", URL: "https://a.ru", UserID: userIDs[0]}})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assertURL(t, expected, urls[0])
+	mutate(urls[0])
+
+	urls, err = r.GetUserURLs(ctx, userIDs[0], &app.UserURLsFilter{})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assertURL(t, expected, urls[0])
+	mutate(urls[0])
+
+	url, err = r.GetURL(ctx, "u1")
+	require.NoError(t, err)
+	assertURL(t, expected, url)
+}
+
+// assertDeletionJob checks stored fields of deletion job.
+func assertDeletionJob(t *testing.T, expected, actual *app.DeletionJob) {
+	t.Helper()
+
+	require.NotNil(t, actual)
+	assert.Equal(t, expected.ID, actual.ID, "ID")
+	assert.Equal(t, expected.UserID, actual.UserID, "UserID")
+	assert.Equal(t, expected.URLIDs, actual.URLIDs, "URLIDs")
+	assert.Equal(t, expected.Status, actual.Status, "Status")
+	assert.Equal(t, expected.Attempts, actual.Attempts, "Attempts")
+	assert.Equal(t, expected.LastError, actual.LastError, "LastError")
+	assertTime(t, &expected.NextAttemptAt, &actual.NextAttemptAt, "NextAttemptAt")
+	assertTime(t, &expected.CreatedAt, &actual.CreatedAt, "CreatedAt")
+	assertTime(t, expected.FinishedAt, actual.FinishedAt, "FinishedAt")
+	assert.Equal(t, expected.IgnoredURLIDs, actual.IgnoredURLIDs, "IgnoredURLIDs")
+}
+
+// deletionJobIDs returns IDs of deletion jobs in the same order.
+func deletionJobIDs(jobs []*app.DeletionJob) []string {
+	ids := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+	return ids
+}
+
+func testDeletionJobs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 1)
+	now := time.Now().Truncate(time.Second)
+
+	newJob := func(id string, status string, nextAttemptAt time.Time) *app.DeletionJob {
+		return &app.DeletionJob{
+			ID:            id,
+			UserID:        userIDs[0],
+			URLIDs:        []string{"u1", "u2"},
+			Status:        status,
+			NextAttemptAt: nextAttemptAt,
+			CreatedAt:     now.Add(-time.Hour),
+		}
+	}
+	jobs := []*app.DeletionJob{
+		newJob("job1", app.DeletionJobPending, now.Add(-time.Minute)),
+		newJob("job2", app.DeletionJobPending, now.Add(-2*time.Minute)),
+		newJob("job3", app.DeletionJobPending, now.Add(time.Hour)),
+		newJob("job4", app.DeletionJobDone, now.Add(-3*time.Minute)),
+		newJob("job0", app.DeletionJobPending, now.Add(-time.Minute)),
+	}
+	for _, job := range jobs {
+		err := r.AddDeletionJob(ctx, job)
+		require.NoError(t, err)
+	}
+
+	// due jobs are sorted by time of next attempt and then by ID
+	dueJobs, err := r.GetDueDeletionJobs(ctx, now, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"job2", "job0", "job1"}, deletionJobIDs(dueJobs))
+
+	dueJobs, err = r.GetDueDeletionJobs(ctx, now, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"job2", "job0"}, deletionJobIDs(dueJobs))
+
+	job, err := r.GetDeletionJob(ctx, "job1")
+	require.NoError(t, err)
+	assertDeletionJob(t, jobs[0], job)
+
+	// returned job is copy
+	job.URLIDs[0] = "changed"
+	job, err = r.GetDeletionJob(ctx, "job1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u1", "u2"}, job.URLIDs)
+
+	_, err = r.GetDeletionJob(ctx, "unknown")
+	require.ErrorIs(t, err, app.ErrDeletionJobNotFound)
+
+	count, err := r.CountPendingDeletionJobs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(4), count)
+
+	updatedJob := *jobs[0]
+	updatedJob.URLIDs = slices.Clone(jobs[0].URLIDs)
+	updatedJob.Status = app.DeletionJobFailed
+	updatedJob.Attempts = 3
+	updatedJob.LastError = "test error"
+	updatedJob.NextAttemptAt = now.Add(time.Minute)
+	updatedJob.FinishedAt = &now
+	updatedJob.IgnoredURLIDs = []string{"u2"}
+	err = r.UpdateDeletionJob(ctx, &updatedJob)
+	require.NoError(t, err)
+
+	job, err = r.GetDeletionJob(ctx, "job1")
+	require.NoError(t, err)
+	assertDeletionJob(t, &updatedJob, job)
+
+	count, err = r.CountPendingDeletionJobs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), count)
+
+	// retried job is due at time of next attempt
+	retriedJob := newJob("job2", app.DeletionJobPending, now.Add(-time.Second))
+	retriedJob.Attempts = 1
+	err = r.UpdateDeletionJob(ctx, retriedJob)
+	require.NoError(t, err)
+
+	dueJobs, err = r.GetDueDeletionJobs(ctx, now, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"job0", "job2"}, deletionJobIDs(dueJobs))
+
+	err = r.UpdateDeletionJob(ctx, newJob("unknown", app.DeletionJobDone, now))
+	require.ErrorIs(t, err, app.ErrDeletionJobNotFound)
+}
+
+func testPurgeDeletionJobs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 1)
+	now := time.Now().Truncate(time.Second)
+
+	newJob := func(id string, status string, finishedAt *time.Time) *app.DeletionJob {
+		return &app.DeletionJob{
+			ID:            id,
+			UserID:        userIDs[0],
+			URLIDs:        []string{"u1"},
+			Status:        status,
+			NextAttemptAt: now.Add(-2 * time.Hour),
+			CreatedAt:     now.Add(-2 * time.Hour),
+			FinishedAt:    finishedAt,
+		}
+	}
+	oldFinishedAt := now.Add(-time.Hour)
+	newFinishedAt := now.Add(-time.Minute)
+	jobs := []*app.DeletionJob{
+		newJob("done_old", app.DeletionJobDone, &oldFinishedAt),
+		newJob("failed_old", app.DeletionJobFailed, &oldFinishedAt),
+		newJob("done_new", app.DeletionJobDone, &newFinishedAt),
+		newJob("pending", app.DeletionJobPending, nil),
+	}
+	for _, job := range jobs {
+		err := r.AddDeletionJob(ctx, job)
+		require.NoError(t, err)
+	}
+
+	count, err := r.PurgeDeletionJobs(ctx, now.Add(-30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+
+	for _, id := range []string{"done_old", "failed_old"} {
+		_, err = r.GetDeletionJob(ctx, id)
+		assert.ErrorIs(t, err, app.ErrDeletionJobNotFound, id)
+	}
+	for _, job := range jobs[2:] {
+		actual, err := r.GetDeletionJob(ctx, job.ID)
+		require.NoError(t, err)
+		assertDeletionJob(t, job, actual)
+	}
+
+	// pending job is not purged however old it is
+	count, err = r.PurgeDeletionJobs(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), count)
+
+	dueJobs, err := r.GetDueDeletionJobs(ctx, now, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pending"}, deletionJobIDs(dueJobs))
+}
+
+func testUserScopedGetOrCreateURL(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+
+	mustCreateURL(t, r, "a1", "https://a.ru", userIDs[0], nil)
+
+	// every user gets own URL, so user can see and delete it
+	mustCreateURL(t, r, "b1", "https://a.ru", userIDs[1], nil)
+	urls, err := r.GetUserURLs(ctx, userIDs[1], &app.UserURLsFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b1"}, urlIDs(urls))
+
+	// saved URL of the same user is returned
+	url, err := r.GetOrCreateURL(ctx, "a2", "https://a.ru", userIDs[0], nil)
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "a1", URL: "https://a.ru", UserID: userIDs[0]}, url)
+
+	_, err = r.GetOrCreateURL(ctx, "a1", "https://d.ru", userIDs[1], nil)
+	require.ErrorIs(t, err, app.ErrURLIDExists)
+
+	// deleted URL of user is still returned, other users are not affected
+	err = r.DeleteUserURLs(ctx, []*app.URL{{ID: "b1", UserID: userIDs[1]}})
+	require.NoError(t, err)
+	url, err = r.GetOrCreateURL(ctx, "b2", "https://a.ru", userIDs[1], nil)
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "b1", URL: "https://a.ru", UserID: userIDs[1], IsDeleted: true}, url)
+	url, err = r.GetURL(ctx, "a1")
+	require.NoError(t, err)
+	assert.False(t, url.IsDeleted)
+
+	// original URL can be shortened again by user after its URL is purged
+	_, err = r.PurgeDeletedURLs(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	mustCreateURL(t, r, "b3", "https://a.ru", userIDs[1], nil)
+
+	count, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
+func testUserScopedGetOrCreateURLs(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+
+	mustCreateURL(t, r, "e1", "https://existing.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "o1", "https://other.ru", userIDs[1], nil)
+
+	urls, err := r.GetOrCreateURLs(ctx, []*app.URL{
+		{ID: "n1", URL: "https://existing.ru", UserID: userIDs[0]},
+		{ID: "n2", URL: "https://other.ru", UserID: userIDs[0]},
+		{ID: "n3", URL: "https://existing.ru", UserID: userIDs[1]},
+		{ID: "n4", URL: "https://other.ru", UserID: userIDs[0]}, // duplicate of URL of the same user in batch
+	})
+	require.NoError(t, err)
+	require.Len(t, urls, 4)
+	assertURL(t, &app.URL{ID: "e1", URL: "https://existing.ru", UserID: userIDs[0]}, urls[0])
+	assertURL(t, &app.URL{ID: "n2", URL: "https://other.ru", UserID: userIDs[0]}, urls[1])
+	assertURL(t, &app.URL{ID: "n3", URL: "https://existing.ru", UserID: userIDs[1]}, urls[2])
+	assertURL(t, &app.URL{ID: "n2", URL: "https://other.ru", UserID: userIDs[0]}, urls[3])
+
+	count, err := r.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(4), count)
+}
+
+func testUserScopedUpdateURL(t *testing.T, newRepo NewAppRepoFunc) {
+	ctx := context.Background()
+	r, userIDs := newRepo(t, 2)
+	editedAt := time.Now().Truncate(time.Second)
+
+	mustCreateURL(t, r, "u1", "https://a.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "u2", "https://b.ru", userIDs[0], nil)
+	mustCreateURL(t, r, "o1", "https://c.ru", userIDs[1], nil)
+
+	// original URL can be changed to URL shortened by another user
+	url, err := r.UpdateURL(ctx, "u1", userIDs[0], "https://c.ru", editedAt)
+	require.NoError(t, err)
+	assertURL(t, &app.URL{ID: "u1", URL: "https://c.ru", UserID: userIDs[0]}, url)
+
+	// but not to URL shortened by the same user with another ID
+	_, err = r.UpdateURL(ctx, "u2", userIDs[0], "https://c.ru", editedAt)
+	require.ErrorIs(t, err, app.ErrURLExists)
+
+	// changed URL is found by original URL only for its user
+	url, err = r.GetOrCreateURL(ctx, "u3", "https://c.ru", userIDs[0], nil)
+	require.NoError(t, err)
+	assert.Equal(t, "u1", url.ID)
+	url, err = r.GetOrCreateURL(ctx, "o2", "https://c.ru", userIDs[1], nil)
+	require.NoError(t, err)
+	assert.Equal(t, "o1", url.ID)
+}
+
+func testChangeURLUniquenessGlobalToUser(t *testing.T, newRepos NewAppReposFunc) {
+	ctx := context.Background()
+	globalRepo, userRepo, userIDs := newRepos(t, 2)
+
+	mustCreateURL(t, globalRepo, "g1", "https://a.ru", userIDs[0], nil)
+
+	// URL saved for all users is returned to its owner and is not returned to another user
+	url, err := userRepo.GetOrCreateURL(ctx, "u1", "https://a.ru", userIDs[0], nil)
+	require.NoError(t, err)
+	assert.Equal(t, "g1", url.ID)
+	url, err = userRepo.GetOrCreateURL(ctx, "u2", "https://a.ru", userIDs[1], nil)
+	require.NoError(t, err)
+	assert.Equal(t, "u2", url.ID)
+
+	urls, err := userRepo.GetOrCreateURLs(ctx, []*app.URL{
+		{ID: "u3", URL: "https://a.ru", UserID: userIDs[0]},
+		{ID: "u4", URL: "https://b.ru", UserID: userIDs[0]},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"g1", "u4"}, urlIDs(urls))
+
+	// URL saved for user is not returned to other users in global scope
+	url, err = globalRepo.GetOrCreateURL(ctx, "g2", "https://a.ru", userIDs[1], nil)
+	require.NoError(t, err)
+	assert.Equal(t, "g1", url.ID)
+}
+
+func testChangeURLUniquenessUserToGlobal(t *testing.T, newRepos NewAppReposFunc) {
+	ctx := context.Background()
+	globalRepo, userRepo, userIDs := newRepos(t, 2)
+
+	mustCreateURL(t, userRepo, "u1", "https://a.ru", userIDs[0], nil)
+	mustCreateURL(t, userRepo, "u2", "https://b.ru", userIDs[0], nil)
+	mustCreateURL(t, userRepo, "u3", "https://c.ru", userIDs[0], nil)
+
+	// URL saved for user is returned to its owner in global scope
+	url, err := globalRepo.GetOrCreateURL(ctx, "g1", "https://a.ru", userIDs[0], nil)
+	require.NoError(t, err)
+	assert.Equal(t, "u1", url.ID)
+
+	urls, err := globalRepo.GetOrCreateURLs(ctx, []*app.URL{
+		{ID: "g2", URL: "https://new.ru", UserID: userIDs[0]},
+		{ID: "g3", URL: "https://b.ru", UserID: userIDs[0]},
+		{ID: "g4", URL: "https://new.ru", UserID: userIDs[0]},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"g2", "u2", "g2"}, urlIDs(urls))
+
+	urls, err = globalRepo.ImportURLs(ctx, []*app.URL{
+		{ID: "g5", URL: "https://c.ru", UserID: userIDs[0]},
+		{ID: "g6", URL: "https://imported.ru", UserID: userIDs[0]},
+	}, []*app.URLEdit{{URLID: "g5", OldURL: "https://old.ru", NewURL: "https://c.ru", EditedAt: time.Now()}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u3", "g6"}, urlIDs(urls))
+	edits, err := globalRepo.GetURLEdits(ctx, "u3")
+	require.NoError(t, err)
+	assert.Empty(t, edits)
+
+	// URL saved for user is not returned to another user, he gets URL for all users
+	url, err = globalRepo.GetOrCreateURL(ctx, "g7", "https://a.ru", userIDs[1], nil)
+	require.NoError(t, err)
+	assert.Equal(t, "g7", url.ID)
+	url, err = globalRepo.GetOrCreateURL(ctx, "g8", "https://a.ru", userIDs[0], nil)
+	require.NoError(t, err)
+	assert.Equal(t, "g7", url.ID)
+
+	// used ID is still reported
+	_, err = globalRepo.GetOrCreateURL(ctx, "u1", "https://d.ru", userIDs[0], nil)
+	require.ErrorIs(t, err, app.ErrURLIDExists)
+	_, err = globalRepo.GetOrCreateURLs(ctx, []*app.URL{{ID: "u2", URL: "https://d.ru", UserID: userIDs[0]}})
+	require.ErrorIs(t, err, app.ErrURLIDExists)
+
+	count, err := globalRepo.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(6), count)
+}
@@ -0,0 +1,887 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteUniqueConstraintPrefix precedes columns of violated unique index in SQLite error message.
+const SQLiteUniqueConstraintPrefix string = "UNIQUE constraint failed: "
+
+// sqliteUniqueIndexes maps columns of unique indexes of url table to names of indexes.
+// SQLite reports columns of violated index instead of its name, names are the same as in Postgres.
+var sqliteUniqueIndexes = map[string]string{
+	"url.url_id":           URLIDConstraintName,
+	"url.url":              URLConstraintName,
+	"url.user_id, url.url": URLUserIDConstraintName,
+}
+
+// convertSQLiteError converts SQLite errors to app errors.
+// Only unique violations with extended error code of url table indexes are converted.
+func convertSQLiteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return err
+	}
+	message := sqliteErr.Error()
+	i := strings.Index(message, SQLiteUniqueConstraintPrefix)
+	if i < 0 {
+		return err
+	}
+	columns := strings.TrimSuffix(message[i+len(SQLiteUniqueConstraintPrefix):], fmt.Sprintf(" (%d)", sqliteErr.Code()))
+	return convertConstraintError(sqliteUniqueIndexes[columns], err)
+}
+
+// startSQLiteQuerySpan creates span of SQLite query executed by repo method operation.
+func startSQLiteQuerySpan(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "AppRepoSQLite."+operation,
+		semconv.DBSystemSqlite,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(query),
+	)
+}
+
+// utcTime converts t to UTC, so times written to SQLite are compared in the same order as times.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// placeholders returns count groups of n SQL placeholders: (?, ?), (?, ?).
+func placeholders(count, n int) string {
+	group := "(" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+	return strings.TrimSuffix(strings.Repeat(group+", ", count), ", ")
+}
+
+// AppRepoSQLite application data storage in embedded SQLite DB.
+// Unique and ownership semantics are the same as in AppRepoPostgres.
+type AppRepoSQLite struct {
+	db           *sql.DB
+	queryTimeout time.Duration // max duration of one query
+	userScoped   bool          // original URL is unique only among URLs of its user
+}
+
+// NewAppRepoSQLite creates *AppRepoSQLite.
+func NewAppRepoSQLite(db *sql.DB, queryTimeout time.Duration, urlUniqueness string) (*AppRepoSQLite, error) {
+	return &AppRepoSQLite{
+		db:           db,
+		queryTimeout: queryTimeout,
+		userScoped:   urlUniqueness == app.URLUniquenessUser,
+	}, nil
+}
+
+// GetOrCreateURL insert new URL in DB or get existed URL.
+func (ars *AppRepoSQLite) GetOrCreateURL(ctx context.Context, id, rawURL string, userID uint, expiresAt *time.Time) (*app.URL, error) {
+	query := `INSERT INTO url (url, url_id, user_id, user_scoped, expires_at, created_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT ` + urlConflictTarget(ars.userScoped) + ` DO UPDATE SET url = excluded.url, user_id = COALESCE(url.user_id, excluded.user_id)
+RETURNING url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at;`
+	url := &app.URL{URL: rawURL}
+	ctx, span := startSQLiteQuerySpan(ctx, "GetOrCreateURL", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	err := ars.db.QueryRowContext(ctx, query, rawURL, id, userID, ars.userScoped, utcTime(expiresAt), time.Now().UTC()).Scan(
+		&url.ID, &url.UserID, &url.IsDeleted, &url.DeletedAt, &url.ExpiresAt, &url.CreatedAt, &url.Clicks, &url.LastAccessedAt,
+	)
+	if err != nil {
+		tracing.RecordError(span, err)
+		err = convertSQLiteError(err)
+	}
+	if errors.Is(err, app.ErrURLExists) && !ars.userScoped {
+		// URL saved by user scoped repo before uniqueness is changed is not conflict target, it is returned to its owner
+		savedURLs, err := ars.getUserScopedURLs(ctx, ars.db, []*app.URL{{URL: rawURL, UserID: userID}})
+		if err != nil {
+			return nil, err
+		}
+		if savedURL, ok := savedURLs[urlKey(ars.userScoped, userID, rawURL)]; ok {
+			return savedURL, nil
+		}
+		return nil, app.ErrURLExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return url, nil
+}
+
+// getUserScopedURLs gets URLs saved by user scoped repo for the same users and original URLs as urls from DB.
+func (ars *AppRepoSQLite) getUserScopedURLs(ctx context.Context, q queryer, urls []*app.URL) (map[string]*app.URL, error) {
+	args := make([]interface{}, 0, len(urls)*2)
+	for _, url := range urls {
+		args = append(args, url.UserID, url.URL)
+	}
+	query := `SELECT url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at
+FROM url WHERE user_scoped AND (user_id, url) IN (VALUES ` + placeholders(len(urls), 2) + `);`
+
+	ctx, span := startSQLiteQuerySpan(ctx, "getUserScopedURLs", query)
+	defer span.End()
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	savedURLs, err := scanURLs(rows, ars.userScoped)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return savedURLs, nil
+}
+
+// GetURL get URL from DB.
+func (ars *AppRepoSQLite) GetURL(ctx context.Context, id string) (*app.URL, error) {
+	query := `SELECT url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at FROM url WHERE url_id = ?;`
+	url := &app.URL{}
+	ctx, span := startSQLiteQuerySpan(ctx, "GetURL", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	err := ars.db.QueryRowContext(ctx, query, id).Scan(
+		&url.URL, &url.ID, &url.UserID, &url.IsDeleted, &url.DeletedAt, &url.ExpiresAt, &url.CreatedAt, &url.Clicks, &url.LastAccessedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, app.ErrURLNotFound
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return url, nil
+}
+
+// CheckIDExistence check ID existence in DB.
+func (ars *AppRepoSQLite) CheckIDExistence(ctx context.Context, id string) (bool, error) {
+	query := `SELECT true FROM url WHERE url_id = ?;`
+	var exists bool
+	ctx, span := startSQLiteQuerySpan(ctx, "CheckIDExistence", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	err := ars.db.QueryRowContext(ctx, query, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// CountURLs returns count of URLs in DB.
+func (ars *AppRepoSQLite) CountURLs(ctx context.Context) (uint, error) {
+	query := `SELECT COUNT(*) FROM url;`
+	var count uint
+	ctx, span := startSQLiteQuerySpan(ctx, "CountURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	err := ars.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// Ping ping DB.
+func (ars *AppRepoSQLite) Ping(ctx context.Context) error {
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	return ars.db.PingContext(ctx)
+}
+
+// GetOrCreateURLs insert batch URLs or get existed URLs from DB.
+// Result contains saved URL for every URL of batch in the same order.
+func (ars *AppRepoSQLite) GetOrCreateURLs(ctx context.Context, urls []*app.URL) ([]*app.URL, error) {
+	if len(urls) == 0 {
+		return []*app.URL{}, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "AppRepoSQLite.GetOrCreateURLs")
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	save := func(urls []*app.URL) (map[string]*app.URL, error) {
+		return ars.saveURLs(ctx, urls)
+	}
+	getUserScoped := func(urls []*app.URL) (map[string]*app.URL, error) {
+		return ars.getUserScopedURLs(ctx, ars.db, urls)
+	}
+
+	savedURLs, err := saveURLsOrGetUserScoped(uniqueURLs(urls, ars.userScoped), ars.userScoped, save, getUserScoped)
+	if err != nil {
+		return nil, err
+	}
+	return orderURLs(urls, savedURLs, ars.userScoped)
+}
+
+// saveURLs inserts new URLs of batch without repeated original URLs in DB or gets existed URLs.
+func (ars *AppRepoSQLite) saveURLs(ctx context.Context, urls []*app.URL) (map[string]*app.URL, error) {
+	now := time.Now().UTC()
+	args := make([]interface{}, 0, len(urls)*6)
+	for _, url := range urls {
+		args = append(args, url.URL, url.ID, url.UserID, ars.userScoped, utcTime(url.ExpiresAt), now)
+	}
+	query := `INSERT INTO url (url, url_id, user_id, user_scoped, expires_at, created_at) VALUES ` + placeholders(len(urls), 6) + `
+ON CONFLICT ` + urlConflictTarget(ars.userScoped) + ` DO UPDATE SET url = excluded.url, user_id = COALESCE(url.user_id, excluded.user_id)
+RETURNING url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at;`
+
+	ctx, span := startSQLiteQuerySpan(ctx, "saveURLs", query)
+	defer span.End()
+
+	rows, err := ars.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, convertSQLiteError(err)
+	}
+	savedURLs, err := scanURLs(rows, ars.userScoped)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, convertSQLiteError(err)
+	}
+	return savedURLs, nil
+}
+
+// ImportURLs gets saved or saves URLs with all their fields like GetOrCreateURLs in DB.
+// History of changes of original URL from edits is saved only for new URLs.
+func (ars *AppRepoSQLite) ImportURLs(ctx context.Context, urls []*app.URL, edits []*app.URLEdit) ([]*app.URL, error) {
+	if len(urls) == 0 {
+		return []*app.URL{}, nil
+	}
+
+	newURLs := uniqueURLs(urls, ars.userScoped)
+	selectArgs := make([]interface{}, 0, len(newURLs))
+	for _, url := range newURLs {
+		selectArgs = append(selectArgs, url.ID)
+	}
+	selectQuery := `SELECT url_id FROM url WHERE url_id IN ` + placeholders(1, len(newURLs)) + `;`
+	editQuery := `INSERT INTO url_edit (url_id, old_url, new_url, edited_at) VALUES (?, ?, ?, ?);`
+
+	ctx, span := tracing.Start(ctx, "AppRepoSQLite.ImportURLs")
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	// DB has one connection, so URLs can not be saved by another query during transaction
+	tx, err := ars.db.BeginTx(ctx, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// URL is new if its ID is not saved before insert
+	savedIDs, err := queryURLIDs(ctx, tx, selectQuery, selectArgs)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	// user scoped URLs are got before insert like in Postgres, where transaction can not be used after failed insert
+	savedURLs := map[string]*app.URL{}
+	if !ars.userScoped {
+		savedURLs, err = ars.getUserScopedURLs(ctx, tx, newURLs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if insertURLs := unsavedURLs(newURLs, savedURLs, ars.userScoped); len(insertURLs) > 0 {
+		insertedURLs, err := ars.insertImportedURLs(ctx, tx, insertURLs)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(savedURLs, insertedURLs)
+	}
+
+	urlEdits := groupURLEdits(edits)
+	for _, url := range savedURLs {
+		if _, ok := savedIDs[url.ID]; ok {
+			continue
+		}
+		for _, edit := range urlEdits[url.ID] {
+			_, err = tx.ExecContext(ctx, editQuery, edit.URLID, edit.OldURL, edit.NewURL, edit.EditedAt.UTC())
+			if err != nil {
+				tracing.RecordError(span, err)
+				return nil, err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return orderURLs(urls, savedURLs, ars.userScoped)
+}
+
+// insertImportedURLs inserts URLs of batch without repeated original URLs with all their fields in DB or gets existed URLs.
+func (ars *AppRepoSQLite) insertImportedURLs(ctx context.Context, q queryer, urls []*app.URL) (map[string]*app.URL, error) {
+	now := time.Now().UTC()
+	args := make([]interface{}, 0, len(urls)*10)
+	for _, url := range urls {
+		createdAt := url.CreatedAt.UTC()
+		if url.CreatedAt.IsZero() {
+			createdAt = now
+		}
+		args = append(
+			args, url.URL, url.ID, url.UserID, ars.userScoped, url.IsDeleted, utcTime(url.DeletedAt), utcTime(url.ExpiresAt), createdAt,
+			url.Clicks, utcTime(url.LastAccessedAt),
+		)
+	}
+	query := `INSERT INTO url (url, url_id, user_id, user_scoped, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at)
+VALUES ` + placeholders(len(urls), 10) + `
+ON CONFLICT ` + urlConflictTarget(ars.userScoped) + ` DO UPDATE SET url = excluded.url, user_id = COALESCE(url.user_id, excluded.user_id)
+RETURNING url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at;`
+
+	ctx, span := startSQLiteQuerySpan(ctx, "insertImportedURLs", query)
+	defer span.End()
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, convertSQLiteError(err)
+	}
+	savedURLs, err := scanURLs(rows, ars.userScoped)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, convertSQLiteError(err)
+	}
+	return savedURLs, nil
+}
+
+// GetUserURLs get page of user URLs sorted by creation time from DB.
+func (ars *AppRepoSQLite) GetUserURLs(ctx context.Context, userID uint, filter *app.UserURLsFilter) ([]*app.URL, error) {
+	query := `SELECT url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at
+FROM url WHERE user_id = ?`
+	args := []interface{}{userID}
+
+	if !filter.IncludeDeleted {
+		query += ` AND NOT is_deleted`
+	}
+	if filter.Search != "" {
+		args = append(args, filter.Search)
+		query += ` AND instr(url, ?) > 0`
+	}
+
+	order := "ASC"
+	comparison := ">"
+	if filter.Desc {
+		order = "DESC"
+		comparison = "<"
+	}
+
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt.UTC(), filter.After.ID)
+		query += ` AND (created_at, url_id) ` + comparison + ` (?, ?)`
+	}
+
+	// url_id is compared byte-wise by default BINARY collation like in other storages
+	query += ` ORDER BY created_at ` + order + `, url_id ` + order
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += ` LIMIT ?`
+	}
+	query += ";"
+
+	ctx, span := startSQLiteQuerySpan(ctx, "GetUserURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	rows, err := ars.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	urls := []*app.URL{}
+	for rows.Next() {
+		url := &app.URL{}
+		err = rows.Scan(
+			&url.URL, &url.ID, &url.UserID, &url.IsDeleted, &url.DeletedAt, &url.ExpiresAt, &url.CreatedAt, &url.Clicks, &url.LastAccessedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+// DeleteUserURLs delete user URLs from DB.
+func (ars *AppRepoSQLite) DeleteUserURLs(ctx context.Context, urls []*app.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(urls)*2+1)
+	for _, url := range urls {
+		args = append(args, url.ID, url.UserID)
+	}
+	args = append(args, time.Now().UTC())
+	// time of deletion of already deleted URL is not changed
+	query := `WITH v (url_id, user_id) AS (VALUES ` + placeholders(len(urls), 2) + `)
+UPDATE url SET is_deleted = true, deleted_at = ?
+WHERE NOT is_deleted AND EXISTS (SELECT 1 FROM v WHERE v.url_id = url.url_id AND v.user_id = url.user_id);`
+
+	ctx, span := startSQLiteQuerySpan(ctx, "DeleteUserURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	_, err := ars.db.ExecContext(ctx, query, args...)
+	tracing.RecordError(span, err)
+
+	return err
+}
+
+// DeleteExpiredURLs marks URLs expired at the moment now as deleted in DB.
+func (ars *AppRepoSQLite) DeleteExpiredURLs(ctx context.Context, now time.Time) error {
+	query := `UPDATE url SET is_deleted = true, deleted_at = ?1 WHERE NOT is_deleted AND expires_at <= ?1;`
+	ctx, span := startSQLiteQuerySpan(ctx, "DeleteExpiredURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	_, err := ars.db.ExecContext(ctx, query, now.UTC())
+	tracing.RecordError(span, err)
+	return err
+}
+
+// GetUserURLIDs gets IDs of URLs among ids which belong to user from DB.
+func (ars *AppRepoSQLite) GetUserURLIDs(ctx context.Context, userID uint, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, userID)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := `SELECT url_id FROM url WHERE user_id = ? AND url_id IN ` + placeholders(1, len(ids)) + `;`
+
+	ctx, span := startSQLiteQuerySpan(ctx, "GetUserURLIDs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	rows, err := ars.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	userURLIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userURLIDs = append(userURLIDs, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return userURLIDs, nil
+}
+
+// RestoreUserURLs cancels deletion of user URLs which are not expired at the moment now in DB.
+func (ars *AppRepoSQLite) RestoreUserURLs(ctx context.Context, userID uint, ids []string, now time.Time) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	args := make([]interface{}, 0, len(ids)+2)
+	args = append(args, userID, now.UTC())
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := `UPDATE url SET is_deleted = false, deleted_at = NULL
+WHERE user_id = ? AND is_deleted AND (expires_at IS NULL OR expires_at > ?) AND url_id IN ` + placeholders(1, len(ids)) + `
+RETURNING url_id;`
+
+	ctx, span := startSQLiteQuerySpan(ctx, "RestoreUserURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	rows, err := ars.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	restoredIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		restoredIDs = append(restoredIDs, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return restoredIDs, nil
+}
+
+// PurgeDeletedURLs removes URLs deleted before deletedBefore from DB.
+func (ars *AppRepoSQLite) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (uint, error) {
+	query := `DELETE FROM url WHERE is_deleted AND deleted_at < ?;`
+	ctx, span := startSQLiteQuerySpan(ctx, "PurgeDeletedURLs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	result, err := ars.db.ExecContext(ctx, query, deletedBefore.UTC())
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return uint(count), nil
+}
+
+// UpdateURL changes original URL of not deleted user URL and saves change in history of URL in one transaction.
+// Nothing is changed if URL already has rawURL.
+func (ars *AppRepoSQLite) UpdateURL(ctx context.Context, id string, userID uint, rawURL string, editedAt time.Time) (*app.URL, error) {
+	selectQuery := `SELECT url FROM url WHERE url_id = ? AND user_id = ? AND NOT is_deleted;`
+	// URL changed by user scoped repo becomes user scoped, so it does not conflict with the same URL of other users
+	updateQuery := `UPDATE url SET url = ?, user_scoped = user_scoped OR ? WHERE url_id = ?
+RETURNING url, url_id, user_id, is_deleted, deleted_at, expires_at, created_at, clicks, last_accessed_at;`
+	insertQuery := `INSERT INTO url_edit (url_id, old_url, new_url, edited_at) VALUES (?, ?, ?, ?);`
+	ctx, span := startSQLiteQuerySpan(ctx, "UpdateURL", updateQuery)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	// DB has one connection, so URL can not be changed by another query during transaction
+	tx, err := ars.db.BeginTx(ctx, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var oldURL string
+	err = tx.QueryRowContext(ctx, selectQuery, id, userID).Scan(&oldURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, app.ErrURLNotFound
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	url := &app.URL{}
+	err = tx.QueryRowContext(ctx, updateQuery, rawURL, ars.userScoped, id).Scan(
+		&url.URL, &url.ID, &url.UserID, &url.IsDeleted, &url.DeletedAt, &url.ExpiresAt, &url.CreatedAt, &url.Clicks, &url.LastAccessedAt,
+	)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, convertSQLiteError(err)
+	}
+	if oldURL == rawURL {
+		return url, nil
+	}
+
+	_, err = tx.ExecContext(ctx, insertQuery, id, oldURL, rawURL, editedAt.UTC())
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return url, nil
+}
+
+// GetURLEdits gets history of changes of original URL of URL with ID from DB.
+func (ars *AppRepoSQLite) GetURLEdits(ctx context.Context, id string) ([]*app.URLEdit, error) {
+	query := `SELECT url_id, old_url, new_url, edited_at FROM url_edit WHERE url_id = ? ORDER BY edited_at, id;`
+	ctx, span := startSQLiteQuerySpan(ctx, "GetURLEdits", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	rows, err := ars.db.QueryContext(ctx, query, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []*app.URLEdit{}
+	for rows.Next() {
+		edit := &app.URLEdit{}
+		err = rows.Scan(&edit.URLID, &edit.OldURL, &edit.NewURL, &edit.EditedAt)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return edits, nil
+}
+
+// AddURLsClicks adds redirects to URLs statistics in DB.
+func (ars *AppRepoSQLite) AddURLsClicks(ctx context.Context, urlsClicks []*app.URLClicks) error {
+	if len(urlsClicks) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(urlsClicks)*3)
+	for _, urlClicks := range urlsClicks {
+		args = append(args, urlClicks.ID, int64(urlClicks.Count), urlClicks.LastAccessedAt.UTC())
+	}
+	query := `WITH v (url_id, clicks, last_accessed_at) AS (VALUES ` + placeholders(len(urlsClicks), 3) + `)
+UPDATE url SET clicks = url.clicks + v.clicks,
+last_accessed_at = MAX(COALESCE(url.last_accessed_at, v.last_accessed_at), v.last_accessed_at)
+FROM v WHERE url.url_id = v.url_id;`
+
+	ctx, span := startSQLiteQuerySpan(ctx, "AddURLsClicks", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	_, err := ars.db.ExecContext(ctx, query, args...)
+	tracing.RecordError(span, err)
+
+	return err
+}
+
+// AddDeletionJob inserts new deletion job in DB.
+func (ars *AppRepoSQLite) AddDeletionJob(ctx context.Context, job *app.DeletionJob) error {
+	urlIDs, err := json.Marshal(job.URLIDs)
+	if err != nil {
+		return err
+	}
+	ignoredURLIDs, err := json.Marshal(job.IgnoredURLIDs)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO deletion_job (id, user_id, url_ids, status, attempts, next_attempt_at, last_error, created_at, finished_at, ignored_url_ids)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	ctx, span := startSQLiteQuerySpan(ctx, "AddDeletionJob", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	_, err = ars.db.ExecContext(ctx, query,
+		job.ID, job.UserID, string(urlIDs), job.Status, job.Attempts, job.NextAttemptAt.UTC(), job.LastError, job.CreatedAt.UTC(),
+		utcTime(job.FinishedAt), string(ignoredURLIDs),
+	)
+	tracing.RecordError(span, err)
+	return err
+}
+
+// scanDeletionJobs reads deletion jobs from rows.
+func scanDeletionJobs(rows *sql.Rows) ([]*app.DeletionJob, error) {
+	jobs := []*app.DeletionJob{}
+	for rows.Next() {
+		job := &app.DeletionJob{}
+		var urlIDs, ignoredURLIDs string
+		err := rows.Scan(
+			&job.ID, &job.UserID, &urlIDs, &job.Status, &job.Attempts, &job.NextAttemptAt, &job.LastError, &job.CreatedAt, &job.FinishedAt,
+			&ignoredURLIDs,
+		)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal([]byte(urlIDs), &job.URLIDs)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal([]byte(ignoredURLIDs), &job.IgnoredURLIDs)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// GetDueDeletionJobs gets pending deletion jobs which should be attempted at the moment now from DB.
+// Jobs are sorted by time of next attempt.
+func (ars *AppRepoSQLite) GetDueDeletionJobs(ctx context.Context, now time.Time, limit uint) ([]*app.DeletionJob, error) {
+	query := `SELECT id, user_id, url_ids, status, attempts, next_attempt_at, last_error, created_at, finished_at, ignored_url_ids
+FROM deletion_job WHERE status = ? AND next_attempt_at <= ?
+ORDER BY next_attempt_at, id`
+	args := []interface{}{app.DeletionJobPending, now.UTC()}
+	if limit > 0 {
+		args = append(args, limit)
+		query += ` LIMIT ?`
+	}
+	query += ";"
+
+	ctx, span := startSQLiteQuerySpan(ctx, "GetDueDeletionJobs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	rows, err := ars.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDeletionJobs(rows)
+}
+
+// GetDeletionJob gets deletion job with ID from DB.
+func (ars *AppRepoSQLite) GetDeletionJob(ctx context.Context, id string) (*app.DeletionJob, error) {
+	query := `SELECT id, user_id, url_ids, status, attempts, next_attempt_at, last_error, created_at, finished_at, ignored_url_ids
+FROM deletion_job WHERE id = ?;`
+	ctx, span := startSQLiteQuerySpan(ctx, "GetDeletionJob", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	rows, err := ars.db.QueryContext(ctx, query, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs, err := scanDeletionJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, app.ErrDeletionJobNotFound
+	}
+	return jobs[0], nil
+}
+
+// CountPendingDeletionJobs returns count of pending deletion jobs in DB.
+func (ars *AppRepoSQLite) CountPendingDeletionJobs(ctx context.Context) (uint, error) {
+	query := `SELECT COUNT(*) FROM deletion_job WHERE status = ?;`
+	var count uint
+	ctx, span := startSQLiteQuerySpan(ctx, "CountPendingDeletionJobs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	err := ars.db.QueryRowContext(ctx, query, app.DeletionJobPending).Scan(&count)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// UpdateDeletionJob updates status, attempts and outcome of URLs of deletion job in DB.
+func (ars *AppRepoSQLite) UpdateDeletionJob(ctx context.Context, job *app.DeletionJob) error {
+	ignoredURLIDs, err := json.Marshal(job.IgnoredURLIDs)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE deletion_job SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, finished_at = ?, ignored_url_ids = ?
+WHERE id = ?;`
+	ctx, span := startSQLiteQuerySpan(ctx, "UpdateDeletionJob", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	result, err := ars.db.ExecContext(ctx, query,
+		job.Status, job.Attempts, job.NextAttemptAt.UTC(), job.LastError, utcTime(job.FinishedAt), string(ignoredURLIDs), job.ID,
+	)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return app.ErrDeletionJobNotFound
+	}
+	return nil
+}
+
+// PurgeDeletionJobs removes done and dead letter deletion jobs finished before finishedBefore from DB.
+func (ars *AppRepoSQLite) PurgeDeletionJobs(ctx context.Context, finishedBefore time.Time) (uint, error) {
+	// status is not passed as argument, so partial index of finished jobs is used
+	query := `DELETE FROM deletion_job WHERE status <> 'pending' AND finished_at < ?;`
+	ctx, span := startSQLiteQuerySpan(ctx, "PurgeDeletionJobs", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, ars.queryTimeout)
+	defer cancel()
+
+	result, err := ars.db.ExecContext(ctx, query, finishedBefore.UTC())
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return uint(count), nil
+}
+
+// Close finishes working with the db.
+func (ars *AppRepoSQLite) Close() error {
+	return ars.db.Close()
+}
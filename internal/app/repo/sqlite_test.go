@@ -0,0 +1,346 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/app"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app/repo/repotest"
+	"github.com/MisterMaks/go-yandex-shortener/internal/app/usecase"
+	"github.com/MisterMaks/go-yandex-shortener/internal/database"
+	userRepoInternal "github.com/MisterMaks/go-yandex-shortener/internal/user/repo"
+	"github.com/MisterMaks/go-yandex-shortener/migrations"
+)
+
+// newSQLiteTestDB creates SQLite DB in temp dir and applies migrations.
+func newSQLiteTestDB(t *testing.T) *sql.DB {
+	db, driver, err := database.Open(database.SQLiteScheme + filepath.Join(t.TempDir(), "shortener.db"))
+	require.NoError(t, err)
+	require.Equal(t, database.DriverSQLite, driver)
+	t.Cleanup(func() {
+		err := db.Close()
+		assert.NoError(t, err)
+	})
+
+	fsys, err := fs.Sub(migrations.FS, migrations.SQLiteDir)
+	require.NoError(t, err)
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, fsys)
+	require.NoError(t, err)
+	_, err = provider.Up(context.Background())
+	require.NoError(t, err, "Failed to apply migrations")
+
+	return db
+}
+
+// newSQLiteTestRepo creates app repo with urlUniqueness on SQLite DB and users which own URLs.
+func newSQLiteTestRepo(t *testing.T, countUsers int, urlUniqueness string) (*AppRepoSQLite, []uint) {
+	db := newSQLiteTestDB(t)
+
+	r, err := NewAppRepoSQLite(db, TestQueryTimeout, urlUniqueness)
+	require.NoError(t, err, "Failed to run NewAppRepoSQLite()")
+
+	ur, err := userRepoInternal.NewUserRepoSQLite(db, TestQueryTimeout)
+	require.NoError(t, err, "Failed to run NewUserRepoSQLite()")
+
+	userIDs := make([]uint, 0, countUsers)
+	for i := 0; i < countUsers; i++ {
+		user, err := ur.CreateUser(context.Background())
+		require.NoError(t, err)
+		userIDs = append(userIDs, user.ID)
+	}
+
+	return r, userIDs
+}
+
+func TestAppRepoSQLite_GetOrCreateURL(t *testing.T) {
+	r, userIDs := newSQLiteTestRepo(t, 2, app.URLUniquenessGlobal)
+
+	testURL := &app.URL{ID: "1", URL: "https://test.ru", UserID: userIDs[0], IsDeleted: false}
+
+	actualURL, err := r.GetOrCreateURL(context.Background(), "1", "https://test.ru", userIDs[0], nil)
+	require.NoError(t, err)
+	resetCreatedAt(t, actualURL)
+	assert.Equal(t, testURL, actualURL)
+
+	actualURL, err = r.GetOrCreateURL(context.Background(), "2", "https://test.ru", userIDs[1], nil)
+	require.NoError(t, err)
+	resetCreatedAt(t, actualURL)
+	assert.Equal(t, testURL, actualURL)
+
+	_, err = r.GetOrCreateURL(context.Background(), "1", "https://test2.ru", userIDs[1], nil)
+	require.ErrorIs(t, err, app.ErrURLIDExists)
+
+	// URL of unknown user violates foreign key like in Postgres
+	_, err = r.GetOrCreateURL(context.Background(), "3", "https://test3.ru", 100, nil)
+	require.Error(t, err)
+}
+
+func TestAppRepoSQLite_GetURL(t *testing.T) {
+	r, userIDs := newSQLiteTestRepo(t, 1, app.URLUniquenessGlobal)
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	_, err := r.GetOrCreateURL(context.Background(), "1", "https://test.ru", userIDs[0], &expiresAt)
+	require.NoError(t, err)
+
+	actualURL, err := r.GetURL(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://test.ru", actualURL.URL)
+	assert.Equal(t, userIDs[0], actualURL.UserID)
+	require.NotNil(t, actualURL.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*actualURL.ExpiresAt))
+
+	_, err = r.GetURL(context.Background(), "2")
+	require.ErrorIs(t, err, app.ErrURLNotFound)
+
+	ok, err := r.CheckIDExistence(context.Background(), "1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = r.CheckIDExistence(context.Background(), "2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAppRepoSQLite_GetOrCreateURLs(t *testing.T) {
+	r, userIDs := newSQLiteTestRepo(t, 3, app.URLUniquenessGlobal)
+
+	testURLs := []*app.URL{
+		{ID: "1", URL: "https://test.ru", UserID: userIDs[0], IsDeleted: false},
+		{ID: "2", URL: "https://test2.ru", UserID: userIDs[0], IsDeleted: false},
+		{ID: "3", URL: "https://test3.ru", UserID: userIDs[1], IsDeleted: false},
+	}
+
+	actualURLs, err := r.GetOrCreateURLs(context.Background(), testURLs)
+	require.NoError(t, err)
+	resetCreatedAt(t, actualURLs...)
+	assert.Equal(t, testURLs, actualURLs)
+
+	actualURLs, err = r.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "4", URL: "https://test.ru", UserID: userIDs[2], IsDeleted: false},
+		{ID: "5", URL: "https://test2.ru", UserID: userIDs[2], IsDeleted: false},
+		{ID: "6", URL: "https://test3.ru", UserID: userIDs[2], IsDeleted: false},
+	})
+	require.NoError(t, err)
+	resetCreatedAt(t, actualURLs...)
+	assert.Equal(t, testURLs, actualURLs)
+
+	_, err = r.GetOrCreateURLs(context.Background(), []*app.URL{
+		{ID: "1", URL: "https://test4.ru", UserID: userIDs[2], IsDeleted: false},
+	})
+	require.ErrorIs(t, err, app.ErrURLIDExists)
+
+	count, err := r.CountURLs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), count)
+}
+
+func TestAppRepoSQLite_GetUserURLs(t *testing.T) {
+	r, userIDs := newSQLiteTestRepo(t, 2, app.URLUniquenessGlobal)
+
+	testURLs := []*app.URL{
+		{ID: "1", URL: "https://test.ru", UserID: userIDs[0]},
+		{ID: "2", URL: "https://test2.ru", UserID: userIDs[0]},
+		{ID: "3", URL: "https://test3.ru", UserID: userIDs[1]},
+	}
+	for _, u := range testURLs {
+		_, err := r.GetOrCreateURL(context.Background(), u.ID, u.URL, u.UserID, nil)
+		require.NoError(t, err)
+	}
+
+	userURLs, err := r.GetUserURLs(context.Background(), userIDs[0], &app.UserURLsFilter{})
+	require.NoError(t, err)
+	resetCreatedAt(t, userURLs...)
+	assert.Equal(t, testURLs[:2], userURLs)
+
+	err = r.DeleteUserURLs(context.Background(), []*app.URL{
+		{ID: "1", UserID: userIDs[0]},
+		// URL of another user is not deleted
+		{ID: "3", UserID: userIDs[0]},
+	})
+	require.NoError(t, err)
+
+	userURLs, err = r.GetUserURLs(context.Background(), userIDs[0], &app.UserURLsFilter{})
+	require.NoError(t, err)
+	require.Len(t, userURLs, 1)
+	assert.Equal(t, "2", userURLs[0].ID)
+
+	user2URLs, err := r.GetUserURLs(context.Background(), userIDs[1], &app.UserURLsFilter{})
+	require.NoError(t, err)
+	require.Len(t, user2URLs, 1)
+	assert.False(t, user2URLs[0].IsDeleted)
+
+	userURLs, err = r.GetUserURLs(context.Background(), userIDs[0], &app.UserURLsFilter{Limit: 1, IncludeDeleted: true, Desc: true})
+	require.NoError(t, err)
+	require.Len(t, userURLs, 1)
+	assert.Equal(t, "2", userURLs[0].ID)
+
+	userURLs, err = r.GetUserURLs(context.Background(), userIDs[0], &app.UserURLsFilter{
+		After:          &app.URLCursor{CreatedAt: userURLs[0].CreatedAt, ID: userURLs[0].ID},
+		IncludeDeleted: true,
+		Desc:           true,
+	})
+	require.NoError(t, err)
+	require.Len(t, userURLs, 1)
+	assert.Equal(t, "1", userURLs[0].ID)
+	assert.True(t, userURLs[0].IsDeleted)
+
+	userURLs, err = r.GetUserURLs(context.Background(), userIDs[0], &app.UserURLsFilter{Search: "test2"})
+	require.NoError(t, err)
+	require.Len(t, userURLs, 1)
+	assert.Equal(t, "2", userURLs[0].ID)
+}
+
+func TestAppRepoSQLite_DeleteExpiredURLs(t *testing.T) {
+	r, userIDs := newSQLiteTestRepo(t, 1, app.URLUniquenessGlobal)
+
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Hour)
+
+	_, err := r.GetOrCreateURL(context.Background(), "1", "https://test.ru", userIDs[0], &past)
+	require.NoError(t, err)
+	_, err = r.GetOrCreateURL(context.Background(), "2", "https://test2.ru", userIDs[0], &future)
+	require.NoError(t, err)
+
+	err = r.DeleteExpiredURLs(context.Background(), now)
+	require.NoError(t, err)
+
+	u, err := r.GetURL(context.Background(), "1")
+	require.NoError(t, err)
+	assert.True(t, u.IsDeleted)
+
+	u, err = r.GetURL(context.Background(), "2")
+	require.NoError(t, err)
+	assert.False(t, u.IsDeleted)
+}
+
+func TestAppRepoSQLite_AddURLsClicks(t *testing.T) {
+	r, userIDs := newSQLiteTestRepo(t, 1, app.URLUniquenessGlobal)
+
+	_, err := r.GetOrCreateURL(context.Background(), "1", "https://test.ru", userIDs[0], nil)
+	require.NoError(t, err)
+
+	lastAccessedAt := time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	err = r.AddURLsClicks(context.Background(), []*app.URLClicks{
+		{ID: "1", Count: 2, LastAccessedAt: lastAccessedAt},
+		{ID: "2", Count: 1, LastAccessedAt: lastAccessedAt},
+	})
+	require.NoError(t, err)
+	err = r.AddURLsClicks(context.Background(), []*app.URLClicks{
+		{ID: "1", Count: 3, LastAccessedAt: lastAccessedAt.Add(-time.Hour)},
+	})
+	require.NoError(t, err)
+
+	u, err := r.GetURL(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), u.Clicks)
+	require.NotNil(t, u.LastAccessedAt)
+	assert.True(t, lastAccessedAt.Equal(*u.LastAccessedAt))
+}
+
+func TestAppRepoSQLite_DeletionJobs(t *testing.T) {
+	r, _ := newSQLiteTestRepo(t, 0, app.URLUniquenessGlobal)
+
+	now := time.Date(2024, 10, 16, 15, 0, 0, 0, time.UTC)
+
+	jobs := []*app.DeletionJob{
+		{ID: "1", UserID: 1, URLIDs: []string{"a", "b"}, Status: app.DeletionJobPending, NextAttemptAt: now, CreatedAt: now},
+		{ID: "2", UserID: 1, URLIDs: []string{"c"}, Status: app.DeletionJobPending, NextAttemptAt: now.Add(-time.Second), CreatedAt: now},
+		{ID: "3", UserID: 2, URLIDs: []string{"d"}, Status: app.DeletionJobPending, NextAttemptAt: now.Add(time.Second), CreatedAt: now},
+	}
+	for _, job := range jobs {
+		err := r.AddDeletionJob(context.Background(), job)
+		require.NoError(t, err)
+	}
+
+	dueJobs, err := r.GetDueDeletionJobs(context.Background(), now, 0)
+	require.NoError(t, err)
+	require.Len(t, dueJobs, 2)
+	assert.Equal(t, "2", dueJobs[0].ID)
+	assert.Equal(t, "1", dueJobs[1].ID)
+	assert.Equal(t, []string{"a", "b"}, dueJobs[1].URLIDs)
+
+	dueJobs, err = r.GetDueDeletionJobs(context.Background(), now, 1)
+	require.NoError(t, err)
+	require.Len(t, dueJobs, 1)
+	assert.Equal(t, "2", dueJobs[0].ID)
+
+	dueJobs[0].Status = app.DeletionJobDone
+	err = r.UpdateDeletionJob(context.Background(), dueJobs[0])
+	require.NoError(t, err)
+
+	jobs[0].Attempts = 1
+	jobs[0].LastError = "test error"
+	jobs[0].NextAttemptAt = now.Add(time.Minute)
+	err = r.UpdateDeletionJob(context.Background(), jobs[0])
+	require.NoError(t, err)
+
+	err = r.UpdateDeletionJob(context.Background(), &app.DeletionJob{ID: "unknown"})
+	assert.ErrorIs(t, err, app.ErrDeletionJobNotFound)
+
+	job, err := r.GetDeletionJob(context.Background(), "2")
+	require.NoError(t, err)
+	assert.Equal(t, app.DeletionJobDone, job.Status)
+	assert.Equal(t, []string{"c"}, job.URLIDs)
+	assert.True(t, now.Equal(job.CreatedAt))
+
+	_, err = r.GetDeletionJob(context.Background(), "unknown")
+	assert.ErrorIs(t, err, app.ErrDeletionJobNotFound)
+
+	count, err := r.CountPendingDeletionJobs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+
+	dueJobs, err = r.GetDueDeletionJobs(context.Background(), now.Add(time.Minute), 0)
+	require.NoError(t, err)
+	require.Len(t, dueJobs, 2)
+	assert.Equal(t, "3", dueJobs[0].ID)
+	assert.Equal(t, "1", dueJobs[1].ID)
+	assert.Equal(t, uint(1), dueJobs[1].Attempts)
+	assert.Equal(t, "test error", dueJobs[1].LastError)
+}
+
+func TestAppRepoSQLite_Ping(t *testing.T) {
+	r, _ := newSQLiteTestRepo(t, 0, app.URLUniquenessGlobal)
+
+	err := r.Ping(context.Background())
+	require.NoError(t, err)
+
+	err = r.Close()
+	require.NoError(t, err)
+
+	err = r.Ping(context.Background())
+	require.Error(t, err)
+}
+
+func TestAppRepoSQLite_Conformance(t *testing.T) {
+	repotest.RunAppRepoSuite(t, func(t *testing.T, countUsers int) (usecase.AppRepoInterface, []uint) {
+		return newSQLiteTestRepo(t, countUsers, app.URLUniquenessGlobal)
+	})
+}
+
+func TestAppRepoSQLite_Conformance_UserScoped(t *testing.T) {
+	repotest.RunAppRepoUserScopedSuite(t, func(t *testing.T, countUsers int) (usecase.AppRepoInterface, []uint) {
+		return newSQLiteTestRepo(t, countUsers, app.URLUniquenessUser)
+	})
+}
+
+// URLs saved in different scopes of uniqueness are in the same table, so mode of app can be changed.
+func TestAppRepoSQLite_ChangeURLUniqueness(t *testing.T) {
+	repotest.RunAppRepoChangeURLUniquenessSuite(t, func(t *testing.T, countUsers int) (usecase.AppRepoInterface, usecase.AppRepoInterface, []uint) {
+		globalRepo, userIDs := newSQLiteTestRepo(t, countUsers, app.URLUniquenessGlobal)
+		userRepo, err := NewAppRepoSQLite(globalRepo.db, TestQueryTimeout, app.URLUniquenessUser)
+		require.NoError(t, err)
+		return globalRepo, userRepo, userIDs
+	})
+}
@@ -5,7 +5,9 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	app "github.com/MisterMaks/go-yandex-shortener/internal/app"
 	gomock "github.com/golang/mock/gomock"
@@ -34,19 +36,47 @@ func (m *MockAppRepoInterface) EXPECT() *MockAppRepoInterfaceMockRecorder {
 	return m.recorder
 }
 
+// AddDeletionJob mocks base method.
+func (m *MockAppRepoInterface) AddDeletionJob(ctx context.Context, job *app.DeletionJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDeletionJob", ctx, job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDeletionJob indicates an expected call of AddDeletionJob.
+func (mr *MockAppRepoInterfaceMockRecorder) AddDeletionJob(ctx, job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDeletionJob", reflect.TypeOf((*MockAppRepoInterface)(nil).AddDeletionJob), ctx, job)
+}
+
+// AddURLsClicks mocks base method.
+func (m *MockAppRepoInterface) AddURLsClicks(ctx context.Context, urlsClicks []*app.URLClicks) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddURLsClicks", ctx, urlsClicks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddURLsClicks indicates an expected call of AddURLsClicks.
+func (mr *MockAppRepoInterfaceMockRecorder) AddURLsClicks(ctx, urlsClicks interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddURLsClicks", reflect.TypeOf((*MockAppRepoInterface)(nil).AddURLsClicks), ctx, urlsClicks)
+}
+
 // CheckIDExistence mocks base method.
-func (m *MockAppRepoInterface) CheckIDExistence(id string) (bool, error) {
+func (m *MockAppRepoInterface) CheckIDExistence(ctx context.Context, id string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CheckIDExistence", id)
+	ret := m.ctrl.Call(m, "CheckIDExistence", ctx, id)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CheckIDExistence indicates an expected call of CheckIDExistence.
-func (mr *MockAppRepoInterfaceMockRecorder) CheckIDExistence(id interface{}) *gomock.Call {
+func (mr *MockAppRepoInterfaceMockRecorder) CheckIDExistence(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckIDExistence", reflect.TypeOf((*MockAppRepoInterface)(nil).CheckIDExistence), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckIDExistence", reflect.TypeOf((*MockAppRepoInterface)(nil).CheckIDExistence), ctx, id)
 }
 
 // Close mocks base method.
@@ -63,76 +93,320 @@ func (mr *MockAppRepoInterfaceMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockAppRepoInterface)(nil).Close))
 }
 
+// CountPendingDeletionJobs mocks base method.
+func (m *MockAppRepoInterface) CountPendingDeletionJobs(ctx context.Context) (uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountPendingDeletionJobs", ctx)
+	ret0, _ := ret[0].(uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountPendingDeletionJobs indicates an expected call of CountPendingDeletionJobs.
+func (mr *MockAppRepoInterfaceMockRecorder) CountPendingDeletionJobs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountPendingDeletionJobs", reflect.TypeOf((*MockAppRepoInterface)(nil).CountPendingDeletionJobs), ctx)
+}
+
+// CountURLs mocks base method.
+func (m *MockAppRepoInterface) CountURLs(ctx context.Context) (uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountURLs", ctx)
+	ret0, _ := ret[0].(uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountURLs indicates an expected call of CountURLs.
+func (mr *MockAppRepoInterfaceMockRecorder) CountURLs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).CountURLs), ctx)
+}
+
+// DeleteExpiredURLs mocks base method.
+func (m *MockAppRepoInterface) DeleteExpiredURLs(ctx context.Context, now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredURLs", ctx, now)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExpiredURLs indicates an expected call of DeleteExpiredURLs.
+func (mr *MockAppRepoInterfaceMockRecorder) DeleteExpiredURLs(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).DeleteExpiredURLs), ctx, now)
+}
+
 // DeleteUserURLs mocks base method.
-func (m *MockAppRepoInterface) DeleteUserURLs(urls []*app.URL) error {
+func (m *MockAppRepoInterface) DeleteUserURLs(ctx context.Context, urls []*app.URL) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUserURLs", urls)
+	ret := m.ctrl.Call(m, "DeleteUserURLs", ctx, urls)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteUserURLs indicates an expected call of DeleteUserURLs.
-func (mr *MockAppRepoInterfaceMockRecorder) DeleteUserURLs(urls interface{}) *gomock.Call {
+func (mr *MockAppRepoInterfaceMockRecorder) DeleteUserURLs(ctx, urls interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).DeleteUserURLs), ctx, urls)
+}
+
+// GetDeletionJob mocks base method.
+func (m *MockAppRepoInterface) GetDeletionJob(ctx context.Context, id string) (*app.DeletionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletionJob", ctx, id)
+	ret0, _ := ret[0].(*app.DeletionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletionJob indicates an expected call of GetDeletionJob.
+func (mr *MockAppRepoInterfaceMockRecorder) GetDeletionJob(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).DeleteUserURLs), urls)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletionJob", reflect.TypeOf((*MockAppRepoInterface)(nil).GetDeletionJob), ctx, id)
+}
+
+// GetDueDeletionJobs mocks base method.
+func (m *MockAppRepoInterface) GetDueDeletionJobs(ctx context.Context, now time.Time, limit uint) ([]*app.DeletionJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueDeletionJobs", ctx, now, limit)
+	ret0, _ := ret[0].([]*app.DeletionJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueDeletionJobs indicates an expected call of GetDueDeletionJobs.
+func (mr *MockAppRepoInterfaceMockRecorder) GetDueDeletionJobs(ctx, now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueDeletionJobs", reflect.TypeOf((*MockAppRepoInterface)(nil).GetDueDeletionJobs), ctx, now, limit)
 }
 
 // GetOrCreateURL mocks base method.
-func (m *MockAppRepoInterface) GetOrCreateURL(id, rawURL string, userID uint) (*app.URL, error) {
+func (m *MockAppRepoInterface) GetOrCreateURL(ctx context.Context, id, rawURL string, userID uint, expiresAt *time.Time) (*app.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrCreateURL", id, rawURL, userID)
+	ret := m.ctrl.Call(m, "GetOrCreateURL", ctx, id, rawURL, userID, expiresAt)
 	ret0, _ := ret[0].(*app.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOrCreateURL indicates an expected call of GetOrCreateURL.
-func (mr *MockAppRepoInterfaceMockRecorder) GetOrCreateURL(id, rawURL, userID interface{}) *gomock.Call {
+func (mr *MockAppRepoInterfaceMockRecorder) GetOrCreateURL(ctx, id, rawURL, userID, expiresAt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrCreateURL", reflect.TypeOf((*MockAppRepoInterface)(nil).GetOrCreateURL), id, rawURL, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrCreateURL", reflect.TypeOf((*MockAppRepoInterface)(nil).GetOrCreateURL), ctx, id, rawURL, userID, expiresAt)
 }
 
 // GetOrCreateURLs mocks base method.
-func (m *MockAppRepoInterface) GetOrCreateURLs(urls []*app.URL) ([]*app.URL, error) {
+func (m *MockAppRepoInterface) GetOrCreateURLs(ctx context.Context, urls []*app.URL) ([]*app.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrCreateURLs", urls)
+	ret := m.ctrl.Call(m, "GetOrCreateURLs", ctx, urls)
 	ret0, _ := ret[0].([]*app.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOrCreateURLs indicates an expected call of GetOrCreateURLs.
-func (mr *MockAppRepoInterfaceMockRecorder) GetOrCreateURLs(urls interface{}) *gomock.Call {
+func (mr *MockAppRepoInterfaceMockRecorder) GetOrCreateURLs(ctx, urls interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrCreateURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).GetOrCreateURLs), urls)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrCreateURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).GetOrCreateURLs), ctx, urls)
 }
 
 // GetURL mocks base method.
-func (m *MockAppRepoInterface) GetURL(id string) (*app.URL, error) {
+func (m *MockAppRepoInterface) GetURL(ctx context.Context, id string) (*app.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetURL", id)
+	ret := m.ctrl.Call(m, "GetURL", ctx, id)
 	ret0, _ := ret[0].(*app.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetURL indicates an expected call of GetURL.
-func (mr *MockAppRepoInterfaceMockRecorder) GetURL(id interface{}) *gomock.Call {
+func (mr *MockAppRepoInterfaceMockRecorder) GetURL(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockAppRepoInterface)(nil).GetURL), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockAppRepoInterface)(nil).GetURL), ctx, id)
+}
+
+// GetURLEdits mocks base method.
+func (m *MockAppRepoInterface) GetURLEdits(ctx context.Context, id string) ([]*app.URLEdit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetURLEdits", ctx, id)
+	ret0, _ := ret[0].([]*app.URLEdit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetURLEdits indicates an expected call of GetURLEdits.
+func (mr *MockAppRepoInterfaceMockRecorder) GetURLEdits(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURLEdits", reflect.TypeOf((*MockAppRepoInterface)(nil).GetURLEdits), ctx, id)
+}
+
+// GetUserURLIDs mocks base method.
+func (m *MockAppRepoInterface) GetUserURLIDs(ctx context.Context, userID uint, ids []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserURLIDs", ctx, userID, ids)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserURLIDs indicates an expected call of GetUserURLIDs.
+func (mr *MockAppRepoInterfaceMockRecorder) GetUserURLIDs(ctx, userID, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserURLIDs", reflect.TypeOf((*MockAppRepoInterface)(nil).GetUserURLIDs), ctx, userID, ids)
 }
 
 // GetUserURLs mocks base method.
-func (m *MockAppRepoInterface) GetUserURLs(userID uint) ([]*app.URL, error) {
+func (m *MockAppRepoInterface) GetUserURLs(ctx context.Context, userID uint, filter *app.UserURLsFilter) ([]*app.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserURLs", userID)
+	ret := m.ctrl.Call(m, "GetUserURLs", ctx, userID, filter)
 	ret0, _ := ret[0].([]*app.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserURLs indicates an expected call of GetUserURLs.
-func (mr *MockAppRepoInterfaceMockRecorder) GetUserURLs(userID interface{}) *gomock.Call {
+func (mr *MockAppRepoInterfaceMockRecorder) GetUserURLs(ctx, userID, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).GetUserURLs), ctx, userID, filter)
+}
+
+// ImportURLs mocks base method.
+func (m *MockAppRepoInterface) ImportURLs(ctx context.Context, urls []*app.URL, edits []*app.URLEdit) ([]*app.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportURLs", ctx, urls, edits)
+	ret0, _ := ret[0].([]*app.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportURLs indicates an expected call of ImportURLs.
+func (mr *MockAppRepoInterfaceMockRecorder) ImportURLs(ctx, urls, edits interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).ImportURLs), ctx, urls, edits)
+}
+
+// Ping mocks base method.
+func (m *MockAppRepoInterface) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockAppRepoInterfaceMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockAppRepoInterface)(nil).Ping), ctx)
+}
+
+// PurgeDeletedURLs mocks base method.
+func (m *MockAppRepoInterface) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedURLs", ctx, deletedBefore)
+	ret0, _ := ret[0].(uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedURLs indicates an expected call of PurgeDeletedURLs.
+func (mr *MockAppRepoInterfaceMockRecorder) PurgeDeletedURLs(ctx, deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).PurgeDeletedURLs), ctx, deletedBefore)
+}
+
+// PurgeDeletionJobs mocks base method.
+func (m *MockAppRepoInterface) PurgeDeletionJobs(ctx context.Context, finishedBefore time.Time) (uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletionJobs", ctx, finishedBefore)
+	ret0, _ := ret[0].(uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletionJobs indicates an expected call of PurgeDeletionJobs.
+func (mr *MockAppRepoInterfaceMockRecorder) PurgeDeletionJobs(ctx, finishedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletionJobs", reflect.TypeOf((*MockAppRepoInterface)(nil).PurgeDeletionJobs), ctx, finishedBefore)
+}
+
+// RestoreUserURLs mocks base method.
+func (m *MockAppRepoInterface) RestoreUserURLs(ctx context.Context, userID uint, ids []string, now time.Time) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUserURLs", ctx, userID, ids, now)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUserURLs indicates an expected call of RestoreUserURLs.
+func (mr *MockAppRepoInterfaceMockRecorder) RestoreUserURLs(ctx, userID, ids, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUserURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).RestoreUserURLs), ctx, userID, ids, now)
+}
+
+// UpdateDeletionJob mocks base method.
+func (m *MockAppRepoInterface) UpdateDeletionJob(ctx context.Context, job *app.DeletionJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDeletionJob", ctx, job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDeletionJob indicates an expected call of UpdateDeletionJob.
+func (mr *MockAppRepoInterfaceMockRecorder) UpdateDeletionJob(ctx, job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDeletionJob", reflect.TypeOf((*MockAppRepoInterface)(nil).UpdateDeletionJob), ctx, job)
+}
+
+// UpdateURL mocks base method.
+func (m *MockAppRepoInterface) UpdateURL(ctx context.Context, id string, userID uint, rawURL string, editedAt time.Time) (*app.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateURL", ctx, id, userID, rawURL, editedAt)
+	ret0, _ := ret[0].(*app.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateURL indicates an expected call of UpdateURL.
+func (mr *MockAppRepoInterfaceMockRecorder) UpdateURL(ctx, id, userID, rawURL, editedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateURL", reflect.TypeOf((*MockAppRepoInterface)(nil).UpdateURL), ctx, id, userID, rawURL, editedAt)
+}
+
+// MockStorageCompactor is a mock of StorageCompactor interface.
+type MockStorageCompactor struct {
+	ctrl     *gomock.Controller
+	recorder *MockStorageCompactorMockRecorder
+}
+
+// MockStorageCompactorMockRecorder is the mock recorder for MockStorageCompactor.
+type MockStorageCompactorMockRecorder struct {
+	mock *MockStorageCompactor
+}
+
+// NewMockStorageCompactor creates a new mock instance.
+func NewMockStorageCompactor(ctrl *gomock.Controller) *MockStorageCompactor {
+	mock := &MockStorageCompactor{ctrl: ctrl}
+	mock.recorder = &MockStorageCompactorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStorageCompactor) EXPECT() *MockStorageCompactorMockRecorder {
+	return m.recorder
+}
+
+// Compact mocks base method.
+func (m *MockStorageCompactor) Compact(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Compact", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Compact indicates an expected call of Compact.
+func (mr *MockStorageCompactorMockRecorder) Compact(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserURLs", reflect.TypeOf((*MockAppRepoInterface)(nil).GetUserURLs), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Compact", reflect.TypeOf((*MockStorageCompactor)(nil).Compact), ctx)
 }
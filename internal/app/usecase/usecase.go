@@ -1,23 +1,49 @@
 package usecase
 
 import (
-	"database/sql"
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"math/rand"
 	"net/url"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
 	loggerInternal "github.com/MisterMaks/go-yandex-shortener/internal/logger"
+	"github.com/MisterMaks/go-yandex-shortener/internal/metrics"
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
 )
 
 // Constants for usecase.
 const (
-	Symbols      string = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789" // symbols for generating short URL
-	CountSymbols        = len(Symbols)                                                     // count symbols for generating short URL
+	Symbols        string = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789" // symbols for generating short URL
+	CountSymbols          = len(Symbols)                                                     // count symbols for generating short URL
+	AliasSymbols          = Symbols + "-_"                                                   // symbols allowed in custom URL ID
+	MinLengthAlias uint   = 3                                                                // min length of custom URL ID
+	MaxLengthAlias uint   = 64                                                               // max length of custom URL ID
+
+	MaxUserURLsLimit     uint = 1000 // max count of user URLs in page
+	DefaultUserURLsLimit uint = 100  // count of user URLs in page if limit is not set
+	ExportPageSize       uint = 1000 // count of user URLs read by one GetUserURLs call on export
+
+	MaxImportUserURLs int = 10000 // max count of rows in import of user URLs
+	ImportBatchSize   int = 100   // count of rows saved by one GetOrCreateURLs call on import
+
+	MaxRestoreUserURLs int = 1000 // max count of URLs in one restore request
+
+	DeletionWorkerStallFactor = 3 // deletion worker is stalled if it did not run for this count of intervals
+
+	DroppedClicksLogInterval = 10 * time.Second // dropped clicks are logged at most once per this interval
 )
 
 // Errors for usecase.
@@ -26,6 +52,22 @@ var (
 	ErrZeroMaxLengthID         = errors.New("max length ID == 0")
 	ErrMaxLengthIDLessLengthID = errors.New("max length ID is less length ID")
 	ErrInvalidBaseURL          = errors.New("invalid Base URL")
+	ErrInvalidWaitingTime      = errors.New("waiting time of background task is not positive")
+	ErrInvalidAlias            = errors.New("invalid alias")
+	ErrReservedAlias           = errors.New("alias is reserved by app route")
+	ErrAliasTaken              = errors.New("alias is already taken")
+	ErrInvalidExpiration       = errors.New("invalid expiration")
+	ErrForbidden               = errors.New("resource belongs to another user")
+	ErrInvalidLimit            = errors.New("invalid limit")
+	ErrInvalidCursor           = errors.New("invalid cursor")
+	ErrDeletionWorkerStopped   = errors.New("deletion worker is stopped")
+	ErrDeletionWorkerStalled   = errors.New("deletion worker is stalled")
+	ErrShuttingDown            = errors.New("app is shutting down")
+	ErrCompactionNotSupported  = errors.New("storage compaction is not supported")
+	ErrTooManyImportURLs       = errors.New("too many URLs to import")
+	ErrEmptyOriginalURL        = errors.New("empty original URL")
+	ErrTooManyRestoreURLs      = errors.New("too many URLs to restore")
+	ErrURLDeleted              = errors.New("url is deleted or expired")
 )
 
 func generateID(length uint) (string, error) {
@@ -39,6 +81,57 @@ func generateID(length uint) (string, error) {
 	return string(b), nil
 }
 
+func validateAlias(alias string) error {
+	length := uint(len(alias))
+	if length < MinLengthAlias || length > MaxLengthAlias {
+		return ErrInvalidAlias
+	}
+	for _, r := range alias {
+		if !strings.ContainsRune(AliasSymbols, r) {
+			return ErrInvalidAlias
+		}
+	}
+	return nil
+}
+
+// GetExpirationTime returns URL expiration time for absolute expiresAt or TTL in seconds.
+// Func return nil if URL never expires.
+func GetExpirationTime(expiresAt *time.Time, ttl int64, now time.Time) (*time.Time, error) {
+	switch {
+	case expiresAt != nil && ttl != 0:
+		return nil, ErrInvalidExpiration
+	case ttl < 0:
+		return nil, ErrInvalidExpiration
+	case ttl > 0:
+		t := now.Add(time.Duration(ttl) * time.Second)
+		return &t, nil
+	case expiresAt != nil && !expiresAt.After(now):
+		return nil, ErrInvalidExpiration
+	}
+	return expiresAt, nil
+}
+
+func encodeURLCursor(cursor *app.URLCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeURLCursor(s string) (*app.URLCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &app.URLCursor{}
+	err = json.Unmarshal(data, cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
 func parseURL(rawURL string) (string, error) {
 	matched, err := regexp.MatchString("^https?://", rawURL)
 	if err != nil {
@@ -55,17 +148,52 @@ func parseURL(rawURL string) (string, error) {
 	return parsedRequestURI, nil
 }
 
+// parseShortURLID returns short ID of short URL: its last path segment.
+// Func returns empty string for empty short URL.
+func parseShortURLID(shortURL string) (string, error) {
+	if shortURL == "" {
+		return "", nil
+	}
+	id := shortURL[strings.LastIndexByte(shortURL, '/')+1:]
+	if err := validateAlias(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
 // AppRepoInterface contains the necessary functions for storage.
 type AppRepoInterface interface {
-	GetOrCreateURL(id, rawURL string, userID uint) (*app.URL, error) // get created or create short URL for request URL
-	GetURL(id string) (*app.URL, error)                              // get original URL for short URL
-	CheckIDExistence(id string) (bool, error)                        // check URL ID existence
-	GetOrCreateURLs(urls []*app.URL) ([]*app.URL, error)             // get created or create URLs
-	GetUserURLs(userID uint) ([]*app.URL, error)                     // get user URLs
-	DeleteUserURLs(urls []*app.URL) error                            // delete urls
+	GetOrCreateURL(ctx context.Context, id, rawURL string, userID uint, expiresAt *time.Time) (*app.URL, error) // get created or create short URL for request URL
+	GetURL(ctx context.Context, id string) (*app.URL, error)                                                    // get original URL for short URL
+	CheckIDExistence(ctx context.Context, id string) (bool, error)                                              // check URL ID existence
+	GetOrCreateURLs(ctx context.Context, urls []*app.URL) ([]*app.URL, error)                                   // get created or create URLs
+	ImportURLs(ctx context.Context, urls []*app.URL, edits []*app.URLEdit) ([]*app.URL, error)                  // get created or create URLs with all fields and history of new URLs
+	GetUserURLs(ctx context.Context, userID uint, filter *app.UserURLsFilter) ([]*app.URL, error)               // get page of user URLs
+	DeleteUserURLs(ctx context.Context, urls []*app.URL) error                                                  // delete urls
+	DeleteExpiredURLs(ctx context.Context, now time.Time) error                                                 // delete URLs expired at the moment now
+	GetUserURLIDs(ctx context.Context, userID uint, ids []string) ([]string, error)                             // get IDs of URLs among ids which belong to user
+	RestoreUserURLs(ctx context.Context, userID uint, ids []string, now time.Time) ([]string, error)            // restore deleted user URLs not expired at the moment now and get IDs of restored URLs
+	PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (uint, error)                                // remove URLs deleted before time and get count of removed URLs
+	UpdateURL(ctx context.Context, id string, userID uint, rawURL string, editedAt time.Time) (*app.URL, error) // change original URL of not deleted user URL and save change in history of URL
+	GetURLEdits(ctx context.Context, id string) ([]*app.URLEdit, error)                                         // get changes of original URL sorted by time
+	AddURLsClicks(ctx context.Context, urlsClicks []*app.URLClicks) error                                       // add redirects to URLs statistics
+	AddDeletionJob(ctx context.Context, job *app.DeletionJob) error                                             // save new deletion job
+	GetDueDeletionJobs(ctx context.Context, now time.Time, limit uint) ([]*app.DeletionJob, error)              // get pending deletion jobs which should be attempted at the moment now
+	UpdateDeletionJob(ctx context.Context, job *app.DeletionJob) error                                          // save status, attempts and outcome of URLs of deletion job
+	GetDeletionJob(ctx context.Context, id string) (*app.DeletionJob, error)                                    // get deletion job with ID
+	CountPendingDeletionJobs(ctx context.Context) (uint, error)                                                 // get count of pending deletion jobs
+	PurgeDeletionJobs(ctx context.Context, finishedBefore time.Time) (uint, error)                              // remove done and dead letter deletion jobs finished before time and get count of removed jobs
+	CountURLs(ctx context.Context) (uint, error)                                                                // get count of URLs
+	Ping(ctx context.Context) error                                                                             // check that storage is available
 	Close() error
 }
 
+// StorageCompactor is implemented by storages which append changes to log
+// and can rewrite it to snapshot.
+type StorageCompactor interface {
+	Compact(ctx context.Context) error // rewrite log of storage to snapshot
+}
+
 // AppUsecase business logic struct.
 type AppUsecase struct {
 	AppRepo AppRepoInterface // storage
@@ -74,62 +202,195 @@ type AppUsecase struct {
 	CountRegenerationsForLengthID uint   // count regenerations for length ID
 	LengthID                      uint   // length ID
 	MaxLengthID                   uint   // max length ID
+	URLUniqueness                 string // scope of original URL uniqueness: app.URLUniquenessGlobal or app.URLUniquenessUser
+
+	deleteURLsBatchSize      uint // max count of deletion jobs processed at once
+	deleteURLsWaitingTime    time.Duration
+	deleteURLsTicker         *time.Ticker
+	deleteURLsHeartbeat      atomic.Int64  // unix time in nanoseconds of last deletion worker run, 0 if worker is stopped
+	deleteURLsMaxAttempts    uint          // failed job is moved to dead letter after this count of attempts
+	deleteURLsRetryBaseDelay time.Duration // delay before first retry, it is doubled for every next retry
+	deleteURLsRetryMaxDelay  time.Duration // max delay between retries
+
+	deleteExpiredURLsTicker *time.Ticker
+
+	purgeDeletedURLsTicker *time.Ticker  // nil if deleted URLs are not purged
+	deletedURLsGracePeriod time.Duration // deleted URL can be restored during this period, then it is purged
+
+	purgeDeletionJobsTicker *time.Ticker  // nil if finished deletion jobs are not purged
+	deletionJobsRetention   time.Duration // done and dead letter deletion jobs are kept during this period, then they are purged
+
+	clicksChan         chan *app.URLClicks
+	clicksTicker       *time.Ticker
+	droppedClicks      atomic.Uint64 // count of clicks dropped since last log
+	droppedClicksLogAt atomic.Int64  // unix time in nanoseconds of last log of dropped clicks
 
-	db *sql.DB
+	compactStorageTicker *time.Ticker // nil if storage is not compacted periodically
 
-	deleteURLsChan   chan *app.URL
-	deleteURLsTicker *time.Ticker
+	reservedIDs map[string]struct{} // IDs which are paths of app routes, short URLs with them do not redirect
 
-	doneCh chan struct{}
+	doneCh       chan struct{}
+	doneOnce     sync.Once
+	drainCtx     context.Context // background tasks flush their work till drainCtx is done, it is set before doneCh is closed
+	wg           sync.WaitGroup
+	shuttingDown atomic.Bool
+}
+
+// AppUsecaseConfig contains settings of AppUsecase and its background tasks.
+type AppUsecaseConfig struct {
+	BaseURL                       string // base URL
+	CountRegenerationsForLengthID uint   // count regenerations for length ID
+	LengthID                      uint   // length ID
+	MaxLengthID                   uint   // max length ID
+	URLUniqueness                 string // scope of original URL uniqueness: app.URLUniquenessGlobal or app.URLUniquenessUser
+
+	DeleteURLsBatchSize      uint          // max count of deletion jobs processed at once
+	DeleteURLsWaitingTime    time.Duration // period of deletion worker runs
+	DeleteURLsMaxAttempts    uint          // failed job is moved to dead letter after this count of attempts
+	DeleteURLsRetryBaseDelay time.Duration // delay before first retry, it is doubled for every next retry
+	DeleteURLsRetryMaxDelay  time.Duration // max delay between retries
+
+	DeleteExpiredURLsWaitingTime time.Duration // period of removing expired URLs
+
+	PurgeDeletedURLsWaitingTime time.Duration // period of purging deleted URLs
+	DeletedURLsGracePeriod      time.Duration // deleted URL can be restored during this period, negative disables purging
+
+	PurgeDeletionJobsWaitingTime time.Duration // period of purging finished deletion jobs
+	DeletionJobsRetention        time.Duration // finished deletion jobs are kept during this period, negative disables purging
+
+	ClicksChanSize    uint          // size of buffer of clicks
+	ClicksWaitingTime time.Duration // period of saving clicks
+
+	CompactStorageWaitingTime time.Duration // period of storage compaction, not positive disables periodic compaction
+}
+
+// validateWaitingTimes checks that periods of enabled background tasks are positive, otherwise their tickers can not be created.
+func (c AppUsecaseConfig) validateWaitingTimes() error {
+	waitingTimes := []time.Duration{c.DeleteURLsWaitingTime, c.DeleteExpiredURLsWaitingTime, c.ClicksWaitingTime}
+	if c.DeletedURLsGracePeriod >= 0 {
+		waitingTimes = append(waitingTimes, c.PurgeDeletedURLsWaitingTime)
+	}
+	if c.DeletionJobsRetention >= 0 {
+		waitingTimes = append(waitingTimes, c.PurgeDeletionJobsWaitingTime)
+	}
+	for _, waitingTime := range waitingTimes {
+		if waitingTime <= 0 {
+			return ErrInvalidWaitingTime
+		}
+	}
+	return nil
 }
 
 // NewAppUsecase creates *AppUsecase.
-func NewAppUsecase(
-	appRepo AppRepoInterface,
-	baseURL string,
-	countRegenerationsForLengthID, lengthID, maxLengthID uint,
-	db *sql.DB,
-	deleteURLsChanSize uint,
-	deleteURLsWaitingTime time.Duration,
-) (*AppUsecase, error) {
-	if lengthID == 0 {
+func NewAppUsecase(appRepo AppRepoInterface, config AppUsecaseConfig) (*AppUsecase, error) {
+	if config.LengthID == 0 {
 		return nil, ErrZeroLengthID
 	}
-	if maxLengthID == 0 {
+	if config.MaxLengthID == 0 {
 		return nil, ErrZeroMaxLengthID
 	}
-	if maxLengthID < lengthID {
+	if config.MaxLengthID < config.LengthID {
 		return nil, ErrMaxLengthIDLessLengthID
 	}
-	u, err := url.ParseRequestURI(baseURL)
+	u, err := url.ParseRequestURI(config.BaseURL)
 	if err != nil {
 		return nil, err
 	}
 	if u.Path == "" {
 		return nil, ErrInvalidBaseURL
 	}
+	err = config.validateWaitingTimes()
+	if err != nil {
+		return nil, err
+	}
 
 	doneCh := make(chan struct{})
 
 	appUsecase := &AppUsecase{
 		AppRepo:                       appRepo,
-		BaseURL:                       baseURL,
-		CountRegenerationsForLengthID: countRegenerationsForLengthID,
-		LengthID:                      lengthID,
-		MaxLengthID:                   maxLengthID,
-		db:                            db,
-		deleteURLsChan:                make(chan *app.URL, deleteURLsChanSize),
-		deleteURLsTicker:              time.NewTicker(deleteURLsWaitingTime),
+		BaseURL:                       config.BaseURL,
+		CountRegenerationsForLengthID: config.CountRegenerationsForLengthID,
+		LengthID:                      config.LengthID,
+		MaxLengthID:                   config.MaxLengthID,
+		URLUniqueness:                 config.URLUniqueness,
+		deleteURLsBatchSize:           config.DeleteURLsBatchSize,
+		deleteURLsWaitingTime:         config.DeleteURLsWaitingTime,
+		deleteURLsTicker:              time.NewTicker(config.DeleteURLsWaitingTime),
+		deleteURLsMaxAttempts:         config.DeleteURLsMaxAttempts,
+		deleteURLsRetryBaseDelay:      config.DeleteURLsRetryBaseDelay,
+		deleteURLsRetryMaxDelay:       config.DeleteURLsRetryMaxDelay,
+		deleteExpiredURLsTicker:       time.NewTicker(config.DeleteExpiredURLsWaitingTime),
+		deletedURLsGracePeriod:        config.DeletedURLsGracePeriod,
+		deletionJobsRetention:         config.DeletionJobsRetention,
+		clicksChan:                    make(chan *app.URLClicks, config.ClicksChanSize),
+		clicksTicker:                  time.NewTicker(config.ClicksWaitingTime),
 
 		doneCh: doneCh,
 	}
 
-	go appUsecase.deleteUserURLs()
+	appUsecase.runWorker(appUsecase.deleteUserURLs)
+	appUsecase.runWorker(appUsecase.deleteExpiredURLs)
+	appUsecase.runWorker(appUsecase.addURLsClicks)
+
+	// deleted URLs are purged only if grace period is not negative
+	if config.DeletedURLsGracePeriod >= 0 {
+		appUsecase.purgeDeletedURLsTicker = time.NewTicker(config.PurgeDeletedURLsWaitingTime)
+		appUsecase.runWorker(appUsecase.purgeDeletedURLs)
+	}
+
+	// finished deletion jobs are purged only if retention is not negative
+	if config.DeletionJobsRetention >= 0 {
+		appUsecase.purgeDeletionJobsTicker = time.NewTicker(config.PurgeDeletionJobsWaitingTime)
+		appUsecase.runWorker(appUsecase.purgeDeletionJobs)
+	}
+
+	// storage is compacted periodically only if it supports compaction and waiting time is positive
+	if _, ok := appRepo.(StorageCompactor); ok && config.CompactStorageWaitingTime > 0 {
+		appUsecase.compactStorageTicker = time.NewTicker(config.CompactStorageWaitingTime)
+		appUsecase.runWorker(appUsecase.compactStorage)
+	}
 
 	return appUsecase, nil
 }
 
-func (au *AppUsecase) generateID() (string, error) {
+// runWorker runs background task, Close waits for its finish.
+func (au *AppUsecase) runWorker(worker func()) {
+	au.wg.Add(1)
+	go func() {
+		defer au.wg.Done()
+		worker()
+	}()
+}
+
+// ReserveIDs forbids IDs which are used by app routes as aliases and generated IDs.
+// It must be called before usecase starts handling requests.
+func (au *AppUsecase) ReserveIDs(ids ...string) {
+	if au.reservedIDs == nil {
+		au.reservedIDs = make(map[string]struct{}, len(ids))
+	}
+	for _, id := range ids {
+		au.reservedIDs[id] = struct{}{}
+	}
+}
+
+// checkReservedID returns ErrReservedAlias if id is reserved by app route.
+func (au *AppUsecase) checkReservedID(id string) error {
+	if _, ok := au.reservedIDs[id]; ok {
+		return ErrReservedAlias
+	}
+	return nil
+}
+
+// validateAlias checks symbols and length of alias and that it is not reserved.
+func (au *AppUsecase) validateAlias(alias string) error {
+	err := validateAlias(alias)
+	if err != nil {
+		return err
+	}
+	return au.checkReservedID(alias)
+}
+
+func (au *AppUsecase) generateID(ctx context.Context) (string, error) {
 	if au.LengthID > au.MaxLengthID {
 		return "", ErrMaxLengthIDLessLengthID
 	}
@@ -142,46 +403,122 @@ func (au *AppUsecase) generateID() (string, error) {
 		if err != nil {
 			return "", err
 		}
-		checked, err = au.AppRepo.CheckIDExistence(id)
+		if au.checkReservedID(id) != nil {
+			checked = true
+			continue
+		}
+		checked, err = au.AppRepo.CheckIDExistence(ctx, id)
 		if err != nil {
 			return "", err
 		}
 		if checked {
+			metrics.IDGenerationRetriesTotal.Inc()
 			continue
 		}
 		break
 	}
 
 	if checked {
+		metrics.IDLengthEscalationsTotal.Inc()
 		au.LengthID++
-		return au.generateID()
+		return au.generateID(ctx)
 	}
 
 	return id, nil
 }
 
 // GetOrCreateURL get created or create short URL for request URL.
-// Func generate unique short URL for rawURL, save and return it or return short URL (if rawURL existed).
+// Func generate unique short URL for rawURL (or use alias if it is not empty), save and return it
+// or return short URL (if rawURL existed). New URL expires at expiresAt if it is not nil.
+// Alias of deleted or expired URL is taken till the URL is purged, it is not returned as short URL of rawURL.
+// Alias is taken too if rawURL is already shortened with another ID.
+// Func returns ErrURLDeleted if rawURL is shortened, but its short URL is deleted or expired and is not purged yet.
 // Func return URL struct, true if rawURL is new or false if rawURL exists and error.
-func (au *AppUsecase) GetOrCreateURL(rawURL string, userID uint) (*app.URL, bool, error) {
+func (au *AppUsecase) GetOrCreateURL(ctx context.Context, rawURL, alias string, expiresAt *time.Time, userID uint) (*app.URL, bool, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.GetOrCreateURL")
+	defer span.End()
+
 	_, err := parseURL(rawURL)
 	if err != nil {
 		return nil, false, err
 	}
-	id, err := au.generateID()
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, false, ErrInvalidExpiration
+	}
+
+	if alias != "" {
+		return au.getOrCreateURLWithAlias(ctx, rawURL, alias, expiresAt, userID)
+	}
+
+	id, err := au.generateID(ctx)
 	if err != nil {
 		return nil, false, err
 	}
-	appURL, err := au.AppRepo.GetOrCreateURL(id, rawURL, userID)
+	appURL, err := au.AppRepo.GetOrCreateURL(ctx, id, rawURL, userID, expiresAt)
 	if err != nil {
 		return nil, false, err
 	}
+	// short URL of deleted or expired URL does not redirect, so it is not returned as existing short URL
+	if appURL.ID != id && (appURL.IsDeleted || appURL.IsExpired(time.Now())) {
+		return nil, false, ErrURLDeleted
+	}
 	return appURL, appURL.ID != id, err
 }
 
+func (au *AppUsecase) getOrCreateURLWithAlias(ctx context.Context, rawURL, alias string, expiresAt *time.Time, userID uint) (*app.URL, bool, error) {
+	err := au.validateAlias(alias)
+	if err != nil {
+		return nil, false, err
+	}
+
+	exists, err := au.AppRepo.CheckIDExistence(ctx, alias)
+	if err != nil {
+		return nil, false, err
+	}
+	if exists {
+		appURL, err := au.AppRepo.GetURL(ctx, alias)
+		if err != nil {
+			return nil, false, err
+		}
+		if appURL.URL != rawURL || appURL.IsDeleted || appURL.IsExpired(time.Now()) {
+			return nil, false, ErrAliasTaken
+		}
+		// every user has own short URLs, so alias of another user is not returned
+		if au.URLUniqueness == app.URLUniquenessUser && appURL.UserID != userID {
+			return nil, false, ErrAliasTaken
+		}
+		return appURL, true, nil
+	}
+
+	appURL, err := au.AppRepo.GetOrCreateURL(ctx, alias, rawURL, userID, expiresAt)
+	if errors.Is(err, app.ErrURLIDExists) {
+		return nil, false, ErrAliasTaken
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	// rawURL is already shortened with another ID, so alias can not be given to it
+	if appURL.ID != alias {
+		return nil, false, ErrAliasTaken
+	}
+	return appURL, false, nil
+}
+
 // GetURL get original URL for short URL.
-func (au *AppUsecase) GetURL(id string) (*app.URL, error) {
-	return au.AppRepo.GetURL(id)
+func (au *AppUsecase) GetURL(ctx context.Context, id string) (*app.URL, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.GetURL")
+	defer span.End()
+
+	return au.AppRepo.GetURL(ctx, id)
+}
+
+// CountURLs get count of short URLs.
+func (au *AppUsecase) CountURLs(ctx context.Context) (uint, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.CountURLs")
+	defer span.End()
+
+	return au.AppRepo.CountURLs(ctx)
 }
 
 // GenerateShortURL generate short URL.
@@ -190,120 +527,946 @@ func (au *AppUsecase) GenerateShortURL(id string) string {
 	return au.BaseURL + id
 }
 
-// Ping ping database.
-func (au *AppUsecase) Ping() error {
-	return au.db.Ping()
+// Ping checks that storage is available: database is reachable or storage files are writable.
+func (au *AppUsecase) Ping(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "AppUsecase.Ping")
+	defer span.End()
+
+	return au.AppRepo.Ping(ctx)
 }
 
-// GetOrCreateURLs get created or create short URLs for request batch URLs.
-// Func generate unique short URL for every OriginalURL (or get existed short URL for OriginalURL) in requestBatchURLs,
-// save new URLs in repo and return []app.ResponseBatchURL.
-func (au *AppUsecase) GetOrCreateURLs(requestBatchURLs []app.RequestBatchURL, userID uint) ([]app.ResponseBatchURL, error) {
-	urls := []*app.URL{}
-	for _, rbu := range requestBatchURLs {
-		id, err := au.generateID()
+// checkDeletionWorker checks that deletion worker is running and was not stalled at the moment now.
+func (au *AppUsecase) checkDeletionWorker(now time.Time) error {
+	heartbeat := au.deleteURLsHeartbeat.Load()
+	if heartbeat == 0 {
+		return ErrDeletionWorkerStopped
+	}
+	if now.Sub(time.Unix(0, heartbeat)) > DeletionWorkerStallFactor*au.deleteURLsWaitingTime {
+		return ErrDeletionWorkerStalled
+	}
+	return nil
+}
+
+// StartShutdown marks app as not ready to serve new requests.
+func (au *AppUsecase) StartShutdown() {
+	au.shuttingDown.Store(true)
+}
+
+// CheckReadiness checks storage, deletion worker and shutdown state.
+// App is ready if all checks passed.
+func (au *AppUsecase) CheckReadiness(ctx context.Context) *app.ResponseReadiness {
+	ctx, span := tracing.Start(ctx, "AppUsecase.CheckReadiness")
+	defer span.End()
+
+	var shutdownErr error
+	if au.shuttingDown.Load() {
+		shutdownErr = ErrShuttingDown
+	}
+
+	errs := map[string]error{
+		app.ReadinessStorage:        au.Ping(ctx),
+		app.ReadinessDeletionWorker: au.checkDeletionWorker(time.Now()),
+		app.ReadinessShutdown:       shutdownErr,
+	}
+
+	resp := &app.ResponseReadiness{
+		Status: app.HealthStatusOK,
+		Checks: make(map[string]app.ResponseHealthCheck, len(errs)),
+	}
+	for name, err := range errs {
+		check := app.ResponseHealthCheck{Status: app.HealthStatusOK}
 		if err != nil {
-			return nil, err
+			check = app.ResponseHealthCheck{Status: app.HealthStatusFail, Error: err.Error()}
+			resp.Status = app.HealthStatusFail
 		}
-		urls = append(urls, &app.URL{ID: id, URL: rbu.OriginalURL, UserID: userID})
+		resp.Checks[name] = check
 	}
+	return resp
+}
 
-	urls, err := au.AppRepo.GetOrCreateURLs(urls)
+// GetOrCreateURLs get created or create short URLs for request batch URLs.
+// Func generate unique short URL (or use Alias if it is not empty) for every OriginalURL
+// (or get existed short URL for OriginalURL) in requestBatchURLs,
+// save new URLs in repo and return []app.ResponseBatchURL.
+// New URL expires at ExpiresAt or after TTL if one of them is set.
+// Func returns ErrAliasTaken if OriginalURL with Alias is already shortened with another ID
+// and ErrURLDeleted if short URL of OriginalURL is deleted or expired and is not purged yet.
+func (au *AppUsecase) GetOrCreateURLs(ctx context.Context, requestBatchURLs []app.RequestBatchURL, userID uint) ([]app.ResponseBatchURL, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.GetOrCreateURLs")
+	defer span.End()
+
+	newURLs, urls, err := au.saveBatchURLs(ctx, requestBatchURLs, userID)
 	if err != nil {
 		return nil, err
 	}
+	now := time.Now()
+	for i, appURL := range urls {
+		// original URL is already shortened with another ID, so alias can not be given to it
+		if alias := requestBatchURLs[i].Alias; alias != "" && appURL.ID != alias {
+			return nil, ErrAliasTaken
+		}
+		// short URL of deleted or expired URL does not redirect, so it is not returned as existing short URL
+		if appURL.ID != newURLs[i].ID && (appURL.IsDeleted || appURL.IsExpired(now)) {
+			return nil, ErrURLDeleted
+		}
+	}
 
-	responseBatchURLs := []app.ResponseBatchURL{}
-	for _, appURL := range urls {
-		for _, rbu := range requestBatchURLs {
-			if appURL.URL == rbu.OriginalURL {
-				responseBatchURLs = append(responseBatchURLs, app.ResponseBatchURL{
-					CorrelationID: rbu.CorrelationID,
-					ShortURL:      au.GenerateShortURL(appURL.ID),
-				})
+	responseBatchURLs := make([]app.ResponseBatchURL, 0, len(urls))
+	for i, appURL := range urls {
+		responseBatchURLs = append(responseBatchURLs, app.ResponseBatchURL{
+			CorrelationID: requestBatchURLs[i].CorrelationID,
+			ShortURL:      au.GenerateShortURL(appURL.ID),
+		})
+	}
+
+	return responseBatchURLs, nil
+}
+
+// saveBatchURLs saves request batch URLs like GetOrCreateURLs.
+// Func returns URLs with requested or generated IDs and saved URLs for every request URL in the same order.
+func (au *AppUsecase) saveBatchURLs(ctx context.Context, requestBatchURLs []app.RequestBatchURL, userID uint) ([]*app.URL, []*app.URL, error) {
+	now := time.Now()
+	urls := []*app.URL{}
+	for _, rbu := range requestBatchURLs {
+		_, err := parseURL(rbu.OriginalURL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		expiresAt, err := GetExpirationTime(rbu.ExpiresAt, rbu.TTL, now)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		id := rbu.Alias
+		if id == "" {
+			var err error
+			id, err = au.generateID(ctx)
+			if err != nil {
+				return nil, nil, err
 			}
+		} else if err := au.validateAlias(id); err != nil {
+			return nil, nil, err
 		}
+		urls = append(urls, &app.URL{ID: id, URL: rbu.OriginalURL, UserID: userID, ExpiresAt: expiresAt})
 	}
 
-	return responseBatchURLs, nil
+	// repo returns saved URL for every request URL in the same order
+	savedURLs, err := au.AppRepo.GetOrCreateURLs(ctx, urls)
+	if errors.Is(err, app.ErrURLIDExists) {
+		return nil, nil, ErrAliasTaken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return urls, savedURLs, nil
 }
 
-// GetUserURLs get short and original URLs for user.
-func (au *AppUsecase) GetUserURLs(userID uint) ([]app.ResponseUserURL, error) {
-	urls, err := au.AppRepo.GetUserURLs(userID)
+// GetUserURLs get page of short and original URLs for user sorted by creation time.
+// Func return cursor of next page or empty string if page is last.
+func (au *AppUsecase) GetUserURLs(ctx context.Context, userID uint, params app.UserURLsParams) ([]app.ResponseUserURL, string, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.GetUserURLs")
+	defer span.End()
+
+	if params.Limit > MaxUserURLsLimit {
+		return nil, "", ErrInvalidLimit
+	}
+
+	filter := &app.UserURLsFilter{
+		Search:         params.Search,
+		IncludeDeleted: params.IncludeDeleted,
+		Desc:           params.Desc,
+	}
+	if params.Cursor != "" {
+		after, err := decodeURLCursor(params.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.After = after
+	}
+	// user URLs are always read by pages, so request without limit does not load all URLs of user
+	limit := params.Limit
+	if limit == 0 {
+		limit = min(DefaultUserURLsLimit, MaxUserURLsLimit)
+	}
+	// one more URL to know that next page exists
+	filter.Limit = limit + 1
+
+	urls, err := au.AppRepo.GetUserURLs(ctx, userID, filter)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if uint(len(urls)) > limit {
+		urls = urls[:limit]
+		lastURL := urls[len(urls)-1]
+		nextCursor, err = encodeURLCursor(&app.URLCursor{CreatedAt: lastURL.CreatedAt, ID: lastURL.ID})
+		if err != nil {
+			return nil, "", err
+		}
 	}
 
 	responseUserURLs := []app.ResponseUserURL{}
 	for _, appURL := range urls {
-		responseUserURLs = append(responseUserURLs, app.ResponseUserURL{
+		responseUserURL := app.ResponseUserURL{
 			ShortURL:    au.GenerateShortURL(appURL.ID),
 			OriginalURL: appURL.URL,
+			ExpiresAt:   appURL.ExpiresAt,
+			IsDeleted:   appURL.IsDeleted,
+			DeletedAt:   appURL.DeletedAt,
+		}
+		if !appURL.CreatedAt.IsZero() {
+			createdAt := appURL.CreatedAt
+			responseUserURL.CreatedAt = &createdAt
+		}
+		responseUserURLs = append(responseUserURLs, responseUserURL)
+	}
+
+	return responseUserURLs, nextCursor, nil
+}
+
+// ExportUserURLs passes all URLs of user including deleted sorted by creation time to write page by page.
+// Pages are read after cursor of previous page, so URLs of user are not loaded at once.
+// Export is stopped by error of write.
+func (au *AppUsecase) ExportUserURLs(ctx context.Context, userID uint, write func(records []app.UserURLRecord) error) error {
+	ctx, span := tracing.Start(ctx, "AppUsecase.ExportUserURLs")
+	defer span.End()
+
+	filter := &app.UserURLsFilter{Limit: ExportPageSize, IncludeDeleted: true}
+	for {
+		urls, err := au.AppRepo.GetUserURLs(ctx, userID, filter)
+		if err != nil {
+			return err
+		}
+		if len(urls) == 0 {
+			return nil
+		}
+
+		records := make([]app.UserURLRecord, 0, len(urls))
+		for _, appURL := range urls {
+			record := app.UserURLRecord{
+				ShortURL:    au.GenerateShortURL(appURL.ID),
+				OriginalURL: appURL.URL,
+				IsDeleted:   appURL.IsDeleted,
+			}
+			if !appURL.CreatedAt.IsZero() {
+				createdAt := appURL.CreatedAt
+				record.CreatedAt = &createdAt
+			}
+			records = append(records, record)
+		}
+		if err = write(records); err != nil {
+			return err
+		}
+
+		if uint(len(urls)) < filter.Limit {
+			return nil
+		}
+		last := urls[len(urls)-1]
+		filter.After = &app.URLCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// ImportUserURLs saves URLs of records for user like GetOrCreateURLs and returns outcome of every record.
+// Short ID of record is used as alias, deleted records are skipped.
+// Records are saved in batches, batch with taken alias is saved record by record
+// to find records with taken aliases.
+func (au *AppUsecase) ImportUserURLs(ctx context.Context, userID uint, records []app.UserURLRecord) ([]app.ResponseImportUserURL, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.ImportUserURLs")
+	defer span.End()
+
+	if len(records) > MaxImportUserURLs {
+		return nil, ErrTooManyImportURLs
+	}
+
+	responses := make([]app.ResponseImportUserURL, len(records))
+	batch := make([]app.RequestBatchURL, 0, ImportBatchSize)
+	for i, record := range records {
+		responses[i].Row = i + 1
+		if record.IsDeleted {
+			responses[i].Status = app.ImportStatusSkipped
+			continue
+		}
+
+		alias, err := parseShortURLID(record.ShortURL)
+		if err == nil && alias != "" {
+			err = au.checkReservedID(alias)
+		}
+		if err == nil && record.OriginalURL == "" {
+			err = ErrEmptyOriginalURL
+		}
+		if err == nil {
+			_, err = parseURL(record.OriginalURL)
+		}
+		if err != nil {
+			responses[i].Status = app.ImportStatusInvalid
+			responses[i].Error = err.Error()
+			continue
+		}
+
+		// correlation ID is index of record
+		batch = append(batch, app.RequestBatchURL{
+			CorrelationID: strconv.Itoa(i),
+			OriginalURL:   record.OriginalURL,
+			Alias:         alias,
 		})
+		if len(batch) == ImportBatchSize {
+			if err := au.importURLs(ctx, userID, batch, responses); err != nil {
+				return nil, err
+			}
+			batch = batch[:0]
+		}
 	}
 
-	return responseUserURLs, nil
+	if err := au.importURLs(ctx, userID, batch, responses); err != nil {
+		return nil, err
+	}
+
+	return responses, nil
 }
 
-// SendDeleteUserURLsInChan send urls in delete chan.
-func (au *AppUsecase) SendDeleteUserURLsInChan(userID uint, urlIDs []string) {
-	go func() {
-		for _, urlID := range urlIDs {
-			select {
-			case <-au.doneCh:
-				return
-			case au.deleteURLsChan <- &app.URL{ID: urlID, UserID: userID}:
+// importURLs saves batch of imported URLs and sets outcomes of their records in responses.
+func (au *AppUsecase) importURLs(ctx context.Context, userID uint, batch []app.RequestBatchURL, responses []app.ResponseImportUserURL) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	urls, savedURLs, err := au.saveBatchURLs(ctx, batch, userID)
+	if errors.Is(err, ErrAliasTaken) && len(batch) > 1 {
+		for i := range batch {
+			if err := au.importURLs(ctx, userID, batch[i:i+1], responses); err != nil {
+				return err
 			}
 		}
+		return nil
+	}
+	if errors.Is(err, ErrAliasTaken) {
+		i, _ := strconv.Atoi(batch[0].CorrelationID)
+		responses[i].Status = app.ImportStatusAliasTaken
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// URL is imported only if it is saved with short ID of row or with generated ID
+	for j, savedURL := range savedURLs {
+		i, _ := strconv.Atoi(batch[j].CorrelationID)
+		responses[i].ShortURL = au.GenerateShortURL(savedURL.ID)
+		responses[i].Status = app.ImportStatusImported
+		if savedURL.ID != urls[j].ID {
+			responses[i].Status = app.ImportStatusExists
+		}
+	}
+	return nil
+}
+
+// EnqueueDeleteUserURLs saves job to delete user URLs in persistent queue and returns job ID.
+// URLs are deleted in background, failed job is retried with exponential backoff.
+func (au *AppUsecase) EnqueueDeleteUserURLs(ctx context.Context, userID uint, urlIDs []string) (string, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.EnqueueDeleteUserURLs")
+	defer span.End()
+
+	now := time.Now()
+	job := &app.DeletionJob{
+		ID:            uuid.NewString(),
+		UserID:        userID,
+		URLIDs:        urlIDs,
+		Status:        app.DeletionJobPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	err := au.AppRepo.AddDeletionJob(ctx, job)
+	if err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// RestoreUserURLs cancels deletion of user URLs and returns status of every requested URL.
+// URL is restored only if it is deleted, is not expired and is not purged yet.
+func (au *AppUsecase) RestoreUserURLs(ctx context.Context, userID uint, urlIDs []string) ([]app.ResponseRestoreUserURL, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.RestoreUserURLs")
+	defer span.End()
+
+	if len(urlIDs) > MaxRestoreUserURLs {
+		return nil, ErrTooManyRestoreURLs
+	}
+
+	restoredIDs, err := au.AppRepo.RestoreUserURLs(ctx, userID, urlIDs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	restored := make(map[string]struct{}, len(restoredIDs))
+	for _, id := range restoredIDs {
+		restored[id] = struct{}{}
+	}
+
+	responses := make([]app.ResponseRestoreUserURL, 0, len(urlIDs))
+	for _, id := range urlIDs {
+		status := app.RestoreURLIgnored
+		if _, ok := restored[id]; ok {
+			status = app.RestoreURLRestored
+		}
+		responses = append(responses, app.ResponseRestoreUserURL{ID: id, Status: status})
+	}
+
+	return responses, nil
+}
+
+// GetDeletionJob get status of user deletion job and of every URL in it.
+// URL has status of job, but URL which did not exist or belonged to another user when job was done is ignored.
+// Status of URL is saved when job is done, so later restore or purge of URL does not change it.
+func (au *AppUsecase) GetDeletionJob(ctx context.Context, jobID string, userID uint) (*app.ResponseDeletionJob, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.GetDeletionJob")
+	defer span.End()
+
+	job, err := au.AppRepo.GetDeletionJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.UserID != userID {
+		return nil, ErrForbidden
+	}
+
+	resp := &app.ResponseDeletionJob{
+		JobID:     job.ID,
+		Status:    job.Status,
+		Attempts:  job.Attempts,
+		CreatedAt: job.CreatedAt,
+		URLs:      make([]app.ResponseDeletionJobURL, 0, len(job.URLIDs)),
+	}
+	for _, urlID := range job.URLIDs {
+		status := job.Status
+		if job.Status == app.DeletionJobDone && slices.Contains(job.IgnoredURLIDs, urlID) {
+			status = app.DeletionURLIgnored
+		}
+		resp.URLs = append(resp.URLs, app.ResponseDeletionJobURL{ID: urlID, Status: status})
+	}
+
+	return resp, nil
+}
+
+// retryDelay returns delay before next attempt of job failed attempts times.
+func retryDelay(attempts uint, baseDelay, maxDelay time.Duration) time.Duration {
+	delay := baseDelay
+	for i := uint(1); i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+func deletionJobsURLs(jobs ...*app.DeletionJob) []*app.URL {
+	urls := []*app.URL{}
+	for _, job := range jobs {
+		for _, urlID := range job.URLIDs {
+			urls = append(urls, &app.URL{ID: urlID, UserID: job.UserID})
+		}
+	}
+	return urls
+}
+
+func (au *AppUsecase) deleteURLs(ctx context.Context, urls []*app.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	return au.AppRepo.DeleteUserURLs(ctx, urls)
+}
+
+// setIgnoredURLs saves in job IDs of URLs which do not exist or belong to another user.
+func (au *AppUsecase) setIgnoredURLs(ctx context.Context, job *app.DeletionJob) error {
+	userURLIDs, err := au.AppRepo.GetUserURLIDs(ctx, job.UserID, job.URLIDs)
+	if err != nil {
+		return err
+	}
+
+	job.IgnoredURLIDs = nil
+	for _, urlID := range job.URLIDs {
+		if !slices.Contains(userURLIDs, urlID) {
+			job.IgnoredURLIDs = append(job.IgnoredURLIDs, urlID)
+		}
+	}
+	return nil
+}
+
+// finishDeletionJobAttempt saves result of deletion job attempt.
+// Outcome of every URL is saved with done job, attempt is failed if it can not be got.
+func (au *AppUsecase) finishDeletionJobAttempt(ctx context.Context, job *app.DeletionJob, deleteErr error, now time.Time) {
+	logger := loggerInternal.Log
+
+	if deleteErr == nil {
+		deleteErr = au.setIgnoredURLs(ctx, job)
+	}
+
+	if deleteErr == nil {
+		job.Status = app.DeletionJobDone
+		job.LastError = ""
+		job.FinishedAt = &now
+	} else {
+		job.Attempts++
+		job.LastError = deleteErr.Error()
+		if job.Attempts >= au.deleteURLsMaxAttempts {
+			job.Status = app.DeletionJobFailed
+			job.FinishedAt = &now
+			logger.Error("Deletion job attempts are exhausted, job is moved to dead letter",
+				zap.String("job_id", job.ID),
+				zap.Uint("attempts", job.Attempts),
+				zap.Error(deleteErr),
+			)
+		} else {
+			job.NextAttemptAt = now.Add(retryDelay(job.Attempts, au.deleteURLsRetryBaseDelay, au.deleteURLsRetryMaxDelay))
+			logger.Warn("Failed to delete user URLs, deletion job will be retried",
+				zap.String("job_id", job.ID),
+				zap.Uint("attempts", job.Attempts),
+				zap.Time("next_attempt_at", job.NextAttemptAt),
+				zap.Error(deleteErr),
+			)
+		}
+	}
+
+	// job stays pending if it is not saved, so it will be processed again
+	err := au.AppRepo.UpdateDeletionJob(ctx, job)
+	if err != nil {
+		logger.Error("Failed to update deletion job",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// processDeletionJobs deletes URLs of due deletion jobs in one batch.
+// If batch fails, every job is attempted separately, so one failed job does not block others.
+// Func returns count of processed jobs.
+func (au *AppUsecase) processDeletionJobs(ctx context.Context, now time.Time) int {
+	logger := loggerInternal.Log
+
+	ctx, span := tracing.Start(ctx, "AppUsecase.processDeletionJobs")
+	defer span.End()
+
+	jobs, err := au.AppRepo.GetDueDeletionJobs(ctx, now, au.deleteURLsBatchSize)
+	if err != nil {
+		logger.Error("Failed to get deletion jobs",
+			zap.Error(err),
+		)
+		return 0
+	}
+	if len(jobs) == 0 {
+		return 0
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.DeleteQueueFlushDuration.Observe(time.Since(start).Seconds())
 	}()
+
+	urls := deletionJobsURLs(jobs...)
+	logger.Debug("Deleting user URLs",
+		zap.Any("urls", urls),
+	)
+	err = au.deleteURLs(ctx, urls)
+	if err == nil {
+		for _, job := range jobs {
+			au.finishDeletionJobAttempt(ctx, job, nil, now)
+		}
+		return len(jobs)
+	}
+
+	logger.Warn("Failed to delete user URLs in batch",
+		zap.Error(err),
+	)
+	for _, job := range jobs {
+		au.finishDeletionJobAttempt(ctx, job, au.deleteURLs(ctx, deletionJobsURLs(job)), now)
+	}
+	return len(jobs)
+}
+
+// drainDeletionJobs processes due deletion jobs batch by batch till queue is empty or ctx is done.
+// Not processed and retried jobs are left in queue till restart.
+func (au *AppUsecase) drainDeletionJobs(ctx context.Context) {
+	for ctx.Err() == nil {
+		processed := au.processDeletionJobs(ctx, time.Now())
+		if processed == 0 || uint(processed) < au.deleteURLsBatchSize {
+			return
+		}
+	}
+}
+
+// updateDeleteQueueDepth updates metric of pending deletion jobs count.
+func (au *AppUsecase) updateDeleteQueueDepth() {
+	ctx, span := tracing.Start(context.Background(), "AppUsecase.updateDeleteQueueDepth")
+	defer span.End()
+
+	count, err := au.AppRepo.CountPendingDeletionJobs(ctx)
+	if err != nil {
+		loggerInternal.Log.Error("Failed to count pending deletion jobs",
+			zap.Error(err),
+		)
+		return
+	}
+	metrics.DeleteQueueDepth.Set(float64(count))
 }
 
 func (au *AppUsecase) deleteUserURLs() {
+	au.deleteURLsHeartbeat.Store(time.Now().UnixNano())
+	defer au.deleteURLsHeartbeat.Store(0)
+
+	for {
+		select {
+		case now := <-au.deleteURLsTicker.C:
+			au.processDeletionJobs(context.Background(), now)
+			au.updateDeleteQueueDepth()
+			au.deleteURLsHeartbeat.Store(time.Now().UnixNano())
+		case <-au.doneCh:
+			au.drainDeletionJobs(au.drainCtx)
+			return
+		}
+	}
+}
+
+func (au *AppUsecase) deleteExpiredURLs() {
 	logger := loggerInternal.Log
 
-	urls := make([]*app.URL, 0, 2*len(au.deleteURLsChan))
+	for {
+		select {
+		case now := <-au.deleteExpiredURLsTicker.C:
+			logger.Debug("Deleting expired URLs",
+				zap.Time("now", now),
+			)
+			ctx, span := tracing.Start(context.Background(), "AppUsecase.deleteExpiredURLs")
+			err := au.AppRepo.DeleteExpiredURLs(ctx, now)
+			if err != nil {
+				logger.Error("Failed to delete expired URLs",
+					zap.Error(err),
+				)
+			}
+			span.End()
+		case <-au.doneCh:
+			return
+		}
+	}
+}
+
+// purgeDeletedURLs removes URLs deleted before now minus grace period.
+// Removed URLs can not be restored, their IDs and original URLs can be used again.
+func (au *AppUsecase) purgeDeletedURLs() {
+	logger := loggerInternal.Log
 
 	for {
 		select {
-		case appURL := <-au.deleteURLsChan:
-			urls = append(urls, appURL)
-		case <-au.deleteURLsTicker.C:
-			if len(urls) == 0 {
+		case now := <-au.purgeDeletedURLsTicker.C:
+			deletedBefore := now.Add(-au.deletedURLsGracePeriod)
+			ctx, span := tracing.Start(context.Background(), "AppUsecase.purgeDeletedURLs")
+			count, err := au.AppRepo.PurgeDeletedURLs(ctx, deletedBefore)
+			span.End()
+			if err != nil {
+				logger.Error("Failed to purge deleted URLs",
+					zap.Error(err),
+				)
 				continue
 			}
-			logger.Debug("Deleting user URLs",
-				zap.Any("urls", urls),
-			)
-			err := au.AppRepo.DeleteUserURLs(urls)
+			if count > 0 {
+				logger.Info("Deleted URLs purged",
+					zap.Uint("count", count),
+					zap.Time("deleted_before", deletedBefore),
+				)
+			}
+		case <-au.doneCh:
+			return
+		}
+	}
+}
+
+// purgeDeletionJobs removes done and dead letter deletion jobs finished before now minus retention.
+// Status of removed job can not be got anymore.
+func (au *AppUsecase) purgeDeletionJobs() {
+	logger := loggerInternal.Log
+
+	for {
+		select {
+		case now := <-au.purgeDeletionJobsTicker.C:
+			finishedBefore := now.Add(-au.deletionJobsRetention)
+			ctx, span := tracing.Start(context.Background(), "AppUsecase.purgeDeletionJobs")
+			count, err := au.AppRepo.PurgeDeletionJobs(ctx, finishedBefore)
+			span.End()
 			if err != nil {
-				logger.Error("Failed to delete user URLs",
+				logger.Error("Failed to purge deletion jobs",
 					zap.Error(err),
 				)
 				continue
 			}
-			urls = urls[:0]
-		case <-au.doneCh:
-			if len(urls) == 0 {
-				return
+			if count > 0 {
+				logger.Info("Deletion jobs purged",
+					zap.Uint("count", count),
+					zap.Time("finished_before", finishedBefore),
+				)
 			}
-			logger.Debug("Deleting user URLs",
-				zap.Any("urls", urls),
+		case <-au.doneCh:
+			return
+		}
+	}
+}
+
+// SendURLClickInChan send redirect to URL in clicks chan.
+// Click is dropped if chan is full to not slow down redirects.
+// Dropped clicks are counted in metrics and logged at most once per DroppedClicksLogInterval.
+func (au *AppUsecase) SendURLClickInChan(urlID string) {
+	now := time.Now()
+	select {
+	case au.clicksChan <- &app.URLClicks{ID: urlID, Count: 1, LastAccessedAt: now}:
+	default:
+		metrics.ClicksDroppedTotal.Inc()
+		au.droppedClicks.Add(1)
+
+		loggedAt := au.droppedClicksLogAt.Load()
+		if now.UnixNano()-loggedAt < int64(DroppedClicksLogInterval) || !au.droppedClicksLogAt.CompareAndSwap(loggedAt, now.UnixNano()) {
+			return
+		}
+		loggerInternal.Log.Warn("Clicks chan is full, clicks are dropped",
+			zap.Uint64("dropped_clicks", au.droppedClicks.Swap(0)),
+		)
+	}
+}
+
+func (au *AppUsecase) addURLsClicks() {
+	logger := loggerInternal.Log
+
+	urlsClicks := make(map[string]*app.URLClicks, cap(au.clicksChan))
+
+	flush := func(ctx context.Context) {
+		if len(urlsClicks) == 0 {
+			return
+		}
+		urlsClicksSlice := make([]*app.URLClicks, 0, len(urlsClicks))
+		for _, urlClicks := range urlsClicks {
+			urlsClicksSlice = append(urlsClicksSlice, urlClicks)
+		}
+		logger.Debug("Adding URLs clicks",
+			zap.Any("urls_clicks", urlsClicksSlice),
+		)
+		ctx, span := tracing.Start(ctx, "AppUsecase.addURLsClicks")
+		defer span.End()
+		err := au.AppRepo.AddURLsClicks(ctx, urlsClicksSlice)
+		if err != nil {
+			logger.Error("Failed to add URLs clicks",
+				zap.Error(err),
 			)
-			err := au.AppRepo.DeleteUserURLs(urls)
+			return
+		}
+		clear(urlsClicks)
+	}
+
+	add := func(urlClick *app.URLClicks) {
+		urlClicks, ok := urlsClicks[urlClick.ID]
+		if !ok {
+			urlsClicks[urlClick.ID] = urlClick
+			return
+		}
+		urlClicks.Count += urlClick.Count
+		if urlClicks.LastAccessedAt.Before(urlClick.LastAccessedAt) {
+			urlClicks.LastAccessedAt = urlClick.LastAccessedAt
+		}
+	}
+
+	for {
+		select {
+		case urlClick := <-au.clicksChan:
+			add(urlClick)
+		case <-au.clicksTicker.C:
+			flush(context.Background())
+		case <-au.doneCh:
+			// clicks sent before shutdown are still in chan
+			for len(au.clicksChan) > 0 {
+				add(<-au.clicksChan)
+			}
+			flush(au.drainCtx)
+			return
+		}
+	}
+}
+
+// GetURLStats get statistics of user URL.
+func (au *AppUsecase) GetURLStats(ctx context.Context, id string, userID uint) (*app.ResponseURLStats, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.GetURLStats")
+	defer span.End()
+
+	appURL, err := au.AppRepo.GetURL(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if appURL.UserID != userID {
+		return nil, ErrForbidden
+	}
+
+	return &app.ResponseURLStats{
+		ShortURL:       au.GenerateShortURL(appURL.ID),
+		OriginalURL:    appURL.URL,
+		Clicks:         appURL.Clicks,
+		LastAccessedAt: appURL.LastAccessedAt,
+		IsDeleted:      appURL.IsDeleted,
+		ExpiresAt:      appURL.ExpiresAt,
+	}, nil
+}
+
+// UpdateUserURL changes original URL of user URL, short URL is not changed.
+// Deleted or expired URL can not be changed. Original URL can not be changed to URL shortened with another ID.
+func (au *AppUsecase) UpdateUserURL(ctx context.Context, id string, userID uint, rawURL string) (*app.ResponseUpdateUserURL, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.UpdateUserURL")
+	defer span.End()
+
+	_, err := parseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	appURL, err := au.AppRepo.GetURL(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if appURL.UserID != userID {
+		return nil, ErrForbidden
+	}
+
+	now := time.Now()
+	if appURL.IsDeleted || appURL.IsExpired(now) {
+		return nil, ErrURLDeleted
+	}
+
+	appURL, err = au.AppRepo.UpdateURL(ctx, id, userID, rawURL, now)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := au.getURLHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &app.ResponseUpdateUserURL{
+		ShortURL:    au.GenerateShortURL(appURL.ID),
+		OriginalURL: appURL.URL,
+		History:     history,
+	}, nil
+}
+
+// GetUserURLHistory get changes of original URL of user URL sorted by time.
+func (au *AppUsecase) GetUserURLHistory(ctx context.Context, id string, userID uint) ([]app.ResponseURLEdit, error) {
+	ctx, span := tracing.Start(ctx, "AppUsecase.GetUserURLHistory")
+	defer span.End()
+
+	appURL, err := au.AppRepo.GetURL(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if appURL.UserID != userID {
+		return nil, ErrForbidden
+	}
+
+	return au.getURLHistory(ctx, id)
+}
+
+func (au *AppUsecase) getURLHistory(ctx context.Context, id string) ([]app.ResponseURLEdit, error) {
+	edits, err := au.AppRepo.GetURLEdits(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]app.ResponseURLEdit, 0, len(edits))
+	for _, edit := range edits {
+		history = append(history, app.ResponseURLEdit{OldURL: edit.OldURL, NewURL: edit.NewURL, EditedAt: edit.EditedAt})
+	}
+	return history, nil
+}
+
+// CompactStorage rewrites log of storage to snapshot, so startup reads snapshot and short tail of log.
+func (au *AppUsecase) CompactStorage(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "AppUsecase.CompactStorage")
+	defer span.End()
+
+	compactor, ok := au.AppRepo.(StorageCompactor)
+	if !ok {
+		return ErrCompactionNotSupported
+	}
+
+	return compactor.Compact(ctx)
+}
+
+func (au *AppUsecase) compactStorage() {
+	logger := loggerInternal.Log
+
+	for {
+		select {
+		case <-au.compactStorageTicker.C:
+			start := time.Now()
+			err := au.CompactStorage(context.Background())
 			if err != nil {
-				logger.Error("Failed to delete user URLs",
+				logger.Error("Failed to compact storage",
 					zap.Error(err),
 				)
 				continue
 			}
+			logger.Info("Storage compacted",
+				zap.Duration("duration", time.Since(start)),
+			)
+		case <-au.doneCh:
 			return
 		}
 	}
 }
 
+// Shutdown stops background tasks after flushing their work: due deletion jobs are processed
+// and buffered clicks are saved to storage.
+// Func waits for background tasks to finish till ctx is done and returns ctx error if they did not,
+// then tasks may still use storage, so it must not be closed.
+// Not processed deletion jobs are left in persistent queue till restart.
+func (au *AppUsecase) Shutdown(ctx context.Context) error {
+	au.StartShutdown()
+	au.doneOnce.Do(func() {
+		au.drainCtx = ctx
+		close(au.doneCh)
+		au.stopTickers()
+	})
+
+	finished := make(chan struct{})
+	go func() {
+		au.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopTickers stops tickers of background tasks, tickers of disabled tasks are nil.
+func (au *AppUsecase) stopTickers() {
+	for _, ticker := range []*time.Ticker{
+		au.deleteURLsTicker,
+		au.deleteExpiredURLsTicker,
+		au.purgeDeletedURLsTicker,
+		au.purgeDeletionJobsTicker,
+		au.clicksTicker,
+		au.compactStorageTicker,
+	} {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}
+}
+
 // Close closing channels and stop executing requests/tasks.
+// Func waits for background tasks to finish.
 func (au *AppUsecase) Close() error {
-	close(au.doneCh)
-	return nil
+	return au.Shutdown(context.Background())
 }
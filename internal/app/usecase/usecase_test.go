@@ -1,14 +1,19 @@
 package usecase
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/MisterMaks/go-yandex-shortener/internal/app"
 	"github.com/MisterMaks/go-yandex-shortener/internal/app/usecase/mocks"
+	"github.com/MisterMaks/go-yandex-shortener/internal/metrics"
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -17,6 +22,14 @@ const (
 	TestAddr  string = "localhost:8080"
 	TestURLID string = "1"
 	TestURL   string = "example.com"
+
+	TestAlias        string = "spring-sale"
+	TestTakenAlias   string = "taken"
+	TestDeletedAlias string = "deleted"
+	TestExpiredAlias string = "expired"
+
+	TestOtherUserAlias string = "other-user"
+	TestShortenedAlias string = "shortened"
 )
 
 var (
@@ -69,6 +82,36 @@ func Test_generateID(t *testing.T) {
 	}
 }
 
+func TestGetExpirationTime(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Hour)
+	afterTTL := now.Add(60 * time.Second)
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		ttl       int64
+		want      *time.Time
+		wantErr   error
+	}{
+		{name: "never expires", expiresAt: nil, ttl: 0, want: nil, wantErr: nil},
+		{name: "absolute expiration", expiresAt: &future, ttl: 0, want: &future, wantErr: nil},
+		{name: "TTL", expiresAt: nil, ttl: 60, want: &afterTTL, wantErr: nil},
+		{name: "expiration in the past", expiresAt: &past, ttl: 0, want: nil, wantErr: ErrInvalidExpiration},
+		{name: "negative TTL", expiresAt: nil, ttl: -1, want: nil, wantErr: ErrInvalidExpiration},
+		{name: "both expiration and TTL", expiresAt: &future, ttl: 60, want: nil, wantErr: ErrInvalidExpiration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiresAt, err := GetExpirationTime(tt.expiresAt, tt.ttl, now)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, expiresAt)
+		})
+	}
+}
+
 func TestNewAppUsecase(t *testing.T) {
 	// создаём контроллер
 	ctrl := gomock.NewController(t)
@@ -77,7 +120,8 @@ func TestNewAppUsecase(t *testing.T) {
 	// создаём объект-заглушку
 	m := mocks.NewMockAppRepoInterface(ctrl)
 
-	m.EXPECT().DeleteUserURLs(gomock.Any()).Return(nil).AnyTimes()
+	m.EXPECT().DeleteUserURLs(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	m.EXPECT().GetDueDeletionJobs(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 
 	type args struct {
 		resultAddrPrefix              string
@@ -110,8 +154,15 @@ func TestNewAppUsecase(t *testing.T) {
 					CountRegenerationsForLengthID: 1,
 					LengthID:                      1,
 					MaxLengthID:                   1,
+					URLUniqueness:                 app.URLUniquenessGlobal,
+					deleteURLsBatchSize:           100,
 					deleteURLsTicker:              time.NewTicker(5 * time.Second),
-					deleteURLsChan:                make(chan *app.URL, 1024),
+					deleteURLsMaxAttempts:         5,
+					deleteURLsRetryBaseDelay:      time.Second,
+					deleteURLsRetryMaxDelay:       time.Minute,
+					deleteExpiredURLsTicker:       time.NewTicker(time.Minute),
+					clicksChan:                    make(chan *app.URLClicks, 1024),
+					clicksTicker:                  time.NewTicker(5 * time.Second),
 				},
 				wantErr: false,
 			},
@@ -185,34 +236,83 @@ func TestNewAppUsecase(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			appUsecase, err := NewAppUsecase(
-				m,
-				tt.args.resultAddrPrefix,
-				tt.args.countRegenerationsForLengthID,
-				tt.args.lengthID,
-				tt.args.maxLengthID,
-				nil,
-				1024,
-				5*time.Second,
-			)
+			appUsecase, err := NewAppUsecase(m, AppUsecaseConfig{
+				BaseURL:                       tt.args.resultAddrPrefix,
+				CountRegenerationsForLengthID: tt.args.countRegenerationsForLengthID,
+				LengthID:                      tt.args.lengthID,
+				MaxLengthID:                   tt.args.maxLengthID,
+				URLUniqueness:                 app.URLUniquenessGlobal,
+				DeleteURLsBatchSize:           100,
+				DeleteURLsWaitingTime:         5 * time.Second,
+				DeleteURLsMaxAttempts:         5,
+				DeleteURLsRetryBaseDelay:      time.Second,
+				DeleteURLsRetryMaxDelay:       time.Minute,
+				DeleteExpiredURLsWaitingTime:  time.Minute,
+				PurgeDeletedURLsWaitingTime:   time.Hour,
+				DeletedURLsGracePeriod:        24 * time.Hour,
+				PurgeDeletionJobsWaitingTime:  time.Hour,
+				DeletionJobsRetention:         7 * 24 * time.Hour,
+				ClicksChanSize:                1024,
+				ClicksWaitingTime:             5 * time.Second,
+			})
 			if tt.want.wantErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
+				// background workers are stopped before comparison, because they change fields of appUsecase
+				require.NoError(t, appUsecase.Shutdown(context.Background()))
 			}
 			assert.EqualExportedValues(t, tt.want.appUsecase, appUsecase)
 		})
 	}
 }
 
+func TestNewAppUsecase_InvalidWaitingTime(t *testing.T) {
+	validConfig := func() AppUsecaseConfig {
+		return AppUsecaseConfig{
+			BaseURL:                      "http://example.com/",
+			LengthID:                     1,
+			MaxLengthID:                  1,
+			DeleteURLsWaitingTime:        time.Second,
+			DeleteExpiredURLsWaitingTime: time.Second,
+			PurgeDeletedURLsWaitingTime:  time.Second,
+			PurgeDeletionJobsWaitingTime: time.Second,
+			ClicksWaitingTime:            time.Second,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *AppUsecaseConfig)
+	}{
+		{name: "zero deletion waiting time", change: func(c *AppUsecaseConfig) { c.DeleteURLsWaitingTime = 0 }},
+		{name: "negative expired URLs waiting time", change: func(c *AppUsecaseConfig) { c.DeleteExpiredURLsWaitingTime = -time.Second }},
+		{name: "zero clicks waiting time", change: func(c *AppUsecaseConfig) { c.ClicksWaitingTime = 0 }},
+		{name: "zero purge of deleted URLs waiting time", change: func(c *AppUsecaseConfig) { c.PurgeDeletedURLsWaitingTime = 0 }},
+		{name: "zero purge of deletion jobs waiting time", change: func(c *AppUsecaseConfig) { c.PurgeDeletionJobsWaitingTime = 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			tt.change(&config)
+			appUsecase, err := NewAppUsecase(nil, config)
+			assert.ErrorIs(t, err, ErrInvalidWaitingTime)
+			assert.Nil(t, appUsecase)
+		})
+	}
+}
+
 func TestAppUsecase_GetOrCreateURL(t *testing.T) {
 	type fields struct {
 		countRegenerationsForLengthID uint
 		lengthID                      uint
 		maxLengthID                   uint
+		urlUniqueness                 string
 	}
 	type args struct {
 		rawURL string
+		alias  string
 		userID uint
 	}
 	type want struct {
@@ -274,6 +374,178 @@ func TestAppUsecase_GetOrCreateURL(t *testing.T) {
 				err: ErrZeroLengthID,
 			},
 		},
+		{
+			name: "new alias",
+			fields: fields{
+				countRegenerationsForLengthID: 1,
+				lengthID:                      1,
+				maxLengthID:                   1,
+			},
+			args: args{
+				rawURL: TestURL,
+				alias:  TestAlias,
+				userID: 1,
+			},
+			want: want{
+				url: &app.URL{ID: TestAlias, URL: TestURL, UserID: 1},
+				err: nil,
+			},
+		},
+		{
+			name: "taken alias",
+			fields: fields{
+				countRegenerationsForLengthID: 1,
+				lengthID:                      1,
+				maxLengthID:                   1,
+			},
+			args: args{
+				rawURL: TestURL,
+				alias:  TestTakenAlias,
+				userID: 1,
+			},
+			want: want{
+				url: nil,
+				err: ErrAliasTaken,
+			},
+		},
+		{
+			name: "alias of deleted URL",
+			fields: fields{
+				countRegenerationsForLengthID: 1,
+				lengthID:                      1,
+				maxLengthID:                   1,
+			},
+			args: args{
+				rawURL: TestURL,
+				alias:  TestDeletedAlias,
+				userID: 1,
+			},
+			want: want{
+				url: nil,
+				err: ErrAliasTaken,
+			},
+		},
+		{
+			name: "alias of expired URL",
+			fields: fields{
+				countRegenerationsForLengthID: 1,
+				lengthID:                      1,
+				maxLengthID:                   1,
+			},
+			args: args{
+				rawURL: TestURL,
+				alias:  TestExpiredAlias,
+				userID: 1,
+			},
+			want: want{
+				url: nil,
+				err: ErrAliasTaken,
+			},
+		},
+		{
+			name: "alias of the same URL of another user",
+			fields: fields{
+				countRegenerationsForLengthID: 1,
+				lengthID:                      1,
+				maxLengthID:                   1,
+			},
+			args: args{
+				rawURL: TestURL,
+				alias:  TestOtherUserAlias,
+				userID: 1,
+			},
+			want: want{
+				url: &app.URL{ID: TestOtherUserAlias, URL: TestURL, UserID: 2},
+				err: nil,
+			},
+		},
+		{
+			name: "alias of the same URL of another user in user scope",
+			fields: fields{
+				countRegenerationsForLengthID: 1,
+				lengthID:                      1,
+				maxLengthID:                   1,
+				urlUniqueness:                 app.URLUniquenessUser,
+			},
+			args: args{
+				rawURL: TestURL,
+				alias:  TestOtherUserAlias,
+				userID: 1,
+			},
+			want: want{
+				url: nil,
+				err: ErrAliasTaken,
+			},
+		},
+		{
+			name: "alias of the same URL of user in user scope",
+			fields: fields{
+				countRegenerationsForLengthID: 1,
+				lengthID:                      1,
+				maxLengthID:                   1,
+				urlUniqueness:                 app.URLUniquenessUser,
+			},
+			args: args{
+				rawURL: TestURL,
+				alias:  TestOtherUserAlias,
+				userID: 2,
+			},
+			want: want{
+				url: &app.URL{ID: TestOtherUserAlias, URL: TestURL, UserID: 2},
+				err: nil,
+			},
+		},
+		{
+			name: "alias of URL shortened with another ID",
+			fields: fields{
+				countRegenerationsForLengthID: 1,
+				lengthID:                      1,
+				maxLengthID:                   1,
+			},
+			args: args{
+				rawURL: TestURL,
+				alias:  TestShortenedAlias,
+				userID: 1,
+			},
+			want: want{
+				url: nil,
+				err: ErrAliasTaken,
+			},
+		},
+		{
+			name: "invalid alias symbols",
+			fields: fields{
+				countRegenerationsForLengthID: 1,
+				lengthID:                      1,
+				maxLengthID:                   1,
+			},
+			args: args{
+				rawURL: TestURL,
+				alias:  "spring sale!",
+				userID: 1,
+			},
+			want: want{
+				url: nil,
+				err: ErrInvalidAlias,
+			},
+		},
+		{
+			name: "too short alias",
+			fields: fields{
+				countRegenerationsForLengthID: 1,
+				lengthID:                      1,
+				maxLengthID:                   1,
+			},
+			args: args{
+				rawURL: TestURL,
+				alias:  "ab",
+				userID: 1,
+			},
+			want: want{
+				url: nil,
+				err: ErrInvalidAlias,
+			},
+		},
 	}
 
 	// создаём контроллер
@@ -283,12 +555,26 @@ func TestAppUsecase_GetOrCreateURL(t *testing.T) {
 	// создаём объект-заглушку
 	m := mocks.NewMockAppRepoInterface(ctrl)
 
-	m.EXPECT().GetOrCreateURL(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, rawURL string, userID uint) (*app.URL, error) {
+	m.EXPECT().GetOrCreateURL(gomock.Any(), TestAlias, gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id, rawURL string, userID uint, _ *time.Time) (*app.URL, error) {
+		url := &app.URL{ID: id, URL: rawURL, UserID: userID}
+		return url, nil
+	}).AnyTimes()
+	m.EXPECT().GetOrCreateURL(gomock.Any(), TestShortenedAlias, gomock.Any(), gomock.Any(), gomock.Any()).Return(&app.URL{ID: TestURLID, URL: TestURL, UserID: 1}, nil).AnyTimes()
+	m.EXPECT().GetOrCreateURL(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _, rawURL string, userID uint, _ *time.Time) (*app.URL, error) {
 		url := &app.URL{ID: TestURLID, URL: rawURL, UserID: userID}
 		return url, nil
 	}).AnyTimes()
-	m.EXPECT().CheckIDExistence(TestURLID).Return(true, nil).AnyTimes()
-	m.EXPECT().CheckIDExistence(gomock.Any()).Return(false, nil).AnyTimes()
+	m.EXPECT().CheckIDExistence(gomock.Any(), TestURLID).Return(true, nil).AnyTimes()
+	m.EXPECT().CheckIDExistence(gomock.Any(), TestTakenAlias).Return(true, nil).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), TestTakenAlias).Return(&app.URL{ID: TestTakenAlias, URL: "https://other.com", UserID: 2}, nil).AnyTimes()
+	expiredAt := time.Now().Add(-time.Hour)
+	m.EXPECT().CheckIDExistence(gomock.Any(), TestDeletedAlias).Return(true, nil).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), TestDeletedAlias).Return(&app.URL{ID: TestDeletedAlias, URL: TestURL, UserID: 1, IsDeleted: true}, nil).AnyTimes()
+	m.EXPECT().CheckIDExistence(gomock.Any(), TestExpiredAlias).Return(true, nil).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), TestExpiredAlias).Return(&app.URL{ID: TestExpiredAlias, URL: TestURL, UserID: 1, ExpiresAt: &expiredAt}, nil).AnyTimes()
+	m.EXPECT().CheckIDExistence(gomock.Any(), TestOtherUserAlias).Return(true, nil).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), TestOtherUserAlias).Return(&app.URL{ID: TestOtherUserAlias, URL: TestURL, UserID: 2}, nil).AnyTimes()
+	m.EXPECT().CheckIDExistence(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -297,14 +583,68 @@ func TestAppUsecase_GetOrCreateURL(t *testing.T) {
 				CountRegenerationsForLengthID: tt.fields.countRegenerationsForLengthID,
 				LengthID:                      tt.fields.lengthID,
 				MaxLengthID:                   tt.fields.maxLengthID,
+				URLUniqueness:                 tt.fields.urlUniqueness,
 			}
-			url, _, err := au.GetOrCreateURL(tt.args.rawURL, tt.args.userID)
+			url, _, err := au.GetOrCreateURL(context.Background(), tt.args.rawURL, tt.args.alias, nil, tt.args.userID)
 			assert.ErrorIs(t, err, tt.want.err)
 			assert.Equal(t, tt.want.url, url)
 		})
 	}
 }
 
+func TestAppUsecase_ReserveIDs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().CheckIDExistence(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+
+	au := &AppUsecase{AppRepo: m, CountRegenerationsForLengthID: 1, LengthID: 1, MaxLengthID: 2}
+	au.ReserveIDs("ping", "metrics")
+
+	url, _, err := au.GetOrCreateURL(context.Background(), TestURL, "ping", nil, 1)
+	assert.ErrorIs(t, err, ErrReservedAlias)
+	assert.Nil(t, url)
+
+	urls, err := au.GetOrCreateURLs(context.Background(), []app.RequestBatchURL{{CorrelationID: "1", OriginalURL: TestURL, Alias: "metrics"}}, 1)
+	assert.ErrorIs(t, err, ErrReservedAlias)
+	assert.Nil(t, urls)
+
+	// every ID of length 1 is reserved, so length of generated ID is increased
+	au.ReserveIDs(strings.Split(Symbols, "")...)
+	id, err := au.generateID(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, id, 2)
+}
+
+func TestAppUsecase_GetOrCreateURL_GoneURL(t *testing.T) {
+	expiredAt := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name     string
+		savedURL *app.URL
+	}{
+		{name: "deleted URL", savedURL: &app.URL{ID: TestURLID, URL: TestURL, UserID: 1, IsDeleted: true}},
+		{name: "expired URL", savedURL: &app.URL{ID: TestURLID, URL: TestURL, UserID: 1, ExpiresAt: &expiredAt}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := mocks.NewMockAppRepoInterface(ctrl)
+			m.EXPECT().CheckIDExistence(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+			m.EXPECT().GetOrCreateURL(gomock.Any(), gomock.Any(), TestURL, uint(1), gomock.Any()).Return(tt.savedURL, nil)
+
+			au := &AppUsecase{AppRepo: m, CountRegenerationsForLengthID: 1, LengthID: 5, MaxLengthID: 5}
+			url, exists, err := au.GetOrCreateURL(context.Background(), TestURL, "", nil, 1)
+			assert.ErrorIs(t, err, ErrURLDeleted)
+			assert.False(t, exists)
+			assert.Nil(t, url)
+		})
+	}
+}
+
 func TestAppUsecase_GetURL(t *testing.T) {
 	type fields struct {
 		countRegenerationsForLengthID uint
@@ -367,11 +707,11 @@ func TestAppUsecase_GetURL(t *testing.T) {
 	// создаём объект-заглушку
 	m := mocks.NewMockAppRepoInterface(ctrl)
 
-	m.EXPECT().GetURL(TestURLID).Return(&app.URL{
+	m.EXPECT().GetURL(gomock.Any(), TestURLID).Return(&app.URL{
 		ID:  TestURLID,
 		URL: TestURL,
 	}, nil).AnyTimes()
-	m.EXPECT().GetURL(gomock.Any()).Return(nil, ErrTestIDNotFound)
+	m.EXPECT().GetURL(gomock.Any(), gomock.Any()).Return(nil, ErrTestIDNotFound)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -381,7 +721,7 @@ func TestAppUsecase_GetURL(t *testing.T) {
 				LengthID:                      tt.fields.lengthID,
 				MaxLengthID:                   tt.fields.maxLengthID,
 			}
-			url, err := au.GetURL(tt.args.id)
+			url, err := au.GetURL(context.Background(), tt.args.id)
 			assert.ErrorIs(t, err, tt.want.err)
 			assert.Equal(t, tt.want.url, url)
 		})
@@ -468,11 +808,11 @@ func TestAppUsecase_GetOrCreateURLs(t *testing.T) {
 
 	// создаём объект-заглушку
 	m := mocks.NewMockAppRepoInterface(ctrl)
-	m.EXPECT().GetOrCreateURLs(gomock.Any()).Return([]*app.URL{
+	m.EXPECT().GetOrCreateURLs(gomock.Any(), gomock.Any()).Return([]*app.URL{
 		{ID: "11", URL: "https://test.ru", UserID: testUserID, IsDeleted: false},
 		{ID: "22", URL: "https://test2.ru", UserID: testUserID, IsDeleted: false},
-	}, nil).AnyTimes()
-	m.EXPECT().CheckIDExistence(gomock.Any()).Return(false, nil).AnyTimes()
+	}, nil).Times(1)
+	m.EXPECT().CheckIDExistence(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
 
 	au := &AppUsecase{
 		AppRepo:                       m,
@@ -482,13 +822,40 @@ func TestAppUsecase_GetOrCreateURLs(t *testing.T) {
 		BaseURL:                       "http://example.com/",
 	}
 
-	urls, err := au.GetOrCreateURLs(testRequestBatchURLs, testUserID)
+	urls, err := au.GetOrCreateURLs(context.Background(), testRequestBatchURLs, testUserID)
 
 	require.NoError(t, err)
 	assert.Equal(t, []app.ResponseBatchURL{
 		{CorrelationID: "1", ShortURL: "http://example.com/11"},
 		{CorrelationID: "2", ShortURL: "http://example.com/22"},
 	}, urls)
+
+	// alias of original URL shortened with another ID is taken
+	m.EXPECT().GetOrCreateURLs(gomock.Any(), gomock.Any()).Return([]*app.URL{
+		{ID: "11", URL: "https://test.ru", UserID: testUserID},
+	}, nil).Times(1)
+	urls, err = au.GetOrCreateURLs(context.Background(), []app.RequestBatchURL{
+		{CorrelationID: "1", OriginalURL: "https://test.ru", Alias: TestAlias},
+	}, testUserID)
+	assert.ErrorIs(t, err, ErrAliasTaken)
+	assert.Nil(t, urls)
+
+	// short URL of deleted original URL is not returned
+	m.EXPECT().GetOrCreateURLs(gomock.Any(), gomock.Any()).Return([]*app.URL{
+		{ID: "11", URL: "https://test.ru", UserID: testUserID},
+		{ID: "22", URL: "https://test2.ru", UserID: testUserID, IsDeleted: true},
+	}, nil).Times(1)
+	urls, err = au.GetOrCreateURLs(context.Background(), testRequestBatchURLs, testUserID)
+	assert.ErrorIs(t, err, ErrURLDeleted)
+	assert.Nil(t, urls)
+
+	// batch with invalid original URL is not saved
+	urls, err = au.GetOrCreateURLs(context.Background(), []app.RequestBatchURL{
+		{CorrelationID: "1", OriginalURL: "https://test.ru"},
+		{CorrelationID: "2", OriginalURL: "https://invalid host"},
+	}, testUserID)
+	assert.Error(t, err)
+	assert.Nil(t, urls)
 }
 
 func TestAppUsecase_GetUserURLs(t *testing.T) {
@@ -500,7 +867,7 @@ func TestAppUsecase_GetUserURLs(t *testing.T) {
 
 	// создаём объект-заглушку
 	m := mocks.NewMockAppRepoInterface(ctrl)
-	m.EXPECT().GetUserURLs(testUserID).Return([]*app.URL{
+	m.EXPECT().GetUserURLs(gomock.Any(), testUserID, &app.UserURLsFilter{Limit: DefaultUserURLsLimit + 1}).Return([]*app.URL{
 		{ID: "11", URL: "https://test.ru", UserID: testUserID, IsDeleted: false},
 		{ID: "22", URL: "https://test2.ru", UserID: testUserID, IsDeleted: false},
 	}, nil).AnyTimes()
@@ -513,49 +880,80 @@ func TestAppUsecase_GetUserURLs(t *testing.T) {
 		BaseURL:                       "http://example.com/",
 	}
 
-	urls, err := au.GetUserURLs(testUserID)
+	urls, nextCursor, err := au.GetUserURLs(context.Background(), testUserID, app.UserURLsParams{})
 
 	require.NoError(t, err)
+	assert.Equal(t, "", nextCursor)
 	assert.Equal(t, []app.ResponseUserURL{
 		{OriginalURL: "https://test.ru", ShortURL: "http://example.com/11"},
 		{OriginalURL: "https://test2.ru", ShortURL: "http://example.com/22"},
 	}, urls)
 }
 
-func TestAppUsecase_SendDeleteUserURLsInChan(t *testing.T) {
+func TestAppUsecase_GetUserURLs_Pagination(t *testing.T) {
+	testUserID := uint(1)
+	createdAt := time.Date(2024, 10, 16, 14, 0, 0, 0, time.UTC)
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().GetUserURLs(gomock.Any(), testUserID, &app.UserURLsFilter{Limit: 2, Search: "test", Desc: true}).Return([]*app.URL{
+		{ID: "22", URL: "https://test2.ru", UserID: testUserID, CreatedAt: createdAt.Add(time.Second)},
+		{ID: "11", URL: "https://test.ru", UserID: testUserID, CreatedAt: createdAt},
+	}, nil).Times(1)
+	m.EXPECT().GetUserURLs(gomock.Any(), testUserID, &app.UserURLsFilter{
+		Limit:  2,
+		After:  &app.URLCursor{CreatedAt: createdAt.Add(time.Second), ID: "22"},
+		Search: "test",
+		Desc:   true,
+	}).Return([]*app.URL{
+		{ID: "11", URL: "https://test.ru", UserID: testUserID, CreatedAt: createdAt},
+	}, nil).Times(1)
+
 	au := &AppUsecase{
-		AppRepo:                       nil,
-		BaseURL:                       "",
+		AppRepo:                       m,
 		CountRegenerationsForLengthID: 1,
 		LengthID:                      1,
 		MaxLengthID:                   1,
-		db:                            nil,
-		deleteURLsChan:                make(chan *app.URL, 1),
-		deleteURLsTicker:              time.NewTicker(5 * time.Millisecond),
-		doneCh:                        make(chan struct{}),
+		BaseURL:                       "http://example.com/",
 	}
 
-	testURL := &app.URL{ID: TestURLID, UserID: 1}
-
-	au.SendDeleteUserURLsInChan(testURL.UserID, []string{testURL.ID})
+	params := app.UserURLsParams{Limit: 1, Search: "test", Desc: true}
 
-	actualURL := <-au.deleteURLsChan
-	assert.Equal(t, testURL, actualURL)
+	urls, nextCursor, err := au.GetUserURLs(context.Background(), testUserID, params)
+	require.NoError(t, err)
+	require.NotEmpty(t, nextCursor)
+	secondCreatedAt := createdAt.Add(time.Second)
+	assert.Equal(t, []app.ResponseUserURL{
+		{OriginalURL: "https://test2.ru", ShortURL: "http://example.com/22", CreatedAt: &secondCreatedAt},
+	}, urls)
 
-	err := au.Close()
+	params.Cursor = nextCursor
+	urls, nextCursor, err = au.GetUserURLs(context.Background(), testUserID, params)
 	require.NoError(t, err)
+	assert.Equal(t, "", nextCursor)
+	assert.Equal(t, []app.ResponseUserURL{
+		{OriginalURL: "https://test.ru", ShortURL: "http://example.com/11", CreatedAt: &createdAt},
+	}, urls)
 
-	time.Sleep(time.Millisecond)
+	_, _, err = au.GetUserURLs(context.Background(), testUserID, app.UserURLsParams{Limit: MaxUserURLsLimit + 1})
+	assert.ErrorIs(t, err, ErrInvalidLimit)
 
-	au.SendDeleteUserURLsInChan(testURL.UserID, []string{testURL.ID})
+	_, _, err = au.GetUserURLs(context.Background(), testUserID, app.UserURLsParams{Cursor: "!invalid"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
 }
 
-func TestAppUsecase_deleteUserURLs(t *testing.T) {
-	testURL := &app.URL{
-		ID:        "1",
-		URL:       "https://test.ru",
-		UserID:    1,
-		IsDeleted: false,
+func TestAppUsecase_GetUserURLs_DefaultLimit(t *testing.T) {
+	testUserID := uint(1)
+	createdAt := time.Date(2024, 10, 16, 14, 0, 0, 0, time.UTC)
+
+	// user has more URLs than default page
+	userURLs := make([]*app.URL, 0, DefaultUserURLsLimit+1)
+	for i := uint(0); i <= DefaultUserURLsLimit; i++ {
+		userURLs = append(userURLs, &app.URL{ID: fmt.Sprintf("id%d", i), URL: "https://test.ru", UserID: testUserID, CreatedAt: createdAt})
 	}
 
 	// создаём контроллер
@@ -564,50 +962,837 @@ func TestAppUsecase_deleteUserURLs(t *testing.T) {
 
 	// создаём объект-заглушку
 	m := mocks.NewMockAppRepoInterface(ctrl)
-	m.EXPECT().DeleteUserURLs([]*app.URL{testURL}).Return(nil).AnyTimes()
+	m.EXPECT().GetUserURLs(gomock.Any(), testUserID, &app.UserURLsFilter{Limit: DefaultUserURLsLimit + 1}).Return(userURLs, nil).Times(1)
 
 	au := &AppUsecase{
-		AppRepo:                       m,
-		BaseURL:                       "",
-		CountRegenerationsForLengthID: 1,
-		LengthID:                      1,
-		MaxLengthID:                   1,
-		db:                            nil,
-		deleteURLsChan:                make(chan *app.URL, 1),
-		deleteURLsTicker:              time.NewTicker(5 * time.Millisecond),
-		doneCh:                        make(chan struct{}),
+		AppRepo: m,
+		BaseURL: "http://example.com/",
 	}
 
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		au.deleteUserURLs()
-	}()
+	urls, nextCursor, err := au.GetUserURLs(context.Background(), testUserID, app.UserURLsParams{})
+	require.NoError(t, err)
+	assert.Len(t, urls, int(DefaultUserURLsLimit))
 
-	au.deleteURLsChan <- testURL
+	lastURL := userURLs[DefaultUserURLsLimit-1]
+	after, err := decodeURLCursor(nextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, &app.URLCursor{CreatedAt: lastURL.CreatedAt, ID: lastURL.ID}, after)
+}
 
-	time.Sleep(10 * time.Millisecond)
+func TestAppUsecase_ExportUserURLs(t *testing.T) {
+	testUserID := uint(1)
+	createdAt := time.Date(2024, 10, 16, 14, 0, 0, 0, time.UTC)
 
-	au.deleteURLsChan <- testURL
+	// first page is full, so the next page is read after its last URL
+	firstPage := make([]*app.URL, 0, ExportPageSize)
+	for i := uint(0); i < ExportPageSize; i++ {
+		firstPage = append(firstPage, &app.URL{ID: fmt.Sprintf("id%d", i), URL: "https://test.ru", UserID: testUserID, CreatedAt: createdAt})
+	}
+	lastPage := []*app.URL{
+		{ID: "22", URL: "https://test2.ru", UserID: testUserID, IsDeleted: true, CreatedAt: createdAt.Add(time.Second)},
+	}
 
-	err := au.Close()
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	gomock.InOrder(
+		m.EXPECT().GetUserURLs(gomock.Any(), testUserID, &app.UserURLsFilter{
+			Limit:          ExportPageSize,
+			IncludeDeleted: true,
+		}).Return(firstPage, nil).Times(1),
+		m.EXPECT().GetUserURLs(gomock.Any(), testUserID, &app.UserURLsFilter{
+			Limit:          ExportPageSize,
+			IncludeDeleted: true,
+			After:          &app.URLCursor{CreatedAt: createdAt, ID: fmt.Sprintf("id%d", ExportPageSize-1)},
+		}).Return(lastPage, nil).Times(1),
+	)
+
+	au := &AppUsecase{AppRepo: m, BaseURL: "http://example.com/"}
+
+	pages := [][]app.UserURLRecord{}
+	err := au.ExportUserURLs(context.Background(), testUserID, func(records []app.UserURLRecord) error {
+		pages = append(pages, records)
+		return nil
+	})
 	require.NoError(t, err)
+	require.Len(t, pages, 2)
+	require.Len(t, pages[0], int(ExportPageSize))
+	assert.Equal(t, app.UserURLRecord{ShortURL: "http://example.com/id0", OriginalURL: "https://test.ru", CreatedAt: &createdAt}, pages[0][0])
+	lastCreatedAt := createdAt.Add(time.Second)
+	assert.Equal(t, []app.UserURLRecord{
+		{ShortURL: "http://example.com/22", OriginalURL: "https://test2.ru", IsDeleted: true, CreatedAt: &lastCreatedAt},
+	}, pages[1])
 
-	time.Sleep(10 * time.Millisecond)
+	t.Run("write error", func(t *testing.T) {
+		m.EXPECT().GetUserURLs(gomock.Any(), testUserID, gomock.Any()).Return(firstPage, nil).Times(1)
 
-	wg.Wait()
+		writeErr := errors.New("write error")
+		err := au.ExportUserURLs(context.Background(), testUserID, func(records []app.UserURLRecord) error {
+			return writeErr
+		})
+		assert.ErrorIs(t, err, writeErr)
+	})
+
+	t.Run("without URLs", func(t *testing.T) {
+		m.EXPECT().GetUserURLs(gomock.Any(), testUserID, gomock.Any()).Return([]*app.URL{}, nil).Times(1)
+
+		err := au.ExportUserURLs(context.Background(), testUserID, func(records []app.UserURLRecord) error {
+			t.Error("nothing is written")
+			return nil
+		})
+		assert.NoError(t, err)
+	})
 }
 
-func TestAppUsecase_Close(t *testing.T) {
-	au := &AppUsecase{
-		AppRepo:                       nil,
+func TestAppUsecase_ImportUserURLs(t *testing.T) {
+	testUserID := uint(1)
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().GetOrCreateURLs(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, urls []*app.URL) ([]*app.URL, error) {
+		savedURLs := make([]*app.URL, 0, len(urls))
+		for _, url := range urls {
+			switch {
+			case url.ID == "taken":
+				return nil, app.ErrURLIDExists
+			case url.URL == "https://exists.ru":
+				savedURLs = append(savedURLs, &app.URL{ID: "old", URL: url.URL, UserID: testUserID})
+			default:
+				savedURLs = append(savedURLs, url)
+			}
+		}
+		return savedURLs, nil
+	}).MinTimes(1)
+	m.EXPECT().CheckIDExistence(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+
+	au := &AppUsecase{
+		AppRepo:                       m,
+		CountRegenerationsForLengthID: 1,
+		LengthID:                      5,
+		MaxLengthID:                   5,
+		BaseURL:                       "http://example.com/",
+	}
+
+	records := []app.UserURLRecord{
+		{ShortURL: "http://old.example.com/abc", OriginalURL: "https://test.ru"},
+		{ShortURL: "http://old.example.com/new", OriginalURL: "https://exists.ru"},
+		{ShortURL: "taken", OriginalURL: "https://test2.ru"},
+		{ShortURL: "http://old.example.com/del", OriginalURL: "https://test3.ru", IsDeleted: true},
+		{ShortURL: "http://old.example.com/!", OriginalURL: "https://test4.ru"},
+		{ShortURL: "http://old.example.com/empty"},
+		{OriginalURL: "https://test5.ru"},
+		{OriginalURL: "https://exists.ru"},
+	}
+
+	resp, err := au.ImportUserURLs(context.Background(), testUserID, records)
+	require.NoError(t, err)
+	require.Len(t, resp, len(records))
+	assert.Equal(t, app.ResponseImportUserURL{Row: 1, ShortURL: "http://example.com/abc", Status: app.ImportStatusImported}, resp[0])
+	assert.Equal(t, app.ResponseImportUserURL{Row: 2, ShortURL: "http://example.com/old", Status: app.ImportStatusExists}, resp[1])
+	assert.Equal(t, app.ResponseImportUserURL{Row: 3, Status: app.ImportStatusAliasTaken}, resp[2])
+	assert.Equal(t, app.ResponseImportUserURL{Row: 4, Status: app.ImportStatusSkipped}, resp[3])
+	assert.Equal(t, app.ResponseImportUserURL{Row: 5, Status: app.ImportStatusInvalid, Error: ErrInvalidAlias.Error()}, resp[4])
+	assert.Equal(t, app.ResponseImportUserURL{Row: 6, Status: app.ImportStatusInvalid, Error: ErrEmptyOriginalURL.Error()}, resp[5])
+	assert.Equal(t, 7, resp[6].Row)
+	assert.Equal(t, app.ImportStatusImported, resp[6].Status)
+	assert.NotEmpty(t, resp[6].ShortURL)
+	// row without short URL is not imported if its URL is already saved
+	assert.Equal(t, app.ResponseImportUserURL{Row: 8, ShortURL: "http://example.com/old", Status: app.ImportStatusExists}, resp[7])
+
+	_, err = au.ImportUserURLs(context.Background(), testUserID, make([]app.UserURLRecord, MaxImportUserURLs+1))
+	assert.ErrorIs(t, err, ErrTooManyImportURLs)
+}
+
+func TestAppUsecase_RestoreUserURLs(t *testing.T) {
+	testUserID := uint(1)
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().RestoreUserURLs(gomock.Any(), testUserID, []string{"11", "22"}, gomock.Any()).Return([]string{"22"}, nil).Times(1)
+
+	au := &AppUsecase{AppRepo: m}
+
+	responses, err := au.RestoreUserURLs(context.Background(), testUserID, []string{"11", "22"})
+	require.NoError(t, err)
+	assert.Equal(t, []app.ResponseRestoreUserURL{
+		{ID: "11", Status: app.RestoreURLIgnored},
+		{ID: "22", Status: app.RestoreURLRestored},
+	}, responses)
+
+	_, err = au.RestoreUserURLs(context.Background(), testUserID, make([]string, MaxRestoreUserURLs+1))
+	assert.ErrorIs(t, err, ErrTooManyRestoreURLs)
+}
+
+func TestAppUsecase_EnqueueDeleteUserURLs(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+
+	var savedJob *app.DeletionJob
+	m.EXPECT().AddDeletionJob(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, job *app.DeletionJob) error {
+		savedJob = job
+		return nil
+	}).Times(1)
+
+	au := &AppUsecase{AppRepo: m}
+
+	jobID, err := au.EnqueueDeleteUserURLs(context.Background(), uint(1), []string{TestURLID})
+	require.NoError(t, err)
+	require.NotNil(t, savedJob)
+	assert.NotEmpty(t, jobID)
+	assert.Equal(t, jobID, savedJob.ID)
+	assert.Equal(t, uint(1), savedJob.UserID)
+	assert.Equal(t, []string{TestURLID}, savedJob.URLIDs)
+	assert.Equal(t, app.DeletionJobPending, savedJob.Status)
+	assert.Equal(t, uint(0), savedJob.Attempts)
+	assert.False(t, savedJob.NextAttemptAt.After(time.Now()))
+
+	testErr := errors.New("test error")
+	m.EXPECT().AddDeletionJob(gomock.Any(), gomock.Any()).Return(testErr).Times(1)
+
+	_, err = au.EnqueueDeleteUserURLs(context.Background(), uint(1), []string{TestURLID})
+	assert.ErrorIs(t, err, testErr)
+}
+
+func TestAppUsecase_GetDeletionJob(t *testing.T) {
+	createdAt := time.Date(2024, 10, 16, 15, 0, 0, 0, time.UTC)
+	testErr := errors.New("test error")
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	// status of URLs is saved in job, so current state of URLs is not read
+	m.EXPECT().GetDeletionJob(gomock.Any(), "job").Return(&app.DeletionJob{
+		ID:            "job",
+		UserID:        1,
+		URLIDs:        []string{"own", "other", "unknown"},
+		Status:        app.DeletionJobDone,
+		CreatedAt:     createdAt,
+		IgnoredURLIDs: []string{"other", "unknown"},
+	}, nil).AnyTimes()
+	m.EXPECT().GetDeletionJob(gomock.Any(), "pending_job").Return(&app.DeletionJob{
+		ID:        "pending_job",
+		UserID:    1,
+		URLIDs:    []string{"own", "unknown"},
+		Status:    app.DeletionJobPending,
+		Attempts:  1,
+		CreatedAt: createdAt,
+	}, nil).AnyTimes()
+	m.EXPECT().GetDeletionJob(gomock.Any(), "not_found").Return(nil, app.ErrDeletionJobNotFound).AnyTimes()
+	m.EXPECT().GetDeletionJob(gomock.Any(), "error_job").Return(nil, testErr).AnyTimes()
+
+	au := &AppUsecase{AppRepo: m}
+
+	job, err := au.GetDeletionJob(context.Background(), "job", uint(1))
+	require.NoError(t, err)
+	assert.Equal(t, &app.ResponseDeletionJob{
+		JobID:     "job",
+		Status:    app.DeletionJobDone,
+		CreatedAt: createdAt,
+		URLs: []app.ResponseDeletionJobURL{
+			{ID: "own", Status: app.DeletionJobDone},
+			{ID: "other", Status: app.DeletionURLIgnored},
+			{ID: "unknown", Status: app.DeletionURLIgnored},
+		},
+	}, job)
+
+	job, err = au.GetDeletionJob(context.Background(), "pending_job", uint(1))
+	require.NoError(t, err)
+	assert.Equal(t, &app.ResponseDeletionJob{
+		JobID:     "pending_job",
+		Status:    app.DeletionJobPending,
+		Attempts:  1,
+		CreatedAt: createdAt,
+		URLs: []app.ResponseDeletionJobURL{
+			{ID: "own", Status: app.DeletionJobPending},
+			{ID: "unknown", Status: app.DeletionJobPending},
+		},
+	}, job)
+
+	_, err = au.GetDeletionJob(context.Background(), "job", uint(2))
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = au.GetDeletionJob(context.Background(), "not_found", uint(1))
+	assert.ErrorIs(t, err, app.ErrDeletionJobNotFound)
+
+	_, err = au.GetDeletionJob(context.Background(), "error_job", uint(1))
+	assert.ErrorIs(t, err, testErr)
+}
+
+func Test_retryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts uint
+		want     time.Duration
+	}{
+		{name: "first retry", attempts: 1, want: time.Second},
+		{name: "second retry", attempts: 2, want: 2 * time.Second},
+		{name: "fourth retry", attempts: 4, want: 8 * time.Second},
+		{name: "max delay", attempts: 10, want: 10 * time.Second},
+		{name: "overflow", attempts: 1000, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retryDelay(tt.attempts, time.Second, 10*time.Second))
+		})
+	}
+}
+
+func TestAppUsecase_processDeletionJobs(t *testing.T) {
+	now := time.Date(2024, 10, 16, 15, 0, 0, 0, time.UTC)
+	testErr := errors.New("test error")
+
+	newJobs := func() []*app.DeletionJob {
+		return []*app.DeletionJob{
+			{ID: "1", UserID: 1, URLIDs: []string{"a"}, Status: app.DeletionJobPending, NextAttemptAt: now},
+			{ID: "2", UserID: 2, URLIDs: []string{"b"}, Status: app.DeletionJobPending, NextAttemptAt: now},
+			{ID: "3", UserID: 3, URLIDs: []string{"c"}, Status: app.DeletionJobPending, NextAttemptAt: now, Attempts: 2},
+		}
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		// создаём контроллер
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// создаём объект-заглушку
+		m := mocks.NewMockAppRepoInterface(ctrl)
+		m.EXPECT().GetDueDeletionJobs(gomock.Any(), now, uint(10)).Return(newJobs(), nil).Times(1)
+		m.EXPECT().DeleteUserURLs(gomock.Any(), []*app.URL{
+			{ID: "a", UserID: 1},
+			{ID: "b", UserID: 2},
+			{ID: "c", UserID: 3},
+		}).Return(nil).Times(1)
+		m.EXPECT().GetUserURLIDs(gomock.Any(), uint(1), []string{"a"}).Return([]string{"a"}, nil).Times(1)
+		m.EXPECT().GetUserURLIDs(gomock.Any(), uint(2), []string{"b"}).Return([]string{}, nil).Times(1)
+		m.EXPECT().GetUserURLIDs(gomock.Any(), uint(3), []string{"c"}).Return([]string{"c"}, nil).Times(1)
+
+		updatedJobs := map[string]app.DeletionJob{}
+		m.EXPECT().UpdateDeletionJob(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, job *app.DeletionJob) error {
+			updatedJobs[job.ID] = *job
+			return nil
+		}).Times(3)
+
+		au := &AppUsecase{
+			AppRepo:                  m,
+			deleteURLsBatchSize:      10,
+			deleteURLsMaxAttempts:    3,
+			deleteURLsRetryBaseDelay: time.Second,
+			deleteURLsRetryMaxDelay:  time.Minute,
+		}
+
+		au.processDeletionJobs(context.Background(), now)
+
+		for _, id := range []string{"1", "2", "3"} {
+			assert.Equal(t, app.DeletionJobDone, updatedJobs[id].Status)
+			assert.Equal(t, &now, updatedJobs[id].FinishedAt)
+		}
+		// URL which does not belong to user of job is ignored
+		assert.Nil(t, updatedJobs["1"].IgnoredURLIDs)
+		assert.Equal(t, []string{"b"}, updatedJobs["2"].IgnoredURLIDs)
+	})
+
+	t.Run("separate jobs after batch error", func(t *testing.T) {
+		// создаём контроллер
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// создаём объект-заглушку
+		m := mocks.NewMockAppRepoInterface(ctrl)
+		m.EXPECT().GetDueDeletionJobs(gomock.Any(), now, uint(10)).Return(newJobs(), nil).Times(1)
+		m.EXPECT().DeleteUserURLs(gomock.Any(), []*app.URL{
+			{ID: "a", UserID: 1},
+			{ID: "b", UserID: 2},
+			{ID: "c", UserID: 3},
+		}).Return(testErr).Times(1)
+		m.EXPECT().DeleteUserURLs(gomock.Any(), []*app.URL{{ID: "a", UserID: 1}}).Return(nil).Times(1)
+		m.EXPECT().DeleteUserURLs(gomock.Any(), []*app.URL{{ID: "b", UserID: 2}}).Return(testErr).Times(1)
+		m.EXPECT().DeleteUserURLs(gomock.Any(), []*app.URL{{ID: "c", UserID: 3}}).Return(testErr).Times(1)
+		m.EXPECT().GetUserURLIDs(gomock.Any(), uint(1), []string{"a"}).Return([]string{"a"}, nil).Times(1)
+
+		updatedJobs := map[string]app.DeletionJob{}
+		m.EXPECT().UpdateDeletionJob(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, job *app.DeletionJob) error {
+			updatedJobs[job.ID] = *job
+			return nil
+		}).Times(3)
+
+		au := &AppUsecase{
+			AppRepo:                  m,
+			deleteURLsBatchSize:      10,
+			deleteURLsMaxAttempts:    3,
+			deleteURLsRetryBaseDelay: time.Second,
+			deleteURLsRetryMaxDelay:  time.Minute,
+		}
+
+		au.processDeletionJobs(context.Background(), now)
+
+		assert.Equal(t, app.DeletionJobDone, updatedJobs["1"].Status)
+
+		assert.Equal(t, app.DeletionJobPending, updatedJobs["2"].Status)
+		assert.Equal(t, uint(1), updatedJobs["2"].Attempts)
+		assert.Equal(t, now.Add(time.Second), updatedJobs["2"].NextAttemptAt)
+		assert.Equal(t, testErr.Error(), updatedJobs["2"].LastError)
+		assert.Nil(t, updatedJobs["2"].FinishedAt)
+
+		assert.Equal(t, app.DeletionJobFailed, updatedJobs["3"].Status)
+		assert.Equal(t, uint(3), updatedJobs["3"].Attempts)
+		assert.Equal(t, testErr.Error(), updatedJobs["3"].LastError)
+		assert.Equal(t, &now, updatedJobs["3"].FinishedAt)
+	})
+
+	t.Run("get user URLs error", func(t *testing.T) {
+		// создаём контроллер
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// создаём объект-заглушку
+		m := mocks.NewMockAppRepoInterface(ctrl)
+		m.EXPECT().GetDueDeletionJobs(gomock.Any(), now, uint(10)).Return(newJobs()[:1], nil).Times(1)
+		m.EXPECT().DeleteUserURLs(gomock.Any(), []*app.URL{{ID: "a", UserID: 1}}).Return(nil).Times(1)
+		m.EXPECT().GetUserURLIDs(gomock.Any(), uint(1), []string{"a"}).Return(nil, testErr).Times(1)
+
+		var updatedJob app.DeletionJob
+		m.EXPECT().UpdateDeletionJob(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, job *app.DeletionJob) error {
+			updatedJob = *job
+			return nil
+		}).Times(1)
+
+		au := &AppUsecase{
+			AppRepo:                  m,
+			deleteURLsBatchSize:      10,
+			deleteURLsMaxAttempts:    3,
+			deleteURLsRetryBaseDelay: time.Second,
+			deleteURLsRetryMaxDelay:  time.Minute,
+		}
+
+		au.processDeletionJobs(context.Background(), now)
+
+		// job is retried, so outcome of its URLs is saved later
+		assert.Equal(t, app.DeletionJobPending, updatedJob.Status)
+		assert.Equal(t, uint(1), updatedJob.Attempts)
+		assert.Equal(t, testErr.Error(), updatedJob.LastError)
+	})
+
+	t.Run("get jobs error", func(t *testing.T) {
+		// создаём контроллер
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// создаём объект-заглушку
+		m := mocks.NewMockAppRepoInterface(ctrl)
+		m.EXPECT().GetDueDeletionJobs(gomock.Any(), now, uint(10)).Return(nil, testErr).Times(1)
+
+		au := &AppUsecase{AppRepo: m, deleteURLsBatchSize: 10}
+
+		au.processDeletionJobs(context.Background(), now)
+	})
+}
+
+func TestAppUsecase_updateDeleteQueueDepth(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	gomock.InOrder(
+		m.EXPECT().CountPendingDeletionJobs(gomock.Any()).Return(uint(3), nil).Times(1),
+		m.EXPECT().CountPendingDeletionJobs(gomock.Any()).Return(uint(0), errors.New("test error")).Times(1),
+	)
+
+	au := &AppUsecase{AppRepo: m}
+
+	au.updateDeleteQueueDepth()
+	assert.Equal(t, float64(3), testutil.ToFloat64(metrics.DeleteQueueDepth))
+
+	// metric is not changed on error
+	au.updateDeleteQueueDepth()
+	assert.Equal(t, float64(3), testutil.ToFloat64(metrics.DeleteQueueDepth))
+}
+
+func TestAppUsecase_deleteUserURLs(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().GetDueDeletionJobs(gomock.Any(), gomock.Any(), uint(10)).Return([]*app.DeletionJob{}, nil).MinTimes(2)
+	m.EXPECT().CountPendingDeletionJobs(gomock.Any()).Return(uint(0), nil).MinTimes(1)
+
+	au := &AppUsecase{
+		AppRepo:             m,
+		deleteURLsBatchSize: 10,
+		deleteURLsTicker:    time.NewTicker(time.Millisecond),
+		doneCh:              make(chan struct{}),
+	}
+
+	au.runWorker(au.deleteUserURLs)
+
+	time.Sleep(10 * time.Millisecond)
+
+	// Close waits for worker finish
+	err := au.Close()
+	require.NoError(t, err)
+}
+
+func TestAppUsecase_deleteExpiredURLs(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().DeleteExpiredURLs(gomock.Any(), gomock.Any()).Return(nil).MinTimes(1)
+
+	au := &AppUsecase{
+		AppRepo:                 m,
+		deleteExpiredURLsTicker: time.NewTicker(time.Millisecond),
+		doneCh:                  make(chan struct{}),
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		au.deleteExpiredURLs()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	err := au.Close()
+	require.NoError(t, err)
+
+	wg.Wait()
+}
+
+func TestAppUsecase_purgeDeletedURLs(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().PurgeDeletedURLs(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, deletedBefore time.Time) (uint, error) {
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), deletedBefore, time.Minute)
+		return 1, nil
+	}).MinTimes(1)
+
+	au := &AppUsecase{
+		AppRepo:                m,
+		purgeDeletedURLsTicker: time.NewTicker(time.Millisecond),
+		deletedURLsGracePeriod: time.Hour,
+		doneCh:                 make(chan struct{}),
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		au.purgeDeletedURLs()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	err := au.Close()
+	require.NoError(t, err)
+
+	wg.Wait()
+}
+
+func TestAppUsecase_purgeDeletionJobs(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().PurgeDeletionJobs(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, finishedBefore time.Time) (uint, error) {
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), finishedBefore, time.Minute)
+		return 1, nil
+	}).MinTimes(1)
+
+	au := &AppUsecase{
+		AppRepo:                 m,
+		purgeDeletionJobsTicker: time.NewTicker(time.Millisecond),
+		deletionJobsRetention:   time.Hour,
+		doneCh:                  make(chan struct{}),
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		au.purgeDeletionJobs()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	err := au.Close()
+	require.NoError(t, err)
+
+	wg.Wait()
+}
+
+func TestAppUsecase_addURLsClicks(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().AddURLsClicks(gomock.Any(), []*app.URLClicks{
+		{ID: TestURLID, Count: 2, LastAccessedAt: now.Add(time.Second)},
+	}).Return(nil).Times(1)
+
+	au := &AppUsecase{
+		AppRepo:      m,
+		clicksChan:   make(chan *app.URLClicks, 2),
+		clicksTicker: time.NewTicker(time.Hour),
+		doneCh:       make(chan struct{}),
+	}
+
+	au.clicksChan <- &app.URLClicks{ID: TestURLID, Count: 1, LastAccessedAt: now.Add(time.Second)}
+	au.clicksChan <- &app.URLClicks{ID: TestURLID, Count: 1, LastAccessedAt: now}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		au.addURLsClicks()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	err := au.Close()
+	require.NoError(t, err)
+
+	wg.Wait()
+}
+
+func TestAppUsecase_Shutdown(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	gomock.InOrder(
+		m.EXPECT().GetDueDeletionJobs(gomock.Any(), gomock.Any(), uint(1)).Return([]*app.DeletionJob{
+			{ID: "1", UserID: 1, URLIDs: []string{"a"}, Status: app.DeletionJobPending},
+		}, nil),
+		m.EXPECT().GetDueDeletionJobs(gomock.Any(), gomock.Any(), uint(1)).Return([]*app.DeletionJob{
+			{ID: "2", UserID: 1, URLIDs: []string{"b"}, Status: app.DeletionJobPending},
+		}, nil),
+		m.EXPECT().GetDueDeletionJobs(gomock.Any(), gomock.Any(), uint(1)).Return([]*app.DeletionJob{}, nil),
+	)
+	m.EXPECT().DeleteUserURLs(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	m.EXPECT().GetUserURLIDs(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{}, nil).Times(2)
+	m.EXPECT().UpdateDeletionJob(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	m.EXPECT().AddURLsClicks(gomock.Any(), []*app.URLClicks{
+		{ID: TestURLID, Count: 2, LastAccessedAt: now},
+	}).Return(nil).Times(1)
+
+	au := &AppUsecase{
+		AppRepo:             m,
+		deleteURLsBatchSize: 1,
+		deleteURLsTicker:    time.NewTicker(time.Hour),
+		clicksChan:          make(chan *app.URLClicks, 2),
+		clicksTicker:        time.NewTicker(time.Hour),
+		doneCh:              make(chan struct{}),
+	}
+
+	au.runWorker(au.deleteUserURLs)
+	au.runWorker(au.addURLsClicks)
+
+	// clicks are flushed even if worker did not read them before shutdown
+	au.clicksChan <- &app.URLClicks{ID: TestURLID, Count: 1, LastAccessedAt: now}
+	au.clicksChan <- &app.URLClicks{ID: TestURLID, Count: 1, LastAccessedAt: now}
+
+	err := au.Shutdown(context.Background())
+	require.NoError(t, err)
+	assert.True(t, au.shuttingDown.Load())
+
+	// repeated shutdown does not panic
+	err = au.Shutdown(context.Background())
+	require.NoError(t, err)
+}
+
+func TestAppUsecase_Shutdown_Timeout(t *testing.T) {
+	au := &AppUsecase{doneCh: make(chan struct{})}
+
+	block := make(chan struct{})
+	defer close(block)
+	au.runWorker(func() { <-block })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := au.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAppUsecase_SendURLClickInChan(t *testing.T) {
+	au := &AppUsecase{
+		clicksChan: make(chan *app.URLClicks, 1),
+	}
+	dropped := testutil.ToFloat64(metrics.ClicksDroppedTotal)
+
+	au.SendURLClickInChan(TestURLID)
+	// chan is full, clicks must be dropped without blocking
+	au.SendURLClickInChan(TestURLID)
+	au.SendURLClickInChan(TestURLID)
+
+	assert.Equal(t, dropped+2, testutil.ToFloat64(metrics.ClicksDroppedTotal))
+	// first dropped click is logged, next one is counted till next log
+	assert.Equal(t, uint64(1), au.droppedClicks.Load())
+
+	require.Len(t, au.clicksChan, 1)
+	urlClick := <-au.clicksChan
+	assert.Equal(t, TestURLID, urlClick.ID)
+	assert.Equal(t, uint64(1), urlClick.Count)
+}
+
+func TestAppUsecase_GetURLStats(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	lastAccessedAt := time.Now()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().GetURL(gomock.Any(), TestURLID).Return(&app.URL{
+		ID:             TestURLID,
+		URL:            TestURL,
+		UserID:         uint(1),
+		Clicks:         3,
+		LastAccessedAt: &lastAccessedAt,
+	}, nil).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), gomock.Any()).Return(nil, app.ErrURLNotFound).AnyTimes()
+
+	au := &AppUsecase{
+		AppRepo: m,
+		BaseURL: "http://example.com/",
+	}
+
+	stats, err := au.GetURLStats(context.Background(), TestURLID, uint(1))
+	require.NoError(t, err)
+	assert.Equal(t, &app.ResponseURLStats{
+		ShortURL:       "http://example.com/" + TestURLID,
+		OriginalURL:    TestURL,
+		Clicks:         3,
+		LastAccessedAt: &lastAccessedAt,
+	}, stats)
+
+	_, err = au.GetURLStats(context.Background(), TestURLID, uint(2))
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = au.GetURLStats(context.Background(), "not_found", uint(1))
+	assert.ErrorIs(t, err, app.ErrURLNotFound)
+}
+
+func TestAppUsecase_UpdateUserURL(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	editedAt := time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC)
+	newURL := "https://new.ru"
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().GetURL(gomock.Any(), TestURLID).Return(&app.URL{ID: TestURLID, URL: TestURL, UserID: uint(1)}, nil).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), "deleted").Return(&app.URL{ID: "deleted", URL: TestURL, UserID: uint(1), IsDeleted: true}, nil).AnyTimes()
+	m.EXPECT().GetURL(gomock.Any(), gomock.Any()).Return(nil, app.ErrURLNotFound).AnyTimes()
+	m.EXPECT().UpdateURL(gomock.Any(), TestURLID, uint(1), newURL, gomock.Any()).Return(&app.URL{ID: TestURLID, URL: newURL, UserID: uint(1)}, nil).Times(1)
+	m.EXPECT().UpdateURL(gomock.Any(), TestURLID, uint(1), "https://exists.ru", gomock.Any()).Return(nil, app.ErrURLExists).Times(1)
+	m.EXPECT().GetURLEdits(gomock.Any(), TestURLID).Return([]*app.URLEdit{
+		{URLID: TestURLID, OldURL: TestURL, NewURL: newURL, EditedAt: editedAt},
+	}, nil).Times(1)
+
+	au := &AppUsecase{
+		AppRepo: m,
+		BaseURL: "http://example.com/",
+	}
+
+	resp, err := au.UpdateUserURL(context.Background(), TestURLID, uint(1), newURL)
+	require.NoError(t, err)
+	assert.Equal(t, &app.ResponseUpdateUserURL{
+		ShortURL:    "http://example.com/" + TestURLID,
+		OriginalURL: newURL,
+		History:     []app.ResponseURLEdit{{OldURL: TestURL, NewURL: newURL, EditedAt: editedAt}},
+	}, resp)
+
+	_, err = au.UpdateUserURL(context.Background(), TestURLID, uint(1), "https://exists.ru")
+	assert.ErrorIs(t, err, app.ErrURLExists)
+
+	_, err = au.UpdateUserURL(context.Background(), TestURLID, uint(1), "invalid url")
+	assert.Error(t, err)
+
+	_, err = au.UpdateUserURL(context.Background(), TestURLID, uint(2), newURL)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = au.UpdateUserURL(context.Background(), "deleted", uint(1), newURL)
+	assert.ErrorIs(t, err, ErrURLDeleted)
+
+	_, err = au.UpdateUserURL(context.Background(), "not_found", uint(1), newURL)
+	assert.ErrorIs(t, err, app.ErrURLNotFound)
+}
+
+func TestAppUsecase_GetUserURLHistory(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	editedAt := time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC)
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	m.EXPECT().GetURL(gomock.Any(), TestURLID).Return(&app.URL{ID: TestURLID, URL: TestURL, UserID: uint(1)}, nil).AnyTimes()
+	m.EXPECT().GetURLEdits(gomock.Any(), TestURLID).Return([]*app.URLEdit{
+		{URLID: TestURLID, OldURL: "https://old.ru", NewURL: TestURL, EditedAt: editedAt},
+	}, nil).Times(1)
+
+	au := &AppUsecase{AppRepo: m}
+
+	history, err := au.GetUserURLHistory(context.Background(), TestURLID, uint(1))
+	require.NoError(t, err)
+	assert.Equal(t, []app.ResponseURLEdit{{OldURL: "https://old.ru", NewURL: TestURL, EditedAt: editedAt}}, history)
+
+	_, err = au.GetUserURLHistory(context.Background(), TestURLID, uint(2))
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestAppUsecase_Close(t *testing.T) {
+	au := &AppUsecase{
+		AppRepo:                       nil,
 		BaseURL:                       "",
 		CountRegenerationsForLengthID: 1,
 		LengthID:                      1,
 		MaxLengthID:                   1,
-		db:                            nil,
-		deleteURLsChan:                make(chan *app.URL, 1),
 		deleteURLsTicker:              time.NewTicker(time.Second),
 		doneCh:                        make(chan struct{}),
 	}
@@ -618,3 +1803,120 @@ func TestAppUsecase_Close(t *testing.T) {
 	_, ok := <-au.doneCh
 	assert.False(t, ok)
 }
+
+func TestAppUsecase_checkDeletionWorker(t *testing.T) {
+	now := time.Now()
+	au := &AppUsecase{deleteURLsWaitingTime: time.Second}
+
+	err := au.checkDeletionWorker(now)
+	assert.ErrorIs(t, err, ErrDeletionWorkerStopped)
+
+	au.deleteURLsHeartbeat.Store(now.Add(-time.Second).UnixNano())
+	err = au.checkDeletionWorker(now)
+	assert.NoError(t, err)
+
+	au.deleteURLsHeartbeat.Store(now.Add(-DeletionWorkerStallFactor * 2 * time.Second).UnixNano())
+	err = au.checkDeletionWorker(now)
+	assert.ErrorIs(t, err, ErrDeletionWorkerStalled)
+}
+
+func TestAppUsecase_CheckReadiness(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+
+	m.EXPECT().Ping(gomock.Any()).Return(nil)
+	m.EXPECT().Ping(gomock.Any()).Return(errors.New("storage is unavailable"))
+	m.EXPECT().Ping(gomock.Any()).Return(nil)
+
+	au := &AppUsecase{
+		AppRepo:               m,
+		deleteURLsWaitingTime: time.Minute,
+	}
+	au.deleteURLsHeartbeat.Store(time.Now().UnixNano())
+
+	resp := au.CheckReadiness(context.Background())
+	assert.Equal(t, &app.ResponseReadiness{
+		Status: app.HealthStatusOK,
+		Checks: map[string]app.ResponseHealthCheck{
+			app.ReadinessStorage:        {Status: app.HealthStatusOK},
+			app.ReadinessDeletionWorker: {Status: app.HealthStatusOK},
+			app.ReadinessShutdown:       {Status: app.HealthStatusOK},
+		},
+	}, resp)
+
+	resp = au.CheckReadiness(context.Background())
+	assert.Equal(t, app.HealthStatusFail, resp.Status)
+	assert.Equal(t, app.ResponseHealthCheck{Status: app.HealthStatusFail, Error: "storage is unavailable"}, resp.Checks[app.ReadinessStorage])
+
+	au.StartShutdown()
+	resp = au.CheckReadiness(context.Background())
+	assert.Equal(t, app.HealthStatusFail, resp.Status)
+	assert.Equal(t, app.ResponseHealthCheck{Status: app.HealthStatusFail, Error: ErrShuttingDown.Error()}, resp.Checks[app.ReadinessShutdown])
+}
+
+// compactableAppRepo is storage which supports compaction.
+type compactableAppRepo struct {
+	*mocks.MockAppRepoInterface
+	*mocks.MockStorageCompactor
+}
+
+func TestAppUsecase_CompactStorage(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	cm := mocks.NewMockStorageCompactor(ctrl)
+	errCompact := errors.New("compact error")
+	gomock.InOrder(
+		cm.EXPECT().Compact(gomock.Any()).Return(nil),
+		cm.EXPECT().Compact(gomock.Any()).Return(errCompact),
+	)
+
+	au := &AppUsecase{AppRepo: m}
+	err := au.CompactStorage(context.Background())
+	assert.ErrorIs(t, err, ErrCompactionNotSupported)
+
+	au = &AppUsecase{AppRepo: compactableAppRepo{m, cm}}
+	err = au.CompactStorage(context.Background())
+	assert.NoError(t, err)
+	err = au.CompactStorage(context.Background())
+	assert.ErrorIs(t, err, errCompact)
+}
+
+func TestAppUsecase_compactStorage(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockAppRepoInterface(ctrl)
+	cm := mocks.NewMockStorageCompactor(ctrl)
+	compacted := make(chan struct{})
+	cm.EXPECT().Compact(gomock.Any()).DoAndReturn(func(context.Context) error {
+		close(compacted)
+		return nil
+	})
+	cm.EXPECT().Compact(gomock.Any()).Return(nil).AnyTimes()
+
+	au := &AppUsecase{
+		AppRepo:              compactableAppRepo{m, cm},
+		compactStorageTicker: time.NewTicker(time.Millisecond),
+		doneCh:               make(chan struct{}),
+	}
+	au.runWorker(au.compactStorage)
+
+	select {
+	case <-compacted:
+	case <-time.After(time.Second):
+		t.Fatal("Storage is not compacted")
+	}
+
+	err := au.Close()
+	require.NoError(t, err)
+}
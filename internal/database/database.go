@@ -0,0 +1,51 @@
+// Package database opens SQL DB selected by DSN: PostgreSQL or embedded SQLite.
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // PostgreSQL driver
+	_ "modernc.org/sqlite"             // SQLite driver
+)
+
+// Constants for database.
+const (
+	DriverPostgres string = "pgx"    // driver of PostgreSQL
+	DriverSQLite   string = "sqlite" // driver of SQLite
+
+	SQLiteScheme string = "sqlite://" // DSN prefix of SQLite DB, rest of DSN is path to DB file. Example: sqlite:///var/lib/shortener.db
+
+	// SQLite connection options: foreign keys are checked like in PostgreSQL,
+	// times are written in format which is sorted in the same order as times.
+	sqliteOptions string = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+)
+
+// ParseDSN returns driver name and data source name of driver for DSN.
+// DSN with SQLite scheme is SQLite DB, other DSNs are PostgreSQL DSNs.
+func ParseDSN(dsn string) (string, string) {
+	path, ok := strings.CutPrefix(dsn, SQLiteScheme)
+	if !ok {
+		return DriverPostgres, dsn
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return DriverSQLite, path + separator + sqliteOptions
+}
+
+// Open opens DB for DSN and returns DB and its driver name.
+func Open(dsn string) (*sql.DB, string, error) {
+	driver, source := ParseDSN(dsn)
+	db, err := sql.Open(driver, source)
+	if err != nil {
+		return nil, "", err
+	}
+	if driver == DriverSQLite {
+		// SQLite allows only one writer, queries are serialized to avoid busy errors
+		db.SetMaxOpenConns(1)
+	}
+	return db, driver, nil
+}
@@ -0,0 +1,394 @@
+// Package filestorage writes and reads files of records: every line is JSON record with its checksum.
+// Files are synced to disk by sync policy, torn tail of file left by crash is truncated on reading.
+package filestorage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/logger"
+)
+
+// Sync modes of file.
+const (
+	SyncAlways   string = "always"   // file is synced after every record, saved record survives crash
+	SyncInterval string = "interval" // file is synced in background, records of last interval may be lost after crash
+	SyncNever    string = "never"    // file is not synced, OS decides when records are saved
+)
+
+// Constants for file storage.
+const (
+	ChecksumSeparator byte   = '\t'   // separates JSON record and its checksum, JSON does not contain raw tabs
+	TempFileSuffix    string = ".tmp" // file is written to temp file and renamed to replace it atomically
+
+	checksumSize = 8 // hex encoded CRC-32
+)
+
+// Errors for file storage.
+var (
+	ErrUnknownSyncMode     = errors.New("unknown sync mode")                  // sync mode is not always, interval or never
+	ErrInvalidSyncInterval = errors.New("sync interval must be positive")     // interval mode without interval
+	ErrInvalidChecksum     = errors.New("invalid record checksum")            // checksum is not hex encoded CRC-32
+	ErrChecksumMismatch    = errors.New("record checksum mismatch")           // record is changed after it was written
+	ErrUnfinishedRecord    = errors.New("record is not finished by new line") // crash while record was written
+)
+
+// crc32cTable is table of CRC-32 checksums of records.
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// SyncPolicy defines when records of file are synced to disk.
+// Zero policy does not sync files like SyncNever.
+type SyncPolicy struct {
+	Mode     string
+	Interval time.Duration // period of background sync in SyncInterval mode
+}
+
+// Validate checks that policy can be used by writer.
+func (sp SyncPolicy) Validate() error {
+	switch sp.Mode {
+	case "", SyncNever, SyncAlways:
+		return nil
+	case SyncInterval:
+		if sp.Interval <= 0 {
+			return ErrInvalidSyncInterval
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownSyncMode, sp.Mode)
+	}
+}
+
+// MarshalRecord encodes v as line of file: JSON, checksum of JSON and new line.
+func MarshalRecord(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	line := make([]byte, 0, len(data)+checksumSize+2)
+	line = append(line, data...)
+	line = append(line, ChecksumSeparator)
+	line = hex.AppendEncode(line, binary.BigEndian.AppendUint32(nil, crc32.Checksum(data, crc32cTable)))
+	return append(line, '\n'), nil
+}
+
+// parseRecord returns JSON of line and checks its checksum.
+// Line without checksum is written by previous versions of app and is returned as is.
+func parseRecord(line []byte) ([]byte, error) {
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	i := bytes.LastIndexByte(line, ChecksumSeparator)
+	if i < 0 {
+		return line, nil
+	}
+
+	data, checksum := line[:i], line[i+1:]
+	if len(checksum) != checksumSize {
+		return nil, ErrInvalidChecksum
+	}
+	sum := make([]byte, 4)
+	if _, err := hex.Decode(sum, checksum); err != nil {
+		return nil, ErrInvalidChecksum
+	}
+	if binary.BigEndian.Uint32(sum) != crc32.Checksum(data, crc32cTable) {
+		return nil, ErrChecksumMismatch
+	}
+	return data, nil
+}
+
+// ReadRecords reads records of file line by line and passes their JSON to decode.
+// File is created if it does not exist.
+// Broken last record is torn tail left by crash while it was written: file is truncated
+// to the last whole record and warning is logged. Broken record in the middle of file is error.
+// Error of decode is returned as is even for last record, because record with valid checksum is whole.
+func ReadRecords(filename string, decode func(data []byte) error) (err error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+	}()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) == 0 {
+				return nil
+			}
+			return truncateTail(file, offset, fmt.Errorf("line %d: %w", lineNumber, ErrUnfinishedRecord))
+		}
+		if err != nil {
+			return err
+		}
+
+		err = decodeRecord(line, decode)
+		if err != nil {
+			if _, peekErr := reader.Peek(1); errors.Is(peekErr, io.EOF) && isBrokenRecord(err) {
+				return truncateTail(file, offset, fmt.Errorf("line %d: %w", lineNumber, err))
+			}
+			return fmt.Errorf("%s: line %d: %w", filename, lineNumber, err)
+		}
+		offset += int64(len(line))
+	}
+}
+
+// isBrokenRecord reports whether err is caused by record which is not written completely.
+func isBrokenRecord(err error) bool {
+	return errors.Is(err, ErrUnfinishedRecord) || errors.Is(err, ErrInvalidChecksum) || errors.Is(err, ErrChecksumMismatch)
+}
+
+// decodeRecord checks line and passes its JSON to decode. Empty lines are skipped.
+func decodeRecord(line []byte, decode func(data []byte) error) error {
+	data, err := parseRecord(line)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return decode(data)
+}
+
+// truncateTail removes torn tail of file after offset.
+func truncateTail(file *os.File, offset int64, reason error) error {
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+
+	logger.Log.Warn("Torn tail of file is truncated",
+		zap.String("filename", file.Name()),
+		zap.Int64("offset", offset),
+		zap.Error(reason),
+	)
+	return nil
+}
+
+// WriteFileAtomically replaces file with records.
+// Records are written to temp file which is synced and renamed to filename,
+// so file contains either old or new records after crash.
+func WriteFileAtomically(filename string, records []interface{}) (err error) {
+	tmpFilename := filename + TempFileSuffix
+	file, err := os.OpenFile(tmpFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(tmpFilename)
+		}
+	}()
+
+	writer := bufio.NewWriter(file)
+	for _, record := range records {
+		line, err := MarshalRecord(record)
+		if err != nil {
+			return err
+		}
+		if _, err = writer.Write(line); err != nil {
+			return err
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFilename, filename); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(filename))
+}
+
+// syncDir saves renames of files in dir.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	return errors.Join(err, d.Close())
+}
+
+// Writer appends records to file and syncs file by policy.
+// Writer is safe for concurrent use.
+type Writer struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	policy SyncPolicy
+	dirty  bool // file has records which are not synced
+
+	doneCh    chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error // result of first Close, it is returned by next calls
+}
+
+// NewWriter opens file for appending records.
+// Writer with SyncInterval policy syncs file in background till it is closed.
+func NewWriter(filename string, policy SyncPolicy) (*Writer, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		file:   file,
+		writer: bufio.NewWriter(file),
+		policy: policy,
+		doneCh: make(chan struct{}),
+	}
+	if policy.Mode == SyncInterval {
+		w.wg.Add(1)
+		go w.syncPeriodically()
+	}
+	return w, nil
+}
+
+// Name returns filename of writer.
+func (w *Writer) Name() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Name()
+}
+
+// Write appends record to file. Record is synced to disk before return in SyncAlways mode.
+func (w *Writer) Write(v interface{}) error {
+	line, err := MarshalRecord(v)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// записываем событие в буфер
+	if _, err = w.writer.Write(line); err != nil {
+		return err
+	}
+	// записываем буфер в файл
+	if err = w.writer.Flush(); err != nil {
+		return err
+	}
+	w.dirty = true
+
+	if w.policy.Mode == SyncAlways {
+		return w.sync()
+	}
+	return nil
+}
+
+// Sync saves written records to disk.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.sync()
+}
+
+// sync saves written records to disk. Caller must hold the lock.
+func (w *Writer) sync() error {
+	if !w.dirty {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.dirty = false
+	return nil
+}
+
+// Replace atomically replaces file with records and reopens it for appending.
+func (w *Writer) Replace(records ...interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	filename := w.file.Name()
+	if err := WriteFileAtomically(filename, records); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	// old file is already replaced, its records are not needed
+	_ = w.file.Close()
+
+	w.file = file
+	w.writer = bufio.NewWriter(file)
+	w.dirty = false
+	return nil
+}
+
+// Close stops background sync, syncs written records unless policy is SyncNever and closes file.
+// Writer is closed once, next calls return result of the first one.
+func (w *Writer) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.doneCh)
+		w.wg.Wait()
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		var err error
+		if w.policy.Mode == SyncAlways || w.policy.Mode == SyncInterval {
+			err = w.sync()
+		}
+		// закрываем файл
+		w.closeErr = errors.Join(err, w.file.Close())
+	})
+	return w.closeErr
+}
+
+// syncPeriodically syncs file every interval of policy till writer is closed.
+func (w *Writer) syncPeriodically() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := w.Sync()
+			if err != nil {
+				logger.Log.Error("Failed to sync file",
+					zap.String("filename", w.Name()),
+					zap.Error(err),
+				)
+			}
+		case <-w.doneCh:
+			return
+		}
+	}
+}
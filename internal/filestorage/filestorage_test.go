@@ -0,0 +1,252 @@
+package filestorage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRecord struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+func readTestRecords(filename string) ([]testRecord, error) {
+	records := []testRecord{}
+	err := ReadRecords(filename, func(data []byte) error {
+		record := testRecord{}
+		if err := json.Unmarshal(data, &record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+func marshalTestRecords(t *testing.T, records ...testRecord) string {
+	data := ""
+	for _, record := range records {
+		line, err := MarshalRecord(record)
+		require.NoError(t, err)
+		data += string(line)
+	}
+	return data
+}
+
+func TestSyncPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  SyncPolicy
+		wantErr error
+	}{
+		{name: "zero policy", policy: SyncPolicy{}},
+		{name: "always", policy: SyncPolicy{Mode: SyncAlways}},
+		{name: "never", policy: SyncPolicy{Mode: SyncNever}},
+		{name: "interval", policy: SyncPolicy{Mode: SyncInterval, Interval: time.Second}},
+		{name: "interval without interval", policy: SyncPolicy{Mode: SyncInterval}, wantErr: ErrInvalidSyncInterval},
+		{name: "unknown mode", policy: SyncPolicy{Mode: "sometimes"}, wantErr: ErrUnknownSyncMode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMarshalRecord(t *testing.T) {
+	record := testRecord{ID: 1, Text: "tab\there"}
+	line, err := MarshalRecord(record)
+	require.NoError(t, err)
+	assert.Equal(t, byte('\n'), line[len(line)-1])
+
+	data, err := parseRecord(line)
+	require.NoError(t, err)
+	parsed := testRecord{}
+	err = json.Unmarshal(data, &parsed)
+	require.NoError(t, err)
+	assert.Equal(t, record, parsed)
+
+	// record is changed after it was written
+	line[len(`{"id":`)] = '2'
+	_, err = parseRecord(line)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestReadRecords(t *testing.T) {
+	first := testRecord{ID: 1, Text: "first"}
+	second := testRecord{ID: 2, Text: "second"}
+	whole := marshalTestRecords(t, first, second)
+
+	tests := []struct {
+		name        string
+		data        string
+		wantRecords []testRecord
+		wantData    string // content of file after reading
+		wantErr     error
+	}{
+		{
+			name:        "whole file",
+			data:        whole,
+			wantRecords: []testRecord{first, second},
+			wantData:    whole,
+		},
+		{
+			name:        "records without checksum",
+			data:        `{"id":1,"text":"first"}` + "\n\n" + `{"id":2,"text":"second"}` + "\n",
+			wantRecords: []testRecord{first, second},
+			wantData:    `{"id":1,"text":"first"}` + "\n\n" + `{"id":2,"text":"second"}` + "\n",
+		},
+		{
+			name:        "unfinished last record",
+			data:        whole + `{"id":3,"te`,
+			wantRecords: []testRecord{first, second},
+			wantData:    whole,
+		},
+		{
+			name:        "last record without new line",
+			data:        whole[:len(whole)-1],
+			wantRecords: []testRecord{first},
+			wantData:    marshalTestRecords(t, first),
+		},
+		{
+			name:        "zeroed tail",
+			data:        whole + "\x00\x00\x00\x00",
+			wantRecords: []testRecord{first, second},
+			wantData:    whole,
+		},
+		{
+			name:        "last record with wrong checksum",
+			data:        marshalTestRecords(t, first) + `{"id":3,"text":"third"}` + "\t00000000\n",
+			wantRecords: []testRecord{first},
+			wantData:    marshalTestRecords(t, first),
+		},
+		{
+			name:    "record with wrong checksum in the middle",
+			data:    `{"id":3,"text":"third"}` + "\t00000000\n" + whole,
+			wantErr: ErrChecksumMismatch,
+		},
+		{
+			name:    "record with invalid checksum in the middle",
+			data:    `{"id":3,"text":"third"}` + "\tchecksum\n" + whole,
+			wantErr: ErrInvalidChecksum,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "records.json")
+			err := os.WriteFile(filename, []byte(tt.data), 0666)
+			require.NoError(t, err)
+
+			records, err := readTestRecords(filename)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				// file is not changed if it can not be recovered
+				data, err := os.ReadFile(filename)
+				require.NoError(t, err)
+				assert.Equal(t, tt.data, string(data))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRecords, records)
+
+			data, err := os.ReadFile(filename)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, string(data))
+		})
+	}
+
+	t.Run("last record with valid checksum and invalid content", func(t *testing.T) {
+		line, err := MarshalRecord("not a record")
+		require.NoError(t, err)
+		data := whole + string(line)
+		filename := filepath.Join(t.TempDir(), "records.json")
+		err = os.WriteFile(filename, []byte(data), 0666)
+		require.NoError(t, err)
+
+		// record with valid checksum is whole, so it is not torn tail and file is not truncated
+		_, err = readTestRecords(filename)
+		var typeErr *json.UnmarshalTypeError
+		assert.ErrorAs(t, err, &typeErr)
+		fileData, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, data, string(fileData))
+	})
+
+	t.Run("file does not exist", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "records.json")
+		records, err := readTestRecords(filename)
+		require.NoError(t, err)
+		assert.Empty(t, records)
+		assert.FileExists(t, filename)
+	})
+}
+
+func TestWriter(t *testing.T) {
+	policies := []SyncPolicy{
+		{},
+		{Mode: SyncAlways},
+		{Mode: SyncInterval, Interval: time.Millisecond},
+		{Mode: SyncNever},
+	}
+
+	for _, policy := range policies {
+		t.Run(policy.Mode, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "records.json")
+			w, err := NewWriter(filename, policy)
+			require.NoError(t, err)
+			assert.Equal(t, filename, w.Name())
+
+			err = w.Write(testRecord{ID: 1, Text: "first"})
+			require.NoError(t, err)
+			err = w.Sync()
+			require.NoError(t, err)
+			err = w.Write(testRecord{ID: 2, Text: "second"})
+			require.NoError(t, err)
+
+			// records are written to file before they are synced
+			records, err := readTestRecords(filename)
+			require.NoError(t, err)
+			assert.Equal(t, []testRecord{{ID: 1, Text: "first"}, {ID: 2, Text: "second"}}, records)
+
+			err = w.Replace(testRecord{ID: 3, Text: "third"})
+			require.NoError(t, err)
+			err = w.Write(testRecord{ID: 4, Text: "fourth"})
+			require.NoError(t, err)
+			err = w.Close()
+			require.NoError(t, err)
+
+			records, err = readTestRecords(filename)
+			require.NoError(t, err)
+			assert.Equal(t, []testRecord{{ID: 3, Text: "third"}, {ID: 4, Text: "fourth"}}, records)
+			assert.NoFileExists(t, filename+TempFileSuffix)
+		})
+	}
+
+	t.Run("double close", func(t *testing.T) {
+		w, err := NewWriter(filepath.Join(t.TempDir(), "records.json"), SyncPolicy{Mode: SyncInterval, Interval: time.Millisecond})
+		require.NoError(t, err)
+
+		err = w.Close()
+		require.NoError(t, err)
+		err = w.Close()
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		_, err := NewWriter(filepath.Join(t.TempDir(), "records.json"), SyncPolicy{Mode: "sometimes"})
+		assert.ErrorIs(t, err, ErrUnknownSyncMode)
+	})
+}
@@ -2,12 +2,18 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
 // Log is global logger.
@@ -21,6 +27,8 @@ const (
 	MethodKey            string        = "method"
 	URIKey               string        = "uri"
 	RequestIDKey         string        = "request_id"
+	TraceIDKey           string        = "trace_id"
+	SpanIDKey            string        = "span_id"
 	ExecutionDurationKey string        = "execution_duration"
 	StatusCodeKey        string        = "status_code"
 	ResponseBodySizeBKey string        = "response_body_size_B"
@@ -49,11 +57,37 @@ func Initialize(level string) error {
 	return nil
 }
 
+// Sync flushes buffered log entries.
+// Errors of stdout/stderr which do not support sync (terminal, pipe) are ignored.
+func Sync() error {
+	err := Log.Sync()
+	if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY) {
+		return nil
+	}
+	return err
+}
+
 func generateRequestID() string {
 	id := uuid.New()
 	return id.String()
 }
 
+// requestFields returns fields of request logger.
+// Request ID is correlated with trace ID: it is added to current span and trace ID is added to logger.
+func requestFields(ctx context.Context, requestID string) []zap.Field {
+	fields := []zap.Field{zap.String(RequestIDKey, requestID)}
+	span := trace.SpanFromContext(ctx)
+	spanContext := span.SpanContext()
+	if !spanContext.IsValid() {
+		return fields
+	}
+	span.SetAttributes(attribute.String(RequestIDKey, requestID))
+	return append(fields,
+		zap.String(TraceIDKey, spanContext.TraceID().String()),
+		zap.String(SpanIDKey, spanContext.SpanID().String()),
+	)
+}
+
 // LoggerResponseWriter is logger for responses.
 type LoggerResponseWriter struct {
 	http.ResponseWriter
@@ -79,22 +113,18 @@ func RequestLogger(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestID := generateRequestID()
 		lrw := &LoggerResponseWriter{ResponseWriter: w}
-		Log.Info("got incoming HTTP request",
+		ctxLogger := Log.With(requestFields(r.Context(), requestID)...)
+		ctxLogger.Info("got incoming HTTP request",
 			zap.String(MethodKey, r.Method),
 			zap.Any(URIKey, r.RequestURI),
-			zap.String(RequestIDKey, requestID),
-		)
-		ctxLogger := Log.With(
-			zap.String(RequestIDKey, requestID),
 		)
 		ctx := context.WithValue(r.Context(), LoggerKey, ctxLogger)
 		now := time.Now()
 		h.ServeHTTP(lrw, r.WithContext(ctx))
-		Log.Info("processed incoming HTTP request",
+		ctxLogger.Info("processed incoming HTTP request",
 			zap.Int(StatusCodeKey, lrw.statusCode),
 			zap.Int(ResponseBodySizeBKey, lrw.bodySize),
 			zap.Duration(ExecutionDurationKey, time.Since(now)),
-			zap.String(RequestIDKey, requestID),
 		)
 	})
 }
@@ -110,3 +140,20 @@ func GetContextLogger(ctx context.Context) *zap.Logger {
 	}
 	return logger
 }
+
+// RequestLoggerInterceptor is logger interceptor for gRPC server.
+func RequestLoggerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requestID := generateRequestID()
+	ctxLogger := Log.With(requestFields(ctx, requestID)...)
+	ctxLogger.Info("got incoming gRPC request",
+		zap.String(MethodKey, info.FullMethod),
+	)
+	ctx = context.WithValue(ctx, LoggerKey, ctxLogger)
+	now := time.Now()
+	resp, err := handler(ctx, req)
+	ctxLogger.Info("processed incoming gRPC request",
+		zap.String(StatusCodeKey, status.Code(err).String()),
+		zap.Duration(ExecutionDurationKey, time.Since(now)),
+	)
+	return resp, err
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Constants for metrics.
+const (
+	Namespace string = "shortener" // prefix of metrics names
+
+	RedirectHit  string = "hit"  // redirect to existing URL
+	RedirectMiss string = "miss" // URL is not found, deleted or expired
+
+	UnknownRoute string = "unknown" // route label of requests not matched by router
+)
+
+// Registry is registry of app metrics.
+var Registry = prometheus.NewRegistry()
+
+// App metrics.
+var (
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "http_requests_total",
+		Help:      "Count of HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	RedirectsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "redirects_total",
+		Help:      "Count of redirects by result: hit or miss.",
+	}, []string{"result"})
+
+	IDGenerationRetriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "id_generation_retries_total",
+		Help:      "Count of generated short URL IDs which were already used.",
+	})
+	IDLengthEscalationsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "id_length_escalations_total",
+		Help:      "Count of short URL ID length increases after exhausted retries.",
+	})
+
+	ClicksDroppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "clicks_dropped_total",
+		Help:      "Count of redirects not counted in statistics because buffer of clicks was full.",
+	})
+
+	DeleteQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "delete_queue_depth",
+		Help:      "Count of pending deletion jobs.",
+	})
+	DeleteQueueFlushDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "delete_queue_flush_duration_seconds",
+		Help:      "Duration of processing batch of deletion jobs.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestsTotal,
+		HTTPRequestDuration,
+		RedirectsTotal,
+		IDGenerationRetriesTotal,
+		IDLengthEscalationsTotal,
+		ClicksDroppedTotal,
+		DeleteQueueDepth,
+		DeleteQueueFlushDuration,
+	)
+}
+
+// RegisterDBStats registers connection pool statistics of db.
+func RegisterDBStats(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, Namespace))
+}
+
+// Handler returns handler of /metrics endpoint.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware counts HTTP requests and measures their duration.
+// Requests are labeled with chi route pattern, so URL params do not produce new series.
+func Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		now := time.Now()
+		h.ServeHTTP(ww, r)
+		duration := time.Since(now)
+
+		route := UnknownRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		statusCode := ww.Status()
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		status := strconv.Itoa(statusCode)
+
+		HTTPRequestsTotal.WithLabelValues(r.Method, route, status).Inc()
+		HTTPRequestDuration.WithLabelValues(r.Method, route, status).Observe(duration.Seconds())
+	})
+}
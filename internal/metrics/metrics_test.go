@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/api/user/urls/{id}/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	for _, path := range []string{"/api/user/urls/1/stats", "/api/user/urls/2/stats", "/ping", "/unknown"} {
+		resp, err := ts.Client().Get(ts.URL + path)
+		require.NoError(t, err)
+		err = resp.Body.Close()
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(HTTPRequestsTotal.WithLabelValues(http.MethodGet, "/api/user/urls/{id}/stats", "404")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(HTTPRequestsTotal.WithLabelValues(http.MethodGet, "/ping", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(HTTPRequestsTotal.WithLabelValues(http.MethodGet, UnknownRoute, "404")))
+	assert.Equal(t, 3, testutil.CollectAndCount(HTTPRequestDuration))
+}
+
+func TestHandler(t *testing.T) {
+	RedirectsTotal.WithLabelValues(RedirectHit).Inc()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	Handler().ServeHTTP(w, req)
+
+	res := w.Result()
+	err := res.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.True(t, strings.Contains(w.Body.String(), `shortener_redirects_total{result="hit"}`))
+}
@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	TestTraceID      string = "4bf92f3577b34da6a3ce929d0e0e4736"
+	TestParentSpanID string = "00f067aa0ba902b7"
+	TestTraceparent  string = "00-" + TestTraceID + "-" + TestParentSpanID + "-01"
+)
+
+type testSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		TraceID string
+		SpanID  string
+	}
+	Status struct {
+		Code string
+	}
+}
+
+func readSpans(t *testing.T, filename string) map[string]testSpan {
+	file, err := os.Open(filename)
+	require.NoError(t, err)
+	defer func() {
+		err = file.Close()
+		require.NoError(t, err)
+	}()
+
+	spans := map[string]testSpan{}
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for decoder.More() {
+		span := testSpan{}
+		err = decoder.Decode(&span)
+		require.NoError(t, err)
+		spans[span.Name] = span
+	}
+	return spans
+}
+
+func TestInitialize(t *testing.T) {
+	_, err := Initialize(context.Background(), "unknown", "", "")
+	assert.ErrorIs(t, err, ErrUnknownExporter)
+
+	_, err = Initialize(context.Background(), ExporterFile, "", "")
+	assert.ErrorIs(t, err, ErrEmptyFilename)
+
+	shutdown, err := Initialize(context.Background(), ExporterNone, "", "")
+	require.NoError(t, err)
+	err = shutdown(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestFileExporter(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "trace.json")
+
+	shutdown, err := Initialize(context.Background(), ExporterFile, "", filename)
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "handler")
+		defer span.End()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("traceparent", TestTraceparent)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	res := w.Result()
+	err = res.Body.Close()
+	require.NoError(t, err)
+
+	interceptorInfo := &grpc.UnaryServerInfo{FullMethod: "/shortener.Shortener/GetURL"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", TestTraceparent))
+	_, err = UnaryServerInterceptor(ctx, nil, interceptorInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.Equal(t, TestTraceID, trace.SpanContextFromContext(ctx).TraceID().String())
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	err = shutdown(context.Background())
+	require.NoError(t, err)
+
+	spans := readSpans(t, filename)
+	require.Len(t, spans, 3)
+
+	serverSpan, ok := spans["GET /{id}"]
+	require.True(t, ok)
+	assert.Equal(t, TestTraceID, serverSpan.SpanContext.TraceID)
+	assert.Equal(t, TestParentSpanID, serverSpan.Parent.SpanID)
+	assert.Equal(t, "Error", serverSpan.Status.Code)
+
+	handlerSpan, ok := spans["handler"]
+	require.True(t, ok)
+	assert.Equal(t, serverSpan.SpanContext.SpanID, handlerSpan.Parent.SpanID)
+
+	grpcSpan, ok := spans[interceptorInfo.FullMethod]
+	require.True(t, ok)
+	assert.Equal(t, TestTraceID, grpcSpan.SpanContext.TraceID)
+	assert.Equal(t, "Error", grpcSpan.Status.Code)
+}
@@ -0,0 +1,54 @@
+package trustedsubnet
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Used constants.
+const (
+	RealIPKey        string = "X-Real-IP"
+	TrustedSubnetKey string = "trusted_subnet"
+)
+
+// NewTrustedSubnetMiddleware creates middleware which allows requests
+// only from IP in X-Real-IP header that falls within trustedSubnet (CIDR).
+// All requests are forbidden if trustedSubnet is empty.
+func NewTrustedSubnetMiddleware(trustedSubnet string) (func(http.Handler) http.Handler, error) {
+	var ipNet *net.IPNet
+	if trustedSubnet != "" {
+		var err error
+		_, ipNet, err = net.ParseCIDR(trustedSubnet)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctxLogger := logger.GetContextLogger(r.Context())
+
+			if ipNet == nil {
+				ctxLogger.Warn("Trusted subnet is empty")
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			realIP := r.Header.Get(RealIPKey)
+			ip := net.ParseIP(realIP)
+			if ip == nil || !ipNet.Contains(ip) {
+				ctxLogger.Warn("IP is not in trusted subnet",
+					zap.String(RealIPKey, realIP),
+					zap.String(TrustedSubnetKey, trustedSubnet),
+				)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}, nil
+}
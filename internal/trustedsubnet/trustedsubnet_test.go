@@ -0,0 +1,79 @@
+package trustedsubnet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTrustedSubnetMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedSubnet  string
+		realIP         string
+		wantStatusCode int
+	}{
+		{
+			name:           "IP in trusted subnet",
+			trustedSubnet:  "192.168.1.0/24",
+			realIP:         "192.168.1.10",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "IP not in trusted subnet",
+			trustedSubnet:  "192.168.1.0/24",
+			realIP:         "10.0.0.1",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "no X-Real-IP",
+			trustedSubnet:  "192.168.1.0/24",
+			realIP:         "",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "invalid X-Real-IP",
+			trustedSubnet:  "192.168.1.0/24",
+			realIP:         "invalid",
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "empty trusted subnet",
+			trustedSubnet:  "",
+			realIP:         "192.168.1.10",
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware, err := NewTrustedSubnetMiddleware(tt.trustedSubnet)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			if tt.realIP != "" {
+				req.Header.Set(RealIPKey, tt.realIP)
+			}
+
+			w := httptest.NewRecorder()
+
+			middleware(handler).ServeHTTP(w, req)
+
+			res := w.Result()
+			err = res.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantStatusCode, res.StatusCode)
+		})
+	}
+
+	_, err := NewTrustedSubnetMiddleware("invalid")
+	assert.Error(t, err)
+}
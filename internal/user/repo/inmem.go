@@ -1,8 +1,13 @@
 package repo
 
 import (
+	"cmp"
+	"context"
+	"slices"
 	"sync"
 
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
 	"github.com/MisterMaks/go-yandex-shortener/internal/user"
 )
 
@@ -15,7 +20,8 @@ type UserRepoInmem struct {
 }
 
 // NewUserRepoInmem creates *NewUserRepoInmem and loads saved data from file.
-func NewUserRepoInmem(filename string) (*UserRepoInmem, error) {
+// File is synced to disk by syncPolicy.
+func NewUserRepoInmem(filename string, syncPolicy filestorage.SyncPolicy) (*UserRepoInmem, error) {
 	if filename == "" {
 		return &UserRepoInmem{
 			users:    []*user.User{},
@@ -25,27 +31,20 @@ func NewUserRepoInmem(filename string) (*UserRepoInmem, error) {
 		}, nil
 	}
 
-	consumer, err := newConsumer(filename)
+	users, err := readUsers(filename)
 	if err != nil {
 		return nil, err
 	}
-	users, err := consumer.readUsers()
-	if err != nil {
-		return nil, err
-	}
-	if err = consumer.close(); err != nil {
-		return nil, err
-	}
-	producer, err := newProducer(filename)
+	producer, err := newProducer(filename, syncPolicy)
 	if err != nil {
 		return nil, err
 	}
 
+	// users added with their IDs are saved after users with greater IDs
+	slices.SortFunc(users, compareUsers)
 	var maxID uint
-	for _, u := range users {
-		if u.ID > maxID {
-			maxID = u.ID
-		}
+	if len(users) > 0 {
+		maxID = users[len(users)-1].ID
 	}
 
 	return &UserRepoInmem{
@@ -65,8 +64,22 @@ func (uri *UserRepoInmem) Close() error {
 	return nil
 }
 
+// CountUsers returns count of users.
+func (uri *UserRepoInmem) CountUsers(ctx context.Context) (uint, error) {
+	_, span := tracing.Start(ctx, "UserRepoInmem.CountUsers")
+	defer span.End()
+
+	uri.mu.RLock()
+	defer uri.mu.RUnlock()
+
+	return uint(len(uri.users)), nil
+}
+
 // CreateUser creates new user.
-func (uri *UserRepoInmem) CreateUser() (*user.User, error) {
+func (uri *UserRepoInmem) CreateUser(ctx context.Context) (*user.User, error) {
+	_, span := tracing.Start(ctx, "UserRepoInmem.CreateUser")
+	defer span.End()
+
 	uri.mu.Lock()
 	defer uri.mu.Unlock()
 
@@ -83,3 +96,66 @@ func (uri *UserRepoInmem) CreateUser() (*user.User, error) {
 
 	return u, nil
 }
+
+// compareUsers compares users by ID.
+func compareUsers(a, b *user.User) int {
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// GetUsers returns page of users with IDs greater than afterID sorted by ID.
+// Zero limit means without limit.
+func (uri *UserRepoInmem) GetUsers(ctx context.Context, afterID, limit uint) ([]*user.User, error) {
+	_, span := tracing.Start(ctx, "UserRepoInmem.GetUsers")
+	defer span.End()
+
+	uri.mu.RLock()
+	defer uri.mu.RUnlock()
+
+	i, found := slices.BinarySearchFunc(uri.users, &user.User{ID: afterID}, compareUsers)
+	if found {
+		i++
+	}
+	users := uri.users[i:]
+	if limit > 0 && uint(len(users)) > limit {
+		users = users[:limit]
+	}
+
+	page := make([]*user.User, 0, len(users))
+	for _, u := range users {
+		page = append(page, &user.User{ID: u.ID})
+	}
+	return page, nil
+}
+
+// AddUsers saves users with their IDs. Existing users are skipped.
+// Next created user gets ID greater than IDs of added users.
+func (uri *UserRepoInmem) AddUsers(ctx context.Context, users []*user.User) error {
+	_, span := tracing.Start(ctx, "UserRepoInmem.AddUsers")
+	defer span.End()
+
+	uri.mu.Lock()
+	defer uri.mu.Unlock()
+
+	for _, u := range users {
+		i, found := slices.BinarySearchFunc(uri.users, u, compareUsers)
+		if found {
+			continue
+		}
+
+		newUser := &user.User{ID: u.ID}
+		uri.users = slices.Insert(uri.users, i, newUser)
+		if newUser.ID > uri.maxID {
+			uri.maxID = newUser.ID
+		}
+
+		if uri.producer != nil {
+			err := uri.producer.writeUser(newUser)
+			if err != nil {
+				tracing.RecordError(span, err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,11 +1,16 @@
 package repo
 
 import (
+	"context"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 	"github.com/MisterMaks/go-yandex-shortener/internal/user"
+	"github.com/MisterMaks/go-yandex-shortener/internal/user/repo/repotest"
+	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -22,11 +27,11 @@ func TestNewUserRepoInmem(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	r, err := NewUserRepoInmem(tmpFile.Name())
+	r, err := NewUserRepoInmem(tmpFile.Name(), filestorage.SyncPolicy{})
 	assert.NoError(t, err)
 	assert.NotNil(t, r)
 
-	r, err = NewUserRepoInmem("")
+	r, err = NewUserRepoInmem("", filestorage.SyncPolicy{})
 	assert.NoError(t, err)
 	assert.Equal(t, &UserRepoInmem{
 		users:    []*user.User{},
@@ -36,6 +41,41 @@ func TestNewUserRepoInmem(t *testing.T) {
 	}, r)
 }
 
+func TestNewUserRepoInmem_TornTail(t *testing.T) {
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "users.json")
+
+	r, err := NewUserRepoInmem(filename, filestorage.SyncPolicy{})
+	require.NoError(t, err)
+	_, err = r.CreateUser(ctx)
+	require.NoError(t, err)
+	err = r.Close()
+	require.NoError(t, err)
+
+	// crash while user was written
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"ID":`)
+	require.NoError(t, err)
+	err = file.Close()
+	require.NoError(t, err)
+
+	r, err = NewUserRepoInmem(filename, filestorage.SyncPolicy{})
+	require.NoError(t, err)
+	u, err := r.CreateUser(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), u.ID)
+	err = r.Close()
+	require.NoError(t, err)
+
+	r, err = NewUserRepoInmem(filename, filestorage.SyncPolicy{})
+	require.NoError(t, err)
+	defer func() { err = r.Close(); require.NoError(t, err) }()
+	count, err := r.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
 func TestUserRepoInmem_Close(t *testing.T) {
 	tmpFile, err := os.CreateTemp("", TestFilenamePattern)
 	require.NoError(t, err)
@@ -44,7 +84,7 @@ func TestUserRepoInmem_Close(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	r, err := NewUserRepoInmem(tmpFile.Name())
+	r, err := NewUserRepoInmem(tmpFile.Name(), filestorage.SyncPolicy{})
 	require.NoError(t, err)
 	assert.NotNil(t, r)
 
@@ -60,11 +100,67 @@ func TestUserRepoInmem_CreateUser(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	r, err := NewUserRepoInmem(tmpFile.Name())
+	r, err := NewUserRepoInmem(tmpFile.Name(), filestorage.SyncPolicy{})
 	require.NoError(t, err)
 	assert.NotNil(t, r)
 
-	u, err := r.CreateUser()
+	u, err := r.CreateUser(context.Background())
 	require.NoError(t, err)
 	assert.NotNil(t, u)
 }
+
+func TestUserRepoInmem_CountUsers(t *testing.T) {
+	r, err := NewUserRepoInmem("", filestorage.SyncPolicy{})
+	require.NoError(t, err)
+
+	count, err := r.CountUsers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), count)
+
+	_, err = r.CreateUser(context.Background())
+	require.NoError(t, err)
+	_, err = r.CreateUser(context.Background())
+	require.NoError(t, err)
+
+	count, err = r.CountUsers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
+func TestUserRepoInmem_Conformance(t *testing.T) {
+	repotest.RunUserRepoSuite(t, func(t *testing.T) usecase.UserRepoInterface {
+		r, err := NewUserRepoInmem(filepath.Join(t.TempDir(), "users.json"), filestorage.SyncPolicy{Mode: filestorage.SyncAlways})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			err := r.Close()
+			assert.NoError(t, err)
+		})
+		return r
+	})
+}
+
+func TestUserRepoInmem_AddUsers(t *testing.T) {
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "users.json")
+
+	r, err := NewUserRepoInmem(filename, filestorage.SyncPolicy{})
+	require.NoError(t, err)
+	_, err = r.CreateUser(ctx)
+	require.NoError(t, err)
+	err = r.AddUsers(ctx, []*user.User{{ID: 5}, {ID: 3}})
+	require.NoError(t, err)
+	err = r.Close()
+	require.NoError(t, err)
+
+	// users are sorted by ID after they are loaded from file
+	r, err = NewUserRepoInmem(filename, filestorage.SyncPolicy{})
+	require.NoError(t, err)
+	defer func() { err = r.Close(); require.NoError(t, err) }()
+	users, err := r.GetUsers(ctx, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []*user.User{{ID: 1}, {ID: 3}, {ID: 5}}, users)
+
+	u, err := r.CreateUser(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(6), u.ID)
+}
@@ -1,33 +1,154 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
 	"github.com/MisterMaks/go-yandex-shortener/internal/user"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// startQuerySpan creates span of DB query executed by repo method operation.
+func startQuerySpan(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "UserRepoPostgres."+operation,
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(query),
+	)
+}
+
+// withQueryTimeout returns ctx which is canceled after timeout, zero timeout means no timeout.
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 // UserRepoPostgres user data storage in PostgreSQL.
 type UserRepoPostgres struct {
-	db *sql.DB
+	db           *sql.DB
+	queryTimeout time.Duration // max duration of one query
 }
 
 // NewUserRepoPostgres creates *UserRepoPostgres.
-func NewUserRepoPostgres(db *sql.DB) (*UserRepoPostgres, error) {
-	return &UserRepoPostgres{db: db}, nil
+func NewUserRepoPostgres(db *sql.DB, queryTimeout time.Duration) (*UserRepoPostgres, error) {
+	return &UserRepoPostgres{db: db, queryTimeout: queryTimeout}, nil
 }
 
 // CreateUser create user in DB.
-func (urp *UserRepoPostgres) CreateUser() (*user.User, error) {
+func (urp *UserRepoPostgres) CreateUser(ctx context.Context) (*user.User, error) {
 	query := `INSERT INTO "user" DEFAULT VALUES RETURNING id;`
+	ctx, span := startQuerySpan(ctx, "CreateUser", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, urp.queryTimeout)
+	defer cancel()
+
 	var id uint
-	err := urp.db.QueryRow(query).Scan(&id)
+	err := urp.db.QueryRowContext(ctx, query).Scan(&id)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 	u := &user.User{ID: id}
 	return u, nil
 }
 
+// CountUsers returns count of users in DB.
+func (urp *UserRepoPostgres) CountUsers(ctx context.Context) (uint, error) {
+	query := `SELECT COUNT(*) FROM "user";`
+	ctx, span := startQuerySpan(ctx, "CountUsers", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, urp.queryTimeout)
+	defer cancel()
+
+	var count uint
+	err := urp.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetUsers returns page of users with IDs greater than afterID sorted by ID from DB.
+// Zero limit means without limit.
+func (urp *UserRepoPostgres) GetUsers(ctx context.Context, afterID, limit uint) ([]*user.User, error) {
+	query := `SELECT id FROM "user" WHERE id > $1 ORDER BY id`
+	args := []interface{}{afterID}
+	if limit > 0 {
+		args = append(args, limit)
+		query += ` LIMIT $2`
+	}
+	query += ";"
+
+	ctx, span := startQuerySpan(ctx, "GetUsers", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, urp.queryTimeout)
+	defer cancel()
+
+	rows, err := urp.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*user.User{}
+	for rows.Next() {
+		u := &user.User{}
+		err = rows.Scan(&u.ID)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// AddUsers saves users with their IDs in DB. Existing users are skipped.
+// Sequence of IDs is moved after added users, so next created user gets greater ID.
+func (urp *UserRepoPostgres) AddUsers(ctx context.Context, users []*user.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	query := `WITH inserted AS (INSERT INTO "user" (id) VALUES `
+	args := make([]interface{}, 0, len(users)+1)
+	var maxID uint
+	for i, u := range users {
+		if i > 0 {
+			query += ", "
+		}
+		query += fmt.Sprintf("($%d)", i+1)
+		args = append(args, u.ID)
+		maxID = max(maxID, u.ID)
+	}
+	args = append(args, maxID)
+	// inserted rows are not visible in the same statement, so max of added IDs is passed
+	query += fmt.Sprintf(` ON CONFLICT (id) DO NOTHING) 
+SELECT setval(pg_get_serial_sequence('"user"', 'id'), GREATEST($%d, (SELECT COALESCE(MAX(id), 0) FROM "user"), 1));`, len(args))
+
+	ctx, span := startQuerySpan(ctx, "AddUsers", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, urp.queryTimeout)
+	defer cancel()
+
+	_, err := urp.db.ExecContext(ctx, query, args...)
+	tracing.RecordError(span, err)
+	return err
+}
+
 // Close finishes working with the db.
 func (urp *UserRepoPostgres) Close() error {
 	return urp.db.Close()
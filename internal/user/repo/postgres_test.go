@@ -5,15 +5,22 @@ import (
 	"database/sql"
 	"os"
 	"testing"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/user/repo/repotest"
+	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
 )
 
 var DSN = os.Getenv("TEST_DATABASE_URI")
 
+// TestQueryTimeout is timeout of queries in tests.
+const TestQueryTimeout = 5 * time.Second
+
 func upMigrations(dsn string) error {
 	db, err := goose.OpenDBWithDriver("postgres", dsn)
 	if err != nil {
@@ -81,7 +88,7 @@ func TestNewUserRepoPostgres(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	_, err := NewUserRepoPostgres(te.DB)
+	_, err := NewUserRepoPostgres(te.DB, TestQueryTimeout)
 	assert.NoError(t, err, "Failed to run NewUserRepoPostgres()")
 }
 
@@ -89,21 +96,49 @@ func TestAppRepo_CreateUser(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	userRepo, err := NewUserRepoPostgres(te.DB)
+	userRepo, err := NewUserRepoPostgres(te.DB, TestQueryTimeout)
 	require.NoError(t, err, "Failed to run NewUserRepoPostgres()")
 
-	u, err := userRepo.CreateUser()
+	u, err := userRepo.CreateUser(context.Background())
 	require.NoError(t, err)
 	assert.NotNil(t, u)
 }
 
+func TestUserRepoPostgres_CountUsers(t *testing.T) {
+	te := newTestEnvironment(DSN, t)
+	defer te.clean()
+
+	userRepo, err := NewUserRepoPostgres(te.DB, TestQueryTimeout)
+	require.NoError(t, err, "Failed to run NewUserRepoPostgres()")
+
+	_, err = userRepo.CreateUser(context.Background())
+	require.NoError(t, err)
+	_, err = userRepo.CreateUser(context.Background())
+	require.NoError(t, err)
+
+	count, err := userRepo.CountUsers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
 func TestUserRepoPostgres_Close(t *testing.T) {
 	te := newTestEnvironment(DSN, t)
 	defer te.clean()
 
-	userRepo, err := NewUserRepoPostgres(te.DB)
+	userRepo, err := NewUserRepoPostgres(te.DB, TestQueryTimeout)
 	require.NoError(t, err, "Failed to run NewUserRepoPostgres()")
 
 	err = userRepo.Close()
 	assert.NoError(t, err)
 }
+
+func TestUserRepoPostgres_Conformance(t *testing.T) {
+	repotest.RunUserRepoSuite(t, func(t *testing.T) usecase.UserRepoInterface {
+		te := newTestEnvironment(DSN, t)
+		t.Cleanup(te.clean)
+
+		r, err := NewUserRepoPostgres(te.DB, TestQueryTimeout)
+		require.NoError(t, err, "Failed to run NewUserRepoPostgres()")
+		return r
+	})
+}
@@ -2,23 +2,38 @@ package repo
 
 import (
 	"database/sql"
+	"time"
 
+	"github.com/MisterMaks/go-yandex-shortener/internal/database"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
 )
 
 // NewUserRepo init repo.
-func NewUserRepo(db *sql.DB, filename string) (usecase.UserRepoInterface, error) {
+// Repo is in-memory with file storage if db is nil, otherwise repo is selected by DB driver.
+func NewUserRepo(
+	db *sql.DB,
+	driver string,
+	queryTimeout time.Duration,
+	filename string,
+	syncPolicy filestorage.SyncPolicy,
+) (usecase.UserRepoInterface, error) {
 	var userRepo usecase.UserRepoInterface
 	var err error
 
-	switch db {
-	case nil:
-		userRepo, err = NewUserRepoInmem(filename)
+	switch {
+	case db == nil:
+		userRepo, err = NewUserRepoInmem(filename, syncPolicy)
+		if err != nil {
+			return nil, err
+		}
+	case driver == database.DriverSQLite:
+		userRepo, err = NewUserRepoSQLite(db, queryTimeout)
 		if err != nil {
 			return nil, err
 		}
 	default:
-		userRepo, err = NewUserRepoPostgres(db)
+		userRepo, err = NewUserRepoPostgres(db, queryTimeout)
 		if err != nil {
 			return nil, err
 		}
@@ -5,10 +5,13 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/database"
+	"github.com/MisterMaks/go-yandex-shortener/internal/filestorage"
 )
 
 func TestNewUserRepo(t *testing.T) {
-	r, err := NewUserRepo(nil, "")
+	r, err := NewUserRepo(nil, "", 0, "", filestorage.SyncPolicy{})
 	assert.NoError(t, err)
 	assert.NotNil(t, r)
 
@@ -16,10 +19,17 @@ func TestNewUserRepo(t *testing.T) {
 	assert.True(t, ok)
 
 	db := &sql.DB{}
-	r, err = NewUserRepo(db, "")
+	r, err = NewUserRepo(db, database.DriverPostgres, 0, "", filestorage.SyncPolicy{})
 	assert.NoError(t, err)
 	assert.NotNil(t, r)
 
 	_, ok = r.(*UserRepoPostgres)
 	assert.True(t, ok)
+
+	r, err = NewUserRepo(db, database.DriverSQLite, 0, "", filestorage.SyncPolicy{})
+	assert.NoError(t, err)
+	assert.NotNil(t, r)
+
+	_, ok = r.(*UserRepoSQLite)
+	assert.True(t, ok)
 }
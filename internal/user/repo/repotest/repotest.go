@@ -0,0 +1,141 @@
+// Package repotest provides conformance suite for implementations of usecase.UserRepoInterface.
+package repotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/user"
+	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
+)
+
+// NewUserRepoFunc creates empty storage for one test.
+// Func must release storage with t.Cleanup.
+type NewUserRepoFunc func(t *testing.T) usecase.UserRepoInterface
+
+// RunUserRepoSuite runs conformance tests of user storage.
+func RunUserRepoSuite(t *testing.T, newRepo NewUserRepoFunc) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, newRepo NewUserRepoFunc)
+	}{
+		{name: "CreateUser", run: testCreateUser},
+		{name: "CountUsers", run: testCountUsers},
+		{name: "GetUsers", run: testGetUsers},
+		{name: "AddUsers", run: testAddUsers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo)
+		})
+	}
+}
+
+func testCreateUser(t *testing.T, newRepo NewUserRepoFunc) {
+	ctx := context.Background()
+	r := newRepo(t)
+
+	// IDs of users are positive and increase
+	var lastID uint
+	for i := 0; i < 3; i++ {
+		u, err := r.CreateUser(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, u)
+		assert.Greater(t, u.ID, lastID)
+		lastID = u.ID
+	}
+}
+
+func testCountUsers(t *testing.T, newRepo NewUserRepoFunc) {
+	ctx := context.Background()
+	r := newRepo(t)
+
+	count, err := r.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), count)
+
+	for i := 0; i < 2; i++ {
+		_, err = r.CreateUser(ctx)
+		require.NoError(t, err)
+	}
+
+	count, err = r.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
+// userIDs returns IDs of users in the same order.
+func userIDs(users []*user.User) []uint {
+	ids := make([]uint, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func testGetUsers(t *testing.T, newRepo NewUserRepoFunc) {
+	ctx := context.Background()
+	r := newRepo(t)
+
+	users, err := r.GetUsers(ctx, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	ids := make([]uint, 0, 3)
+	for i := 0; i < 3; i++ {
+		u, err := r.CreateUser(ctx)
+		require.NoError(t, err)
+		ids = append(ids, u.ID)
+	}
+
+	tests := []struct {
+		name    string
+		afterID uint
+		limit   uint
+		wantIDs []uint
+	}{
+		{name: "all users", afterID: 0, limit: 0, wantIDs: ids},
+		{name: "first page", afterID: 0, limit: 2, wantIDs: ids[:2]},
+		{name: "next page", afterID: ids[1], limit: 2, wantIDs: ids[2:]},
+		{name: "after last user", afterID: ids[2], limit: 2, wantIDs: []uint{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := r.GetUsers(ctx, tt.afterID, tt.limit)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantIDs, userIDs(users))
+		})
+	}
+}
+
+func testAddUsers(t *testing.T, newRepo NewUserRepoFunc) {
+	ctx := context.Background()
+	r := newRepo(t)
+
+	err := r.AddUsers(ctx, []*user.User{})
+	require.NoError(t, err)
+
+	err = r.AddUsers(ctx, []*user.User{{ID: 5}, {ID: 2}})
+	require.NoError(t, err)
+	// existing users are skipped
+	err = r.AddUsers(ctx, []*user.User{{ID: 2}, {ID: 3}})
+	require.NoError(t, err)
+
+	users, err := r.GetUsers(ctx, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{2, 3, 5}, userIDs(users))
+
+	count, err := r.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), count)
+
+	// created user does not reuse ID of added user
+	u, err := r.CreateUser(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, u.ID, uint(5))
+}
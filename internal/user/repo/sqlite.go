@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/tracing"
+	"github.com/MisterMaks/go-yandex-shortener/internal/user"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSQLiteQuerySpan creates span of SQLite query executed by repo method operation.
+func startSQLiteQuerySpan(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "UserRepoSQLite."+operation,
+		semconv.DBSystemSqlite,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(query),
+	)
+}
+
+// UserRepoSQLite user data storage in embedded SQLite DB.
+type UserRepoSQLite struct {
+	db           *sql.DB
+	queryTimeout time.Duration // max duration of one query
+}
+
+// NewUserRepoSQLite creates *UserRepoSQLite.
+func NewUserRepoSQLite(db *sql.DB, queryTimeout time.Duration) (*UserRepoSQLite, error) {
+	return &UserRepoSQLite{db: db, queryTimeout: queryTimeout}, nil
+}
+
+// CreateUser create user in DB.
+func (urs *UserRepoSQLite) CreateUser(ctx context.Context) (*user.User, error) {
+	query := `INSERT INTO "user" DEFAULT VALUES RETURNING id;`
+	ctx, span := startSQLiteQuerySpan(ctx, "CreateUser", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, urs.queryTimeout)
+	defer cancel()
+
+	var id uint
+	err := urs.db.QueryRowContext(ctx, query).Scan(&id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	u := &user.User{ID: id}
+	return u, nil
+}
+
+// CountUsers returns count of users in DB.
+func (urs *UserRepoSQLite) CountUsers(ctx context.Context) (uint, error) {
+	query := `SELECT COUNT(*) FROM "user";`
+	ctx, span := startSQLiteQuerySpan(ctx, "CountUsers", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, urs.queryTimeout)
+	defer cancel()
+
+	var count uint
+	err := urs.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetUsers returns page of users with IDs greater than afterID sorted by ID from DB.
+// Zero limit means without limit.
+func (urs *UserRepoSQLite) GetUsers(ctx context.Context, afterID, limit uint) ([]*user.User, error) {
+	query := `SELECT id FROM "user" WHERE id > ? ORDER BY id`
+	args := []interface{}{afterID}
+	if limit > 0 {
+		args = append(args, limit)
+		query += ` LIMIT ?`
+	}
+	query += ";"
+
+	ctx, span := startSQLiteQuerySpan(ctx, "GetUsers", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, urs.queryTimeout)
+	defer cancel()
+
+	rows, err := urs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*user.User{}
+	for rows.Next() {
+		u := &user.User{}
+		err = rows.Scan(&u.ID)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// AddUsers saves users with their IDs in DB. Existing users are skipped.
+// AUTOINCREMENT counter is moved by inserted IDs, so next created user gets greater ID.
+func (urs *UserRepoSQLite) AddUsers(ctx context.Context, users []*user.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	query := `INSERT OR IGNORE INTO "user" (id) VALUES ` + strings.TrimSuffix(strings.Repeat("(?), ", len(users)), ", ") + ";"
+	args := make([]interface{}, 0, len(users))
+	for _, u := range users {
+		args = append(args, u.ID)
+	}
+
+	ctx, span := startSQLiteQuerySpan(ctx, "AddUsers", query)
+	defer span.End()
+	ctx, cancel := withQueryTimeout(ctx, urs.queryTimeout)
+	defer cancel()
+
+	_, err := urs.db.ExecContext(ctx, query, args...)
+	tracing.RecordError(span, err)
+	return err
+}
+
+// Close finishes working with the db.
+func (urs *UserRepoSQLite) Close() error {
+	return urs.db.Close()
+}
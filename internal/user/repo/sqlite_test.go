@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/database"
+	"github.com/MisterMaks/go-yandex-shortener/internal/user/repo/repotest"
+	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase"
+	"github.com/MisterMaks/go-yandex-shortener/migrations"
+)
+
+func TestUserRepoSQLite(t *testing.T) {
+	db, _, err := database.Open(database.SQLiteScheme + filepath.Join(t.TempDir(), "shortener.db"))
+	require.NoError(t, err)
+
+	fsys, err := fs.Sub(migrations.FS, migrations.SQLiteDir)
+	require.NoError(t, err)
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, fsys)
+	require.NoError(t, err)
+	_, err = provider.Up(context.Background())
+	require.NoError(t, err, "Failed to apply migrations")
+
+	userRepo, err := NewUserRepoSQLite(db, TestQueryTimeout)
+	require.NoError(t, err, "Failed to run NewUserRepoSQLite()")
+
+	u, err := userRepo.CreateUser(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), u.ID)
+
+	u, err = userRepo.CreateUser(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), u.ID)
+
+	count, err := userRepo.CountUsers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+
+	err = userRepo.Close()
+	assert.NoError(t, err)
+}
+
+func TestUserRepoSQLite_Conformance(t *testing.T) {
+	repotest.RunUserRepoSuite(t, func(t *testing.T) usecase.UserRepoInterface {
+		db, _, err := database.Open(database.SQLiteScheme + filepath.Join(t.TempDir(), "shortener.db"))
+		require.NoError(t, err)
+
+		fsys, err := fs.Sub(migrations.FS, migrations.SQLiteDir)
+		require.NoError(t, err)
+		provider, err := goose.NewProvider(goose.DialectSQLite3, db, fsys)
+		require.NoError(t, err)
+		_, err = provider.Up(context.Background())
+		require.NoError(t, err, "Failed to apply migrations")
+
+		r, err := NewUserRepoSQLite(db, TestQueryTimeout)
+		require.NoError(t, err, "Failed to run NewUserRepoSQLite()")
+		t.Cleanup(func() {
+			err := r.Close()
+			assert.NoError(t, err)
+		})
+		return r
+	})
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AccessTokenMetadataKey is gRPC metadata key with JWT token.
+const AccessTokenMetadataKey string = "access_token"
+
+func getMetadataAccessToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(AccessTokenMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func withUserID(ctx context.Context, userID uint) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, userID)
+
+	ctxLogger := logger.GetContextLogger(ctx)
+	ctxLogger = ctxLogger.With(zap.Uint(string(UserIDKey), userID))
+	return context.WithValue(ctx, logger.LoggerKey, ctxLogger)
+}
+
+func newMethodsSet(fullMethods []string) map[string]struct{} {
+	methods := make(map[string]struct{}, len(fullMethods))
+	for _, fullMethod := range fullMethods {
+		methods[fullMethod] = struct{}{}
+	}
+	return methods
+}
+
+// AuthenticateOrRegisterInterceptor auths or registers user using JWT token in metadata.
+// It is applied only to gRPC methods from fullMethods.
+// Token of registered user is sent in response header.
+func (uu *UserUsecase) AuthenticateOrRegisterInterceptor(fullMethods ...string) grpc.UnaryServerInterceptor {
+	methods := newMethodsSet(fullMethods)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := methods[info.FullMethod]; !ok {
+			return handler(ctx, req)
+		}
+
+		userID, err := uu.getUserID(getMetadataAccessToken(ctx))
+		if err != nil {
+			u, err := uu.CreateUser(ctx)
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+
+			accessToken, err := uu.buildJWTString(u.ID)
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+
+			err = grpc.SetHeader(ctx, metadata.Pairs(AccessTokenMetadataKey, accessToken))
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+
+			userID = u.ID
+		}
+
+		return handler(withUserID(ctx, userID), req)
+	}
+}
+
+// AuthenticateInterceptor auths user using JWT token in metadata.
+// It is applied only to gRPC methods from fullMethods.
+func (uu *UserUsecase) AuthenticateInterceptor(fullMethods ...string) grpc.UnaryServerInterceptor {
+	methods := newMethodsSet(fullMethods)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := methods[info.FullMethod]; !ok {
+			return handler(ctx, req)
+		}
+
+		userID, err := uu.getUserID(getMetadataAccessToken(ctx))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		return handler(withUserID(ctx, userID), req)
+	}
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MisterMaks/go-yandex-shortener/internal/user"
+	"github.com/MisterMaks/go-yandex-shortener/internal/user/usecase/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testFullMethod      string = "/shortener.Shortener/Test"
+	testOtherFullMethod string = "/shortener.Shortener/Other"
+)
+
+// testServerTransportStream saves headers set by interceptor.
+type testServerTransportStream struct {
+	header metadata.MD
+}
+
+func (s *testServerTransportStream) Method() string {
+	return testFullMethod
+}
+
+func (s *testServerTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *testServerTransportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *testServerTransportStream) SetTrailer(_ metadata.MD) error {
+	return nil
+}
+
+func TestUserUsecase_AuthenticateOrRegisterInterceptor(t *testing.T) {
+	existingUserID := uint(1)
+	newUserID := uint(2)
+
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockUserRepoInterface(ctrl)
+	m.EXPECT().CreateUser(gomock.Any()).Return(&user.User{ID: newUserID}, nil).AnyTimes()
+
+	u, err := NewUserUsecase(m, "secretkey", time.Second)
+	require.NoError(t, err)
+
+	jwt, err := u.buildJWTString(existingUserID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		accessToken    string
+		expectedUserID uint
+		expectedHeader bool
+	}{
+		{
+			name:           "new user",
+			accessToken:    "",
+			expectedUserID: newUserID,
+			expectedHeader: true,
+		},
+		{
+			name:           "existed user",
+			accessToken:    jwt,
+			expectedUserID: existingUserID,
+			expectedHeader: false,
+		},
+		{
+			name:           "bad token",
+			accessToken:    "tratata",
+			expectedUserID: newUserID,
+			expectedHeader: true,
+		},
+	}
+
+	interceptor := u.AuthenticateOrRegisterInterceptor(testFullMethod)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &testServerTransportStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+			if tt.accessToken != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(AccessTokenMetadataKey, tt.accessToken))
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: testFullMethod},
+				func(ctx context.Context, _ interface{}) (interface{}, error) {
+					actualUserID, err := GetContextUserID(ctx)
+					require.NoError(t, err)
+					assert.Equal(t, tt.expectedUserID, actualUserID)
+					return nil, nil
+				},
+			)
+			require.NoError(t, err)
+
+			accessTokens := stream.header.Get(AccessTokenMetadataKey)
+			if !tt.expectedHeader {
+				assert.Empty(t, accessTokens)
+				return
+			}
+			require.Len(t, accessTokens, 1)
+			actualUserID, err := u.getUserID(accessTokens[0])
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedUserID, actualUserID)
+		})
+	}
+
+	t.Run("other method", func(t *testing.T) {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: testOtherFullMethod},
+			func(ctx context.Context, _ interface{}) (interface{}, error) {
+				_, err := GetContextUserID(ctx)
+				assert.Error(t, err)
+				return nil, nil
+			},
+		)
+		require.NoError(t, err)
+	})
+}
+
+func TestUserUsecase_AuthenticateInterceptor(t *testing.T) {
+	existingUserID := uint(1)
+
+	u, err := NewUserUsecase(nil, "secretkey", time.Second)
+	require.NoError(t, err)
+
+	jwt, err := u.buildJWTString(existingUserID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		accessToken  string
+		fullMethod   string
+		expectedCode codes.Code
+	}{
+		{
+			name:         "existed user",
+			accessToken:  jwt,
+			fullMethod:   testFullMethod,
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "no token",
+			accessToken:  "",
+			fullMethod:   testFullMethod,
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "bad token",
+			accessToken:  "tratata",
+			fullMethod:   testFullMethod,
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "other method",
+			accessToken:  "",
+			fullMethod:   testOtherFullMethod,
+			expectedCode: codes.OK,
+		},
+	}
+
+	interceptor := u.AuthenticateInterceptor(testFullMethod)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.accessToken != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(AccessTokenMetadataKey, tt.accessToken))
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.fullMethod},
+				func(ctx context.Context, _ interface{}) (interface{}, error) {
+					return nil, nil
+				},
+			)
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	user "github.com/MisterMaks/go-yandex-shortener/internal/user"
@@ -34,6 +35,20 @@ func (m *MockUserRepoInterface) EXPECT() *MockUserRepoInterfaceMockRecorder {
 	return m.recorder
 }
 
+// AddUsers mocks base method.
+func (m *MockUserRepoInterface) AddUsers(ctx context.Context, users []*user.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUsers", ctx, users)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddUsers indicates an expected call of AddUsers.
+func (mr *MockUserRepoInterfaceMockRecorder) AddUsers(ctx, users interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUsers", reflect.TypeOf((*MockUserRepoInterface)(nil).AddUsers), ctx, users)
+}
+
 // Close mocks base method.
 func (m *MockUserRepoInterface) Close() error {
 	m.ctrl.T.Helper()
@@ -48,17 +63,47 @@ func (mr *MockUserRepoInterfaceMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockUserRepoInterface)(nil).Close))
 }
 
+// CountUsers mocks base method.
+func (m *MockUserRepoInterface) CountUsers(ctx context.Context) (uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsers", ctx)
+	ret0, _ := ret[0].(uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsers indicates an expected call of CountUsers.
+func (mr *MockUserRepoInterfaceMockRecorder) CountUsers(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsers", reflect.TypeOf((*MockUserRepoInterface)(nil).CountUsers), ctx)
+}
+
 // CreateUser mocks base method.
-func (m *MockUserRepoInterface) CreateUser() (*user.User, error) {
+func (m *MockUserRepoInterface) CreateUser(ctx context.Context) (*user.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser")
+	ret := m.ctrl.Call(m, "CreateUser", ctx)
 	ret0, _ := ret[0].(*user.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockUserRepoInterfaceMockRecorder) CreateUser() *gomock.Call {
+func (mr *MockUserRepoInterfaceMockRecorder) CreateUser(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepoInterface)(nil).CreateUser), ctx)
+}
+
+// GetUsers mocks base method.
+func (m *MockUserRepoInterface) GetUsers(ctx context.Context, afterID, limit uint) ([]*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsers", ctx, afterID, limit)
+	ret0, _ := ret[0].([]*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *MockUserRepoInterfaceMockRecorder) GetUsers(ctx, afterID, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepoInterface)(nil).CreateUser))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockUserRepoInterface)(nil).GetUsers), ctx, afterID, limit)
 }
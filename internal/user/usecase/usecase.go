@@ -29,7 +29,10 @@ type Claims struct {
 
 // UserRepoInterface contains the necessary functions for storage.
 type UserRepoInterface interface {
-	CreateUser() (*user.User, error)
+	CreateUser(ctx context.Context) (*user.User, error)
+	CountUsers(ctx context.Context) (uint, error)
+	GetUsers(ctx context.Context, afterID, limit uint) ([]*user.User, error) // get page of users sorted by ID
+	AddUsers(ctx context.Context, users []*user.User) error                 // save users with their IDs, existing users are skipped
 	Close() error
 }
 
@@ -89,8 +92,13 @@ func (uu *UserUsecase) getUserID(tokenString string) (uint, error) {
 }
 
 // CreateUser create user.
-func (uu *UserUsecase) CreateUser() (*user.User, error) {
-	return uu.UserRepo.CreateUser()
+func (uu *UserUsecase) CreateUser(ctx context.Context) (*user.User, error) {
+	return uu.UserRepo.CreateUser(ctx)
+}
+
+// CountUsers returns count of users.
+func (uu *UserUsecase) CountUsers(ctx context.Context) (uint, error) {
+	return uu.UserRepo.CountUsers(ctx)
 }
 
 // AuthenticateOrRegister auths or registers user using JWT token in Cookie.
@@ -104,7 +112,7 @@ func (uu *UserUsecase) AuthenticateOrRegister(h http.Handler) http.Handler {
 		var accessToken string
 
 		if err != nil {
-			u, err = uu.CreateUser()
+			u, err = uu.CreateUser(r.Context())
 			if err != nil {
 				w.WriteHeader(http.StatusBadRequest)
 				return
@@ -130,7 +138,7 @@ func (uu *UserUsecase) AuthenticateOrRegister(h http.Handler) http.Handler {
 		value := cookie.Value
 		userID, err := uu.getUserID(value)
 		if err != nil {
-			u, err = uu.CreateUser()
+			u, err = uu.CreateUser(r.Context())
 			if err != nil {
 				w.WriteHeader(http.StatusBadRequest)
 				return
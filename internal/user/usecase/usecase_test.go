@@ -48,16 +48,33 @@ func TestUserUsecase_CreateUser(t *testing.T) {
 
 	// создаём объект-заглушку
 	m := mocks.NewMockUserRepoInterface(ctrl)
-	m.EXPECT().CreateUser().Return(testUser, nil)
+	m.EXPECT().CreateUser(gomock.Any()).Return(testUser, nil)
 
 	u, err := NewUserUsecase(m, "secretkey", time.Second)
 	require.NoError(t, err)
 
-	actualUser, err := u.CreateUser()
+	actualUser, err := u.CreateUser(context.Background())
 	require.NoError(t, err)
 	assert.Equal(t, testUser, actualUser)
 }
 
+func TestUserUsecase_CountUsers(t *testing.T) {
+	// создаём контроллер
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// создаём объект-заглушку
+	m := mocks.NewMockUserRepoInterface(ctrl)
+	m.EXPECT().CountUsers(gomock.Any()).Return(uint(2), nil)
+
+	u, err := NewUserUsecase(m, "secretkey", time.Second)
+	require.NoError(t, err)
+
+	count, err := u.CountUsers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
 func TestGetContextUserID(t *testing.T) {
 	var ctx context.Context
 
@@ -92,7 +109,7 @@ func TestUserUsecase_AuthenticateOrRegister(t *testing.T) {
 
 	// создаём объект-заглушку
 	m := mocks.NewMockUserRepoInterface(ctrl)
-	m.EXPECT().CreateUser().Return(newUser, nil).AnyTimes()
+	m.EXPECT().CreateUser(gomock.Any()).Return(newUser, nil).AnyTimes()
 
 	u, err := NewUserUsecase(m, "secretkey", time.Second)
 	require.NoError(t, err)
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url ADD COLUMN expires_at timestamptz DEFAULT NULL;
+
+CREATE INDEX url_expires_at_idx ON url (expires_at) WHERE expires_at IS NOT NULL AND NOT is_deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX url_expires_at_idx;
+
+ALTER TABLE url DROP COLUMN expires_at;
+-- +goose StatementEnd
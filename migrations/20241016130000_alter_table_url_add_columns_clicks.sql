@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url ADD COLUMN clicks bigint DEFAULT 0 NOT NULL;
+
+ALTER TABLE url ADD COLUMN last_accessed_at timestamptz DEFAULT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url DROP COLUMN last_accessed_at;
+
+ALTER TABLE url DROP COLUMN clicks;
+-- +goose StatementEnd
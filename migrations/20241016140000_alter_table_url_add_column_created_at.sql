@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url ADD COLUMN created_at timestamptz DEFAULT now() NOT NULL;
+
+CREATE INDEX url_user_id_created_at_idx ON url (user_id, created_at, url_id COLLATE "C");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX url_user_id_created_at_idx;
+
+ALTER TABLE url DROP COLUMN created_at;
+-- +goose StatementEnd
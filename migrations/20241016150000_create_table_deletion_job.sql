@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE deletion_job (
+    id text PRIMARY KEY,
+    user_id integer NOT NULL,
+    url_ids jsonb NOT NULL,
+    status text NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    next_attempt_at timestamptz NOT NULL,
+    last_error text DEFAULT '' NOT NULL,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX deletion_job_pending_idx ON deletion_job (next_attempt_at, id) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE deletion_job;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url ADD COLUMN deleted_at timestamptz DEFAULT NULL;
+
+-- grace period of URLs deleted before time of deletion was stored starts now
+UPDATE url SET deleted_at = now() WHERE is_deleted;
+
+CREATE INDEX url_deleted_at_idx ON url (deleted_at) WHERE is_deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX url_deleted_at_idx;
+
+ALTER TABLE url DROP COLUMN deleted_at;
+-- +goose StatementEnd
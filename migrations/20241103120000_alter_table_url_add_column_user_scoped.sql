@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url ADD COLUMN user_scoped boolean DEFAULT false NOT NULL;
+
+-- original URL is unique among URLs which are not user scoped and among URLs of the same user,
+-- index keeps name of dropped constraint, so unique violations are reported in the same way
+ALTER TABLE url DROP CONSTRAINT url_url_key;
+
+CREATE UNIQUE INDEX url_url_key ON url (url) WHERE NOT user_scoped;
+
+ALTER TABLE url ADD CONSTRAINT url_user_id_url_key UNIQUE (user_id, url);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE url DROP CONSTRAINT url_user_id_url_key;
+
+DROP INDEX url_url_key;
+
+ALTER TABLE url ADD CONSTRAINT url_url_key UNIQUE (url);
+
+ALTER TABLE url DROP COLUMN user_scoped;
+-- +goose StatementEnd
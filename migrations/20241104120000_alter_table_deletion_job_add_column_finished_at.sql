@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE deletion_job ADD COLUMN finished_at timestamptz DEFAULT NULL;
+
+-- retention of jobs finished before time of finish was stored starts now
+UPDATE deletion_job SET finished_at = now() WHERE status <> 'pending';
+
+CREATE INDEX deletion_job_finished_at_idx ON deletion_job (finished_at) WHERE status <> 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX deletion_job_finished_at_idx;
+
+ALTER TABLE deletion_job DROP COLUMN finished_at;
+-- +goose StatementEnd
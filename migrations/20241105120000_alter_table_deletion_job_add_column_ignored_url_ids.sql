@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- URLs of jobs done before outcome of URLs was stored are reported as deleted
+ALTER TABLE deletion_job ADD COLUMN ignored_url_ids jsonb DEFAULT '[]' NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE deletion_job DROP COLUMN ignored_url_ids;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+// Package migrations contains SQL migrations of DB embedded in binary.
+package migrations
+
+import "embed"
+
+// Directories of migrations in FS.
+const (
+	PostgresDir string = "."      // migrations of PostgreSQL
+	SQLiteDir   string = "sqlite" // migrations of SQLite
+)
+
+// FS contains goose migrations.
+//
+//go:embed *.sql sqlite/*.sql
+var FS embed.FS
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE "user" (
+    id integer PRIMARY KEY AUTOINCREMENT
+);
+
+CREATE TABLE url (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    url text UNIQUE NOT NULL CHECK (url <> ''),
+    url_id text UNIQUE NOT NULL CHECK (url_id <> ''),
+    user_id integer DEFAULT NULL REFERENCES "user"(id),
+    is_deleted boolean DEFAULT false NOT NULL,
+    expires_at datetime DEFAULT NULL,
+    clicks integer DEFAULT 0 NOT NULL,
+    last_accessed_at datetime DEFAULT NULL,
+    created_at datetime NOT NULL
+);
+
+CREATE INDEX url_expires_at_idx ON url (expires_at) WHERE expires_at IS NOT NULL AND NOT is_deleted;
+
+CREATE INDEX url_user_id_created_at_idx ON url (user_id, created_at, url_id);
+
+CREATE TABLE deletion_job (
+    id text PRIMARY KEY,
+    user_id integer NOT NULL,
+    url_ids text NOT NULL,
+    status text NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    next_attempt_at datetime NOT NULL,
+    last_error text DEFAULT '' NOT NULL,
+    created_at datetime NOT NULL
+);
+
+CREATE INDEX deletion_job_pending_idx ON deletion_job (next_attempt_at, id) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE deletion_job;
+
+DROP TABLE url;
+
+DROP TABLE "user";
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE url ADD COLUMN deleted_at datetime DEFAULT NULL;
+
+-- grace period of URLs deleted before time of deletion was stored starts now,
+-- time is written in the same format as times written by app
+UPDATE url SET deleted_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now') WHERE is_deleted;
+
+CREATE INDEX url_deleted_at_idx ON url (deleted_at) WHERE is_deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX url_deleted_at_idx;
+
+ALTER TABLE url DROP COLUMN deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE url_edit (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    url_id text NOT NULL REFERENCES url(url_id) ON DELETE CASCADE,
+    old_url text NOT NULL,
+    new_url text NOT NULL,
+    edited_at datetime NOT NULL
+);
+
+CREATE INDEX url_edit_url_id_idx ON url_edit (url_id, edited_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE url_edit;
+-- +goose StatementEnd
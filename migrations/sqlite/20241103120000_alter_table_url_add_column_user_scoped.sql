@@ -0,0 +1,81 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- +goose StatementBegin
+-- unique constraint of column can not be dropped, so table is recreated,
+-- foreign keys are disabled to keep history of URLs which references dropped table
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+CREATE TABLE url_new (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    url text NOT NULL CHECK (url <> ''),
+    url_id text UNIQUE NOT NULL CHECK (url_id <> ''),
+    user_id integer DEFAULT NULL REFERENCES "user"(id),
+    user_scoped boolean DEFAULT false NOT NULL,
+    is_deleted boolean DEFAULT false NOT NULL,
+    expires_at datetime DEFAULT NULL,
+    clicks integer DEFAULT 0 NOT NULL,
+    last_accessed_at datetime DEFAULT NULL,
+    created_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL,
+    UNIQUE (user_id, url)
+);
+
+INSERT INTO url_new (id, url, url_id, user_id, is_deleted, expires_at, clicks, last_accessed_at, created_at, deleted_at)
+SELECT id, url, url_id, user_id, is_deleted, expires_at, clicks, last_accessed_at, created_at, deleted_at FROM url;
+
+DROP TABLE url;
+
+ALTER TABLE url_new RENAME TO url;
+
+-- original URL is unique among URLs which are not user scoped and among URLs of the same user
+CREATE UNIQUE INDEX url_url_key ON url (url) WHERE NOT user_scoped;
+
+CREATE INDEX url_expires_at_idx ON url (expires_at) WHERE expires_at IS NOT NULL AND NOT is_deleted;
+
+CREATE INDEX url_user_id_created_at_idx ON url (user_id, created_at, url_id);
+
+CREATE INDEX url_deleted_at_idx ON url (deleted_at) WHERE is_deleted;
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+CREATE TABLE url_new (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    url text UNIQUE NOT NULL CHECK (url <> ''),
+    url_id text UNIQUE NOT NULL CHECK (url_id <> ''),
+    user_id integer DEFAULT NULL REFERENCES "user"(id),
+    is_deleted boolean DEFAULT false NOT NULL,
+    expires_at datetime DEFAULT NULL,
+    clicks integer DEFAULT 0 NOT NULL,
+    last_accessed_at datetime DEFAULT NULL,
+    created_at datetime NOT NULL,
+    deleted_at datetime DEFAULT NULL
+);
+
+INSERT INTO url_new (id, url, url_id, user_id, is_deleted, expires_at, clicks, last_accessed_at, created_at, deleted_at)
+SELECT id, url, url_id, user_id, is_deleted, expires_at, clicks, last_accessed_at, created_at, deleted_at FROM url;
+
+DROP TABLE url;
+
+ALTER TABLE url_new RENAME TO url;
+
+CREATE INDEX url_expires_at_idx ON url (expires_at) WHERE expires_at IS NOT NULL AND NOT is_deleted;
+
+CREATE INDEX url_user_id_created_at_idx ON url (user_id, created_at, url_id);
+
+CREATE INDEX url_deleted_at_idx ON url (deleted_at) WHERE is_deleted;
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
+-- +goose StatementEnd